@@ -1,13 +1,13 @@
 package main
 
 import (
+	"api/internal/config"
 	"api/internal/server"
 	"context"
+	"flag"
 	"fmt"
 	"log"
-	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
@@ -22,11 +22,22 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 
 	log.Println("shutting down gracefully, press Ctrl+C again to force")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	fiberServer.StopScheduler()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if err := fiberServer.Drain(ctx); err != nil {
+		log.Printf("Error draining websocket connections: %v", err)
+	}
 	if err := fiberServer.ShutdownWithContext(ctx); err != nil {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
+	if err := fiberServer.ShutdownTracing(ctx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	if err := fiberServer.CloseDatabase(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
 
 	log.Println("Server exiting")
 
@@ -34,15 +45,34 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 }
 
 func main() {
-	server := server.New()
+	migrateOnly := flag.Bool("migrate", false, "apply pending database migrations and exit")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	server := server.New(cfg)
+
+	if *migrateOnly || cfg.Database.MigrateOnStart {
+		applied, err := server.Migrate(context.Background())
+		if err != nil {
+			log.Fatalf("Error applying migrations: %v", err)
+		}
+		log.Printf("Applied %d migration(s)", len(applied))
+
+		if *migrateOnly {
+			return
+		}
+	}
 
 	server.RegisterFiberRoutes()
 
 	done := make(chan bool, 1)
 
 	go func() {
-		port, _ := strconv.Atoi(os.Getenv("PORT"))
-		err := server.Listen(fmt.Sprintf(":%d", port))
+		err := server.Listen(fmt.Sprintf(":%d", cfg.Port))
 		if err != nil {
 			panic(fmt.Sprintf("http server error: %s", err))
 		}
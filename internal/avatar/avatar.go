@@ -0,0 +1,151 @@
+// Package avatar checks uploaded profile pictures and prepares them for
+// storage: the image is decoded from its actual content, re-encoded without
+// any metadata such as EXIF, and scaled down into square variants.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Sizes are the edge lengths, in pixels, of the square variants generated
+// for every upload.
+var Sizes = []int{64, 256}
+
+// maxPixels bounds the decoded size of an upload, so a small but highly
+// compressed file cannot exhaust memory.
+const maxPixels = 4096 * 4096
+
+const jpegQuality = 90
+
+var (
+	ErrTooLarge        = errors.New("avatar file is too large")
+	ErrUnsupportedType = errors.New("avatar is not a JPEG, PNG or GIF image")
+	ErrInvalidImage    = errors.New("avatar image could not be read")
+)
+
+// File is one encoded image ready to be stored.
+type File struct {
+	Data        []byte
+	ContentType string
+	// Ext is the file extension matching ContentType, including the dot.
+	Ext string
+}
+
+// Processed is an upload after processing: the cleaned original and one
+// variant per entry of Sizes, keyed by size.
+type Processed struct {
+	Original File
+	Variants map[int]File
+}
+
+// Process validates data as an avatar no larger than maxBytes and returns
+// the files to store. The content type is sniffed from the bytes; the
+// uploaded file name and declared type are not trusted.
+func Process(data []byte, maxBytes int) (Processed, error) {
+	if len(data) > maxBytes {
+		return Processed{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Processed{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return Processed{}, fmt.Errorf("%w: images may be at most %d pixels", ErrInvalidImage, maxPixels)
+	}
+
+	var original File
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Processed{}, ErrInvalidImage
+		}
+		// Re-encoding drops the EXIF block, so apply its rotation first.
+		img = orient(img, exifOrientation(data))
+		original, err = encodeJPEG(img)
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Processed{}, ErrInvalidImage
+		}
+		original, err = encodePNG(img)
+	case "image/gif":
+		// Keep animations intact; the encoder writes no comment or
+		// application blocks other than the loop count.
+		var anim *gif.GIF
+		anim, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(anim.Image) == 0 {
+			return Processed{}, ErrInvalidImage
+		}
+		img = anim.Image[0]
+		var buf bytes.Buffer
+		if err = gif.EncodeAll(&buf, anim); err == nil {
+			original = File{Data: buf.Bytes(), ContentType: "image/gif", Ext: ".gif"}
+		}
+	}
+	if err != nil {
+		return Processed{}, err
+	}
+
+	processed := Processed{Original: original, Variants: make(map[int]File, len(Sizes))}
+	for _, size := range Sizes {
+		thumb := thumbnail(img, size)
+		var variant File
+		if contentType == "image/jpeg" {
+			variant, err = encodeJPEG(thumb)
+		} else {
+			variant, err = encodePNG(thumb)
+		}
+		if err != nil {
+			return Processed{}, err
+		}
+		processed.Variants[size] = variant
+	}
+	return processed, nil
+}
+
+// VariantKey is the storage key of the size variant of the avatar stored
+// under key, e.g. "avatars/<id>_64.png" for "avatars/<id>.png". Variants of
+// GIFs are PNGs.
+func VariantKey(key string, size int) string {
+	ext := path.Ext(key)
+	variantExt := ext
+	if strings.EqualFold(ext, ".gif") {
+		variantExt = ".png"
+	}
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(key, ext), size, variantExt)
+}
+
+func encodeJPEG(img image.Image) (File, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return File{}, err
+	}
+	return File{Data: buf.Bytes(), ContentType: "image/jpeg", Ext: ".jpg"}, nil
+}
+
+func encodePNG(img image.Image) (File, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return File{}, err
+	}
+	return File{Data: buf.Bytes(), ContentType: "image/png", Ext: ".png"}, nil
+}
@@ -0,0 +1,253 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+const testMaxBytes = 1 << 20
+
+// halves is a w×h image, red on the left half and blue on the right.
+func halves(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.SetNRGBA(x, y, red)
+			} else {
+				img.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodeTestPNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeTestGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// resizedPNG claims new dimensions in the PNG's IHDR chunk, keeping its
+// checksum valid, without adding any pixel data.
+func resizedPNG(data []byte, w, h uint32) []byte {
+	out := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(out[16:], w)
+	binary.BigEndian.PutUint32(out[20:], h)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+// resizedGIF claims new logical screen dimensions.
+func resizedGIF(data []byte, w, h uint16) []byte {
+	out := append([]byte{}, data...)
+	binary.LittleEndian.PutUint16(out[6:], w)
+	binary.LittleEndian.PutUint16(out[8:], h)
+	return out
+}
+
+// resizedJPEG claims new dimensions in the baseline SOF0 segment.
+func resizedJPEG(data []byte, w, h uint16) []byte {
+	out := append([]byte{}, data...)
+	sof := bytes.Index(out, []byte{0xff, 0xc0})
+	binary.BigEndian.PutUint16(out[sof+5:], h)
+	binary.BigEndian.PutUint16(out[sof+7:], w)
+	return out
+}
+
+// isRed reports whether c is clearly red rather than blue, allowing for
+// JPEG's lossy compression.
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xc000 && b < 0x4000
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         []byte
+		contentType  string
+		variantsType string
+	}{
+		{name: "png", data: encodeTestPNG(t, halves(300, 200)), contentType: "image/png", variantsType: "image/png"},
+		{name: "jpeg", data: encodeTestJPEG(t, halves(300, 200)), contentType: "image/jpeg", variantsType: "image/jpeg"},
+		{name: "gif", data: encodeTestGIF(t, halves(300, 200)), contentType: "image/gif", variantsType: "image/png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := Process(tt.data, testMaxBytes)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if processed.Original.ContentType != tt.contentType {
+				t.Errorf("original content type = %s, want %s", processed.Original.ContentType, tt.contentType)
+			}
+
+			for _, size := range Sizes {
+				variant, ok := processed.Variants[size]
+				if !ok {
+					t.Fatalf("missing %dpx variant", size)
+				}
+				if variant.ContentType != tt.variantsType {
+					t.Errorf("%dpx variant content type = %s, want %s", size, variant.ContentType, tt.variantsType)
+				}
+				config, _, err := image.DecodeConfig(bytes.NewReader(variant.Data))
+				if err != nil {
+					t.Fatalf("decoding %dpx variant: %v", size, err)
+				}
+				if config.Width != size || config.Height != size {
+					t.Errorf("%dpx variant is %dx%d", size, config.Width, config.Height)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessOrientation(t *testing.T) {
+	plain := encodeTestJPEG(t, halves(32, 16))
+
+	// Which colour ends up in the top-left corner once the stored image,
+	// red left and blue right, is turned upright.
+	tests := []struct {
+		orientation uint16
+		topLeftRed  bool
+	}{
+		{orientation: 1, topLeftRed: true},
+		{orientation: 2, topLeftRed: false},
+		{orientation: 3, topLeftRed: false},
+		{orientation: 4, topLeftRed: true},
+		{orientation: 5, topLeftRed: true},
+		{orientation: 6, topLeftRed: true},
+		{orientation: 7, topLeftRed: false},
+		{orientation: 8, topLeftRed: false},
+	}
+
+	for _, tt := range tests {
+		data := withOrientation(plain, tt.orientation, binary.BigEndian)
+		processed, err := Process(data, testMaxBytes)
+		if err != nil {
+			t.Fatalf("Process(orientation %d) error = %v", tt.orientation, err)
+		}
+		if bytes.Contains(processed.Original.Data, []byte("Exif")) {
+			t.Errorf("orientation %d: EXIF block survived re-encoding", tt.orientation)
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(processed.Original.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.Pt(32, 16)
+		if tt.orientation >= 5 {
+			want = image.Pt(16, 32)
+		}
+		if size := img.Bounds().Size(); size != want {
+			t.Errorf("orientation %d: size = %v, want %v", tt.orientation, size, want)
+		}
+		if got := isRed(img.At(3, 3)); got != tt.topLeftRed {
+			t.Errorf("orientation %d: top-left red = %v, want %v", tt.orientation, got, tt.topLeftRed)
+		}
+	}
+}
+
+func TestProcessOversized(t *testing.T) {
+	small := halves(4, 4)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "png too wide", data: resizedPNG(encodeTestPNG(t, small), 4097, 4096)},
+		{name: "png huge", data: resizedPNG(encodeTestPNG(t, small), 1<<20, 1<<20)},
+		{name: "png one pixel tall", data: resizedPNG(encodeTestPNG(t, small), maxPixels+1, 1)},
+		{name: "gif", data: resizedGIF(encodeTestGIF(t, small), 65535, 65535)},
+		{name: "jpeg", data: resizedJPEG(encodeTestJPEG(t, small), 65535, 65535)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data, testMaxBytes)
+			if !errors.Is(err, ErrInvalidImage) || !strings.Contains(err.Error(), "pixels") {
+				t.Errorf("Process() error = %v, want the pixel limit", err)
+			}
+		})
+	}
+
+	// The file itself must be small enough before anything is decoded.
+	large := encodeTestPNG(t, halves(64, 64))
+	if _, err := Process(large, len(large)-1); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Process() error = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestProcessCorrupt(t *testing.T) {
+	pngData := encodeTestPNG(t, halves(64, 64))
+	jpegData := encodeTestJPEG(t, halves(64, 64))
+	gifData := encodeTestGIF(t, halves(64, 64))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "empty", data: nil, want: ErrUnsupportedType},
+		{name: "text", data: []byte("definitely not an image"), want: ErrUnsupportedType},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), want: ErrUnsupportedType},
+		{name: "png signature only", data: pngData[:8], want: ErrInvalidImage},
+		{name: "truncated png", data: pngData[:len(pngData)/2], want: ErrInvalidImage},
+		{name: "png cut inside its header", data: pngData[:29], want: ErrInvalidImage},
+		{name: "jpeg header only", data: jpegData[:4], want: ErrInvalidImage},
+		{name: "truncated jpeg", data: jpegData[:len(jpegData)/2], want: ErrInvalidImage},
+		{name: "gif header only", data: gifData[:6], want: ErrInvalidImage},
+		{name: "truncated gif", data: gifData[:len(gifData)/2], want: ErrInvalidImage},
+		{name: "zero size png", data: resizedPNG(pngData, 0, 0), want: ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Process(tt.data, testMaxBytes); !errors.Is(err, tt.want) {
+				t.Errorf("Process() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		size int
+		want string
+	}{
+		{key: "avatars/abc.png", size: 64, want: "avatars/abc_64.png"},
+		{key: "avatars/abc.jpg", size: 256, want: "avatars/abc_256.jpg"},
+		{key: "avatars/abc.gif", size: 64, want: "avatars/abc_64.png"},
+		{key: "avatars/abc.GIF", size: 64, want: "avatars/abc_64.png"},
+	}
+
+	for _, tt := range tests {
+		if got := VariantKey(tt.key, tt.size); got != tt.want {
+			t.Errorf("VariantKey(%q, %d) = %q, want %q", tt.key, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package avatar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{R: 0xff, A: 0xff}
+	blue = color.NRGBA{B: 0xff, A: 0xff}
+)
+
+// withOrientation inserts an EXIF APP1 segment holding just the orientation
+// tag straight after the JPEG's SOI marker.
+func withOrientation(data []byte, orientation uint16, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	return withAPP1(data, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func withAPP1(data, segment []byte) []byte {
+	header := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func encodeTestJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExifOrientation(t *testing.T) {
+	plain := encodeTestJPEG(t, image.NewNRGBA(image.Rect(0, 0, 8, 8)))
+
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := exifOrientation(withOrientation(plain, orientation, order)); got != int(orientation) {
+				t.Errorf("exifOrientation(%d, %v) = %d", orientation, order, got)
+			}
+		}
+	}
+
+	badOffset := withOrientation(plain, 6, binary.BigEndian)
+	// The IFD offset sits 4 bytes into the TIFF header, which starts after
+	// SOI, the APP1 header and "Exif\0\0".
+	binary.BigEndian.PutUint32(badOffset[2+4+6+4:], 0xffff)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "no exif", data: plain},
+		{name: "empty", data: nil},
+		{name: "only soi", data: plain[:2]},
+		{name: "truncated segment", data: withOrientation(plain, 6, binary.BigEndian)[:20]},
+		{name: "not exif", data: withAPP1(plain, []byte("http://ns.adobe.com/xap/1.0/\x00"))},
+		{name: "bad byte order", data: withAPP1(plain, append([]byte("Exif\x00\x00XX"), make([]byte, 24)...))},
+		{name: "ifd out of range", data: badOffset},
+		{name: "not a jpeg", data: []byte("GIF89a\x01\x00\x01\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != 1 {
+				t.Errorf("exifOrientation() = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3×2 image with its stored top-left pixel red and top-right blue.
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	src.SetNRGBA(0, 0, red)
+	src.SetNRGBA(2, 0, blue)
+
+	tests := []struct {
+		orientation int
+		size        image.Point
+		red, blue   image.Point
+	}{
+		{orientation: 1, size: image.Pt(3, 2), red: image.Pt(0, 0), blue: image.Pt(2, 0)},
+		{orientation: 2, size: image.Pt(3, 2), red: image.Pt(2, 0), blue: image.Pt(0, 0)},
+		{orientation: 3, size: image.Pt(3, 2), red: image.Pt(2, 1), blue: image.Pt(0, 1)},
+		{orientation: 4, size: image.Pt(3, 2), red: image.Pt(0, 1), blue: image.Pt(2, 1)},
+		{orientation: 5, size: image.Pt(2, 3), red: image.Pt(0, 0), blue: image.Pt(0, 2)},
+		{orientation: 6, size: image.Pt(2, 3), red: image.Pt(1, 0), blue: image.Pt(1, 2)},
+		{orientation: 7, size: image.Pt(2, 3), red: image.Pt(1, 2), blue: image.Pt(1, 0)},
+		{orientation: 8, size: image.Pt(2, 3), red: image.Pt(0, 2), blue: image.Pt(0, 0)},
+		{orientation: 0, size: image.Pt(3, 2), red: image.Pt(0, 0), blue: image.Pt(2, 0)},
+		{orientation: 9, size: image.Pt(3, 2), red: image.Pt(0, 0), blue: image.Pt(2, 0)},
+	}
+
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if size := got.Bounds().Size(); size != tt.size {
+			t.Errorf("orient(%d) size = %v, want %v", tt.orientation, size, tt.size)
+			continue
+		}
+		if c := color.NRGBAModel.Convert(got.At(tt.red.X, tt.red.Y)); c != red {
+			t.Errorf("orient(%d) at %v = %v, want red", tt.orientation, tt.red, c)
+		}
+		if c := color.NRGBAModel.Convert(got.At(tt.blue.X, tt.blue.Y)); c != blue {
+			t.Errorf("orient(%d) at %v = %v, want blue", tt.orientation, tt.blue, c)
+		}
+	}
+}
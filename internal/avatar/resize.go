@@ -0,0 +1,110 @@
+package avatar
+
+import (
+	"image"
+	"image/color"
+)
+
+// thumbnail crops the centre square out of src and scales it to size×size
+// by averaging the source pixels each target pixel covers.
+func thumbnail(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scale := float64(side) / float64(size)
+
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, scale, crop.Min.Y, crop.Max.Y)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, scale, crop.Min.X, crop.Max.X)
+
+			// Sum in premultiplied alpha so transparent pixels do not
+			// darken the edges around them.
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			var c color.NRGBA
+			if a > 0 {
+				c = color.NRGBA{
+					R: uint8(r * 0xff / a),
+					G: uint8(g * 0xff / a),
+					B: uint8(b * 0xff / a),
+					A: uint8(a / n >> 8),
+				}
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
+
+// span returns the source pixel range [lo, hi) that target pixel i covers,
+// always at least one pixel wide so upscaling repeats pixels.
+func span(i int, scale float64, start, end int) (int, int) {
+	lo := start + int(float64(i)*scale)
+	hi := start + int(float64(i+1)*scale)
+	if hi <= lo {
+		hi = lo + 1
+	}
+	if hi > end {
+		hi = end
+	}
+	if lo >= hi {
+		lo = hi - 1
+	}
+	return lo, hi
+}
+
+// orient turns img upright according to an EXIF orientation value (1-8).
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	// Orientations 5-8 are rotated by 90 degrees and swap the dimensions.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,26 @@
+package backplane
+
+import "fmt"
+
+// Backplane relays messages between API instances so a broadcast published
+// on one reaches sockets held by the others.
+type Backplane interface {
+	Publish(channel string, payload []byte) error
+	// Subscribe calls handle for every message published on channel, from
+	// any instance, until the backplane is closed.
+	Subscribe(channel string, handle func(payload []byte)) error
+	Close() error
+}
+
+// New returns the backplane selected by name, or nil when the API runs as a
+// single instance and broadcasts stay in process.
+func New(name, redisURL string) (Backplane, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "redis":
+		return NewRedis(redisURL)
+	default:
+		return nil, fmt.Errorf("unknown backplane %q", name)
+	}
+}
@@ -0,0 +1,83 @@
+package backplane
+
+import (
+	"api/internal/redis"
+	"context"
+	"errors"
+	"sync"
+)
+
+// Redis is a backplane built on Redis pub/sub, publishing through the shared
+// client and holding one connection per subscription.
+type Redis struct {
+	client *redis.Client
+
+	mu     sync.Mutex
+	closed bool
+	subs   []*redis.PubSub
+}
+
+// NewRedis connects to the server at rawURL, e.g. redis://:password@host:6379/0.
+func NewRedis(rawURL string) (*Redis, error) {
+	client, err := redis.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{client: client}, nil
+}
+
+// Publish sends payload to every subscriber of channel.
+func (r *Redis) Publish(channel string, payload []byte) error {
+	if r.isClosed() {
+		return errors.New("backplane closed")
+	}
+
+	return r.client.Publish(context.Background(), channel, payload).Err()
+}
+
+// Subscribe listens on channel in the background and returns once the
+// subscription is in place. go-redis resubscribes whenever the connection
+// drops.
+func (r *Redis) Subscribe(channel string, handle func(payload []byte)) error {
+	ctx := context.Background()
+
+	sub := r.client.Subscribe(ctx, channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		sub.Close()
+		return errors.New("backplane closed")
+	}
+	r.subs = append(r.subs, sub)
+	r.mu.Unlock()
+
+	go func() {
+		for message := range sub.Channel() {
+			handle([]byte(message.Payload))
+		}
+	}()
+
+	return nil
+}
+
+func (r *Redis) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	for _, sub := range r.subs {
+		sub.Close()
+	}
+	return r.client.Close()
+}
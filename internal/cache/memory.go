@@ -0,0 +1,74 @@
+// Package cache holds the in-process key/value store used wherever a Redis
+// store can optionally be swapped in.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory keeps values in this process. It satisfies fiber.Storage, the same
+// interface redis.Storage implements.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		entries: make(map[string]entry),
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		delete(m.entries, key)
+		return nil, nil
+	}
+	return e.value, nil
+}
+
+// Set stores val under key. A zero exp keeps it until it is deleted.
+func (m *Memory) Set(key string, val []byte, exp time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := entry{value: val}
+	if exp > 0 {
+		e.expiresAt = time.Now().Add(exp)
+	}
+	m.entries[key] = e
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *Memory) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[string]entry)
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,673 @@
+// Package config loads the API's settings from the environment once at
+// startup, so the rest of the code receives typed, validated values.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+type Config struct {
+	// Env is "production" or anything else, such as "local" or "staging".
+	// It picks the defaults for settings that only make sense in production.
+	Env         string
+	Port        int
+	AppURL      string
+	AppKey      string
+	FrontendURL string
+
+	Database         Database
+	Session          Session
+	CORS             CORS
+	Deck             Deck
+	Mail             Mail
+	Game             Game
+	LobbyCache       LobbyCache
+	LeaderboardCache LeaderboardCache
+	Presence         Presence
+	RateLimit        RateLimit
+	Lockout          Lockout
+	Security         Security
+	Idempotency      Idempotency
+	Chat             Chat
+	Season           Season
+	Storage          Storage
+	Static           Static
+	Redis            Redis
+	Firebase         Firebase
+	Telemetry        Telemetry
+}
+
+type Database struct {
+	// URL is a full connection string, as handed out by most hosts. When
+	// set it takes precedence over the discrete fields below.
+	URL      string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// RequestTimeout bounds how long the queries of one HTTP request may
+	// run in total. StatementTimeout is sent to Postgres as
+	// statement_timeout and caps every single statement, including those
+	// run outside a request. Zero disables either.
+	RequestTimeout   time.Duration
+	StatementTimeout time.Duration
+
+	// MigrateOnStart applies pending migrations before the server starts
+	// listening.
+	MigrateOnStart bool
+}
+
+type Session struct {
+	// Store selects where session data lives: "memory" keeps it in this
+	// process, "redis" shares it with every instance through Redis.URL.
+	Store string
+	// CookieSecure and CookieHTTPOnly default to off, as the session cookie
+	// has always been sent over plain http and read by browser clients.
+	// Deployments that serve only https and keep the cookie away from
+	// scripts should turn both on.
+	CookieSecure   bool
+	CookieHTTPOnly bool
+	CookieSameSite string
+}
+
+type CORS struct {
+	AllowOrigins []string
+}
+
+type Deck struct {
+	Provider         string
+	CardImageBaseURL string
+}
+
+type Mail struct {
+	Driver       string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+}
+
+type Game struct {
+	// TurnTimerSeconds applies to lobbies that do not set turn_timer_seconds
+	// in their game settings. Zero leaves turns untimed.
+	TurnTimerSeconds      int
+	ReconnectGrace        time.Duration
+	MatchmakingPlayers    int
+	NotificationRetention time.Duration
+	// RequireVerifiedEmail limits ranked and tournament play to accounts
+	// with a verified email address.
+	RequireVerifiedEmail bool
+	// LobbyIdleTimeout is how long a waiting lobby with unready players may
+	// go untouched before it is closed. AbandonAfter is how long a running
+	// game may go without events before it is ended as abandoned.
+	LobbyIdleTimeout time.Duration
+	AbandonAfter     time.Duration
+}
+
+type LobbyCache struct {
+	// TTL is how long a page of the public lobby listing is served from
+	// cache. Zero turns the cache off.
+	TTL time.Duration
+	// Store is "memory" to cache per process or "redis" to share the cache
+	// through Redis.URL.
+	Store string
+}
+
+type LeaderboardCache struct {
+	// TTL is how long a page of the leaderboard is served from cache. The
+	// snapshot refresher empties the cache whenever it rebuilds the table.
+	// Zero turns the cache off.
+	TTL time.Duration
+	// Store is "memory" to cache per process or "redis" to share the cache
+	// through Redis.URL.
+	Store string
+}
+
+type Presence struct {
+	// Store is "memory" to track presence per process or "redis" to share
+	// it through Redis.URL, which multi-instance deployments need.
+	Store string
+	// AwayAfter is how long a user may go without HTTP activity before an
+	// open socket alone only counts as away. OfflineAfter is how long after
+	// their last activity a user without sockets stops counting as away.
+	AwayAfter    time.Duration
+	OfflineAfter time.Duration
+}
+
+// RateLimit caps how often one client may hit the auth and write endpoints.
+// A zero max turns that limit off.
+type RateLimit struct {
+	// Store is "memory" to count per process or "redis" to share the
+	// counters through Redis.URL.
+	Store string
+
+	AuthMax    int
+	AuthWindow time.Duration
+
+	WriteMax    int
+	WriteWindow time.Duration
+}
+
+// Lockout guards against password guessing. MaxAttempts failed logins in a
+// row lock the account for Duration, and every further run of failures
+// doubles the lock up to MaxDuration. MaxAttemptsPerIP failures from one
+// address within Duration block that address. A zero max turns that guard
+// off.
+type Lockout struct {
+	MaxAttempts      int
+	MaxAttemptsPerIP int
+	Duration         time.Duration
+	MaxDuration      time.Duration
+}
+
+type Security struct {
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds, sent on
+	// HTTPS requests only. Zero leaves the header off.
+	HSTSMaxAge int
+	// FrameOptions is the X-Frame-Options value.
+	FrameOptions string
+	// StaticCSP is the Content-Security-Policy for uploaded avatars.
+	StaticCSP string
+	// Compression is "off", "speed" or "best".
+	Compression string
+}
+
+type Idempotency struct {
+	// TTL is how long a response is kept for replay under its
+	// Idempotency-Key. Zero turns replays off.
+	TTL time.Duration
+	// Store is "memory" to keep responses per process or "redis" to share
+	// them through Redis.URL.
+	Store string
+}
+
+type Chat struct {
+	// BlockedWords are masked out of chat messages and refused in lobby
+	// names.
+	BlockedWords []string
+}
+
+// Season sets how long leaderboard seasons run and what happens to ratings
+// when one ends.
+type Season struct {
+	Length time.Duration
+	// RatingDecayPercent is how much of each rating's distance from the
+	// starting rating is removed at rollover; 100 resets everyone.
+	RatingDecayPercent int
+}
+
+// Storage selects where uploads such as avatars are kept.
+type Storage struct {
+	// Driver is "local" to write under LocalRoot or "s3" to use a bucket on
+	// any S3-compatible service, such as AWS or MinIO.
+	Driver string
+	// PublicURL is the base URL uploads are served from. It defaults to
+	// APP_URL for the local driver; with s3, leaving it empty serves signed
+	// URLs that expire after S3URLExpiry.
+	PublicURL string
+	LocalRoot string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// S3PathStyle puts the bucket in the URL path, as MinIO expects.
+	S3PathStyle bool
+	S3URLExpiry time.Duration
+
+	// AvatarMaxBytes caps the size of an uploaded avatar.
+	AvatarMaxBytes int
+}
+
+// Static configures how avatars and card images on local disk are served.
+type Static struct {
+	// Root is the directory served files live under: uploaded avatars in
+	// avatars/ and card images in cards/.
+	Root string
+	// BaseURL is the origin clients fetch static files from, such as a CDN
+	// fronting this server. It defaults to APP_URL.
+	BaseURL string
+	// MaxAge is the Cache-Control max-age for served files. Uploads get a
+	// fresh name on every change, so it can be long.
+	MaxAge time.Duration
+}
+
+type Redis struct {
+	URL string
+	// Backplane selects how hub broadcasts reach other instances: "" or
+	// "none" keeps them in process, "redis" relays them through URL.
+	Backplane string
+}
+
+type Firebase struct {
+	// ProjectID is the Firebase project whose ID tokens are accepted at
+	// POST /firebase. Leaving it empty turns Firebase login off.
+	ProjectID string
+}
+
+// Telemetry configures tracing. Spans are exported over OTLP/HTTP only when
+// an endpoint is set; the exporter reads the other OTEL_EXPORTER_OTLP_*
+// variables, such as headers, itself.
+type Telemetry struct {
+	OTLPEndpoint string
+	ServiceName  string
+}
+
+var defaultAllowOrigins = []string{"https://www.troika.id.lv", "http://10.13.59.2:3000"}
+
+// Load reads the configuration from the environment, filling in defaults,
+// and reports every invalid setting at once.
+func Load() (*Config, error) {
+	var errs []error
+	env := &reader{errs: &errs}
+
+	appEnv := env.string("APP_ENV", "local")
+	hstsMaxAge := 0
+	if appEnv == "production" {
+		hstsMaxAge = 31536000
+	}
+
+	cfg := &Config{
+		Env:         appEnv,
+		Port:        env.int("PORT", 8080),
+		AppURL:      os.Getenv("APP_URL"),
+		AppKey:      os.Getenv("APP_KEY"),
+		FrontendURL: os.Getenv("FRONTEND_URL"),
+
+		Database: Database{
+			URL:      os.Getenv("DATABASE_URL"),
+			Host:     os.Getenv("DB_HOST"),
+			Port:     env.string("DB_PORT", "5432"),
+			User:     os.Getenv("DB_USER"),
+			Password: os.Getenv("DB_PASSWORD"),
+			Name:     os.Getenv("DB_NAME"),
+			SSLMode:  os.Getenv("DB_SSLMODE"),
+
+			MaxOpenConns:    env.int("DB_MAX_OPEN_CONNS", 100),
+			MaxIdleConns:    env.int("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: env.duration("DB_CONN_MAX_LIFETIME", time.Hour),
+			ConnMaxIdleTime: env.duration("DB_CONN_MAX_IDLE_TIME", 0),
+
+			RequestTimeout:   env.duration("DB_REQUEST_TIMEOUT", 10*time.Second),
+			StatementTimeout: env.duration("DB_STATEMENT_TIMEOUT", 0),
+
+			MigrateOnStart: env.bool("MIGRATE_ON_START", false),
+		},
+		Session: Session{
+			Store:          env.string("SESSION_STORE", "memory"),
+			CookieSecure:   env.bool("SESSION_COOKIE_SECURE", false),
+			CookieHTTPOnly: env.bool("SESSION_COOKIE_HTTP_ONLY", false),
+			CookieSameSite: env.string("SESSION_COOKIE_SAMESITE", "Lax"),
+		},
+		CORS: CORS{
+			AllowOrigins: env.list("CORS_ALLOW_ORIGINS", defaultAllowOrigins),
+		},
+		Deck: Deck{
+			Provider:         os.Getenv("DECK_PROVIDER"),
+			CardImageBaseURL: os.Getenv("CARD_IMAGE_BASE_URL"),
+		},
+		Mail: Mail{
+			Driver:       os.Getenv("MAIL_DRIVER"),
+			SMTPHost:     os.Getenv("SMTP_HOST"),
+			SMTPPort:     os.Getenv("SMTP_PORT"),
+			SMTPUsername: os.Getenv("SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+			From:         os.Getenv("MAIL_FROM"),
+		},
+		Game: Game{
+			TurnTimerSeconds:      env.int("TURN_TIMER_SECONDS", 0),
+			ReconnectGrace:        time.Duration(env.int("RECONNECT_GRACE_SECONDS", 60)) * time.Second,
+			MatchmakingPlayers:    env.int("MATCHMAKING_PLAYERS", 2),
+			NotificationRetention: time.Duration(env.int("NOTIFICATION_RETENTION_DAYS", 30)) * 24 * time.Hour,
+			RequireVerifiedEmail:  env.bool("REQUIRE_VERIFIED_EMAIL_FOR_RANKED", false),
+			LobbyIdleTimeout:      env.duration("LOBBY_IDLE_TIMEOUT", time.Hour),
+			AbandonAfter:          env.duration("GAME_ABANDON_AFTER", 30*time.Minute),
+		},
+		LobbyCache: LobbyCache{
+			TTL:   env.duration("LOBBY_CACHE_TTL", 5*time.Second),
+			Store: env.string("LOBBY_CACHE_STORE", "memory"),
+		},
+		LeaderboardCache: LeaderboardCache{
+			TTL:   env.duration("LEADERBOARD_CACHE_TTL", time.Minute),
+			Store: env.string("LEADERBOARD_CACHE_STORE", "memory"),
+		},
+		Presence: Presence{
+			Store:        env.string("PRESENCE_STORE", "memory"),
+			AwayAfter:    env.duration("PRESENCE_AWAY_AFTER", 5*time.Minute),
+			OfflineAfter: env.duration("PRESENCE_OFFLINE_AFTER", 30*time.Minute),
+		},
+		RateLimit: RateLimit{
+			Store:       env.string("RATE_LIMIT_STORE", "memory"),
+			AuthMax:     env.int("RATE_LIMIT_AUTH_MAX", 10),
+			AuthWindow:  env.duration("RATE_LIMIT_AUTH_WINDOW", time.Minute),
+			WriteMax:    env.int("RATE_LIMIT_WRITE_MAX", 20),
+			WriteWindow: env.duration("RATE_LIMIT_WRITE_WINDOW", time.Minute),
+		},
+		Lockout: Lockout{
+			MaxAttempts:      env.int("LOGIN_MAX_ATTEMPTS", 5),
+			MaxAttemptsPerIP: env.int("LOGIN_MAX_ATTEMPTS_PER_IP", 20),
+			Duration:         env.duration("LOGIN_LOCKOUT", 15*time.Minute),
+			MaxDuration:      env.duration("LOGIN_LOCKOUT_MAX", 24*time.Hour),
+		},
+		Security: Security{
+			HSTSMaxAge:   env.int("SECURITY_HSTS_MAX_AGE", hstsMaxAge),
+			FrameOptions: env.string("SECURITY_FRAME_OPTIONS", "DENY"),
+			StaticCSP:    env.string("SECURITY_STATIC_CSP", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox"),
+			Compression:  env.string("COMPRESSION", "speed"),
+		},
+		Idempotency: Idempotency{
+			TTL:   env.duration("IDEMPOTENCY_TTL", 10*time.Minute),
+			Store: env.string("IDEMPOTENCY_STORE", "memory"),
+		},
+		Chat: Chat{
+			BlockedWords: env.list("CHAT_BLOCKED_WORDS", nil),
+		},
+		Season: Season{
+			Length:             env.duration("SEASON_LENGTH", 30*24*time.Hour),
+			RatingDecayPercent: env.int("SEASON_RATING_DECAY_PERCENT", 50),
+		},
+		Storage: Storage{
+			Driver:    env.string("STORAGE_DRIVER", "local"),
+			PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+			LocalRoot: os.Getenv("STORAGE_LOCAL_ROOT"),
+
+			S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+			S3Region:    env.string("S3_REGION", "us-east-1"),
+			S3Bucket:    os.Getenv("S3_BUCKET"),
+			S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+			S3PathStyle: env.bool("S3_PATH_STYLE", false),
+			S3URLExpiry: env.duration("S3_URL_EXPIRY", time.Hour),
+
+			AvatarMaxBytes: env.int("AVATAR_MAX_BYTES", 2<<20),
+		},
+		Static: Static{
+			Root:    env.string("STATIC_ROOT", "./public"),
+			BaseURL: env.string("STATIC_BASE_URL", os.Getenv("APP_URL")),
+			MaxAge:  env.duration("STATIC_MAX_AGE", 7*24*time.Hour),
+		},
+		Redis: Redis{
+			URL:       os.Getenv("REDIS_URL"),
+			Backplane: os.Getenv("HUB_BACKPLANE"),
+		},
+		Firebase: Firebase{
+			ProjectID: os.Getenv("FIREBASE_PROJECT_ID"),
+		},
+		Telemetry: Telemetry{
+			OTLPEndpoint: env.string("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+			ServiceName:  env.string("OTEL_SERVICE_NAME", "shithead-api"),
+		},
+	}
+
+	// Locally stored avatars are served from the static root, so they
+	// default to its directory and base URL.
+	if cfg.Storage.LocalRoot == "" {
+		cfg.Storage.LocalRoot = cfg.Static.Root
+	}
+	if cfg.Storage.Driver == "local" && cfg.Storage.PublicURL == "" {
+		cfg.Storage.PublicURL = cfg.Static.BaseURL
+	}
+	if cfg.Deck.CardImageBaseURL == "" && cfg.Static.BaseURL != "" {
+		cfg.Deck.CardImageBaseURL = strings.TrimRight(cfg.Static.BaseURL, "/") + "/static/cards"
+	}
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	if c.Database.URL == "" {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("DB_HOST is required unless DATABASE_URL is set"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("DB_NAME is required unless DATABASE_URL is set"))
+		}
+	}
+	switch c.Database.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("DB_SSLMODE %q is not a valid sslmode", c.Database.SSLMode))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS cannot be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS cannot exceed DB_MAX_OPEN_CONNS"))
+	}
+	switch c.Session.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when SESSION_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("SESSION_STORE must be memory or redis, got %q", c.Session.Store))
+	}
+	switch c.Session.CookieSameSite {
+	case "Lax", "Strict", "None":
+	default:
+		errs = append(errs, fmt.Errorf("SESSION_COOKIE_SAMESITE must be Lax, Strict or None, got %q", c.Session.CookieSameSite))
+	}
+	if len(c.CORS.AllowOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOW_ORIGINS must list at least one origin"))
+	}
+	if c.Game.TurnTimerSeconds < 0 {
+		errs = append(errs, errors.New("TURN_TIMER_SECONDS cannot be negative"))
+	}
+	if c.Game.ReconnectGrace <= 0 {
+		errs = append(errs, errors.New("RECONNECT_GRACE_SECONDS must be positive"))
+	}
+	if c.Game.MatchmakingPlayers < 2 || c.Game.MatchmakingPlayers > 8 {
+		errs = append(errs, fmt.Errorf("MATCHMAKING_PLAYERS must be between 2 and 8, got %d", c.Game.MatchmakingPlayers))
+	}
+	if c.Game.NotificationRetention <= 0 {
+		errs = append(errs, errors.New("NOTIFICATION_RETENTION_DAYS must be positive"))
+	}
+	if c.Game.LobbyIdleTimeout < time.Minute {
+		errs = append(errs, errors.New("LOBBY_IDLE_TIMEOUT must be at least 1m"))
+	}
+	if c.Game.AbandonAfter < time.Minute {
+		errs = append(errs, errors.New("GAME_ABANDON_AFTER must be at least 1m"))
+	}
+	if c.LobbyCache.TTL < 0 {
+		errs = append(errs, errors.New("LOBBY_CACHE_TTL cannot be negative"))
+	}
+	switch c.LobbyCache.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when LOBBY_CACHE_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LOBBY_CACHE_STORE must be memory or redis, got %q", c.LobbyCache.Store))
+	}
+	switch c.LeaderboardCache.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when LEADERBOARD_CACHE_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("LEADERBOARD_CACHE_STORE must be memory or redis, got %q", c.LeaderboardCache.Store))
+	}
+	switch c.Presence.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when PRESENCE_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("PRESENCE_STORE must be memory or redis, got %q", c.Presence.Store))
+	}
+	if c.Presence.AwayAfter <= 0 || c.Presence.OfflineAfter < c.Presence.AwayAfter {
+		errs = append(errs, errors.New("PRESENCE_AWAY_AFTER must be positive and no longer than PRESENCE_OFFLINE_AFTER"))
+	}
+	switch c.RateLimit.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when RATE_LIMIT_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_STORE must be memory or redis, got %q", c.RateLimit.Store))
+	}
+	if c.RateLimit.AuthMax < 0 || c.RateLimit.WriteMax < 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_AUTH_MAX and RATE_LIMIT_WRITE_MAX cannot be negative"))
+	}
+	if c.RateLimit.AuthWindow <= 0 || c.RateLimit.WriteWindow <= 0 {
+		errs = append(errs, errors.New("RATE_LIMIT_AUTH_WINDOW and RATE_LIMIT_WRITE_WINDOW must be positive"))
+	}
+	if c.Lockout.MaxAttempts < 0 || c.Lockout.MaxAttemptsPerIP < 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_ATTEMPTS and LOGIN_MAX_ATTEMPTS_PER_IP cannot be negative"))
+	}
+	if c.Lockout.Duration <= 0 || c.Lockout.MaxDuration < c.Lockout.Duration {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT must be positive and no longer than LOGIN_LOCKOUT_MAX"))
+	}
+	if c.Security.HSTSMaxAge < 0 {
+		errs = append(errs, errors.New("SECURITY_HSTS_MAX_AGE cannot be negative"))
+	}
+	switch c.Security.FrameOptions {
+	case "DENY", "SAMEORIGIN":
+	default:
+		errs = append(errs, fmt.Errorf("SECURITY_FRAME_OPTIONS must be DENY or SAMEORIGIN, got %q", c.Security.FrameOptions))
+	}
+	switch c.Security.Compression {
+	case "off", "speed", "best":
+	default:
+		errs = append(errs, fmt.Errorf("COMPRESSION must be off, speed or best, got %q", c.Security.Compression))
+	}
+	if c.Idempotency.TTL < 0 {
+		errs = append(errs, errors.New("IDEMPOTENCY_TTL cannot be negative"))
+	}
+	switch c.Idempotency.Store {
+	case "memory":
+	case "redis":
+		if c.Redis.URL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when IDEMPOTENCY_STORE is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("IDEMPOTENCY_STORE must be memory or redis, got %q", c.Idempotency.Store))
+	}
+	if c.Season.Length < 24*time.Hour {
+		errs = append(errs, errors.New("SEASON_LENGTH must be at least 24h"))
+	}
+	if c.Season.RatingDecayPercent < 0 || c.Season.RatingDecayPercent > 100 {
+		errs = append(errs, fmt.Errorf("SEASON_RATING_DECAY_PERCENT must be between 0 and 100, got %d", c.Season.RatingDecayPercent))
+	}
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3Endpoint == "" || c.Storage.S3Bucket == "" {
+			errs = append(errs, errors.New("S3_ENDPOINT and S3_BUCKET are required when STORAGE_DRIVER is s3"))
+		}
+		if c.Storage.S3AccessKey == "" || c.Storage.S3SecretKey == "" {
+			errs = append(errs, errors.New("S3_ACCESS_KEY and S3_SECRET_KEY are required when STORAGE_DRIVER is s3"))
+		}
+		if c.Storage.S3URLExpiry <= 0 || c.Storage.S3URLExpiry > 7*24*time.Hour {
+			errs = append(errs, errors.New("S3_URL_EXPIRY must be positive and at most 168h"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("STORAGE_DRIVER must be local or s3, got %q", c.Storage.Driver))
+	}
+	// Uploads beyond fiber's default 4 MB body limit never reach the handler.
+	if c.Storage.AvatarMaxBytes <= 0 || c.Storage.AvatarMaxBytes > 4<<20 {
+		errs = append(errs, fmt.Errorf("AVATAR_MAX_BYTES must be between 1 and %d, got %d", 4<<20, c.Storage.AvatarMaxBytes))
+	}
+	if c.Redis.Backplane == "redis" && c.Redis.URL == "" {
+		errs = append(errs, errors.New("REDIS_URL is required when HUB_BACKPLANE is redis"))
+	}
+
+	return errs
+}
+
+// reader looks up environment variables, recording a parse error for any
+// value that is set but malformed.
+type reader struct {
+	errs *[]error
+}
+
+func (r *reader) string(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (r *reader) int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		*r.errs = append(*r.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		*r.errs = append(*r.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		*r.errs = append(*r.errs, fmt.Errorf("%s must be a duration such as 30m, got %q", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (r *reader) list(key string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -4,59 +4,63 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"net/url"
 	"strconv"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"api/internal/config"
+	"api/internal/telemetry"
 )
 
 type Service interface {
 	Health() map[string]string
 	Close() error
 	DB() *gorm.DB
+	// Listen subscribes to a Postgres NOTIFY channel on a dedicated
+	// connection and calls handle with each payload until ctx is done or the
+	// connection fails.
+	Listen(ctx context.Context, channel string, handle func(payload string)) error
+	// MigrationVersion returns the schema version goose last applied.
+	MigrationVersion(ctx context.Context) (int64, error)
+	// Migrate applies the pending embedded migrations and returns the
+	// versions it applied.
+	Migrate(ctx context.Context) ([]int64, error)
 }
 
 type service struct {
 	db *gorm.DB
 }
 
-var (
-	dbUser     = os.Getenv("DB_USER")
-	dbPassword = os.Getenv("DB_PASSWORD")
-	dbHost     = os.Getenv("DB_HOST")
-	dbPort     = os.Getenv("DB_PORT")
-	dbName     = os.Getenv("DB_NAME")
-	// dbSSLMode  = os.Getenv("DB_SSLMODE")
-	dbInstance *service
-)
+var dbInstance *service
 
-func New() Service {
+func New(cfg config.Database) Service {
 	if dbInstance != nil {
 		return dbInstance
 	}
 
-	// dbUrl := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-	// 	dbHost, dbUser, dbPassword, dbName, dbPort, dbSSLMode)
-
-	dbUrl := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s",
-		dbHost, dbUser, dbPassword, dbName, dbPort)
-
-	db, err := gorm.Open(postgres.Open(dbUrl))
+	db, err := gorm.Open(postgres.Open(dsn(cfg)))
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if err := db.Use(telemetry.NewGormPlugin()); err != nil {
+		log.Fatal(err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	dbInstance = &service{
 		db: db,
@@ -65,6 +69,45 @@ func New() Service {
 	return dbInstance
 }
 
+// dsn builds the connection string. DATABASE_URL is used as given, apart from
+// DB_SSLMODE and DB_STATEMENT_TIMEOUT overriding what it carries.
+func dsn(cfg config.Database) string {
+	params := make(map[string]string)
+	if cfg.SSLMode != "" {
+		params["sslmode"] = cfg.SSLMode
+	}
+	if cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if cfg.URL != "" {
+		if len(params) == 0 {
+			return cfg.URL
+		}
+		parsed, err := url.Parse(cfg.URL)
+		if err != nil || parsed.Scheme == "" {
+			return cfg.URL + keywordParams(params)
+		}
+		query := parsed.Query()
+		for key, value := range params {
+			query.Set(key, value)
+		}
+		parsed.RawQuery = query.Encode()
+		return parsed.String()
+	}
+
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port) + keywordParams(params)
+}
+
+func keywordParams(params map[string]string) string {
+	var dsn string
+	for key, value := range params {
+		dsn += fmt.Sprintf(" %s=%s", key, value)
+	}
+	return dsn
+}
+
 func (s *service) DB() *gorm.DB {
 	return s.db.Set("gorm:auto_preload", false)
 }
@@ -123,3 +166,60 @@ func (s *service) Close() error {
 	}
 	return sqlDB.Close()
 }
+
+func (s *service) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		// The connection goes back to the pool afterwards, so stop listening.
+		defer pgxConn.Exec(context.Background(), "UNLISTEN *")
+
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			handle(notification.Payload)
+		}
+	})
+}
+
+func (s *service) MigrationVersion(ctx context.Context) (int64, error) {
+	var rows []struct {
+		VersionID int64
+		IsApplied bool
+	}
+	if err := s.db.WithContext(ctx).Table("goose_db_version").
+		Select("version_id, is_applied").
+		Order("id DESC").
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	// goose logs every up and down, so the current version is the newest one
+	// whose most recent entry is an up.
+	rolledBack := make(map[int64]bool)
+	for _, row := range rows {
+		if rolledBack[row.VersionID] {
+			continue
+		}
+		if row.IsApplied {
+			return row.VersionID, nil
+		}
+		rolledBack[row.VersionID] = true
+	}
+	return 0, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pressly/goose/v3"
+)
+
+var setupGoose sync.Once
+
+// Migrate applies every embedded migration newer than the database's current
+// version with goose, so the -- +goose annotations mean what they do for the
+// goose CLI and the Makefile targets, which keep working against the same
+// goose_db_version table.
+func (s *service) Migrate(ctx context.Context) ([]int64, error) {
+	var err error
+	setupGoose.Do(func() {
+		goose.SetBaseFS(migrationFiles)
+		err = goose.SetDialect("postgres")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := goose.EnsureDBVersionContext(ctx, sqlDB)
+	if err != nil {
+		return nil, fmt.Errorf("reading migration version: %w", err)
+	}
+
+	if err := goose.UpContext(ctx, sqlDB, "migrations"); err != nil {
+		return nil, err
+	}
+
+	after, err := goose.GetDBVersionContext(ctx, sqlDB)
+	if err != nil {
+		return nil, fmt.Errorf("reading migration version: %w", err)
+	}
+	if after == before {
+		return nil, nil
+	}
+
+	migrations, err := goose.CollectMigrations("migrations", before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]int64, len(migrations))
+	for i, m := range migrations {
+		applied[i] = m.Version
+	}
+	return applied, nil
+}
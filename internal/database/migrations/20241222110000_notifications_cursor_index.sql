@@ -0,0 +1,5 @@
+-- +goose up
+CREATE INDEX idx_notifications_user_created_at ON notifications(user_id, created_at DESC, id DESC);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_notifications_user_created_at;
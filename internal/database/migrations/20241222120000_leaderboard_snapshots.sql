@@ -0,0 +1,14 @@
+-- +goose up
+CREATE TABLE leaderboard_snapshots (
+    user_id UUID PRIMARY KEY,
+    games_played INTEGER NOT NULL DEFAULT 0,
+    wins INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_leaderboard_snapshots_wins ON leaderboard_snapshots(wins DESC, games_played ASC);
+
+-- +goose down
+DROP TABLE IF EXISTS leaderboard_snapshots;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE players ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+
+-- +goose down
+ALTER TABLE players DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- +goose up
+CREATE TABLE matchmaking_queue (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL UNIQUE,
+    rating INTEGER NOT NULL DEFAULT 1000,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_matchmaking_queue_rating ON matchmaking_queue(rating);
+
+-- +goose down
+DROP TABLE IF EXISTS matchmaking_queue;
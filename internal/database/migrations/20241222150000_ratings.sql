@@ -0,0 +1,31 @@
+-- +goose up
+CREATE TABLE ratings (
+    user_id UUID PRIMARY KEY,
+    rating INTEGER NOT NULL DEFAULT 1000,
+    games_played INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_ratings_rating ON ratings(rating DESC);
+
+CREATE TABLE rating_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    game_id UUID NOT NULL,
+    rating_before INTEGER NOT NULL,
+    rating_after INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    created_at TIMESTAMP NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_rating_history_user_created_at ON rating_history(user_id, created_at DESC);
+
+-- +goose down
+DROP TABLE IF EXISTS rating_history;
+DROP TABLE IF EXISTS ratings;
@@ -0,0 +1,36 @@
+-- +goose up
+CREATE TABLE match_results (
+    id UUID PRIMARY KEY,
+    game_id UUID NOT NULL UNIQUE,
+    lobby_id UUID NOT NULL,
+    game_mode VARCHAR(20) NOT NULL,
+    shithead_user_id UUID NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    FOREIGN KEY (shithead_user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE match_participants (
+    id UUID PRIMARY KEY,
+    match_result_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    player_id UUID NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    placement INTEGER NOT NULL,
+    created_at TIMESTAMP NULL,
+
+    FOREIGN KEY (match_result_id) REFERENCES match_results(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (match_result_id, user_id)
+);
+
+CREATE INDEX idx_match_participants_user_id ON match_participants(user_id);
+
+-- +goose down
+DROP TABLE IF EXISTS match_participants;
+DROP TABLE IF EXISTS match_results;
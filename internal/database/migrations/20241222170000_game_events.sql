@@ -0,0 +1,20 @@
+-- +goose up
+ALTER TABLE games ADD COLUMN event_sequence BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE game_events (
+    id UUID PRIMARY KEY,
+    game_id UUID NOT NULL,
+    sequence BIGINT NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    player_id UUID NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NULL,
+
+    FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    FOREIGN KEY (player_id) REFERENCES players(id) ON DELETE SET NULL,
+    UNIQUE (game_id, sequence)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS game_events;
+ALTER TABLE games DROP COLUMN IF EXISTS event_sequence;
@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE players ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose down
+ALTER TABLE players DROP COLUMN IF EXISTS is_bot;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,15 @@
+-- +goose up
+CREATE TABLE lobby_spectators (
+    id UUID PRIMARY KEY,
+    lobby_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (lobby_id) REFERENCES lobbies(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (lobby_id, user_id)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS lobby_spectators;
@@ -0,0 +1,19 @@
+-- +goose up
+CREATE TABLE friendships (
+    id UUID PRIMARY KEY,
+    requester_id UUID NOT NULL,
+    addressee_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested',
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (addressee_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (requester_id, addressee_id),
+    CHECK (requester_id <> addressee_id)
+);
+
+CREATE INDEX idx_friendships_addressee_status ON friendships(addressee_id, status);
+
+-- +goose down
+DROP TABLE IF EXISTS friendships;
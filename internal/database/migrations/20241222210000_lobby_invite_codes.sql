@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE lobbies ADD COLUMN invite_code VARCHAR(16) NULL UNIQUE;
+ALTER TABLE lobbies ADD COLUMN invite_code_expires_at TIMESTAMP NULL;
+
+-- +goose down
+ALTER TABLE lobbies DROP COLUMN IF EXISTS invite_code_expires_at;
+ALTER TABLE lobbies DROP COLUMN IF EXISTS invite_code;
@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE players ADD COLUMN swap_confirmed BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose down
+ALTER TABLE players DROP COLUMN IF EXISTS swap_confirmed;
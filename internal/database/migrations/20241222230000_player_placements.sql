@@ -0,0 +1,5 @@
+-- +goose up
+ALTER TABLE players ADD COLUMN placement INTEGER NULL;
+
+-- +goose down
+ALTER TABLE players DROP COLUMN IF EXISTS placement;
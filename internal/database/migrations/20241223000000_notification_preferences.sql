@@ -0,0 +1,15 @@
+-- +goose up
+CREATE TABLE notification_preferences (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    category VARCHAR(30) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, category)
+);
+
+-- +goose down
+DROP TABLE IF EXISTS notification_preferences;
@@ -0,0 +1,37 @@
+-- +goose up
+-- Drop duplicates left behind by concurrent joins and invites before the
+-- unique indexes go on, keeping the earliest row of each.
+WITH duplicates AS (
+    DELETE FROM players
+    WHERE id IN (
+        SELECT id FROM (
+            SELECT id, ROW_NUMBER() OVER (PARTITION BY lobby_id, user_id ORDER BY created_at, id) AS n
+            FROM players
+        ) ranked
+        WHERE n > 1
+    )
+    RETURNING lobby_id
+)
+UPDATE lobbies
+SET current_players = (SELECT COUNT(*) FROM players WHERE players.lobby_id = lobbies.id)
+WHERE id IN (SELECT lobby_id FROM duplicates);
+
+UPDATE lobby_invitations
+SET status = 'cancelled', updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY lobby_id, invited_user_id ORDER BY created_at, id) AS n
+        FROM lobby_invitations
+        WHERE status = 'pending'
+    ) ranked
+    WHERE n > 1
+);
+
+CREATE UNIQUE INDEX idx_players_game_user ON players(game_id, user_id);
+CREATE UNIQUE INDEX idx_players_lobby_user ON players(lobby_id, user_id);
+CREATE UNIQUE INDEX idx_lobby_invitations_pending ON lobby_invitations(lobby_id, invited_user_id) WHERE status = 'pending';
+
+-- +goose down
+DROP INDEX IF EXISTS idx_lobby_invitations_pending;
+DROP INDEX IF EXISTS idx_players_lobby_user;
+DROP INDEX IF EXISTS idx_players_game_user;
@@ -0,0 +1,21 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP NULL;
+
+CREATE TABLE login_attempts (
+    id UUID PRIMARY KEY,
+    user_id UUID NULL,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_login_attempts_ip_created ON login_attempts(ip_address, created_at);
+CREATE INDEX idx_login_attempts_created ON login_attempts(created_at);
+
+-- +goose down
+DROP TABLE IF EXISTS login_attempts;
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE users DROP COLUMN IF EXISTS failed_logins;
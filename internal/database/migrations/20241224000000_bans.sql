@@ -0,0 +1,21 @@
+-- +goose up
+CREATE TABLE bans (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP NULL,
+    issued_by UUID NULL,
+    revoked_at TIMESTAMP NULL,
+    revoked_by UUID NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (issued_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (revoked_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_bans_user_active ON bans(user_id) WHERE revoked_at IS NULL;
+
+-- +goose down
+DROP TABLE IF EXISTS bans;
@@ -0,0 +1,47 @@
+-- +goose up
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    reporter_id UUID NOT NULL,
+    reported_user_id UUID NOT NULL,
+    category VARCHAR(20) NOT NULL,
+    game_id UUID NULL,
+    message_id UUID NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    action VARCHAR(20) NULL,
+    resolution_note TEXT NULL,
+    resolved_by UUID NULL,
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reported_user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_reports_status_created ON reports(status, created_at);
+CREATE INDEX idx_reports_reported_user ON reports(reported_user_id);
+
+CREATE TABLE mutes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP NULL,
+    issued_by UUID NULL,
+    revoked_at TIMESTAMP NULL,
+    revoked_by UUID NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (issued_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (revoked_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_mutes_user_active ON mutes(user_id) WHERE revoked_at IS NULL;
+
+-- +goose down
+DROP TABLE IF EXISTS mutes;
+DROP TABLE IF EXISTS reports;
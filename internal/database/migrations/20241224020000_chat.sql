@@ -0,0 +1,31 @@
+-- +goose up
+CREATE TABLE chat_messages (
+    id UUID PRIMARY KEY,
+    game_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chat_messages_game_created ON chat_messages(game_id, created_at);
+
+CREATE TABLE lobby_mutes (
+    id UUID PRIMARY KEY,
+    lobby_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    muted_by UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (lobby_id) REFERENCES lobbies(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_lobby_mutes_lobby_user ON lobby_mutes(lobby_id, user_id);
+
+-- +goose down
+DROP TABLE IF EXISTS lobby_mutes;
+DROP TABLE IF EXISTS chat_messages;
@@ -0,0 +1,18 @@
+-- +goose up
+CREATE TABLE audit_logs (
+    id UUID PRIMARY KEY,
+    actor_id UUID NULL,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(30) NOT NULL DEFAULT '',
+    target_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    details JSONB NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_audit_logs_created ON audit_logs(created_at, id);
+CREATE INDEX idx_audit_logs_actor_created ON audit_logs(actor_id, created_at);
+CREATE INDEX idx_audit_logs_target ON audit_logs(target_type, target_id);
+
+-- +goose down
+DROP TABLE IF EXISTS audit_logs;
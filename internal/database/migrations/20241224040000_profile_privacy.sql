@@ -0,0 +1,21 @@
+-- +goose up
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(50) NULL,
+    ADD COLUMN bio VARCHAR(500) NULL,
+    ADD COLUMN country CHAR(2) NULL;
+
+CREATE TABLE privacy_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    profile_visibility VARCHAR(20) NOT NULL DEFAULT 'public',
+    hide_email BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose down
+DROP TABLE IF EXISTS privacy_settings;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS country,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS display_name;
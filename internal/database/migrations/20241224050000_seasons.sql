@@ -0,0 +1,36 @@
+-- +goose up
+CREATE TABLE seasons (
+    id UUID PRIMARY KEY,
+    number INT NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- At most one season runs at a time.
+CREATE UNIQUE INDEX idx_seasons_active ON seasons(status) WHERE status = 'active';
+
+CREATE TABLE season_standings (
+    id UUID PRIMARY KEY,
+    season_id UUID NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    game_mode VARCHAR(20) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rank INT NOT NULL,
+    rating INT NULL,
+    games_played INT NOT NULL DEFAULT 0,
+    wins INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (season_id, game_mode, user_id)
+);
+
+CREATE INDEX idx_season_standings_rank ON season_standings(season_id, game_mode, rank);
+CREATE INDEX idx_match_results_mode_finished ON match_results(game_mode, finished_at);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_match_results_mode_finished;
+DROP TABLE IF EXISTS season_standings;
+DROP TABLE IF EXISTS seasons;
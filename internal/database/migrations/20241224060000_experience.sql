@@ -0,0 +1,20 @@
+-- +goose up
+ALTER TABLE users ADD COLUMN xp INT NOT NULL DEFAULT 0;
+
+CREATE TABLE xp_events (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount INT NOT NULL,
+    source VARCHAR(30) NOT NULL,
+    game_id UUID NULL REFERENCES games(id) ON DELETE SET NULL,
+    level_before INT NOT NULL,
+    level_after INT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_xp_events_user_created ON xp_events(user_id, created_at);
+CREATE INDEX idx_xp_events_game ON xp_events(game_id);
+
+-- +goose down
+DROP TABLE IF EXISTS xp_events;
+ALTER TABLE users DROP COLUMN IF EXISTS xp;
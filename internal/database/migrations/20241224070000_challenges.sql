@@ -0,0 +1,33 @@
+-- +goose up
+CREATE TABLE challenges (
+    id UUID PRIMARY KEY,
+    period VARCHAR(10) NOT NULL,
+    metric VARCHAR(30) NOT NULL,
+    description VARCHAR(100) NOT NULL,
+    target INT NOT NULL,
+    xp_reward INT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (period, starts_at, metric)
+);
+
+CREATE INDEX idx_challenges_window ON challenges(starts_at, ends_at);
+
+CREATE TABLE user_challenges (
+    id UUID PRIMARY KEY,
+    challenge_id UUID NOT NULL REFERENCES challenges(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    progress INT NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP NULL,
+    claimed_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (challenge_id, user_id)
+);
+
+CREATE INDEX idx_user_challenges_user ON user_challenges(user_id);
+
+-- +goose down
+DROP TABLE IF EXISTS user_challenges;
+DROP TABLE IF EXISTS challenges;
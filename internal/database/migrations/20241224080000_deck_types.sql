@@ -0,0 +1,7 @@
+-- +goose up
+ALTER TABLE decks ADD COLUMN pack_count INT NOT NULL DEFAULT 1;
+ALTER TABLE decks ADD COLUMN joker_count INT NOT NULL DEFAULT 0;
+
+-- +goose down
+ALTER TABLE decks DROP COLUMN IF EXISTS joker_count;
+ALTER TABLE decks DROP COLUMN IF EXISTS pack_count;
@@ -0,0 +1,11 @@
+-- +goose up
+ALTER TABLE players ADD COLUMN team INT NULL;
+ALTER TABLE match_results ADD COLUMN winning_team INT NULL;
+ALTER TABLE match_participants ADD COLUMN team INT NULL;
+ALTER TABLE match_participants ADD COLUMN team_placement INT NULL;
+
+-- +goose down
+ALTER TABLE match_participants DROP COLUMN IF EXISTS team_placement;
+ALTER TABLE match_participants DROP COLUMN IF EXISTS team;
+ALTER TABLE match_results DROP COLUMN IF EXISTS winning_team;
+ALTER TABLE players DROP COLUMN IF EXISTS team;
@@ -0,0 +1,11 @@
+-- +goose up
+-- Card codes only need to be unique within a game. Schemas created from the
+-- models carry a table-wide constraint, which stopped two games from both
+-- holding the same card.
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS cards_code_key;
+ALTER TABLE cards DROP CONSTRAINT IF EXISTS uni_cards_code;
+DROP INDEX IF EXISTS idx_cards_code;
+CREATE UNIQUE INDEX idx_cards_game_code ON cards(game_id, code);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_cards_game_code;
@@ -0,0 +1,9 @@
+-- +goose up
+-- pile_position orders the cards on the play pile, and keeps that order once
+-- they are burned. It is cleared when a card leaves the piles.
+ALTER TABLE cards ADD COLUMN pile_position INT NULL;
+CREATE INDEX idx_cards_game_location ON cards(game_id, location_type, pile_position);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_cards_game_location;
+ALTER TABLE cards DROP COLUMN IF EXISTS pile_position;
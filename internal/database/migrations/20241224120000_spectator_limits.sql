@@ -0,0 +1,10 @@
+-- +goose up
+ALTER TABLE lobbies ADD COLUMN max_spectators INT NOT NULL DEFAULT 20;
+UPDATE lobby_queues SET queue_type = 'player' WHERE queue_type = 'waiting';
+ALTER TABLE lobby_queues ALTER COLUMN queue_type SET DEFAULT 'player';
+CREATE INDEX IF NOT EXISTS idx_lobby_queues_lobby_type ON lobby_queues (lobby_id, queue_type);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_lobby_queues_lobby_type;
+ALTER TABLE lobby_queues ALTER COLUMN queue_type SET DEFAULT 'waiting';
+ALTER TABLE lobbies DROP COLUMN IF EXISTS max_spectators;
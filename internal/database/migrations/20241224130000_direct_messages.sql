@@ -0,0 +1,16 @@
+-- +goose up
+CREATE TABLE direct_messages (
+    id UUID PRIMARY KEY,
+    sender_id UUID NOT NULL,
+    recipient_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (recipient_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_direct_messages_conversation ON direct_messages(sender_id, recipient_id, created_at);
+
+-- +goose down
+DROP TABLE IF EXISTS direct_messages;
@@ -0,0 +1,7 @@
+-- +goose up
+UPDATE personal_access_tokens SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+
+-- +goose down
+-- The plain tokens cannot be recovered from their hashes. Rolling back
+-- leaves every token unusable until it is issued again.
+DELETE FROM personal_access_tokens;
@@ -0,0 +1,7 @@
+-- +goose up
+CREATE INDEX idx_match_results_created_at ON match_results(created_at DESC, id DESC);
+CREATE INDEX idx_game_events_game_created_at ON game_events(game_id, created_at, id);
+
+-- +goose down
+DROP INDEX IF EXISTS idx_game_events_game_created_at;
+DROP INDEX IF EXISTS idx_match_results_created_at;
@@ -3,22 +3,36 @@ package models
 import (
 	"encoding/json"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"time"
 )
 
+// User is an account. Credentials and contact details never appear in its
+// JSON, so a user nested in any response cannot leak them; the account owner
+// gets those through the handlers' UserResponse.
 type User struct {
-	ID              uuid.UUID      `gorm:"primaryKey;column:id" json:"id"`
-	Name            string         `gorm:"column:name;not null" json:"name"`
-	Email           string         `gorm:"column:email;unique;not null" json:"email"`
-	EmailVerifiedAt *time.Time     `gorm:"column:email_verified_at" json:"email_verified_at"`
-	Password        string         `gorm:"column:password;not null" json:"password"`
-	Avatar          *string        `gorm:"column:avatar" json:"avatar"`
-	RememberToken   *string        `gorm:"column:remember_token;size:100" json:"remember_token"`
-	CreatedAt       *time.Time     `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt       *time.Time     `gorm:"column:updated_at" json:"updated_at"`
-	Lobbies         []Lobby        `gorm:"foreignKey:OwnerID" json:"lobbies"`
-	Players         []Player       `gorm:"foreignKey:UserID" json:"players"`
-	Notifications   []Notification `gorm:"foreignKey:UserID" json:"notifications"`
+	ID              uuid.UUID         `gorm:"primaryKey;column:id" json:"id"`
+	Name            string            `gorm:"column:name;not null" json:"name"`
+	DisplayName     *string           `gorm:"column:display_name;size:50" json:"display_name"`
+	Bio             *string           `gorm:"column:bio;size:500" json:"bio"`
+	Country         *string           `gorm:"column:country;size:2" json:"country"`
+	Email           string            `gorm:"column:email;unique;not null" json:"-"`
+	EmailVerifiedAt *time.Time        `gorm:"column:email_verified_at" json:"-"`
+	Password        string            `gorm:"column:password;not null" json:"-"`
+	Avatar          *string           `gorm:"column:avatar" json:"avatar"`
+	AvatarURL       *string           `gorm:"-" json:"avatar_url,omitempty"`
+	AvatarVariants  map[string]string `gorm:"-" json:"avatar_variants,omitempty"`
+	RememberToken   *string           `gorm:"column:remember_token;size:100" json:"-"`
+	IsBot           bool              `gorm:"column:is_bot;default:false;not null" json:"is_bot"`
+	IsAdmin         bool              `gorm:"column:is_admin;default:false;not null" json:"-"`
+	XP              int               `gorm:"column:xp;default:0;not null" json:"xp"`
+	FailedLogins    int               `gorm:"column:failed_logins;default:0;not null" json:"-"`
+	LockedUntil     *time.Time        `gorm:"column:locked_until" json:"-"`
+	CreatedAt       *time.Time        `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       *time.Time        `gorm:"column:updated_at" json:"updated_at"`
+	Lobbies         []Lobby           `gorm:"foreignKey:OwnerID" json:"lobbies"`
+	Players         []Player          `gorm:"foreignKey:UserID" json:"players"`
+	Notifications   []Notification    `gorm:"foreignKey:UserID" json:"notifications"`
 }
 
 func (User) TableName() string {
@@ -27,7 +41,7 @@ func (User) TableName() string {
 
 type PasswordResetToken struct {
 	Email     string     `gorm:"primaryKey;column:email" json:"email"`
-	Token     string     `gorm:"column:token;not null" json:"token"`
+	Token     string     `gorm:"column:token;not null" json:"-"`
 	CreatedAt *time.Time `gorm:"column:created_at" json:"created_at"`
 }
 
@@ -35,12 +49,86 @@ func (PasswordResetToken) TableName() string {
 	return "password_reset_tokens"
 }
 
+// LoginAttempt records a failed login, with the account it targeted when the
+// email belongs to one.
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	UserID    *uuid.UUID `gorm:"column:user_id" json:"user_id"`
+	Email     string     `gorm:"column:email;not null" json:"email"`
+	IPAddress string     `gorm:"column:ip_address;size:45;not null" json:"ip_address"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// Ban keeps a user out of the API until ExpiresAt, or for good when it is
+// nil. Lifting a ban sets RevokedAt rather than deleting it, so the history
+// stays visible to admins.
+type Ban struct {
+	ID        uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uuid.UUID  `gorm:"column:user_id;not null;index" json:"user_id"`
+	Reason    string     `gorm:"column:reason;not null" json:"reason"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	IssuedBy  *uuid.UUID `gorm:"column:issued_by" json:"issued_by"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	RevokedBy *uuid.UUID `gorm:"column:revoked_by" json:"revoked_by"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;not null" json:"updated_at"`
+}
+
+func (Ban) TableName() string {
+	return "bans"
+}
+
+// Mute stops a user from chatting until ExpiresAt, or for good when it is
+// nil. Like bans, lifted mutes keep their row with RevokedAt set.
+type Mute struct {
+	ID        uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uuid.UUID  `gorm:"column:user_id;not null;index" json:"user_id"`
+	Reason    string     `gorm:"column:reason;not null" json:"reason"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	IssuedBy  *uuid.UUID `gorm:"column:issued_by" json:"issued_by"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	RevokedBy *uuid.UUID `gorm:"column:revoked_by" json:"revoked_by"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;not null" json:"updated_at"`
+}
+
+func (Mute) TableName() string {
+	return "mutes"
+}
+
+// Report is a player's complaint about another player, optionally tied to the
+// game or chat message it concerns. Admins resolve it with an Action.
+type Report struct {
+	ID             uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	ReporterID     uuid.UUID  `gorm:"column:reporter_id;not null" json:"reporter_id"`
+	ReportedUserID uuid.UUID  `gorm:"column:reported_user_id;not null;index" json:"reported_user_id"`
+	Category       string     `gorm:"column:category;type:varchar(20);not null" json:"category"`
+	GameID         *uuid.UUID `gorm:"column:game_id" json:"game_id"`
+	MessageID      *uuid.UUID `gorm:"column:message_id" json:"message_id"`
+	Description    string     `gorm:"column:description;not null;default:''" json:"description"`
+	Status         string     `gorm:"column:status;type:varchar(20);default:'open';not null" json:"status"`
+	Action         *string    `gorm:"column:action;type:varchar(20)" json:"action"`
+	ResolutionNote *string    `gorm:"column:resolution_note" json:"resolution_note"`
+	ResolvedBy     *uuid.UUID `gorm:"column:resolved_by" json:"resolved_by"`
+	ResolvedAt     *time.Time `gorm:"column:resolved_at" json:"resolved_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;not null" json:"updated_at"`
+}
+
+func (Report) TableName() string {
+	return "reports"
+}
+
 type Session struct {
 	ID           uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
 	UserID       uuid.UUID `gorm:"column:user_id" json:"user_id"`
 	IPAddress    string    `gorm:"column:ip_address;size:45" json:"ip_address"`
 	UserAgent    string    `gorm:"column:user_agent;type:text" json:"user_agent"`
-	Payload      string    `gorm:"column:payload;type:text;not null" json:"payload"`
+	Payload      string    `gorm:"column:payload;type:text;not null" json:"-"`
 	LastActivity int       `gorm:"column:last_activity;not null;index" json:"last_activity"`
 	User         *User     `gorm:"foreignKey:UserID" json:"user"`
 }
@@ -59,9 +147,13 @@ type Lobby struct {
 	MaxPlayers       int               `gorm:"column:max_players;default:4;not null" json:"max_players"`
 	CurrentPlayers   int               `gorm:"column:current_players;default:0;not null" json:"current_players"`
 	PrivacyLevel     string            `gorm:"column:privacy_level;type:varchar(20);default:'open';not null" json:"privacy_level"`
-	PasswordHash     *string           `gorm:"column:password_hash" json:"password_hash"`
+	PasswordHash     *string           `gorm:"column:password_hash" json:"-"`
+	HasPassword      bool              `gorm:"-" json:"has_password"`
+	InviteCode       *string           `gorm:"column:invite_code;unique" json:"-"`
+	InviteExpiresAt  *time.Time        `gorm:"column:invite_code_expires_at" json:"-"`
 	SpectatorAllowed bool              `gorm:"column:spectator_allowed;default:true;not null" json:"spectator_allowed"`
 	SpectatorCount   int               `gorm:"column:spectator_count;default:0;not null" json:"spectator_count"`
+	MaxSpectators    int               `gorm:"column:max_spectators;default:20;not null" json:"max_spectators"`
 	GameMode         string            `gorm:"column:game_mode;type:varchar(20);default:'casual';not null" json:"game_mode"`
 	GameSettings     json.RawMessage   `gorm:"column:game_settings;type:jsonb" json:"game_settings"`
 	CreatedAt        time.Time         `gorm:"column:created_at" json:"created_at"`
@@ -76,6 +168,13 @@ func (Lobby) TableName() string {
 	return "lobbies"
 }
 
+// AfterFind records whether the lobby is password protected, which survives
+// JSON encoding where the hash itself does not.
+func (l *Lobby) AfterFind(*gorm.DB) error {
+	l.HasPassword = l.PasswordHash != nil
+	return nil
+}
+
 type Game struct {
 	ID                  uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
 	LobbyID             uuid.UUID `gorm:"column:lobby_id" json:"lobby_id"`
@@ -85,6 +184,7 @@ type Game struct {
 	CurrentTurnPlayerID uuid.UUID `gorm:"column:current_turn_player_id;null" json:"current_turn_player_id"`
 	RoundNumber         int       `gorm:"column:round_number;default:1;not null" json:"round_number"`
 	Winner              string    `gorm:"column:winner;type:varchar(20);default:'none';not null" json:"winner"`
+	EventSequence       int64     `gorm:"column:event_sequence;default:0;not null" json:"event_sequence"`
 	CreatedAt           time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt           time.Time `gorm:"column:updated_at" json:"updated_at"`
 
@@ -118,6 +218,8 @@ type Deck struct {
 	GameID            uuid.UUID       `gorm:"column:game_id;not null" json:"game_id"`
 	Game              Game            `gorm:"foreignKey:GameID" json:"game"`
 	DeckType          string          `gorm:"column:deck_type;type:varchar(20);default:'standard';not null" json:"deck_type"`
+	PackCount         int             `gorm:"column:pack_count;default:1;not null" json:"pack_count"`
+	JokerCount        int             `gorm:"column:joker_count;default:0;not null" json:"joker_count"`
 	TotalCards        int             `gorm:"column:total_cards;default:52;not null" json:"total_cards"`
 	RemainingCards    int             `gorm:"column:remaining_cards;default:52;not null" json:"remaining_cards"`
 	ExternalDeckID    string          `gorm:"column:external_deck_id" json:"external_deck_id"`
@@ -135,9 +237,9 @@ type Card struct {
 	ID            uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
 	DeckID        uuid.UUID  `gorm:"column:deck_id;not null" json:"deck_id"`
 	Deck          Deck       `gorm:"foreignKey:DeckID" json:"deck"`
-	GameID        uuid.UUID  `gorm:"column:game_id;not null" json:"game_id"`
+	GameID        uuid.UUID  `gorm:"column:game_id;not null;uniqueIndex:idx_cards_game_code" json:"game_id"`
 	Game          Game       `gorm:"foreignKey:GameID" json:"game"`
-	Code          string     `gorm:"column:code;unique;not null;size:10" json:"code"`
+	Code          string     `gorm:"column:code;not null;size:10;uniqueIndex:idx_cards_game_code" json:"code"`
 	Value         string     `gorm:"column:value;size:10;not null" json:"value"`
 	Suit          string     `gorm:"column:suit;size:10;not null" json:"suit"`
 	ImageURL      *string    `gorm:"column:image_url" json:"image_url"`
@@ -145,6 +247,7 @@ type Card struct {
 	LocationType  string     `gorm:"column:location_type;type:varchar(20);default:'deck';not null" json:"location_type"`
 	PlayerID      *uuid.UUID `gorm:"column:player_id" json:"player_id"`
 	Player        *User      `gorm:"foreignKey:PlayerID" json:"player"`
+	PilePosition  *int       `gorm:"column:pile_position" json:"pile_position,omitempty"`
 	IsSpecialCard bool       `gorm:"column:is_special_card;default:false;not null" json:"is_special_card"`
 	SpecialAction string     `gorm:"column:special_action;type:varchar(20);default:'none';not null" json:"special_action"`
 	CreatedAt     time.Time  `gorm:"column:created_at" json:"created_at"`
@@ -156,15 +259,20 @@ func (Card) TableName() string {
 }
 
 type Player struct {
-	ID        uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
-	GameID    uuid.UUID  `gorm:"column:game_id;not null" json:"game_id"`
-	UserID    uuid.UUID  `gorm:"column:user_id;not null" json:"user_id"`
-	LobbyID   uuid.UUID  `gorm:"column:lobby_id;not null" json:"lobby_id"`
-	Role      string     `gorm:"column:role;type:varchar(20);default:'player1';not null" json:"role"`
-	IsReady   bool       `gorm:"column:is_ready;default:false;not null" json:"is_ready"`
-	Score     int        `gorm:"column:score;default:0;not null" json:"score"`
-	CreatedAt *time.Time `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt *time.Time `gorm:"column:updated_at" json:"updated_at"`
+	ID            uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	GameID        uuid.UUID  `gorm:"column:game_id;not null;uniqueIndex:idx_players_game_user" json:"game_id"`
+	UserID        uuid.UUID  `gorm:"column:user_id;not null;uniqueIndex:idx_players_game_user;uniqueIndex:idx_players_lobby_user" json:"user_id"`
+	LobbyID       uuid.UUID  `gorm:"column:lobby_id;not null;uniqueIndex:idx_players_lobby_user" json:"lobby_id"`
+	Role          string     `gorm:"column:role;type:varchar(20);default:'player1';not null" json:"role"`
+	IsReady       bool       `gorm:"column:is_ready;default:false;not null" json:"is_ready"`
+	Score         int        `gorm:"column:score;default:0;not null" json:"score"`
+	Status        string     `gorm:"column:status;type:varchar(20);default:'active';not null" json:"status"`
+	IsBot         bool       `gorm:"column:is_bot;default:false;not null" json:"is_bot"`
+	SwapConfirmed bool       `gorm:"column:swap_confirmed;default:false;not null" json:"swap_confirmed"`
+	Placement     *int       `gorm:"column:placement" json:"placement"`
+	Team          *int       `gorm:"column:team" json:"team,omitempty"`
+	CreatedAt     *time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     *time.Time `gorm:"column:updated_at" json:"updated_at"`
 
 	User  User  `gorm:"foreignKey:UserID" json:"user"`
 	Lobby Lobby `gorm:"foreignKey:LobbyID" json:"lobby"`
@@ -181,7 +289,7 @@ type LobbyQueue struct {
 	Lobby     Lobby      `gorm:"foreignKey:LobbyID" json:"lobby"`
 	UserID    uuid.UUID  `gorm:"column:user_id;not null" json:"user_id"`
 	User      User       `gorm:"foreignKey:UserID" json:"user"`
-	QueueType string     `gorm:"column:queue_type;type:varchar(20);default:'waiting';not null" json:"queue_type"`
+	QueueType string     `gorm:"column:queue_type;type:varchar(20);default:'player';not null" json:"queue_type"`
 	Priority  int        `gorm:"column:priority;default:0;not null" json:"priority"`
 	Position  *int       `gorm:"column:position" json:"position"`
 	CreatedAt *time.Time `gorm:"column:created_at" json:"created_at"`
@@ -192,6 +300,19 @@ func (LobbyQueue) TableName() string {
 	return "lobby_queues"
 }
 
+type LobbySpectator struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	LobbyID   uuid.UUID `gorm:"column:lobby_id;not null" json:"lobby_id"`
+	UserID    uuid.UUID `gorm:"column:user_id;not null" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"user"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (LobbySpectator) TableName() string {
+	return "lobby_spectators"
+}
+
 type Notification struct {
 	ID        uuid.UUID       `gorm:"type:uuid;primaryKey;column:id" json:"id"`
 	Type      *string         `gorm:"column:type" json:"type"`
@@ -212,7 +333,7 @@ type PersonalAccessToken struct {
 	TokenableType string     `gorm:"column:tokenable_type;not null" json:"tokenable_type"`
 	TokenableID   uuid.UUID  `gorm:"column:tokenable_id;not null" json:"tokenable_id"`
 	Name          string     `gorm:"column:name;not null" json:"name"`
-	Token         string     `gorm:"column:token;unique;not null;size:64" json:"token"`
+	Token         string     `gorm:"column:token;unique;not null;size:64" json:"-"`
 	Abilities     *string    `gorm:"column:abilities;type:text" json:"abilities"`
 	LastUsedAt    *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
 	ExpiresAt     *time.Time `gorm:"column:expires_at" json:"expires_at"`
@@ -223,3 +344,307 @@ type PersonalAccessToken struct {
 func (PersonalAccessToken) TableName() string {
 	return "personal_access_tokens"
 }
+
+type LeaderboardSnapshot struct {
+	UserID      uuid.UUID `gorm:"primaryKey;column:user_id" json:"user_id"`
+	User        User      `gorm:"foreignKey:UserID" json:"user"`
+	GamesPlayed int       `gorm:"column:games_played;default:0;not null" json:"games_played"`
+	Wins        int       `gorm:"column:wins;default:0;not null" json:"wins"`
+	ComputedAt  time.Time `gorm:"column:computed_at;not null" json:"computed_at"`
+}
+
+func (LeaderboardSnapshot) TableName() string {
+	return "leaderboard_snapshots"
+}
+
+type MatchmakingEntry struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uuid.UUID `gorm:"column:user_id;unique;not null" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID" json:"user"`
+	Rating    int       `gorm:"column:rating;default:1000;not null;index" json:"rating"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (MatchmakingEntry) TableName() string {
+	return "matchmaking_queue"
+}
+
+type Rating struct {
+	UserID      uuid.UUID `gorm:"primaryKey;column:user_id" json:"user_id"`
+	User        User      `gorm:"foreignKey:UserID" json:"user"`
+	Rating      int       `gorm:"column:rating;default:1000;not null;index" json:"rating"`
+	GamesPlayed int       `gorm:"column:games_played;default:0;not null" json:"games_played"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Rating) TableName() string {
+	return "ratings"
+}
+
+type RatingHistory struct {
+	ID           uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	UserID       uuid.UUID `gorm:"column:user_id;not null;index" json:"user_id"`
+	GameID       uuid.UUID `gorm:"column:game_id;not null" json:"game_id"`
+	RatingBefore int       `gorm:"column:rating_before;not null" json:"rating_before"`
+	RatingAfter  int       `gorm:"column:rating_after;not null" json:"rating_after"`
+	Delta        int       `gorm:"column:delta;not null" json:"delta"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (RatingHistory) TableName() string {
+	return "rating_history"
+}
+
+type MatchResult struct {
+	ID              uuid.UUID          `gorm:"primaryKey;column:id" json:"id"`
+	GameID          uuid.UUID          `gorm:"column:game_id;unique;not null" json:"game_id"`
+	LobbyID         uuid.UUID          `gorm:"column:lobby_id;not null" json:"lobby_id"`
+	GameMode        string             `gorm:"column:game_mode;type:varchar(20);not null" json:"game_mode"`
+	ShitheadUserID  *uuid.UUID         `gorm:"column:shithead_user_id" json:"shithead_user_id"`
+	WinningTeam     *int               `gorm:"column:winning_team" json:"winning_team,omitempty"`
+	StartedAt       time.Time          `gorm:"column:started_at;not null" json:"started_at"`
+	FinishedAt      time.Time          `gorm:"column:finished_at;not null" json:"finished_at"`
+	DurationSeconds int                `gorm:"column:duration_seconds;default:0;not null" json:"duration_seconds"`
+	CreatedAt       time.Time          `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time          `gorm:"column:updated_at" json:"updated_at"`
+	Participants    []MatchParticipant `gorm:"foreignKey:MatchResultID" json:"participants"`
+}
+
+func (MatchResult) TableName() string {
+	return "match_results"
+}
+
+type MatchParticipant struct {
+	ID            uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	MatchResultID uuid.UUID `gorm:"column:match_result_id;not null" json:"match_result_id"`
+	UserID        uuid.UUID `gorm:"column:user_id;not null;index" json:"user_id"`
+	User          User      `gorm:"foreignKey:UserID" json:"user"`
+	PlayerID      uuid.UUID `gorm:"column:player_id;not null" json:"player_id"`
+	Role          string    `gorm:"column:role;type:varchar(20);not null" json:"role"`
+	Placement     int       `gorm:"column:placement;not null" json:"placement"`
+	Team          *int      `gorm:"column:team" json:"team,omitempty"`
+	TeamPlacement *int      `gorm:"column:team_placement" json:"team_placement,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (MatchParticipant) TableName() string {
+	return "match_participants"
+}
+
+type GameEvent struct {
+	ID        uuid.UUID       `gorm:"primaryKey;column:id" json:"id"`
+	GameID    uuid.UUID       `gorm:"column:game_id;not null;uniqueIndex:idx_game_events_sequence" json:"game_id"`
+	Sequence  int64           `gorm:"column:sequence;not null;uniqueIndex:idx_game_events_sequence" json:"sequence"`
+	Type      string          `gorm:"column:type;type:varchar(30);not null" json:"type"`
+	PlayerID  *uuid.UUID      `gorm:"column:player_id" json:"player_id"`
+	Payload   json.RawMessage `gorm:"column:payload;type:jsonb;not null" json:"payload"`
+	CreatedAt time.Time       `gorm:"column:created_at" json:"created_at"`
+}
+
+func (GameEvent) TableName() string {
+	return "game_events"
+}
+
+type Friendship struct {
+	ID          uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	RequesterID uuid.UUID `gorm:"column:requester_id;not null" json:"requester_id"`
+	Requester   User      `gorm:"foreignKey:RequesterID" json:"requester"`
+	AddresseeID uuid.UUID `gorm:"column:addressee_id;not null;index" json:"addressee_id"`
+	Addressee   User      `gorm:"foreignKey:AddresseeID" json:"addressee"`
+	Status      string    `gorm:"column:status;type:varchar(20);default:'requested';not null" json:"status"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Friendship) TableName() string {
+	return "friendships"
+}
+
+type NotificationPreference struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uuid.UUID `gorm:"column:user_id;not null;uniqueIndex:idx_notification_preferences_user_category" json:"user_id"`
+	Category  string    `gorm:"column:category;type:varchar(30);not null;uniqueIndex:idx_notification_preferences_user_category" json:"category"`
+	Enabled   bool      `gorm:"column:enabled;default:true;not null" json:"enabled"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// ChatMessage is a message sent in a game's chat, stored after the blocked
+// word filter has masked it.
+type ChatMessage struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	GameID    uuid.UUID `gorm:"column:game_id;not null" json:"game_id"`
+	UserID    uuid.UUID `gorm:"column:user_id;not null" json:"user_id"`
+	Body      string    `gorm:"column:body;not null" json:"body"`
+	CreatedAt time.Time `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (ChatMessage) TableName() string {
+	return "chat_messages"
+}
+
+// DirectMessage is a private message from one user to another, outside any
+// game.
+type DirectMessage struct {
+	ID          uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	SenderID    uuid.UUID `gorm:"column:sender_id;not null" json:"sender_id"`
+	RecipientID uuid.UUID `gorm:"column:recipient_id;not null" json:"recipient_id"`
+	Body        string    `gorm:"column:body;not null" json:"body"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (DirectMessage) TableName() string {
+	return "direct_messages"
+}
+
+// LobbyMute silences a player in one lobby's chat. It is set by the lobby
+// owner and goes away with the lobby.
+type LobbyMute struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	LobbyID   uuid.UUID `gorm:"column:lobby_id;not null;uniqueIndex:idx_lobby_mutes_lobby_user" json:"lobby_id"`
+	UserID    uuid.UUID `gorm:"column:user_id;not null;uniqueIndex:idx_lobby_mutes_lobby_user" json:"user_id"`
+	MutedBy   uuid.UUID `gorm:"column:muted_by;not null" json:"muted_by"`
+	CreatedAt time.Time `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (LobbyMute) TableName() string {
+	return "lobby_mutes"
+}
+
+// AuditLog is one entry in the trail of sensitive actions. ActorID is nil for
+// actions taken by the server itself, such as scheduled cleanup. It has no
+// foreign key so entries outlive the accounts they mention.
+type AuditLog struct {
+	ID         uuid.UUID       `gorm:"primaryKey;column:id" json:"id"`
+	ActorID    *uuid.UUID      `gorm:"column:actor_id" json:"actor_id"`
+	Action     string          `gorm:"column:action;type:varchar(50);not null" json:"action"`
+	TargetType string          `gorm:"column:target_type;type:varchar(30);not null" json:"target_type"`
+	TargetID   string          `gorm:"column:target_id;type:varchar(64);not null" json:"target_id"`
+	IPAddress  string          `gorm:"column:ip_address;size:45;not null" json:"ip_address"`
+	Details    json.RawMessage `gorm:"column:details;type:jsonb" json:"details"`
+	CreatedAt  time.Time       `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// PrivacySettings controls who can see a user's profile. Users without a row
+// get DefaultPrivacySettings.
+type PrivacySettings struct {
+	UserID uuid.UUID `gorm:"primaryKey;column:user_id" json:"-"`
+	// ProfileVisibility is "public", "friends" or "private".
+	ProfileVisibility string    `gorm:"column:profile_visibility;type:varchar(20);not null" json:"profile_visibility"`
+	HideEmail         bool      `gorm:"column:hide_email;not null" json:"hide_email"`
+	CreatedAt         time.Time `gorm:"column:created_at" json:"-"`
+	UpdatedAt         time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (PrivacySettings) TableName() string {
+	return "privacy_settings"
+}
+
+func DefaultPrivacySettings(userID uuid.UUID) PrivacySettings {
+	return PrivacySettings{
+		UserID:            userID,
+		ProfileVisibility: "public",
+		HideEmail:         true,
+	}
+}
+
+// Season is a stretch of time leaderboards are ranked over. When it ends its
+// standings are archived and ratings decay before the next one starts.
+type Season struct {
+	ID     uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	Number int       `gorm:"column:number;unique;not null" json:"number"`
+	Name   string    `gorm:"column:name;size:100;not null" json:"name"`
+	// Status is "active" for the running season and "archived" after.
+	Status     string     `gorm:"column:status;type:varchar(20);not null" json:"status"`
+	StartsAt   time.Time  `gorm:"column:starts_at;not null" json:"starts_at"`
+	EndsAt     time.Time  `gorm:"column:ends_at;not null" json:"ends_at"`
+	ArchivedAt *time.Time `gorm:"column:archived_at" json:"archived_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Season) TableName() string {
+	return "seasons"
+}
+
+// SeasonStanding is a user's final place in one game mode of an archived
+// season. Rating is only set for ranked.
+type SeasonStanding struct {
+	ID          uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	SeasonID    uuid.UUID `gorm:"column:season_id;not null" json:"season_id"`
+	GameMode    string    `gorm:"column:game_mode;type:varchar(20);not null" json:"game_mode"`
+	UserID      uuid.UUID `gorm:"column:user_id;not null" json:"user_id"`
+	User        User      `gorm:"foreignKey:UserID" json:"user"`
+	Rank        int       `gorm:"column:rank;not null" json:"rank"`
+	Rating      *int      `gorm:"column:rating" json:"rating"`
+	GamesPlayed int       `gorm:"column:games_played;not null" json:"games_played"`
+	Wins        int       `gorm:"column:wins;not null" json:"wins"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (SeasonStanding) TableName() string {
+	return "season_standings"
+}
+
+// XPEvent records one grant of experience, with the levels before and after
+// so level-ups can be announced once the grant is committed.
+type XPEvent struct {
+	ID     uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	UserID uuid.UUID `gorm:"column:user_id;not null" json:"user_id"`
+	Amount int       `gorm:"column:amount;not null" json:"amount"`
+	// Source is what earned the XP, such as "game".
+	Source      string     `gorm:"column:source;type:varchar(30);not null" json:"source"`
+	GameID      *uuid.UUID `gorm:"column:game_id" json:"game_id"`
+	LevelBefore int        `gorm:"column:level_before;not null" json:"level_before"`
+	LevelAfter  int        `gorm:"column:level_after;not null" json:"level_after"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (XPEvent) TableName() string {
+	return "xp_events"
+}
+
+// Challenge is a daily or weekly goal offered to every player while it runs.
+type Challenge struct {
+	ID uuid.UUID `gorm:"primaryKey;column:id" json:"id"`
+	// Period is "daily" or "weekly".
+	Period      string    `gorm:"column:period;type:varchar(10);not null" json:"period"`
+	Metric      string    `gorm:"column:metric;type:varchar(30);not null" json:"metric"`
+	Description string    `gorm:"column:description;size:100;not null" json:"description"`
+	Target      int       `gorm:"column:target;not null" json:"target"`
+	XPReward    int       `gorm:"column:xp_reward;not null" json:"xp_reward"`
+	StartsAt    time.Time `gorm:"column:starts_at;not null" json:"starts_at"`
+	EndsAt      time.Time `gorm:"column:ends_at;not null" json:"ends_at"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (Challenge) TableName() string {
+	return "challenges"
+}
+
+// UserChallenge tracks one player's progress on a challenge. Rows are only
+// created once the player makes progress.
+type UserChallenge struct {
+	ID          uuid.UUID  `gorm:"primaryKey;column:id" json:"id"`
+	ChallengeID uuid.UUID  `gorm:"column:challenge_id;not null" json:"challenge_id"`
+	UserID      uuid.UUID  `gorm:"column:user_id;not null" json:"user_id"`
+	Progress    int        `gorm:"column:progress;default:0;not null" json:"progress"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+	ClaimedAt   *time.Time `gorm:"column:claimed_at" json:"claimed_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (UserChallenge) TableName() string {
+	return "user_challenges"
+}
@@ -0,0 +1,238 @@
+// Package firebase verifies Firebase Authentication ID tokens, so the API
+// only trusts the identity Google signed rather than what the client says
+// about itself.
+package firebase
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CertsURL publishes the public keys Firebase signs ID tokens with.
+const CertsURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+// clockSkew is how far the token's timestamps may disagree with ours.
+const clockSkew = 5 * time.Minute
+
+var (
+	ErrNotConfigured = errors.New("firebase: project ID is not configured")
+	ErrInvalidToken  = errors.New("firebase: invalid ID token")
+)
+
+// Claims is the verified identity carried by an ID token.
+type Claims struct {
+	UID           string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// Verifier checks ID tokens issued for one Firebase project. The signing
+// keys are fetched on first use and cached for as long as Google allows.
+type Verifier struct {
+	projectID string
+	certsURL  string
+	client    *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+	expires time.Time
+}
+
+func NewVerifier(projectID string) *Verifier {
+	return &Verifier{
+		projectID: projectID,
+		certsURL:  CertsURL,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type payload struct {
+	Iss           string `json:"iss"`
+	Aud           string `json:"aud"`
+	Sub           string `json:"sub"`
+	Iat           int64  `json:"iat"`
+	Exp           int64  `json:"exp"`
+	AuthTime      int64  `json:"auth_time"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+// Verify checks the token's signature, audience, issuer and lifetime as
+// Firebase documents, and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, idToken string) (*Claims, error) {
+	if v.projectID == "" {
+		return nil, ErrNotConfigured
+	}
+
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if h.Alg != "RS256" || h.Kid == "" {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := v.key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var p payload
+	if err := decodeSegment(parts[1], &p); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	switch {
+	case p.Aud != v.projectID:
+		return nil, ErrInvalidToken
+	case p.Iss != "https://securetoken.google.com/"+v.projectID:
+		return nil, ErrInvalidToken
+	case p.Sub == "" || len(p.Sub) > 128:
+		return nil, ErrInvalidToken
+	case time.Unix(p.Exp, 0).Before(now.Add(-clockSkew)):
+		return nil, ErrInvalidToken
+	case time.Unix(p.Iat, 0).After(now.Add(clockSkew)):
+		return nil, ErrInvalidToken
+	case time.Unix(p.AuthTime, 0).After(now.Add(clockSkew)):
+		return nil, ErrInvalidToken
+	}
+
+	return &Claims{
+		UID:           p.Sub,
+		Email:         p.Email,
+		EmailVerified: p.EmailVerified,
+		Name:          p.Name,
+		Picture:       p.Picture,
+	}, nil
+}
+
+// key returns the public key with the given ID, refreshing the cached set
+// when it has expired or does not know the ID, since Google rotates keys.
+// Unknown IDs refresh at most once a minute so forged tokens cannot make
+// every login fetch the keys.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if time.Now().Before(v.expires) {
+		if key, ok := v.keys[kid]; ok {
+			return key, nil
+		}
+		if time.Since(v.fetched) < time.Minute {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	keys, expires, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.fetched = time.Now()
+	v.expires = expires
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.certsURL, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("firebase: fetching signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("firebase: fetching signing keys: status %d", resp.StatusCode)
+	}
+
+	var certs map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+		return nil, time.Time{}, fmt.Errorf("firebase: decoding signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(certs))
+	for kid, certPEM := range certs {
+		block, _ := pem.Decode([]byte(certPEM))
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			keys[kid] = key
+		}
+	}
+
+	return keys, time.Now().Add(maxAge(resp.Header.Get("Cache-Control"))), nil
+}
+
+// maxAge reads max-age from a Cache-Control header, falling back to an hour.
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || name != "max-age" {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return time.Hour
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,132 @@
+package firebase
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testProject = "shithead-test"
+
+func newTestVerifier(t *testing.T) (*Verifier, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "securetoken"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(map[string]string{"kid-1": string(certPEM)})
+	}))
+	t.Cleanup(server.Close)
+
+	v := NewVerifier(testProject)
+	v.certsURL = server.URL
+	return v, key
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now().Unix()
+	return map[string]interface{}{
+		"iss":            "https://securetoken.google.com/" + testProject,
+		"aud":            testProject,
+		"sub":            "firebase-uid",
+		"iat":            now - 10,
+		"exp":            now + 3600,
+		"auth_time":      now - 10,
+		"email":          "player@example.com",
+		"email_verified": true,
+	}
+}
+
+func TestVerifyAcceptsSignedToken(t *testing.T) {
+	v, key := newTestVerifier(t)
+
+	claims, err := v.Verify(context.Background(), sign(t, key, "kid-1", validClaims()))
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.UID != "firebase-uid" || claims.Email != "player@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestVerifyRejectsBadTokens(t *testing.T) {
+	v, key := newTestVerifier(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		claims[name] = value
+		return claims
+	}
+
+	tests := map[string]string{
+		"malformed":     "not-a-token",
+		"wrong key":     sign(t, otherKey, "kid-1", validClaims()),
+		"unknown kid":   sign(t, key, "kid-2", validClaims()),
+		"wrong project": sign(t, key, "kid-1", with("aud", "someone-else")),
+		"wrong issuer":  sign(t, key, "kid-1", with("iss", "https://example.com")),
+		"no subject":    sign(t, key, "kid-1", with("sub", "")),
+		"expired":       sign(t, key, "kid-1", with("exp", time.Now().Add(-time.Hour).Unix())),
+		"issued later":  sign(t, key, "kid-1", with("iat", time.Now().Add(time.Hour).Unix())),
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyWithoutProject(t *testing.T) {
+	if _, err := NewVerifier("").Verify(context.Background(), "a.b.c"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+}
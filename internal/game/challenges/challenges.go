@@ -0,0 +1,100 @@
+// Package challenges defines the daily and weekly challenges players can
+// complete for XP, and the periods they run over.
+package challenges
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+type Period string
+
+const (
+	Daily  Period = "daily"
+	Weekly Period = "weekly"
+)
+
+// Metric is what a challenge counts.
+type Metric string
+
+const (
+	GamesPlayed        Metric = "games_played"
+	GamesWon           Metric = "games_won"
+	CardsPlayed        Metric = "cards_played"
+	SpecialCardsPlayed Metric = "special_cards_played"
+	PilesBurned        Metric = "piles_burned"
+)
+
+// Template describes a challenge that can be handed out.
+type Template struct {
+	Metric Metric
+	Target int
+	XP     int
+}
+
+// Description is the text shown to players, such as "Win 2 games".
+func (t Template) Description() string {
+	switch t.Metric {
+	case GamesPlayed:
+		return fmt.Sprintf("Play %d games", t.Target)
+	case GamesWon:
+		return fmt.Sprintf("Win %d games", t.Target)
+	case CardsPlayed:
+		return fmt.Sprintf("Play %d cards", t.Target)
+	case SpecialCardsPlayed:
+		return fmt.Sprintf("Play %d special cards", t.Target)
+	case PilesBurned:
+		return fmt.Sprintf("Burn the pile %d times", t.Target)
+	}
+	return string(t.Metric)
+}
+
+// Catalog lists the challenges each period draws from. Every metric appears
+// at most once per period so a period never hands out the same goal twice.
+var Catalog = map[Period][]Template{
+	Daily: {
+		{Metric: GamesPlayed, Target: 3, XP: 40},
+		{Metric: GamesWon, Target: 2, XP: 60},
+		{Metric: CardsPlayed, Target: 40, XP: 40},
+		{Metric: SpecialCardsPlayed, Target: 5, XP: 50},
+		{Metric: PilesBurned, Target: 2, XP: 50},
+	},
+	Weekly: {
+		{Metric: GamesPlayed, Target: 15, XP: 200},
+		{Metric: GamesWon, Target: 7, XP: 300},
+		{Metric: CardsPlayed, Target: 250, XP: 200},
+		{Metric: SpecialCardsPlayed, Target: 30, XP: 200},
+		{Metric: PilesBurned, Target: 10, XP: 250},
+	},
+}
+
+// PerPeriod is how many challenges run at once in each period.
+const PerPeriod = 3
+
+// Bounds returns the period containing t: the UTC day, or the ISO week
+// starting on Monday.
+func Bounds(period Period, t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == Weekly {
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Pick chooses the challenges for the period starting at start. The choice
+// only depends on the period, so every instance generating challenges at
+// the same time agrees on them.
+func Pick(period Period, start time.Time) []Template {
+	catalog := append([]Template(nil), Catalog[period]...)
+	random := rand.New(rand.NewSource(start.Unix()))
+	random.Shuffle(len(catalog), func(i, j int) {
+		catalog[i], catalog[j] = catalog[j], catalog[i]
+	})
+	if len(catalog) > PerPeriod {
+		catalog = catalog[:PerPeriod]
+	}
+	return catalog
+}
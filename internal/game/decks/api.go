@@ -0,0 +1,112 @@
+package decks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type apiDeck struct {
+	Success   bool   `json:"success"`
+	DeckID    string `json:"deck_id"`
+	Cards     []Card `json:"cards"`
+	Remaining int    `json:"remaining"`
+}
+
+type APIProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewAPIProvider() *APIProvider {
+	return &APIProvider{
+		client: &http.Client{
+			Timeout: time.Second * 10,
+		},
+		baseURL: "https://deckofcardsapi.com/api/deck",
+	}
+}
+
+func (p *APIProvider) Cards(spec Spec) ([]Card, error) {
+	resp, err := p.client.Get(p.baseURL + "/new/shuffle/?" + newDeckQuery(spec).Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error creating new deck: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+
+	var deckResp apiDeck
+	if err := json.Unmarshal(body, &deckResp); err != nil {
+		return nil, fmt.Errorf("error decoding deck response: %v", err)
+	}
+
+	if !deckResp.Success {
+		return nil, fmt.Errorf("deck creation unsuccessful")
+	}
+
+	drawURL := fmt.Sprintf("%s/%s/draw/?count=%d", p.baseURL, deckResp.DeckID, spec.Size())
+	drawResp, err := p.client.Get(drawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error drawing cards: %v", err)
+	}
+	defer drawResp.Body.Close()
+
+	body, err = io.ReadAll(drawResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading draw response body: %v", err)
+	}
+
+	var deck apiDeck
+	if err := json.Unmarshal(body, &deck); err != nil {
+		return nil, fmt.Errorf("error decoding cards response: %v", err)
+	}
+
+	if !deck.Success {
+		return nil, fmt.Errorf("card draw unsuccessful")
+	}
+
+	if len(deck.Cards) != spec.Size() {
+		return nil, fmt.Errorf("expected %d cards, got %d", spec.Size(), len(deck.Cards))
+	}
+
+	numberCopies(deck.Cards)
+	return deck.Cards, nil
+}
+
+// newDeckQuery asks deckofcardsapi.com for the deck in spec. Short decks are
+// requested as a partial deck listing every card code, once per pack.
+func newDeckQuery(spec Spec) url.Values {
+	query := url.Values{}
+	if spec.Type == Short {
+		codes := make([]string, 0, spec.Size())
+		for pack := 0; pack < spec.Packs(); pack++ {
+			for _, suit := range suits {
+				for _, value := range spec.Values() {
+					codes = append(codes, Code(value, suit))
+				}
+			}
+			if spec.Jokers {
+				for _, suit := range jokerSuits {
+					codes = append(codes, Code(Joker, suit))
+				}
+			}
+		}
+		query.Set("cards", strings.Join(codes, ","))
+		return query
+	}
+
+	query.Set("deck_count", strconv.Itoa(spec.Packs()))
+	if spec.Jokers {
+		query.Set("jokers_enabled", "true")
+	}
+	return query
+}
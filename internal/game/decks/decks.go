@@ -0,0 +1,197 @@
+package decks
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+type Card struct {
+	Code  string `json:"code"`
+	Image string `json:"image"`
+	Value string `json:"value"`
+	Suit  string `json:"suit"`
+}
+
+// Provider deals out a shuffled deck built to spec.
+type Provider interface {
+	Cards(spec Spec) ([]Card, error)
+}
+
+// Type is the kind of deck a game is played with.
+type Type string
+
+const (
+	// Standard is a single 52 card pack.
+	Standard Type = "standard"
+	// Short is a 36 card pack running from 6 up to ace.
+	Short Type = "short"
+	// Double is two standard packs shuffled together.
+	Double Type = "double"
+)
+
+// Joker is the value of a joker card. Each pack brings one black and one
+// red joker when they are enabled.
+const Joker = "JOKER"
+
+// JokersPerPack is how many jokers a pack adds when jokers are enabled.
+const JokersPerPack = 2
+
+// Spec describes the deck to build. Large tables may need more packs than
+// the type brings on its own; Extra adds that many on top.
+type Spec struct {
+	Type   Type
+	Jokers bool
+	Extra  int
+}
+
+var (
+	suits       = []string{"SPADES", "HEARTS", "DIAMONDS", "CLUBS"}
+	values      = []string{"ACE", "2", "3", "4", "5", "6", "7", "8", "9", "10", "JACK", "QUEEN", "KING"}
+	shortValues = []string{"ACE", "6", "7", "8", "9", "10", "JACK", "QUEEN", "KING"}
+	jokerSuits  = []string{"BLACK", "RED"}
+)
+
+// ValidType reports whether t is a known deck type.
+func ValidType(t Type) bool {
+	switch t {
+	case Standard, Short, Double:
+		return true
+	}
+	return false
+}
+
+// Packs is how many packs the deck is made of.
+func (s Spec) Packs() int {
+	packs := 1
+	if s.Type == Double {
+		packs = 2
+	}
+	return packs + s.Extra
+}
+
+// PackSize is the number of cards in a single pack, jokers included.
+func (s Spec) PackSize() int {
+	size := len(suits) * len(s.Values())
+	if s.Jokers {
+		size += JokersPerPack
+	}
+	return size
+}
+
+// Holding returns the spec with just enough extra packs for the deck to hold
+// at least cards cards.
+func (s Spec) Holding(cards int) Spec {
+	s.Extra = 0
+	if short := cards - s.Size(); short > 0 {
+		s.Extra = (short + s.PackSize() - 1) / s.PackSize()
+	}
+	return s
+}
+
+// Values lists the card values in each pack, jokers aside.
+func (s Spec) Values() []string {
+	if s.Type == Short {
+		return shortValues
+	}
+	return values
+}
+
+// JokerCount is how many jokers the deck holds.
+func (s Spec) JokerCount() int {
+	if !s.Jokers {
+		return 0
+	}
+	return JokersPerPack * s.Packs()
+}
+
+// Size is the number of cards in the deck.
+func (s Spec) Size() int {
+	return s.Packs() * s.PackSize()
+}
+
+func NewProvider(name, imageBaseURL string) Provider {
+	switch name {
+	case "deckofcardsapi":
+		return NewAPIProvider()
+	default:
+		return NewLocalProvider(imageBaseURL)
+	}
+}
+
+type LocalProvider struct {
+	imageBaseURL string
+}
+
+func NewLocalProvider(imageBaseURL string) *LocalProvider {
+	if imageBaseURL == "" {
+		imageBaseURL = "/static/cards"
+	}
+	return &LocalProvider{
+		imageBaseURL: strings.TrimRight(imageBaseURL, "/"),
+	}
+}
+
+func (p *LocalProvider) Cards(spec Spec) ([]Card, error) {
+	cards := make([]Card, 0, spec.Size())
+	for pack := 0; pack < spec.Packs(); pack++ {
+		for _, suit := range suits {
+			for _, value := range spec.Values() {
+				cards = append(cards, p.card(value, suit))
+			}
+		}
+		if spec.Jokers {
+			for _, suit := range jokerSuits {
+				cards = append(cards, p.card(Joker, suit))
+			}
+		}
+	}
+	numberCopies(cards)
+
+	rand.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+
+	return cards, nil
+}
+
+func (p *LocalProvider) card(value, suit string) Card {
+	code := Code(value, suit)
+	return Card{
+		Code:  code,
+		Image: fmt.Sprintf("%s/%s.png", p.imageBaseURL, code),
+		Value: value,
+		Suit:  suit,
+	}
+}
+
+// Code builds the two character card code used by deckofcardsapi.com, where
+// tens are written as "0" (e.g. "0H", "AS", "KD") and jokers are "X1"
+// (black) and "X2" (red).
+func Code(value, suit string) string {
+	if value == Joker {
+		if suit == "RED" {
+			return "X2"
+		}
+		return "X1"
+	}
+
+	prefix := value[:1]
+	if value == "10" {
+		prefix = "0"
+	}
+	return prefix + suit[:1]
+}
+
+// numberCopies keeps codes unique within a deck made of several packs by
+// suffixing every repeat of a code with its copy number, e.g. "AS-2".
+func numberCopies(cards []Card) {
+	seen := make(map[string]int, len(cards))
+	for i := range cards {
+		code := cards[i].Code
+		seen[code]++
+		if copies := seen[code]; copies > 1 {
+			cards[i].Code = fmt.Sprintf("%s-%d", code, copies)
+		}
+	}
+}
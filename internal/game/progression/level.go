@@ -0,0 +1,99 @@
+// Package progression turns experience points into levels. Every level takes
+// StepXP more than the one before: level 2 needs 100 XP in total, level 3
+// 300, level 4 600 and so on.
+package progression
+
+import "math"
+
+// StepXP is how much longer each level takes than the previous one.
+const StepXP = 100
+
+// Game XP: every finished game earns FinishXP, the winner WinBonusXP on top
+// and each player PlacementBonusXP for every opponent they finished ahead of.
+const (
+	FinishXP         = 20
+	WinBonusXP       = 30
+	PlacementBonusXP = 5
+)
+
+// Progress describes where a player stands on the level curve.
+type Progress struct {
+	XP    int `json:"xp"`
+	Level int `json:"level"`
+	// LevelXP and NextLevelXP are the totals at which the current and the
+	// next level start.
+	LevelXP     int `json:"level_xp"`
+	NextLevelXP int `json:"next_level_xp"`
+}
+
+// XPForLevel is the total XP at which level starts.
+func XPForLevel(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	return StepXP * level * (level - 1) / 2
+}
+
+// LevelFor returns the level reached with xp in total, starting at 1.
+func LevelFor(xp int) int {
+	if xp <= 0 {
+		return 1
+	}
+	// Solve StepXP * L * (L-1) / 2 <= xp for the largest L, then correct
+	// for floating point error at the boundaries.
+	level := int((1 + math.Sqrt(1+8*float64(xp)/StepXP)) / 2)
+	for XPForLevel(level+1) <= xp {
+		level++
+	}
+	for level > 1 && XPForLevel(level) > xp {
+		level--
+	}
+	return level
+}
+
+// ProgressFor places xp on the level curve.
+func ProgressFor(xp int) Progress {
+	level := LevelFor(xp)
+	return Progress{
+		XP:          xp,
+		Level:       level,
+		LevelXP:     XPForLevel(level),
+		NextLevelXP: XPForLevel(level + 1),
+	}
+}
+
+// GameXP is the XP earned for finishing at placement (1 being first) in a
+// game of players.
+func GameXP(placement, players int) int {
+	xp := FinishXP + PlacementBonusXP*(players-placement)
+	if placement == 1 {
+		xp += WinBonusXP
+	}
+	return xp
+}
+
+// Achievement is an in-game feat worth bonus XP on top of GameXP.
+type Achievement struct {
+	Name string `json:"name"`
+	XP   int    `json:"xp"`
+}
+
+// Feats counts what a player did during a game that achievements look at.
+type Feats struct {
+	Pickups int
+	Burns   int
+}
+
+// Achievements returns the feats earned by a player who finished the game in
+// placement: winning without ever picking up the pile, and burning it three
+// times or more.
+func Achievements(placement int, feats Feats) []Achievement {
+	var earned []Achievement
+	if placement == 1 && feats.Pickups == 0 {
+		earned = append(earned, Achievement{Name: "clean_sweep", XP: 25})
+	}
+	if feats.Burns >= 3 {
+		earned = append(earned, Achievement{Name: "pyromaniac", XP: 15})
+	}
+	return earned
+}
@@ -0,0 +1,68 @@
+// Package rating implements the Elo calculation used for ranked games.
+package rating
+
+import "math"
+
+const (
+	// Default is the rating every player starts from.
+	Default = 1000
+	// KFactor caps how many points a single pairing can move.
+	KFactor = 32
+)
+
+// Expected returns the probability that a player rated a beats one rated b.
+func Expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Adjust scores a finished game as one pairing between every winner and the
+// shithead. Each winner gains points for beating the shithead and the shithead
+// loses the average of those gains, so an upset against strong opponents costs
+// less than losing to weaker ones.
+func Adjust(winners []int, shithead int) ([]int, int) {
+	deltas := make([]int, len(winners))
+	if len(winners) == 0 {
+		return deltas, 0
+	}
+
+	total := 0
+	for i, winner := range winners {
+		deltas[i] = int(math.Round(KFactor * (1 - Expected(winner, shithead))))
+		total += deltas[i]
+	}
+
+	loss := int(math.Round(float64(total) / float64(len(winners))))
+	return deltas, -loss
+}
+
+// AdjustTeams scores a finished team game as one pairing between the winning
+// team and every other team, each team rated at its members' average. The
+// losers drop what the winners gained against them and the winners gain the
+// average of those pairings.
+func AdjustTeams(winners int, losers []int) (int, []int) {
+	deltas := make([]int, len(losers))
+	if len(losers) == 0 {
+		return 0, deltas
+	}
+
+	total := 0
+	for i, loser := range losers {
+		gain := int(math.Round(KFactor * (1 - Expected(winners, loser))))
+		deltas[i] = -gain
+		total += gain
+	}
+
+	return int(math.Round(float64(total) / float64(len(losers)))), deltas
+}
+
+// Average is the rating a team plays at.
+func Average(ratings []int) int {
+	if len(ratings) == 0 {
+		return Default
+	}
+	total := 0
+	for _, r := range ratings {
+		total += r
+	}
+	return int(math.Round(float64(total) / float64(len(ratings))))
+}
@@ -0,0 +1,278 @@
+package rules
+
+import "errors"
+
+type Effect string
+
+const (
+	EffectNone         Effect = "none"
+	EffectReset        Effect = "reset"
+	EffectLowerOrEqual Effect = "lower_or_equal"
+	EffectBurn         Effect = "burn"
+	// EffectWild can be played on anything and is see-through: the next
+	// card has to beat whatever lies beneath it.
+	EffectWild Effect = "wild"
+)
+
+type Phase string
+
+const (
+	PhaseHand     Phase = "hand"
+	PhaseFaceUp   Phase = "faceup"
+	PhaseFaceDown Phase = "hidden"
+	PhaseFinished Phase = "finished"
+)
+
+var (
+	ErrEmptyPlay     = errors.New("no cards played")
+	ErrMixedValues   = errors.New("cards played together must have the same value")
+	ErrTooLow        = errors.New("card does not beat the top of the play pile")
+	ErrMustPlayLower = errors.New("card must be lower than or equal to the top of the play pile")
+	ErrWrongPhase    = errors.New("card cannot be played in the current phase")
+	ErrInvalidSwap   = errors.New("swap must exchange the same number of hand and face-up cards")
+	ErrMustPlay      = errors.New("the pile can only be picked up when no card can be played")
+)
+
+type Config struct {
+	SpecialCards  map[string]Effect
+	BurnCount     int
+	HandSize      int
+	FaceUpCount   int
+	FaceDownCount int
+	// AllowPickupChoice lets a player pick up the pile instead of playing.
+	AllowPickupChoice bool
+	// ReshuffleBurned puts burned cards back into the deck once it can no
+	// longer cover a draw.
+	ReshuffleBurned bool
+}
+
+type Outcome struct {
+	Burned    bool
+	ExtraTurn bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SpecialCards: map[string]Effect{
+			"2":  EffectReset,
+			"7":  EffectLowerOrEqual,
+			"10": EffectBurn,
+		},
+		BurnCount:         4,
+		HandSize:          3,
+		FaceUpCount:       3,
+		FaceDownCount:     3,
+		AllowPickupChoice: true,
+	}
+}
+
+func Rank(value string) int {
+	ranks := map[string]int{
+		"2":     2,
+		"3":     3,
+		"4":     4,
+		"5":     5,
+		"6":     6,
+		"7":     7,
+		"8":     8,
+		"9":     9,
+		"10":    10,
+		"JACK":  11,
+		"QUEEN": 12,
+		"KING":  13,
+		"ACE":   14,
+	}
+	return ranks[value]
+}
+
+func (c Config) EffectOf(value string) Effect {
+	if effect, ok := c.SpecialCards[value]; ok {
+		return effect
+	}
+	return EffectNone
+}
+
+func (c Config) IsSpecial(value string) bool {
+	return c.EffectOf(value) != EffectNone
+}
+
+// CanPlay reports whether the given values may be laid on the pile, where
+// pile holds the values of the play pile from bottom to top.
+func CanPlay(cfg Config, pile []string, values []string) error {
+	if len(values) == 0 {
+		return ErrEmptyPlay
+	}
+
+	value := values[0]
+	for _, v := range values[1:] {
+		if v != value {
+			return ErrMixedValues
+		}
+	}
+
+	switch cfg.EffectOf(value) {
+	case EffectReset, EffectBurn, EffectWild:
+		return nil
+	}
+
+	top, ok := effectiveTop(cfg, pile)
+	if !ok {
+		return nil
+	}
+
+	switch cfg.EffectOf(top) {
+	case EffectReset:
+		return nil
+	case EffectLowerOrEqual:
+		if Rank(value) > Rank(top) {
+			return ErrMustPlayLower
+		}
+		return nil
+	}
+
+	if Rank(value) < Rank(top) {
+		return ErrTooLow
+	}
+	return nil
+}
+
+// effectiveTop returns the topmost card of the pile that is not a wild card,
+// reporting false when there is none.
+func effectiveTop(cfg Config, pile []string) (string, bool) {
+	for i := len(pile) - 1; i >= 0; i-- {
+		if cfg.EffectOf(pile[i]) != EffectWild {
+			return pile[i], true
+		}
+	}
+	return "", false
+}
+
+func ResolvePlay(cfg Config, pile []string, values []string) (Outcome, error) {
+	if err := CanPlay(cfg, pile, values); err != nil {
+		return Outcome{}, err
+	}
+
+	next := make([]string, 0, len(pile)+len(values))
+	next = append(next, pile...)
+	next = append(next, values...)
+
+	if ShouldBurn(cfg, next) {
+		return Outcome{Burned: true, ExtraTurn: true}, nil
+	}
+	return Outcome{}, nil
+}
+
+func ShouldBurn(cfg Config, pile []string) bool {
+	if len(pile) == 0 {
+		return false
+	}
+
+	top := pile[len(pile)-1]
+	if cfg.EffectOf(top) == EffectBurn {
+		return true
+	}
+
+	if cfg.BurnCount <= 0 || len(pile) < cfg.BurnCount {
+		return false
+	}
+	for _, v := range pile[len(pile)-cfg.BurnCount:] {
+		if v != top {
+			return false
+		}
+	}
+	return true
+}
+
+func HasLegalPlay(cfg Config, pile []string, values []string) bool {
+	for _, v := range values {
+		if CanPlay(cfg, pile, []string{v}) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CanPickUp reports whether a player in phase may pick up the pile, where
+// values are the cards they can play from. Unless the config allows picking
+// up by choice, that is only when none of them can be played; face-down
+// cards always have to be tried.
+func CanPickUp(cfg Config, phase Phase, pile []string, values []string) error {
+	if cfg.AllowPickupChoice {
+		return nil
+	}
+	if phase == PhaseFaceDown || HasLegalPlay(cfg, pile, values) {
+		return ErrMustPlay
+	}
+	return nil
+}
+
+func CurrentPhase(handCount, faceUpCount, faceDownCount int) Phase {
+	switch {
+	case handCount > 0:
+		return PhaseHand
+	case faceUpCount > 0:
+		return PhaseFaceUp
+	case faceDownCount > 0:
+		return PhaseFaceDown
+	}
+	return PhaseFinished
+}
+
+func CanPlayFrom(phase Phase, status string) error {
+	if phase == PhaseFinished || string(phase) != status {
+		return ErrWrongPhase
+	}
+	return nil
+}
+
+// CardsToDraw is how many cards a player holding handCount cards needs to
+// draw to get back to a full hand.
+func CardsToDraw(cfg Config, handCount int) int {
+	return max(cfg.HandSize-handCount, 0)
+}
+
+func ValidateSwap(handCount, faceUpCount, fromHand, fromFaceUp int) error {
+	if fromHand == 0 || fromHand != fromFaceUp || fromHand > handCount || fromFaceUp > faceUpCount {
+		return ErrInvalidSwap
+	}
+	return nil
+}
+
+// NextTurn returns the index of the next player still in the game after
+// current, or -1 if nobody else is left.
+func NextTurn(current int, finished []bool) int {
+	n := len(finished)
+	for step := 1; step <= n; step++ {
+		next := (current + step) % n
+		if !finished[next] {
+			return next
+		}
+	}
+	return -1
+}
+
+func IsGameOver(finished []bool) bool {
+	remaining := 0
+	for _, done := range finished {
+		if !done {
+			remaining++
+		}
+	}
+	return remaining <= 1
+}
+
+// Shithead returns the index of the last player still holding cards, or -1
+// while more than one player remains.
+func Shithead(finished []bool) int {
+	index := -1
+	for i, done := range finished {
+		if done {
+			continue
+		}
+		if index != -1 {
+			return -1
+		}
+		index = i
+	}
+	return index
+}
@@ -0,0 +1,274 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+)
+
+// houseConfig is a variant with a see-through 8, 3 as the reset card, three
+// of a kind burning and picking up only as a last resort.
+func houseConfig() Config {
+	cfg := DefaultConfig()
+	cfg.SpecialCards = map[string]Effect{
+		"3":  EffectReset,
+		"7":  EffectLowerOrEqual,
+		"8":  EffectWild,
+		"10": EffectBurn,
+	}
+	cfg.BurnCount = 3
+	cfg.AllowPickupChoice = false
+	return cfg
+}
+
+func TestCanPlay(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		pile   []string
+		values []string
+		want   error
+	}{
+		{name: "nothing played", cfg: DefaultConfig(), pile: []string{"5"}, values: nil, want: ErrEmptyPlay},
+		{name: "mixed values", cfg: DefaultConfig(), pile: nil, values: []string{"5", "6"}, want: ErrMixedValues},
+		{name: "empty pile", cfg: DefaultConfig(), pile: nil, values: []string{"3"}, want: nil},
+		{name: "higher card", cfg: DefaultConfig(), pile: []string{"5"}, values: []string{"9"}, want: nil},
+		{name: "equal card", cfg: DefaultConfig(), pile: []string{"9"}, values: []string{"9", "9"}, want: nil},
+		{name: "lower card", cfg: DefaultConfig(), pile: []string{"KING"}, values: []string{"QUEEN"}, want: ErrTooLow},
+		{name: "king under ace", cfg: DefaultConfig(), pile: []string{"ACE"}, values: []string{"KING"}, want: ErrTooLow},
+		{name: "reset on anything", cfg: DefaultConfig(), pile: []string{"ACE"}, values: []string{"2"}, want: nil},
+		{name: "burn on anything", cfg: DefaultConfig(), pile: []string{"ACE"}, values: []string{"10"}, want: nil},
+		{name: "anything on reset", cfg: DefaultConfig(), pile: []string{"ACE", "2"}, values: []string{"3"}, want: nil},
+		{name: "lower on seven", cfg: DefaultConfig(), pile: []string{"7"}, values: []string{"4"}, want: nil},
+		{name: "equal on seven", cfg: DefaultConfig(), pile: []string{"7"}, values: []string{"7"}, want: nil},
+		{name: "higher on seven", cfg: DefaultConfig(), pile: []string{"7"}, values: []string{"9"}, want: ErrMustPlayLower},
+		{name: "special on seven", cfg: DefaultConfig(), pile: []string{"7"}, values: []string{"10"}, want: nil},
+		{name: "eight is plain by default", cfg: DefaultConfig(), pile: []string{"9"}, values: []string{"8"}, want: ErrTooLow},
+		{name: "house wild on anything", cfg: houseConfig(), pile: []string{"ACE"}, values: []string{"8"}, want: nil},
+		{name: "house wild is see-through", cfg: houseConfig(), pile: []string{"QUEEN", "8"}, values: []string{"9"}, want: ErrTooLow},
+		{name: "house beat beneath wild", cfg: houseConfig(), pile: []string{"QUEEN", "8", "8"}, values: []string{"KING"}, want: nil},
+		{name: "house only wilds", cfg: houseConfig(), pile: []string{"8"}, values: []string{"4"}, want: nil},
+		{name: "house reset card", cfg: houseConfig(), pile: []string{"ACE"}, values: []string{"3"}, want: nil},
+		{name: "house two is plain", cfg: houseConfig(), pile: []string{"ACE"}, values: []string{"2"}, want: ErrTooLow},
+		{name: "house seven beneath wild", cfg: houseConfig(), pile: []string{"7", "8"}, values: []string{"9"}, want: ErrMustPlayLower},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanPlay(tt.cfg, tt.pile, tt.values); !errors.Is(err, tt.want) {
+				t.Errorf("CanPlay(%v, %v) = %v, want %v", tt.pile, tt.values, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePlay(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		pile   []string
+		values []string
+		want   Outcome
+		err    error
+	}{
+		{name: "plain play", cfg: DefaultConfig(), pile: []string{"5"}, values: []string{"6"}, want: Outcome{}},
+		{name: "ten burns", cfg: DefaultConfig(), pile: []string{"KING"}, values: []string{"10"}, want: Outcome{Burned: true, ExtraTurn: true}},
+		{name: "four of a kind burns", cfg: DefaultConfig(), pile: []string{"6", "6"}, values: []string{"6", "6"}, want: Outcome{Burned: true, ExtraTurn: true}},
+		{name: "completing four of a kind burns", cfg: DefaultConfig(), pile: []string{"6", "6", "6"}, values: []string{"6"}, want: Outcome{Burned: true, ExtraTurn: true}},
+		{name: "three of a kind does not burn", cfg: DefaultConfig(), pile: []string{"6", "6"}, values: []string{"6"}, want: Outcome{}},
+		{name: "house three of a kind burns", cfg: houseConfig(), pile: []string{"6", "6"}, values: []string{"6"}, want: Outcome{Burned: true, ExtraTurn: true}},
+		{name: "illegal play", cfg: DefaultConfig(), pile: []string{"ACE"}, values: []string{"4"}, err: ErrTooLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePlay(tt.cfg, tt.pile, tt.values)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ResolvePlay(%v, %v) error = %v, want %v", tt.pile, tt.values, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePlay(%v, %v) = %+v, want %+v", tt.pile, tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldBurn(t *testing.T) {
+	noCount := DefaultConfig()
+	noCount.BurnCount = 0
+
+	tests := []struct {
+		name string
+		cfg  Config
+		pile []string
+		want bool
+	}{
+		{name: "empty pile", cfg: DefaultConfig(), pile: nil, want: false},
+		{name: "burn card on top", cfg: DefaultConfig(), pile: []string{"4", "10"}, want: true},
+		{name: "burn card buried", cfg: DefaultConfig(), pile: []string{"10", "4"}, want: false},
+		{name: "four of a kind", cfg: DefaultConfig(), pile: []string{"3", "9", "9", "9", "9"}, want: true},
+		{name: "four broken up", cfg: DefaultConfig(), pile: []string{"9", "9", "4", "9", "9"}, want: false},
+		{name: "too few cards", cfg: DefaultConfig(), pile: []string{"9", "9", "9"}, want: false},
+		{name: "count disabled", cfg: noCount, pile: []string{"9", "9", "9", "9"}, want: false},
+		{name: "count disabled burn card", cfg: noCount, pile: []string{"10"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldBurn(tt.cfg, tt.pile); got != tt.want {
+				t.Errorf("ShouldBurn(%v) = %v, want %v", tt.pile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanPickUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		phase  Phase
+		pile   []string
+		values []string
+		want   error
+	}{
+		{name: "by choice", cfg: DefaultConfig(), phase: PhaseHand, pile: []string{"5"}, values: []string{"9"}, want: nil},
+		{name: "by choice face down", cfg: DefaultConfig(), phase: PhaseFaceDown, pile: []string{"5"}, want: nil},
+		{name: "house with a legal play", cfg: houseConfig(), phase: PhaseHand, pile: []string{"5"}, values: []string{"4", "9"}, want: ErrMustPlay},
+		{name: "house with no legal play", cfg: houseConfig(), phase: PhaseFaceUp, pile: []string{"KING"}, values: []string{"4", "9"}, want: nil},
+		{name: "house face down", cfg: houseConfig(), phase: PhaseFaceDown, pile: []string{"ACE"}, want: ErrMustPlay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CanPickUp(tt.cfg, tt.phase, tt.pile, tt.values); !errors.Is(err, tt.want) {
+				t.Errorf("CanPickUp(%s, %v, %v) = %v, want %v", tt.phase, tt.pile, tt.values, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentPhase(t *testing.T) {
+	tests := []struct {
+		hand, faceUp, faceDown int
+		want                   Phase
+	}{
+		{hand: 3, faceUp: 3, faceDown: 3, want: PhaseHand},
+		{hand: 1, faceUp: 0, faceDown: 0, want: PhaseHand},
+		{hand: 0, faceUp: 2, faceDown: 3, want: PhaseFaceUp},
+		{hand: 0, faceUp: 0, faceDown: 1, want: PhaseFaceDown},
+		{hand: 0, faceUp: 0, faceDown: 0, want: PhaseFinished},
+	}
+
+	for _, tt := range tests {
+		if got := CurrentPhase(tt.hand, tt.faceUp, tt.faceDown); got != tt.want {
+			t.Errorf("CurrentPhase(%d, %d, %d) = %s, want %s", tt.hand, tt.faceUp, tt.faceDown, got, tt.want)
+		}
+	}
+}
+
+func TestCanPlayFrom(t *testing.T) {
+	tests := []struct {
+		phase  Phase
+		status string
+		want   error
+	}{
+		{phase: PhaseHand, status: "hand", want: nil},
+		{phase: PhaseHand, status: "faceup", want: ErrWrongPhase},
+		{phase: PhaseFaceUp, status: "faceup", want: nil},
+		{phase: PhaseFaceDown, status: "hidden", want: nil},
+		{phase: PhaseFaceDown, status: "hand", want: ErrWrongPhase},
+		{phase: PhaseFinished, status: "finished", want: ErrWrongPhase},
+	}
+
+	for _, tt := range tests {
+		if err := CanPlayFrom(tt.phase, tt.status); !errors.Is(err, tt.want) {
+			t.Errorf("CanPlayFrom(%s, %q) = %v, want %v", tt.phase, tt.status, err, tt.want)
+		}
+	}
+}
+
+func TestCardsToDraw(t *testing.T) {
+	cfg := DefaultConfig()
+	tests := []struct {
+		hand, want int
+	}{
+		{hand: 0, want: 3},
+		{hand: 2, want: 1},
+		{hand: 3, want: 0},
+		{hand: 7, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := CardsToDraw(cfg, tt.hand); got != tt.want {
+			t.Errorf("CardsToDraw(%d) = %d, want %d", tt.hand, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSwap(t *testing.T) {
+	tests := []struct {
+		name                               string
+		hand, faceUp, fromHand, fromFaceUp int
+		want                               error
+	}{
+		{name: "one for one", hand: 3, faceUp: 3, fromHand: 1, fromFaceUp: 1, want: nil},
+		{name: "all three", hand: 3, faceUp: 3, fromHand: 3, fromFaceUp: 3, want: nil},
+		{name: "nothing", hand: 3, faceUp: 3, fromHand: 0, fromFaceUp: 0, want: ErrInvalidSwap},
+		{name: "uneven", hand: 3, faceUp: 3, fromHand: 2, fromFaceUp: 1, want: ErrInvalidSwap},
+		{name: "more than held", hand: 1, faceUp: 3, fromHand: 2, fromFaceUp: 2, want: ErrInvalidSwap},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSwap(tt.hand, tt.faceUp, tt.fromHand, tt.fromFaceUp); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateSwap = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextTurn(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  int
+		finished []bool
+		want     int
+	}{
+		{name: "next in line", current: 0, finished: []bool{false, false, false}, want: 1},
+		{name: "wraps around", current: 2, finished: []bool{false, false, false}, want: 0},
+		{name: "skips finished", current: 0, finished: []bool{false, true, false}, want: 2},
+		{name: "skips finished when wrapping", current: 1, finished: []bool{true, false, false}, want: 2},
+		{name: "only current left", current: 1, finished: []bool{true, false, true}, want: 1},
+		{name: "nobody left", current: 0, finished: []bool{true, true}, want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextTurn(tt.current, tt.finished); got != tt.want {
+				t.Errorf("NextTurn(%d, %v) = %d, want %d", tt.current, tt.finished, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShithead(t *testing.T) {
+	tests := []struct {
+		finished []bool
+		want     int
+		over     bool
+	}{
+		{finished: []bool{false, false, false}, want: -1, over: false},
+		{finished: []bool{true, false, false}, want: -1, over: false},
+		{finished: []bool{true, false, true}, want: 1, over: true},
+		{finished: []bool{false, true}, want: 0, over: true},
+		{finished: []bool{true, true}, want: -1, over: true},
+	}
+
+	for _, tt := range tests {
+		if got := Shithead(tt.finished); got != tt.want {
+			t.Errorf("Shithead(%v) = %d, want %d", tt.finished, got, tt.want)
+		}
+		if got := IsGameOver(tt.finished); got != tt.over {
+			t.Errorf("IsGameOver(%v) = %v, want %v", tt.finished, got, tt.over)
+		}
+	}
+}
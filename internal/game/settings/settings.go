@@ -0,0 +1,262 @@
+// Package settings defines the house rules a lobby can set in its
+// game_settings and turns them into the rules engine's configuration.
+package settings
+
+import (
+	"api/internal/game/decks"
+	"api/internal/game/rules"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Forfeit card rules: what happens to a forfeiting player's cards.
+const (
+	ForfeitDiscard = "discard"
+	ForfeitShuffle = "shuffle"
+)
+
+// Limits enforced by Parse.
+const (
+	MinFaceDownCount    = 1
+	MaxFaceDownCount    = 5
+	MinTurnTimerSeconds = 10
+	MaxTurnTimerSeconds = 600
+	MaxPlacementPoints  = 8
+	MaxPoints           = 1000
+)
+
+// Settings are a lobby's house rules. The zero value is not meaningful; use
+// Default or Parse.
+type Settings struct {
+	// SpecialCards maps card values to their effect. Values left out have
+	// no effect.
+	SpecialCards map[string]rules.Effect `json:"special_cards"`
+	// TurnTimerSeconds overrides the server's turn timer; 0 turns it off and
+	// nil keeps the server default.
+	TurnTimerSeconds *int `json:"turn_timer_seconds,omitempty"`
+	// AllowPickupChoice lets a player pick the pile up even when they could
+	// play. Without it the pile may only be taken when nothing can be played.
+	AllowPickupChoice bool `json:"allow_pickup_choice"`
+	FaceDownCount     int  `json:"face_down_count"`
+	// DeckType picks a standard, short or double deck, and Jokers adds two
+	// wild jokers per pack.
+	DeckType     decks.Type `json:"deck_type"`
+	Jokers       bool       `json:"jokers"`
+	ForfeitCards string     `json:"forfeit_cards"`
+	// ReshuffleBurned shuffles burned cards back into the deck when it runs
+	// out instead of leaving it empty.
+	ReshuffleBurned bool `json:"reshuffle_burned"`
+	// PlacementPoints are the points for each placement, best first. Empty
+	// scores one point for every player finished ahead of.
+	PlacementPoints []int `json:"placement_points,omitempty"`
+}
+
+// Errors holds a message per invalid setting, keyed by its JSON name.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	messages := make([]string, len(keys))
+	for i, key := range keys {
+		messages[i] = key + ": " + e[key]
+	}
+	return "invalid game settings: " + strings.Join(messages, "; ")
+}
+
+// Default returns the standard rules: 2 resets, 7 forces lower or equal, 10
+// burns, three face-down cards and a standard deck without jokers.
+func Default() Settings {
+	config := rules.DefaultConfig()
+	return Settings{
+		SpecialCards:      config.SpecialCards,
+		AllowPickupChoice: config.AllowPickupChoice,
+		FaceDownCount:     config.FaceDownCount,
+		DeckType:          decks.Standard,
+		ForfeitCards:      ForfeitDiscard,
+	}
+}
+
+// Parse reads game_settings JSON, filling anything left out from Default.
+// Unknown fields and out of range values are rejected with Errors.
+func Parse(raw json.RawMessage) (Settings, error) {
+	settings := Default()
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return settings, nil
+	}
+
+	var input struct {
+		SpecialCards      map[string]rules.Effect `json:"special_cards"`
+		TurnTimerSeconds  *int                    `json:"turn_timer_seconds"`
+		AllowPickupChoice *bool                   `json:"allow_pickup_choice"`
+		FaceDownCount     *int                    `json:"face_down_count"`
+		DeckType          *decks.Type             `json:"deck_type"`
+		Jokers            *bool                   `json:"jokers"`
+		ForfeitCards      *string                 `json:"forfeit_cards"`
+		ReshuffleBurned   *bool                   `json:"reshuffle_burned"`
+		PlacementPoints   []int                   `json:"placement_points"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		return settings, Errors{"game_settings": describeDecodeError(err)}
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return settings, Errors{"game_settings": "must be a single JSON object"}
+	}
+
+	errs := Errors{}
+
+	if input.SpecialCards != nil {
+		settings.SpecialCards = make(map[string]rules.Effect, len(input.SpecialCards))
+		for value, effect := range input.SpecialCards {
+			value = strings.ToUpper(value)
+			if rules.Rank(value) == 0 {
+				errs["special_cards."+value] = "is not a card value"
+				continue
+			}
+			switch effect {
+			case rules.EffectNone:
+			case rules.EffectReset, rules.EffectLowerOrEqual, rules.EffectBurn:
+				settings.SpecialCards[value] = effect
+			default:
+				errs["special_cards."+value] = fmt.Sprintf("must be one of %s, %s, %s or %s",
+					rules.EffectNone, rules.EffectReset, rules.EffectLowerOrEqual, rules.EffectBurn)
+			}
+		}
+	}
+
+	if input.TurnTimerSeconds != nil {
+		seconds := *input.TurnTimerSeconds
+		if seconds != 0 && (seconds < MinTurnTimerSeconds || seconds > MaxTurnTimerSeconds) {
+			errs["turn_timer_seconds"] = fmt.Sprintf("must be 0 or between %d and %d", MinTurnTimerSeconds, MaxTurnTimerSeconds)
+		}
+		settings.TurnTimerSeconds = &seconds
+	}
+
+	if input.AllowPickupChoice != nil {
+		settings.AllowPickupChoice = *input.AllowPickupChoice
+	}
+
+	if input.FaceDownCount != nil {
+		if *input.FaceDownCount < MinFaceDownCount || *input.FaceDownCount > MaxFaceDownCount {
+			errs["face_down_count"] = fmt.Sprintf("must be between %d and %d", MinFaceDownCount, MaxFaceDownCount)
+		}
+		settings.FaceDownCount = *input.FaceDownCount
+	}
+
+	if input.DeckType != nil {
+		if !decks.ValidType(*input.DeckType) {
+			errs["deck_type"] = fmt.Sprintf("must be one of %s, %s or %s", decks.Standard, decks.Short, decks.Double)
+		}
+		settings.DeckType = *input.DeckType
+	}
+
+	if input.Jokers != nil {
+		settings.Jokers = *input.Jokers
+	}
+
+	if input.ForfeitCards != nil {
+		switch *input.ForfeitCards {
+		case ForfeitDiscard, ForfeitShuffle:
+			settings.ForfeitCards = *input.ForfeitCards
+		default:
+			errs["forfeit_cards"] = fmt.Sprintf("must be %s or %s", ForfeitDiscard, ForfeitShuffle)
+		}
+	}
+
+	if input.ReshuffleBurned != nil {
+		settings.ReshuffleBurned = *input.ReshuffleBurned
+	}
+
+	if input.PlacementPoints != nil {
+		if len(input.PlacementPoints) > MaxPlacementPoints {
+			errs["placement_points"] = fmt.Sprintf("must have at most %d entries", MaxPlacementPoints)
+		}
+		for _, points := range input.PlacementPoints {
+			if points < 0 || points > MaxPoints {
+				errs["placement_points"] = fmt.Sprintf("entries must be between 0 and %d", MaxPoints)
+				break
+			}
+		}
+		settings.PlacementPoints = input.PlacementPoints
+	}
+
+	if len(errs) > 0 {
+		return Default(), errs
+	}
+	return settings, nil
+}
+
+// Rules is the rules engine configuration for these settings. Jokers, when
+// enabled, are wild.
+func (s Settings) Rules() rules.Config {
+	config := rules.DefaultConfig()
+	config.SpecialCards = make(map[string]rules.Effect, len(s.SpecialCards)+1)
+	for value, effect := range s.SpecialCards {
+		config.SpecialCards[value] = effect
+	}
+	if s.Jokers {
+		config.SpecialCards[decks.Joker] = rules.EffectWild
+	}
+	config.AllowPickupChoice = s.AllowPickupChoice
+	config.FaceDownCount = s.FaceDownCount
+	config.ReshuffleBurned = s.ReshuffleBurned
+	return config
+}
+
+// Deck is the deck a game of players is dealt from: the chosen deck type,
+// with packs added until everyone can be dealt in full.
+func (s Settings) Deck(players int) decks.Spec {
+	spec := decks.Spec{Type: s.DeckType, Jokers: s.Jokers}
+	return spec.Holding(players * s.CardsPerPlayer())
+}
+
+// CardsPerPlayer is how many cards each player is dealt.
+func (s Settings) CardsPerPlayer() int {
+	config := s.Rules()
+	return config.FaceDownCount + config.FaceUpCount + config.HandSize
+}
+
+// TurnTimer returns the turn timer in seconds, or fallback when the lobby
+// keeps the server default.
+func (s Settings) TurnTimer(fallback int) int {
+	if s.TurnTimerSeconds == nil {
+		return fallback
+	}
+	return *s.TurnTimerSeconds
+}
+
+// Points returns the points awarded to each of playerCount placements, best
+// first.
+func (s Settings) Points(playerCount int) []int {
+	points := make([]int, playerCount)
+	for i := range points {
+		if len(s.PlacementPoints) == 0 {
+			points[i] = playerCount - 1 - i
+		} else if i < len(s.PlacementPoints) {
+			points[i] = s.PlacementPoints[i]
+		}
+	}
+	return points
+}
+
+func describeDecodeError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "unknown setting " + field
+	}
+	return "must be a JSON object"
+}
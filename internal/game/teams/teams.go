@@ -0,0 +1,168 @@
+// Package teams holds the rules of the team game mode: players sit in teams
+// of Size, turns alternate between teams and a team wins once all of its
+// members have shed their cards.
+package teams
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Mode is the lobby game mode played in teams.
+const Mode = "teams"
+
+// Size is how many players make up a team.
+const Size = 2
+
+// MinPlayers is the smallest table a team game can be played at.
+const MinPlayers = 2 * Size
+
+// Count is how many teams a lobby of maxPlayers seats is split into.
+func Count(maxPlayers int) int {
+	return maxPlayers / Size
+}
+
+// ValidTable reports whether maxPlayers seats can be split into full teams.
+func ValidTable(maxPlayers int) bool {
+	return maxPlayers >= MinPlayers && maxPlayers%Size == 0
+}
+
+// Name is the label a team goes by, e.g. as the winner of a game.
+func Name(team int) string {
+	return "team" + strconv.Itoa(team)
+}
+
+// Assign picks the team, numbered from 1, a new player joins: the one with
+// the fewest members, lowest number first. members holds the team of every
+// player already seated. It returns 0 when every team is full.
+func Assign(members []int, count int) int {
+	sizes := make(map[int]int, count)
+	for _, team := range members {
+		sizes[team]++
+	}
+
+	best := 0
+	for team := 1; team <= count; team++ {
+		if sizes[team] >= Size {
+			continue
+		}
+		if best == 0 || sizes[team] < sizes[best] {
+			best = team
+		}
+	}
+	return best
+}
+
+// Balanced reports whether every one of count teams is full.
+func Balanced(members []int, count int) bool {
+	sizes := make(map[int]int, count)
+	for _, team := range members {
+		if team < 1 || team > count {
+			return false
+		}
+		sizes[team]++
+	}
+	for team := 1; team <= count; team++ {
+		if sizes[team] != Size {
+			return false
+		}
+	}
+	return true
+}
+
+// TurnOrder interleaves seats so turns alternate between teams: the first
+// member of every team, then the second and so on. seats holds each
+// player's team in seating order; the result lists seat indexes.
+func TurnOrder(seats []int) []int {
+	byTeam := make(map[int][]int)
+	var order []int
+	for i, team := range seats {
+		if _, ok := byTeam[team]; !ok {
+			order = append(order, team)
+		}
+		byTeam[team] = append(byTeam[team], i)
+	}
+	sort.Ints(order)
+
+	turns := make([]int, 0, len(seats))
+	for round := 0; len(turns) < len(seats); round++ {
+		for _, team := range order {
+			if round < len(byTeam[team]) {
+				turns = append(turns, byTeam[team][round])
+			}
+		}
+	}
+	return turns
+}
+
+// Member is a player's part in deciding a team game.
+type Member struct {
+	Team     int
+	Finished bool
+	// Out is true once the player has finished or forfeited.
+	Out bool
+	// Placement is the player's own placement, 0 while they have none.
+	Placement int
+}
+
+// IsOver reports whether a team game has ended: some team has every member
+// finished, or no more than one team still has players holding cards.
+func IsOver(members []Member) bool {
+	done := make(map[int]bool)
+	playing := make(map[int]bool)
+	for _, m := range members {
+		if _, ok := done[m.Team]; !ok {
+			done[m.Team] = true
+		}
+		done[m.Team] = done[m.Team] && m.Finished
+		if !m.Out {
+			playing[m.Team] = true
+		}
+	}
+
+	for _, complete := range done {
+		if complete {
+			return true
+		}
+	}
+	return len(playing) <= 1
+}
+
+// Rank orders the teams of a finished game, returning each team's placement.
+// Teams whose members all finished come first, then the rest by their best
+// member's placement. members must all be placed.
+func Rank(members []Member) map[int]int {
+	type standing struct {
+		team     int
+		complete bool
+		best     int
+	}
+
+	byTeam := make(map[int]*standing)
+	var standings []*standing
+	for _, m := range members {
+		s, ok := byTeam[m.Team]
+		if !ok {
+			s = &standing{team: m.Team, complete: true, best: m.Placement}
+			byTeam[m.Team] = s
+			standings = append(standings, s)
+		}
+		s.complete = s.complete && m.Finished
+		if m.Placement < s.best {
+			s.best = m.Placement
+		}
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].complete != standings[j].complete {
+			return standings[i].complete
+		}
+		return standings[i].best < standings[j].best
+	})
+
+	ranks := make(map[int]int, len(standings))
+	for i, s := range standings {
+		ranks[s.team] = i + 1
+	}
+	return ranks
+}
@@ -0,0 +1,78 @@
+// Package moderation holds the checks applied to text players write, such as
+// chat messages and lobby names.
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Filter finds blocked words in text. Matching is case-insensitive and on
+// whole words, so blocking "ass" leaves "class" alone.
+type Filter struct {
+	words map[string]bool
+}
+
+// NewFilter builds a filter for the given words. With no words, nothing is
+// ever blocked.
+func NewFilter(words []string) *Filter {
+	blocked := make(map[string]bool, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			blocked[word] = true
+		}
+	}
+	return &Filter{words: blocked}
+}
+
+// Contains reports whether text has any blocked word in it.
+func (f *Filter) Contains(text string) bool {
+	found := false
+	f.scan(text, func(start, end int) {
+		found = true
+	})
+	return found
+}
+
+// Mask replaces every letter of each blocked word in text with an asterisk.
+func (f *Filter) Mask(text string) string {
+	var masked strings.Builder
+	last := 0
+	f.scan(text, func(start, end int) {
+		masked.WriteString(text[last:start])
+		for range text[start:end] {
+			masked.WriteByte('*')
+		}
+		last = end
+	})
+	if last == 0 {
+		return text
+	}
+	masked.WriteString(text[last:])
+	return masked.String()
+}
+
+// scan calls blocked with the byte range of each blocked word in text.
+func (f *Filter) scan(text string, blocked func(start, end int)) {
+	if len(f.words) == 0 {
+		return
+	}
+
+	start := -1
+	check := func(end int) {
+		if start >= 0 && f.words[strings.ToLower(text[start:end])] {
+			blocked(start, end)
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		check(i)
+	}
+	check(len(text))
+}
@@ -0,0 +1,46 @@
+// Package redis connects the API to Redis through go-redis: pub/sub for the
+// hub backplane and key/value access for the shared stores.
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Client is a pool of connections to one server. go-redis redials dropped
+// connections and retries commands that failed on one.
+type Client = goredis.Client
+
+// PubSub is a subscription on its own connection, resubscribed by go-redis
+// whenever that connection drops.
+type PubSub = goredis.PubSub
+
+// NewClient connects to the server at rawURL, such as
+// redis://:password@host:6379/0. An empty URL means a local server on the
+// default port.
+func NewClient(rawURL string) (*Client, error) {
+	if rawURL == "" {
+		rawURL = "redis://localhost:6379"
+	}
+
+	opts, err := goredis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url %q: %w", rawURL, err)
+	}
+	opts.DialTimeout = dialTimeout
+
+	client := goredis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error connecting to redis: %v", err)
+	}
+	return client, nil
+}
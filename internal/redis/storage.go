@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Storage keeps values in Redis under a key prefix. It satisfies
+// fiber.Storage, so session data can be shared by every API instance.
+type Storage struct {
+	client *Client
+	prefix string
+}
+
+func NewStorage(client *Client, prefix string) *Storage {
+	return &Storage{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *Storage) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	value, err := s.client.Get(context.Background(), s.prefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if key == "" || len(val) == 0 {
+		return nil
+	}
+
+	return s.client.Set(context.Background(), s.prefix+key, val, exp).Err()
+}
+
+func (s *Storage) Delete(key string) error {
+	if key == "" {
+		return nil
+	}
+
+	return s.client.Del(context.Background(), s.prefix+key).Err()
+}
+
+// Reset deletes every key under the prefix.
+func (s *Storage) Reset() error {
+	ctx := context.Background()
+
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, s.prefix+"*", 100).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := s.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (s *Storage) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,580 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultStuckAfter is how long a lobby or game must go without an update
+// before the admin listings treat it as stuck.
+const defaultStuckAfter = time.Hour
+
+var errGameClosed = errors.New("game already completed")
+
+// AdminHandler serves the moderation endpoints under /admin. Every route is
+// gated by middleware.RequireAdmin.
+type AdminHandler struct {
+	db      database.Service
+	hub     *GameHub
+	lobbies service.LobbyService
+	games   *GameHandler
+	audit   service.AuditService
+}
+
+type AdminSearchUsersRequest struct {
+	Email string `query:"email" validate:"required,min=2"`
+}
+
+// BanUserRequest bans or mutes a user until ExpiresAt, or permanently when it
+// is omitted.
+type BanUserRequest struct {
+	Reason    string     `json:"reason" validate:"required,max=500"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func NewAdminHandler(db database.Service, hub *GameHub, lobbies service.LobbyService, games *GameHandler, audit service.AuditService) *AdminHandler {
+	return &AdminHandler{
+		db:      db,
+		hub:     hub,
+		lobbies: lobbies,
+		games:   games,
+		audit:   audit,
+	}
+}
+
+// stuckCutoff reads the idle_minutes query parameter, falling back to
+// defaultStuckAfter.
+func stuckCutoff(c *fiber.Ctx) (time.Time, error) {
+	idle := defaultStuckAfter
+	if raw := c.Query("idle_minutes"); raw != "" {
+		minutes, err := strconv.Atoi(raw)
+		if err != nil || minutes < 0 {
+			return time.Time{}, fmt.Errorf("invalid idle_minutes %q", raw)
+		}
+		idle = time.Duration(minutes) * time.Minute
+	}
+	return time.Now().Add(-idle), nil
+}
+
+// Lobbies lists unfinished lobbies that have not been updated within
+// idle_minutes, oldest first.
+func (h *AdminHandler) Lobbies(c *fiber.Ctx) error {
+	cutoff, err := stuckCutoff(c)
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid idle_minutes")
+	}
+	limit := utils.ParseLimit(c.Query("limit"), 50, 200)
+
+	var lobbies []models.Lobby
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Owner").
+		Where("status <> ? AND updated_at < ?", "completed", cutoff).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&lobbies).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching lobbies")
+	}
+
+	return c.JSON(lobbies)
+}
+
+// CloseLobby force-closes a lobby, removing it and everything attached to it
+// the same way an owner leaving an empty lobby does.
+func (h *AdminHandler) CloseLobby(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid lobby ID")
+	}
+
+	var gameIDs []uuid.UUID
+	err = h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var lobby models.Lobby
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Game{}).Where("lobby_id = ?", lobbyID).Pluck("id", &gameIDs).Error; err != nil {
+			return err
+		}
+		return service.DeleteLobby(tx, lobbyID.String())
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error closing lobby")
+	}
+
+	h.lobbies.Invalidate()
+	for _, gameID := range gameIDs {
+		h.games.timers.cancel(gameID)
+		h.games.countdowns.cancel(gameID)
+		h.hub.BroadcastToGame(gameID.String(), GameMessage{
+			Type:    "lobby_closed",
+			Payload: fiber.Map{"lobby_id": lobbyID, "reason": "closed_by_admin"},
+		})
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_lobby_close",
+		TargetType: "lobby",
+		TargetID:   lobbyID.String(),
+	})
+	return c.JSON(fiber.Map{
+		"message": "Lobby closed",
+	})
+}
+
+// ClearLobbyName replaces an abusive lobby name with a neutral one derived
+// from the lobby ID.
+func (h *AdminHandler) ClearLobbyName(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid lobby ID")
+	}
+
+	name := fmt.Sprintf("Lobby %s", lobbyID.String()[:8])
+	result := h.db.DB().WithContext(c.UserContext()).Model(&models.Lobby{}).
+		Where("id = ?", lobbyID).
+		Updates(map[string]interface{}{
+			"name":       name,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating lobby")
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	h.lobbies.Invalidate()
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_lobby_rename",
+		TargetType: "lobby",
+		TargetID:   lobbyID.String(),
+		Details:    map[string]interface{}{"name": name},
+	})
+
+	var game models.Game
+	if err := h.db.DB().WithContext(c.UserContext()).Where("lobby_id = ? AND status = ?", lobbyID, "waiting").First(&game).Error; err == nil {
+		h.hub.BroadcastToGame(game.ID.String(), GameMessage{
+			Type:    "lobby_updated",
+			Payload: fiber.Map{"lobby_id": lobbyID, "name": name},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Lobby name cleared",
+		"name":    name,
+	})
+}
+
+// Games lists unfinished games that have not been updated within
+// idle_minutes, oldest first.
+func (h *AdminHandler) Games(c *fiber.Ctx) error {
+	cutoff, err := stuckCutoff(c)
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid idle_minutes")
+	}
+	limit := utils.ParseLimit(c.Query("limit"), 50, 200)
+
+	var games []models.Game
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Lobby").
+		Where("status <> ? AND updated_at < ?", "completed", cutoff).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&games).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching games")
+	}
+
+	return c.JSON(games)
+}
+
+// CloseGame force-ends a game without a winner. Results and ratings are not
+// recorded since nobody finished it.
+func (h *AdminHandler) CloseGame(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+	err = h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var game models.Game
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", gameID).First(&game).Error; err != nil {
+			return err
+		}
+		if game.Status == "completed" {
+			return errGameClosed
+		}
+
+		if err := tx.Model(&game).Updates(map[string]interface{}{
+			"status":     "completed",
+			"winner":     "none",
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return recordGameEvent(tx, gameID, "game_closed", nil, fiber.Map{
+			"closed_by": adminID,
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "Game not found")
+	} else if errors.Is(err, errGameClosed) {
+		return utils.NewError(fiber.StatusConflict, "Game is already completed")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error closing game")
+	}
+
+	h.games.timers.cancel(gameID)
+	h.games.countdowns.cancel(gameID)
+	h.lobbies.Invalidate()
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type:    "game_closed",
+		Payload: fiber.Map{"game_id": gameID, "reason": "closed_by_admin"},
+	})
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_game_close",
+		TargetType: "game",
+		TargetID:   gameID.String(),
+	})
+	return c.JSON(fiber.Map{
+		"message": "Game closed",
+	})
+}
+
+// GameState returns the full state of any game with every card face shown,
+// including the deck and face-down cards.
+func (h *AdminHandler) GameState(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	snapshot, err := loadGameSnapshot(c.UserContext(), h.db, gameID, uuid.Nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, errGameNotFound):
+			return utils.NewError(fiber.StatusNotFound, "Game not found")
+		case errors.Is(err, errCardsNotDealt):
+			return utils.NewError(fiber.StatusConflict, "Cards have not been dealt yet")
+		}
+		log.Printf("Error loading game state for game %s: %v", gameID, err)
+		return utils.NewError(fiber.StatusInternalServerError, "Error loading game state")
+	}
+
+	var cards []models.Card
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Where("game_id = ?", gameID).
+		Order("created_at ASC, id ASC").
+		Find(&cards).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching cards")
+	}
+
+	gameCards := make([]GameCard, len(cards))
+	for i, card := range cards {
+		gameCards[i] = revealGameCard(card)
+	}
+	snapshot["cards"] = gameCards
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_game_view",
+		TargetType: "game",
+		TargetID:   gameID.String(),
+	})
+
+	return c.JSON(snapshot)
+}
+
+// SearchUsers finds accounts by email. Unlike the public search it matches
+// partial addresses and includes the account's admin and lockout state.
+func (h *AdminHandler) SearchUsers(c *fiber.Ctx) error {
+	var req AdminSearchUsersRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	limit := utils.ParseLimit(c.Query("limit"), 25, 100)
+
+	var users []models.User
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Where("email ILIKE ?", "%"+req.Email+"%").
+		Order("email ASC").
+		Limit(limit).
+		Find(&users).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error searching users")
+	}
+
+	results := make([]fiber.Map, len(users))
+	for i, user := range users {
+		results[i] = fiber.Map{
+			"id":                user.ID,
+			"name":              user.Name,
+			"email":             user.Email,
+			"email_verified_at": user.EmailVerifiedAt,
+			"avatar":            user.Avatar,
+			"is_bot":            user.IsBot,
+			"is_admin":          user.IsAdmin,
+			"failed_logins":     user.FailedLogins,
+			"locked_until":      user.LockedUntil,
+			"created_at":        user.CreatedAt,
+		}
+	}
+
+	return c.JSON(results)
+}
+
+// BanUser bans a user and takes them out of every queue they are waiting in.
+// Their existing sessions and tokens stop working on the next request.
+func (h *AdminHandler) BanUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+	if userID == adminID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot ban yourself")
+	}
+
+	var req BanUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return utils.NewError(fiber.StatusBadRequest, "Ban expiry must be in the future")
+	}
+
+	var ban models.Ban
+	err = h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id").Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		ban, err = issueBan(tx, userID, adminID, req.Reason, req.ExpiresAt)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error banning user")
+	}
+
+	h.lobbies.Invalidate()
+	h.announceBan(ban)
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_ban",
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Details:    map[string]interface{}{"reason": ban.Reason, "expires_at": ban.ExpiresAt},
+	})
+	return c.Status(fiber.StatusCreated).JSON(ban)
+}
+
+// issueBan records a ban and takes the user out of the matchmaking queue and
+// every lobby queue they are waiting in.
+func issueBan(tx *gorm.DB, userID, adminID uuid.UUID, reason string, expiresAt *time.Time) (models.Ban, error) {
+	now := time.Now().UTC()
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	ban := models.Ban{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		IssuedBy:  &adminID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := tx.Create(&ban).Error; err != nil {
+		return ban, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MatchmakingEntry{}).Error; err != nil {
+		return ban, err
+	}
+
+	var queuedIn []uuid.UUID
+	if err := tx.Model(&models.LobbyQueue{}).Where("user_id = ?", userID).Pluck("lobby_id", &queuedIn).Error; err != nil {
+		return ban, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.LobbyQueue{}).Error; err != nil {
+		return ban, err
+	}
+	for _, lobbyID := range queuedIn {
+		if err := service.ReindexQueue(tx, lobbyID); err != nil {
+			return ban, err
+		}
+	}
+
+	return ban, nil
+}
+
+// announceBan tells the banned user's open sockets about the ban so clients
+// can show it without waiting for their next request to fail.
+func (h *AdminHandler) announceBan(ban models.Ban) {
+	h.hub.SendToUser(ban.UserID.String(), GameMessage{
+		Type: "account_banned",
+		Payload: fiber.Map{
+			"reason":     ban.Reason,
+			"expires_at": ban.ExpiresAt,
+		},
+	})
+}
+
+// UnbanUser lifts every ban in force on a user.
+func (h *AdminHandler) UnbanUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+	now := time.Now().UTC()
+	result := h.db.DB().WithContext(c.UserContext()).Model(&models.Ban{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": adminID,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error lifting ban")
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "User is not banned")
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_unban",
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+	return c.JSON(fiber.Map{
+		"message": "Ban lifted",
+	})
+}
+
+// Bans lists a user's bans, newest first, including expired and lifted ones.
+func (h *AdminHandler) Bans(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var bans []models.Ban
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&bans).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching bans")
+	}
+
+	return c.JSON(bans)
+}
+
+// MuteUser mutes a user in every chat.
+func (h *AdminHandler) MuteUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req BanUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return utils.NewError(fiber.StatusBadRequest, "Mute expiry must be in the future")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	var mute models.Mute
+	err = h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id").Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+		mute, err = issueMute(tx, userID, adminID, req.Reason, req.ExpiresAt)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error muting user")
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_mute",
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Details:    map[string]interface{}{"reason": mute.Reason, "expires_at": mute.ExpiresAt},
+	})
+	return c.Status(fiber.StatusCreated).JSON(mute)
+}
+
+// UnmuteUser lifts every global mute in force on a user.
+func (h *AdminHandler) UnmuteUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+	now := time.Now().UTC()
+	result := h.db.DB().WithContext(c.UserContext()).Model(&models.Mute{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": adminID,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error lifting mute")
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "User is not muted")
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_unmute",
+		TargetType: "user",
+		TargetID:   userID.String(),
+	})
+	return c.JSON(fiber.Map{
+		"message": "Mute lifted",
+	})
+}
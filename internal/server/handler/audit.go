@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"api/internal/server/utils"
+	"api/internal/service"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// recordAudit adds an entry for the request to the audit trail, filling in
+// the client IP and, unless the entry names one, the authenticated user as
+// the actor. Failures are logged rather than returned since the action has
+// already taken place.
+func recordAudit(c *fiber.Ctx, audit service.AuditService, entry service.AuditEntry) {
+	if entry.ActorID == nil {
+		if userID, ok := c.Locals("user_id").(uuid.UUID); ok {
+			entry.ActorID = &userID
+		}
+	}
+	entry.IPAddress = c.IP()
+
+	if err := audit.Record(c.UserContext(), entry); err != nil {
+		log.Printf("Error recording %s audit entry: %v", entry.Action, err)
+	}
+}
+
+// AuditLogs lists the audit trail newest first, filtered by actor_id,
+// action, target_type, target_id and an RFC 3339 since/until window.
+func (h *AdminHandler) AuditLogs(c *fiber.Ctx) error {
+	filter := service.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Limit:      utils.ParseLimit(c.Query("limit"), 50, 200),
+	}
+
+	if raw := c.Query("actor_id"); raw != "" {
+		actorID, err := uuid.Parse(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid actor_id")
+		}
+		filter.ActorID = &actorID
+	}
+	for param, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := c.Query(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return utils.NewError(fiber.StatusBadRequest, "Invalid "+param+", expected an RFC 3339 time")
+			}
+			*dst = &parsed
+		}
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		filter.BeforeAt = &cursor.CreatedAt
+		filter.BeforeID = cursor.ID
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := h.audit.List(c.UserContext(), filter)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching audit logs")
+	}
+
+	var nextCursor *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        entries,
+		"next_cursor": nextCursor,
+	})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,12 +13,19 @@ import (
 
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/firebase"
+	"api/internal/server/middleware"
 	"api/internal/server/utils"
+	"api/internal/service"
 )
 
 type AuthHandler struct {
-	store *session.Store
-	db    database.Service
+	store    *session.Store
+	db       database.Service
+	users    service.UserService
+	lockout  *LockoutHandler
+	audit    service.AuditService
+	firebase *firebase.Verifier
 }
 
 type LoginRequest struct {
@@ -29,90 +37,98 @@ type RegisterRequest struct {
 	Name                 string `json:"name" validate:"required"`
 	Email                string `json:"email" validate:"required,email"`
 	Password             string `json:"password" validate:"required,min=6"`
-	PasswordConfirmation string `json:"password_confirmation" validate:"required,min=6"`
-}
-
-type FirebaseUser struct {
-	ID     string `json:"id" validate:"required"`
-	Email  string `json:"email" validate:"required,email"`
-	Name   string `json:"name" validate:"required"`
-	Avatar string `json:"avatar" validate:"required"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
 }
 
+// FirebaseTokenRequest carries a Firebase ID token. The identity is taken
+// from the verified token only, never from anything else the client sends.
 type FirebaseTokenRequest struct {
-	Token    string       `json:"token" validate:"required"`
-	Provider string       `json:"provider" validate:"required"`
-	User     FirebaseUser `json:"user" validate:"required"`
+	Token string `json:"token" validate:"required"`
 }
 
-func NewAuthHandler(db database.Service, store *session.Store) *AuthHandler {
+func NewAuthHandler(db database.Service, store *session.Store, users service.UserService, lockout *LockoutHandler, audit service.AuditService, verifier *firebase.Verifier) *AuthHandler {
 	return &AuthHandler{
-		store: store,
-		db:    db,
+		store:    store,
+		db:       db,
+		users:    users,
+		lockout:  lockout,
+		audit:    audit,
+		firebase: verifier,
 	}
 }
 
+// FirebaseLogin signs in the user whose verified email matches the Firebase
+// ID token. It goes through the same IP block, account lock and ban checks
+// as a password login, and a token that fails verification counts as a
+// failed attempt.
 func (h *AuthHandler) FirebaseLogin(c *fiber.Ctx) error {
 	var req FirebaseTokenRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	sessionID := c.Cookies("session_id")
-	if sessionID != "" {
-		var session models.Session
-		if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"message":    "Already logged in",
-				"session_id": sessionID,
-			})
-		}
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	sess, err := h.store.Get(c)
+	wait, blocked, err := h.lockout.ipBlocked(c.UserContext(), c.IP())
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error getting session",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if blocked {
+		retryAfter(c, wait)
+		return utils.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, please try again later")
 	}
 
-	sess.SetExpiry(time.Hour * 24)
-	sess.Set("user_id", req.User.ID)
-	sess.Set("email", req.User.Email)
-	sess.Set("name", req.User.Name)
-	sess.Set("avatar", req.User.Avatar)
+	claims, err := h.firebase.Verify(c.UserContext(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, firebase.ErrNotConfigured):
+			return utils.NewError(fiber.StatusServiceUnavailable, "Firebase login is not configured")
+		case errors.Is(err, firebase.ErrInvalidToken):
+			if _, err := h.lockout.recordFailure(c.UserContext(), "", c.IP(), nil); err != nil {
+				return utils.NewError(fiber.StatusInternalServerError, "Database error")
+			}
+			return utils.NewError(fiber.StatusUnauthorized, "Invalid Firebase token")
+		default:
+			log.Printf("Error verifying Firebase token: %v", err)
+			return utils.NewError(fiber.StatusServiceUnavailable, "Could not verify Firebase token")
+		}
+	}
 
-	session := models.Session{
-		ID:           uuid.New(),
-		IPAddress:    c.IP(),
-		UserAgent:    c.Get("User-Agent"),
-		LastActivity: int(time.Now().Unix()),
+	if claims.Email == "" || !claims.EmailVerified {
+		return utils.NewError(fiber.StatusForbidden, "Firebase account has no verified email address")
 	}
 
-	if err := h.db.DB().Create(&session).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating session",
-		})
+	var user models.User
+	result := h.db.DB().WithContext(c.UserContext()).Where("email = ?", claims.Email).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "No account is registered with this email")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
-	sess.Set("session_id", session.ID)
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		retryAfter(c, time.Until(*user.LockedUntil))
+		return utils.NewError(fiber.StatusLocked, "Account is temporarily locked, check your email to unlock it")
+	}
 
-	if err := sess.Save(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error saving session",
+	if err := h.lockout.recordSuccess(c.UserContext(), user); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	if session, ok := h.existingSession(c); ok {
+		return c.JSON(fiber.Map{
+			"message":    "Already logged in",
+			"session_id": session.ID,
 		})
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name:     "session_id",
-		Value:    session.ID.String(),
-		Expires:  time.Now().Add(time.Hour * 24),
-		HTTPOnly: true,
-		Secure:   true,
-		SameSite: "Lax",
-	})
+	if err := h.startSession(c, user.ID); err != nil {
+		return err
+	}
+	h.recordLogin(c, user.ID, "firebase")
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -122,34 +138,24 @@ func (h *AuthHandler) FirebaseLogin(c *fiber.Ctx) error {
 func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	var req RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
 	var existingUser models.User
-	result := h.db.DB().Where("email = ?", req.Email).First(&existingUser)
+	result := h.db.DB().WithContext(c.UserContext()).Where("email = ?", req.Email).First(&existingUser)
 	if result.Error == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "User already exists",
-		})
+		return utils.NewError(fiber.StatusConflict, "User already exists")
 	} else if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
-	}
-
-	if req.Password != req.PasswordConfirmation {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Passwords do not match",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error hashing password",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error hashing password")
 	}
 
 	user := models.User{
@@ -159,166 +165,124 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		Password: string(hashedPassword),
 	}
 
-	if err := h.db.DB().Create(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating user",
-		})
+	if err := h.db.DB().WithContext(c.UserContext()).Create(&user).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating user")
 	}
 
-	lastUsedAt := time.Now()
-
-	token := models.PersonalAccessToken{
-		ID:            uuid.New(),
-		TokenableType: "User",
-		TokenableID:   user.ID,
-		Name:          "Primary",
-		Token:         utils.GenerateToken(),
-		Abilities:     nil,
-		LastUsedAt:    &lastUsedAt,
-	}
-
-	if err := h.db.DB().Create(&token).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating token",
-		})
-	}
-
-	sess, err := h.store.Get(c)
+	token, err := issuePrimaryToken(h.db.DB().WithContext(c.UserContext()), user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Session error",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating token")
 	}
 
-	sess.Set("user_id", user.ID)
-	if err := sess.Save(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error saving session",
-		})
+	if err := h.startSession(c, user.ID); err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
 		"message": "User created successfully",
-		"token":   token.Token,
+		"token":   token,
 	})
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req LoginRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	wait, blocked, err := h.lockout.ipBlocked(c.UserContext(), c.IP())
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if blocked {
+		retryAfter(c, wait)
+		return utils.NewError(fiber.StatusTooManyRequests, "Too many failed login attempts, please try again later")
 	}
 
 	var user models.User
-	result := h.db.DB().Where("email = ?", req.Email).First(&user)
+	result := h.db.DB().WithContext(c.UserContext()).Where("email = ?", req.Email).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid credentials",
-			})
+			if _, err := h.lockout.recordFailure(c.UserContext(), req.Email, c.IP(), nil); err != nil {
+				return utils.NewError(fiber.StatusInternalServerError, "Database error")
+			}
+			return utils.NewError(fiber.StatusUnauthorized, "Invalid credentials")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid credentials",
-		})
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		retryAfter(c, time.Until(*user.LockedUntil))
+		return utils.NewError(fiber.StatusLocked, "Account is temporarily locked, check your email to unlock it")
 	}
 
-	sessionID := c.Cookies("session_id")
-	if sessionID != "" {
-		var session models.Session
-		if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err == nil {
-			return c.JSON(fiber.Map{
-				"message":    "Already logged in",
-				"session_id": session.ID,
-			})
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		lockedUntil, err := h.lockout.recordFailure(c.UserContext(), req.Email, c.IP(), &user)
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+		if lockedUntil != nil {
+			retryAfter(c, time.Until(*lockedUntil))
+			return utils.NewError(fiber.StatusLocked, "Too many failed login attempts, check your email to unlock your account")
 		}
+		return utils.NewError(fiber.StatusUnauthorized, "Invalid credentials")
 	}
 
-	sess, err := h.store.Get(c)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Session error",
-		})
+	if err := h.lockout.recordSuccess(c.UserContext(), user); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
 	}
 
-	sess.SetExpiry(time.Hour * 24)
-	sess.Set("user_id", user.ID)
-
-	session := models.Session{
-		ID:           uuid.New(),
-		UserID:       user.ID,
-		IPAddress:    c.IP(),
-		UserAgent:    c.Get("User-Agent"),
-		LastActivity: int(time.Now().Unix()),
-	}
-
-	if err := h.db.DB().Create(&session).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating session",
+	if session, ok := h.existingSession(c); ok {
+		return c.JSON(fiber.Map{
+			"message":    "Already logged in",
+			"session_id": session.ID,
 		})
 	}
 
-	sess.Set("session_id", session.ID)
-	if err := sess.Save(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error saving session",
-		})
+	if err := h.startSession(c, user.ID); err != nil {
+		return err
 	}
+	h.recordLogin(c, user.ID, "password")
 
-	c.Cookie(&fiber.Cookie{
-		Name:     "session_id",
-		Value:    session.ID.String(),
-		Expires:  time.Now().Add(24 * time.Hour),
-		HTTPOnly: false,
-		Secure:   false,
-		SameSite: "Lax",
-	})
-
-	var token models.PersonalAccessToken
-
-	if err := h.db.DB().Where("tokenable_type = ? AND tokenable_id = ?", "User", user.ID).First(&token).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating token",
-		})
+	token, err := issuePrimaryToken(h.db.DB().WithContext(c.UserContext()), user.ID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating token")
 	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
 		"message": "Login successful",
-		"token":   token.Token,
+		"token":   token,
 	})
 }
 
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 	sessionID := c.Cookies("session_id")
 	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
-		})
+		return utils.NewError(fiber.StatusUnauthorized, "Session ID not provided")
 	}
 
 	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return utils.NewError(fiber.StatusUnauthorized, "Invalid session")
 	}
 
-	if err := h.db.DB().Delete(&session).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error logging out. Unable to delete session",
-		})
+	if err := h.db.DB().WithContext(c.UserContext()).Delete(&session).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error logging out. Unable to delete session")
 	}
 
-	c.ClearCookie("session_id")
+	sess, err := h.store.Get(c)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Session error")
+	}
+	if err := sess.Destroy(); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error logging out. Unable to destroy session")
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "Successfully logged out",
@@ -328,24 +292,129 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 func (h *AuthHandler) GetCurrentUser(c *fiber.Ctx) error {
 	sessionID := c.Cookies("session_id")
 	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
+		return utils.NewError(fiber.StatusUnauthorized, "Session ID not provided")
+	}
+
+	session, err := h.users.Session(c.UserContext(), sessionID)
+	if err != nil {
+		return utils.NewError(fiber.StatusUnauthorized, "Invalid session")
+	}
+
+	user, err := h.users.Find(c.UserContext(), session.UserID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	}
+
+	return c.JSON(newUserResponse(user))
+}
+
+// issuePrimaryToken gives the user a fresh Primary token and returns its
+// plain value. Only hashes are stored, so every login rotates the token
+// rather than handing back the one issued before.
+func issuePrimaryToken(db *gorm.DB, userID uuid.UUID) (string, error) {
+	value := utils.GenerateToken()
+	if value == "" {
+		return "", errors.New("error generating token")
+	}
+
+	now := time.Now()
+	result := db.Model(&models.PersonalAccessToken{}).
+		Where("tokenable_type = ? AND tokenable_id = ? AND name = ?", "User", userID, "Primary").
+		Updates(map[string]interface{}{
+			"token":        utils.HashToken(value),
+			"last_used_at": now,
+			"updated_at":   now,
 		})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		return value, nil
+	}
+
+	token := models.PersonalAccessToken{
+		ID:            uuid.New(),
+		TokenableType: "User",
+		TokenableID:   userID,
+		Name:          "Primary",
+		Token:         utils.HashToken(value),
+		LastUsedAt:    &now,
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+	}
+	if err := db.Create(&token).Error; err != nil {
+		return "", err
 	}
+	return value, nil
+}
 
+// existingSession returns the database session named by the request's
+// session cookie, if it is still valid.
+func (h *AuthHandler) existingSession(c *fiber.Ctx) (models.Session, bool) {
 	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+
+	sessionID := c.Cookies("session_id")
+	if sessionID == "" {
+		return session, false
 	}
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", sessionID).First(&session).Error
+	return session, err == nil
+}
+
+// recordLogin adds a successful login to the audit trail.
+func (h *AuthHandler) recordLogin(c *fiber.Ctx, userID uuid.UUID, method string) {
+	recordAudit(c, h.audit, service.AuditEntry{
+		ActorID:    &userID,
+		Action:     "login",
+		TargetType: "user",
+		TargetID:   userID.String(),
+		Details:    map[string]interface{}{"method": method},
+	})
+}
+
+// startSession logs the user in. The session store issues a fresh ID, which
+// doubles as the primary key of the sessions row the auth middleware checks,
+// and saving the store session sets the session_id cookie with the store's
+// cookie settings. Every login path goes through here so the store and the
+// sessions table never disagree, and so banned users are turned away before
+// they get a session.
+func (h *AuthHandler) startSession(c *fiber.Ctx, userID uuid.UUID) error {
+	if err := middleware.CheckBan(c, h.db, userID); err != nil {
+		return err
+	}
+
+	sess, err := h.store.Get(c)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Session error")
+	}
+	if err := sess.Regenerate(); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Session error")
+	}
+
+	sessionID, err := uuid.Parse(sess.ID())
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Session error")
+	}
+
+	session := models.Session{
+		ID:           sessionID,
+		UserID:       userID,
+		IPAddress:    c.IP(),
+		UserAgent:    c.Get("User-Agent"),
+		LastActivity: int(time.Now().Unix()),
+	}
+
+	if err := h.db.DB().WithContext(c.UserContext()).Create(&session).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating session")
+	}
+
+	sess.SetExpiry(24 * time.Hour)
+	sess.Set("user_id", userID.String())
+	sess.Set("session_id", session.ID.String())
+	if err := sess.Save(); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error saving session")
 	}
 
-	return c.JSON(user)
+	return nil
 }
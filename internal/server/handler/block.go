@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Blocks are stored as friendships with status "blocked", where the requester
+// is the user who did the blocking. Blocking replaces any existing friendship
+// or pending request between the two users.
+
+func (h *UserHandler) Block(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	targetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if targetID == userID {
+		return utils.NewError(fiber.StatusBadRequest, "Cannot block yourself")
+	}
+
+	var target models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Select("id").Where("id = ?", targetID).First(&target).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	if err := tx.Where("((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)) AND status <> ?",
+		userID, targetID, targetID, userID, "blocked").
+		Delete(&models.Friendship{}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error removing friendship")
+	}
+
+	var existing int64
+	if err := tx.Model(&models.Friendship{}).
+		Where("requester_id = ? AND addressee_id = ? AND status = ?", userID, targetID, "blocked").
+		Count(&existing).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking block list")
+	}
+
+	if existing == 0 {
+		now := time.Now()
+		if err := tx.Create(&models.Friendship{
+			ID:          uuid.New(),
+			RequesterID: userID,
+			AddresseeID: targetID,
+			Status:      "blocked",
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}).Error; err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error blocking user")
+		}
+	}
+
+	if err := tx.Model(&models.LobbyInvitation{}).
+		Where("invited_user_id = ? AND inviter_id = ? AND status = ?", userID, targetID, "pending").
+		Updates(map[string]interface{}{
+			"status":     "cancelled",
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error removing invitations")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User blocked",
+	})
+}
+
+func (h *UserHandler) Unblock(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	targetID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	result := h.db.DB().WithContext(c.UserContext()).
+		Where("requester_id = ? AND addressee_id = ? AND status = ?", userID, targetID, "blocked").
+		Delete(&models.Friendship{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error unblocking user")
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "User is not blocked")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unblocked",
+	})
+}
+
+func (h *UserHandler) Blocked(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var blocks []models.Friendship
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Addressee", selectPublicUser).
+		Where("requester_id = ? AND status = ?", userID, "blocked").
+		Order("created_at DESC").
+		Find(&blocks).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching blocked users")
+	}
+
+	users := make([]models.User, len(blocks))
+	for i, block := range blocks {
+		users[i] = block.Addressee
+	}
+
+	return c.JSON(fiber.Map{
+		"data": users,
+	})
+}
+
+// blockedPairs returns, for each of the given users, the set of users among
+// them they may not be matched with.
+func blockedPairs(db *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]map[uuid.UUID]bool, error) {
+	pairs := make(map[uuid.UUID]map[uuid.UUID]bool)
+	if len(userIDs) == 0 {
+		return pairs, nil
+	}
+
+	var blocks []models.Friendship
+	if err := db.Where("status = ? AND requester_id IN ? AND addressee_id IN ?", "blocked", userIDs, userIDs).
+		Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		for _, pair := range [][2]uuid.UUID{{block.RequesterID, block.AddresseeID}, {block.AddresseeID, block.RequesterID}} {
+			if pairs[pair[0]] == nil {
+				pairs[pair[0]] = make(map[uuid.UUID]bool)
+			}
+			pairs[pair[0]][pair[1]] = true
+		}
+	}
+	return pairs, nil
+}
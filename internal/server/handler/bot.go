@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const botThinkingDelay = 1500 * time.Millisecond
+
+// botPasswordHash is not a valid bcrypt hash, so bot accounts can never log in.
+const botPasswordHash = "!"
+
+func (h *LobbyHandler) AddBot(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if lobby.OwnerID != userID {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can add bots")
+	}
+
+	if lobby.Status != "waiting" {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Lobby not accepting players")
+	}
+
+	if lobby.CurrentPlayers >= lobby.MaxPlayers {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Lobby is full")
+	}
+
+	game, err := service.LockOpenGame(tx, lobby.ID)
+	if errors.Is(err, service.ErrGameStarted) {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusConflict, "The game has already started")
+	} else if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "No waiting game in this lobby")
+	}
+
+	bot, err := createBotPlayer(tx, &lobby, game.ID)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error adding bot")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Bot added to lobby",
+		"player":  bot,
+	})
+}
+
+// createBotPlayer seats a new bot in the lobby's game. Each bot gets its own
+// user row so it fits the players foreign key like any other participant.
+func createBotPlayer(tx *gorm.DB, lobby *models.Lobby, gameID uuid.UUID) (models.Player, error) {
+	var seated int64
+	if err := tx.Model(&models.Player{}).Where("lobby_id = ?", lobby.ID).Count(&seated).Error; err != nil {
+		return models.Player{}, err
+	}
+
+	now := time.Now()
+	botID := uuid.New()
+	user := models.User{
+		ID:        botID,
+		Name:      fmt.Sprintf("Bot %d", seated+1),
+		Email:     fmt.Sprintf("bot-%s@bots.invalid", botID),
+		Password:  botPasswordHash,
+		IsBot:     true,
+		CreatedAt: &now,
+		UpdatedAt: &now,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return models.Player{}, err
+	}
+
+	team, err := service.AssignTeam(tx, lobby, gameID)
+	if err != nil {
+		return models.Player{}, err
+	}
+
+	player := models.Player{
+		ID:      uuid.New(),
+		LobbyID: lobby.ID,
+		GameID:  gameID,
+		UserID:  user.ID,
+		Team:    team,
+		Role:    fmt.Sprintf("player%d", seated+1),
+		IsReady: true,
+		IsBot:   true,
+	}
+	if err := tx.Create(&player).Error; err != nil {
+		return models.Player{}, err
+	}
+
+	if err := service.ClaimSeat(tx, lobby); err != nil {
+		return models.Player{}, err
+	}
+
+	return player, nil
+}
+
+func (h *GameHandler) playBotTurn(gameID, playerID uuid.UUID) {
+	action, played, ok := h.autoPlayTurn(gameID, playerID)
+	if !ok {
+		return
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "bot_move",
+		Payload: fiber.Map{
+			"game_id":      gameID.String(),
+			"player_id":    playerID,
+			"action":       action,
+			"cards_played": played,
+		},
+	})
+
+	h.announceGameOver(gameID)
+}
+
+// replaceWithBot hands a player's seat to a bot once their reconnect grace
+// period has run out, so the table keeps moving without them.
+func (h *GameHandler) replaceWithBot(gameID, playerID uuid.UUID) {
+	tx := h.db.DB().Begin()
+
+	var player models.Player
+	if err := tx.Where("id = ?", playerID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if player.Status != "disconnected" {
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Model(&player).Updates(map[string]interface{}{
+		"status": "active",
+		"is_bot": true,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error replacing player %s with a bot: %v", playerID, err)
+		return
+	}
+
+	var game models.Game
+	if err := tx.Where("id = ?", gameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing bot replacement: %v", err)
+		return
+	}
+
+	if game.CurrentTurnPlayerID == playerID {
+		h.startTurnTimer(gameID)
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "player_replaced_by_bot",
+		Payload: fiber.Map{
+			"game_id":   gameID.String(),
+			"player_id": playerID,
+			"reason":    "disconnected",
+		},
+	})
+}
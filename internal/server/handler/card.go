@@ -3,42 +3,34 @@ package handler
 import (
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/game/decks"
+	"api/internal/game/progression"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type Card struct {
-	Code  string `json:"code"`
-	Image string `json:"image"`
-	Value string `json:"value"`
-	Suit  string `json:"suit"`
-}
-
-type Deck struct {
-	Success   bool   `json:"success"`
-	DeckID    string `json:"deck_id"`
-	Cards     []Card `json:"cards"`
-	Remaining int    `json:"remaining"`
-}
-
 type GameCard struct {
 	ID           uuid.UUID  `json:"id"`
-	Code         string     `json:"code"`
-	Value        string     `json:"value"`
-	Suit         string     `json:"suit"`
+	Code         string     `json:"code,omitempty"`
+	Value        string     `json:"value,omitempty"`
+	Suit         string     `json:"suit,omitempty"`
 	ImageURL     string     `json:"image_url,omitempty"`
 	Status       string     `json:"status"`
 	LocationType string     `json:"location_type"`
 	PlayerID     *uuid.UUID `json:"player_id,omitempty"`
+	Hidden       bool       `json:"hidden"`
 }
 
 type GameState struct {
@@ -51,14 +43,18 @@ type GameState struct {
 	Game            models.Game     `json:"game"`
 }
 
+// PlayerSummary describes a seated player to everyone in the game. The email
+// is only included when the player made it public.
 type PlayerSummary struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Avatar    *string   `json:"avatar,omitempty"`
-	CardCount int64     `json:"card_count"`
-	IsCurrent bool      `json:"is_current"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	Avatar      *string   `json:"avatar,omitempty"`
+	Level       int       `json:"level"`
+	CardCount   int64     `json:"card_count"`
+	IsCurrent   bool      `json:"is_current"`
+	UserID      uuid.UUID `json:"user_id"`
 }
 
 type LobbyInfo struct {
@@ -71,87 +67,116 @@ type LobbyInfo struct {
 	GameMode       string    `json:"game_mode"`
 }
 
+// cardBatchSize is how many cards go into one INSERT when dealing and into one
+// CASE UPDATE when moving cards. 104 is two full packs, so dealing a
+// double-deck game and picking up any pile a real game builds take a single
+// statement each. A chunk then binds 1,664 parameters on insert (16 columns)
+// and 937 on update, well clear of Postgres's limit of 65,535. Larger chunks
+// only help games of three or more packs; BenchmarkCardBatches compares the
+// sizes either side.
+const cardBatchSize = 104
+
+var (
+	errGameNotFound  = errors.New("game not found")
+	errCardsNotDealt = errors.New("cards not dealt")
+	errAlreadyDealt  = errors.New("cards already dealt")
+)
+
+type cardUpdate struct {
+	ID           uuid.UUID
+	Status       string
+	LocationType string
+	PlayerID     *uuid.UUID
+	// PilePosition is set by stackOnPile for cards laid on the play pile.
+	PilePosition *int
+}
+
 type CardHandler struct {
-	db database.Service
+	db    database.Service
+	decks decks.Provider
 }
 
-func NewCardHandler(db database.Service) *CardHandler {
-	return &CardHandler{db: db}
+func NewCardHandler(db database.Service, deckProvider decks.Provider) *CardHandler {
+	return &CardHandler{
+		db:    db,
+		decks: deckProvider,
+	}
 }
 
 func (h *CardHandler) GetGameCards(c *fiber.Ctx) error {
-	sessionId := c.Cookies("session_id")
-	var session models.Session
-	if err := h.db.DB().
-		Where("id = ?", sessionId).
-		First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
-	}
+	userID := c.Locals("user_id").(uuid.UUID)
 
 	gameId := c.Params("gameId")
 	if gameId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Game ID is required",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Game ID is required")
 	}
 
 	gameUUID, err := uuid.Parse(gameId)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid game ID format",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID format")
 	}
 
 	var player models.Player
-	if err := h.db.DB().
-		Where("user_id = ? AND game_id = ?", session.UserID, gameUUID).
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Where("user_id = ? AND game_id = ?", userID, gameUUID).
 		First(&player).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Player not found in game",
-		})
+		return utils.NewError(fiber.StatusNotFound, "Player not found in game")
 	}
 
+	snapshot, err := loadGameSnapshot(c.UserContext(), h.db, gameUUID, player.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errGameNotFound):
+			return utils.NewError(fiber.StatusNotFound, "Game not found")
+		case errors.Is(err, errCardsNotDealt):
+			return utils.NewError(fiber.StatusConflict, "Cards are dealt when the game starts").WithCode("CARDS_NOT_DEALT")
+		}
+		log.Printf("Error loading game state for game %s: %v", gameUUID, err)
+		return utils.NewError(fiber.StatusInternalServerError, "Error loading game state")
+	}
+
+	return c.JSON(snapshot)
+}
+
+// loadGameSnapshot returns the cards and game state of a game as seen by the
+// given player; pass uuid.Nil to get the view of a non-player.
+func loadGameSnapshot(ctx context.Context, db database.Service, gameUUID, viewerPlayerID uuid.UUID) (fiber.Map, error) {
 	var game models.Game
-	if err := h.db.DB().
+	if err := db.DB().WithContext(ctx).
 		Preload("Lobby").
 		Preload("Lobby.Owner").
 		Where("id = ?", gameUUID).
 		First(&game).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Game not found",
-		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errGameNotFound
+		}
+		return nil, err
 	}
 
-	players, err := h.getPlayerSummaries(gameId, game.CurrentTurnPlayerID)
+	players, err := getPlayerSummaries(ctx, db, gameUUID.String(), game.CurrentTurnPlayerID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to get player information: %v", err),
-		})
+		return nil, fmt.Errorf("failed to get player information: %v", err)
 	}
 
-	cards, err := h.getOrCreateGameCards(gameId)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to get or create game cards: %v", err),
-		})
-	}
-
-	if len(cards) == 0 {
-		if err := h.db.DB().
-			Where("game_id = ?", gameUUID).
-			Find(&cards).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to fetch existing cards",
-			})
+	var deck models.Deck
+	if err := db.DB().WithContext(ctx).Where("game_id = ?", gameUUID).First(&deck).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errCardsNotDealt
 		}
+		return nil, fmt.Errorf("failed to fetch deck: %v", err)
+	}
+
+	var cards []models.Card
+	if err := db.DB().WithContext(ctx).
+		Where("deck_id = ?", deck.ID).
+		Find(&cards).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch existing cards: %v", err)
 	}
 
 	gameState := GameState{
 		ID:              game.ID,
 		Status:          game.Status,
-		CurrentPlayerID: player.ID,
+		CurrentPlayerID: viewerPlayerID,
 		RoundNumber:     game.RoundNumber,
 		Players:         players,
 		Game:            game,
@@ -168,45 +193,21 @@ func (h *CardHandler) GetGameCards(c *fiber.Ctx) error {
 
 	gameCards := make([]GameCard, len(cards))
 	for i, card := range cards {
-		gameCards[i] = GameCard{
-			ID:           card.ID,
-			Code:         card.Code,
-			Value:        card.Value,
-			Suit:         card.Suit,
-			ImageURL:     *card.ImageURL,
-			Status:       card.Status,
-			LocationType: card.LocationType,
-			PlayerID:     card.PlayerID,
-		}
+		gameCards[i] = toGameCard(card, viewerPlayerID)
 	}
 
-	return c.JSON(fiber.Map{
+	return fiber.Map{
 		"cards":      gameCards,
 		"game_state": gameState,
-	})
+	}, nil
 }
 
-func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error) {
-	var cards []models.Card
-	var existingDeck models.Deck
-
-	gameUUID, err := uuid.Parse(gameId)
-	if err != nil {
-		return nil, fmt.Errorf("invalid game ID format: %v", err)
-	}
-
-	if err := h.db.DB().Where("game_id = ?", gameUUID).First(&existingDeck).Error; err == nil {
-		if err := h.db.DB().Where("deck_id = ?", existingDeck.ID).Find(&cards).Error; err != nil {
-			return nil, fmt.Errorf("error fetching existing cards: %v", err)
-		}
-		return cards, nil
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("error checking for existing deck: %v", err)
-	}
-
-	log.Printf("No deck found, creating a new deck for game %s", gameId)
-
-	tx := h.db.DB().Begin()
+// dealCards creates the deck of a waiting game and deals it to exactly the
+// players seated at that moment. It runs once, as the game starts; the game
+// row is locked while dealing so nobody can take a seat halfway through, and
+// a game that already has a deck fails with errAlreadyDealt.
+func dealCards(db database.Service, provider decks.Provider, gameUUID uuid.UUID) ([]models.Card, error) {
+	tx := db.DB().Begin()
 	if tx.Error != nil {
 		return nil, fmt.Errorf("error starting transaction: %v", tx.Error)
 	}
@@ -216,54 +217,102 @@ func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error)
 		}
 	}()
 
-	deck := models.Deck{
-		ID:             uuid.New(),
-		GameID:         gameUUID,
-		DeckType:       "standard",
-		TotalCards:     52,
-		RemainingCards: 52,
-		DeckConfiguration: json.RawMessage(`{
-            "includeJokers": false,
-            "specialCards": {
-                "6": "reset_deck",
-                "10": "clear_deck_extra_move"
-            }
-        }`),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", gameUUID).First(&game).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errGameNotFound
+		}
+		return nil, fmt.Errorf("error fetching game: %v", err)
+	}
+	if game.Status != "waiting" {
+		tx.Rollback()
+		return nil, service.ErrGameStarted
 	}
 
-	if err := tx.Create(&deck).Error; err != nil {
+	var existing int64
+	if err := tx.Model(&models.Deck{}).Where("game_id = ?", gameUUID).Count(&existing).Error; err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("error creating deck: %v", err)
+		return nil, fmt.Errorf("error checking for existing deck: %v", err)
+	}
+	if existing > 0 {
+		tx.Rollback()
+		return nil, errAlreadyDealt
+	}
+
+	house, err := gameSettings(tx, gameUUID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error loading game settings: %v", err)
 	}
+	cfg := house.Rules()
 
 	var players []models.Player
-	if err := tx.Where("game_id = ?", gameUUID).Find(&players).Error; err != nil {
+	if err := tx.Where("game_id = ? AND status <> ?", gameUUID, "forfeited").
+		Order("created_at ASC, id ASC").
+		Find(&players).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("error fetching players: %v", err)
 	}
-	if len(players) == 0 {
+	if len(players) < minPlayersToStart {
 		tx.Rollback()
-		return nil, fmt.Errorf("no players found for game %s", gameId)
+		return nil, fmt.Errorf("not enough players seated in game %s", gameUUID)
 	}
 
-	apiCards, err := FetchAllCards()
+	spec := house.Deck(len(players))
+
+	deckConfiguration, err := json.Marshal(fiber.Map{
+		"includeJokers": house.Jokers,
+		"specialCards":  cfg.SpecialCards,
+	})
 	if err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("error fetching cards from API: %v", err)
+		return nil, fmt.Errorf("error encoding deck configuration: %v", err)
 	}
-	if len(apiCards) != 52 {
+
+	deck := models.Deck{
+		ID:                uuid.New(),
+		GameID:            gameUUID,
+		DeckType:          string(spec.Type),
+		PackCount:         spec.Packs(),
+		JokerCount:        spec.JokerCount(),
+		TotalCards:        spec.Size(),
+		RemainingCards:    spec.Size(),
+		DeckConfiguration: deckConfiguration,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+
+	if err := tx.Create(&deck).Error; err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("expected 52 cards from API, got %d", len(apiCards))
+		return nil, fmt.Errorf("error creating deck: %v", err)
 	}
 
-	cards = make([]models.Card, 0, 52)
+	apiCards, err := provider.Cards(spec)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("error fetching cards from deck provider: %v", err)
+	}
+	if len(apiCards) != spec.Size() {
+		tx.Rollback()
+		return nil, fmt.Errorf("expected %d cards from deck provider, got %d", spec.Size(), len(apiCards))
+	}
+
+	cards := make([]models.Card, 0, len(apiCards))
 	cardIndex := 0
 
+	deal := []struct {
+		status string
+		count  int
+	}{
+		{"hidden", cfg.FaceDownCount},
+		{"faceup", cfg.FaceUpCount},
+		{"hand", cfg.HandSize},
+	}
+
 	for _, player := range players {
-		for _, status := range []string{"hidden", "faceup", "hand"} {
-			for i := 0; i < 3; i++ {
+		for _, pile := range deal {
+			for i := 0; i < pile.count; i++ {
 				if cardIndex >= len(apiCards) {
 					tx.Rollback()
 					return nil, fmt.Errorf("not enough cards for distribution at index %d", cardIndex)
@@ -277,11 +326,11 @@ func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error)
 					Value:         apiCards[cardIndex].Value,
 					Suit:          apiCards[cardIndex].Suit,
 					ImageURL:      &apiCards[cardIndex].Image,
-					Status:        status,
+					Status:        pile.status,
 					LocationType:  "player",
 					PlayerID:      &player.ID,
-					IsSpecialCard: isSpecialCard(apiCards[cardIndex].Value),
-					SpecialAction: getSpecialAction(apiCards[cardIndex].Value),
+					IsSpecialCard: cfg.IsSpecial(apiCards[cardIndex].Value),
+					SpecialAction: string(cfg.EffectOf(apiCards[cardIndex].Value)),
 					CreatedAt:     time.Now(),
 					UpdatedAt:     time.Now(),
 				}
@@ -302,8 +351,8 @@ func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error)
 			ImageURL:      &apiCards[i].Image,
 			Status:        "in_deck",
 			LocationType:  "deck",
-			IsSpecialCard: isSpecialCard(apiCards[i].Value),
-			SpecialAction: getSpecialAction(apiCards[i].Value),
+			IsSpecialCard: cfg.IsSpecial(apiCards[i].Value),
+			SpecialAction: string(cfg.EffectOf(apiCards[i].Value)),
 			CreatedAt:     time.Now(),
 			UpdatedAt:     time.Now(),
 		}
@@ -316,7 +365,7 @@ func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error)
 		return nil, fmt.Errorf("error updating deck remaining cards: %v", err)
 	}
 
-	if err := tx.Create(&cards).Error; err != nil {
+	if err := tx.CreateInBatches(&cards, cardBatchSize).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("error creating cards: %v", err)
 	}
@@ -325,113 +374,161 @@ func (h *CardHandler) getOrCreateGameCards(gameId string) ([]models.Card, error)
 		return nil, fmt.Errorf("error committing transaction: %v", err)
 	}
 
-	log.Printf("Successfully created deck and distributed %d cards for game %s", len(cards), gameId)
+	log.Printf("Dealt %d cards to %d players for game %s", len(cards), len(players), gameUUID)
 	return cards, nil
 }
 
-func FetchAllCards() ([]Card, error) {
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+// batchUpdateCards moves many cards in a single UPDATE per chunk using CASE
+// expressions keyed by card ID instead of one statement per card.
+func batchUpdateCards(tx *gorm.DB, updates []cardUpdate) error {
+	return updateCardsInBatches(tx, updates, cardBatchSize)
+}
 
-	resp, err := client.Get("https://deckofcardsapi.com/api/deck/new/shuffle/")
-	if err != nil {
-		return nil, fmt.Errorf("error creating new deck: %v", err)
-	}
-	defer resp.Body.Close()
+func updateCardsInBatches(tx *gorm.DB, updates []cardUpdate, batchSize int) error {
+	for start := 0; start < len(updates); start += batchSize {
+		end := start + batchSize
+		if end > len(updates) {
+			end = len(updates)
+		}
+		chunk := updates[start:end]
+
+		var statusCase, locationCase, playerCase, positionCase strings.Builder
+		statusArgs := make([]interface{}, 0, len(chunk)*2)
+		locationArgs := make([]interface{}, 0, len(chunk)*2)
+		playerArgs := make([]interface{}, 0, len(chunk)*2)
+		positionArgs := make([]interface{}, 0, len(chunk)*2)
+		ids := make([]uuid.UUID, len(chunk))
+
+		for i, u := range chunk {
+			statusCase.WriteString(" WHEN ? THEN ?")
+			locationCase.WriteString(" WHEN ? THEN ?")
+			playerCase.WriteString(" WHEN ? THEN CAST(? AS uuid)")
+			positionCase.WriteString(" WHEN ? THEN CAST(? AS int)")
+			statusArgs = append(statusArgs, u.ID, u.Status)
+			locationArgs = append(locationArgs, u.ID, u.LocationType)
+			playerArgs = append(playerArgs, u.ID, u.PlayerID)
+			positionArgs = append(positionArgs, u.ID, u.PilePosition)
+			ids[i] = u.ID
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
-	}
+		query := fmt.Sprintf(
+			"UPDATE cards SET status = CASE id%s END, location_type = CASE id%s END, player_id = CASE id%s END, pile_position = CASE id%s END, updated_at = ? WHERE id IN ?",
+			statusCase.String(), locationCase.String(), playerCase.String(), positionCase.String(),
+		)
 
-	fmt.Printf("Deck creation response: %s\n", string(body))
+		args := make([]interface{}, 0, len(statusArgs)*4+2)
+		args = append(args, statusArgs...)
+		args = append(args, locationArgs...)
+		args = append(args, playerArgs...)
+		args = append(args, positionArgs...)
+		args = append(args, time.Now(), ids)
 
-	var deckResp struct {
-		Success bool   `json:"success"`
-		DeckID  string `json:"deck_id"`
-	}
-	if err := json.Unmarshal(body, &deckResp); err != nil {
-		return nil, fmt.Errorf("error decoding deck response: %v", err)
+		if err := tx.Exec(query, args...).Error; err != nil {
+			return fmt.Errorf("error batch updating cards: %v", err)
+		}
 	}
 
-	if !deckResp.Success {
-		return nil, fmt.Errorf("deck creation unsuccessful")
-	}
+	return nil
+}
 
-	drawURL := fmt.Sprintf("https://deckofcardsapi.com/api/deck/%s/draw/?count=52", deckResp.DeckID)
-	drawResp, err := client.Get(drawURL)
-	if err != nil {
-		return nil, fmt.Errorf("error drawing cards: %v", err)
+func toGameCard(card models.Card, viewerPlayerID uuid.UUID) GameCard {
+	if isHiddenFrom(card, viewerPlayerID) {
+		return GameCard{
+			ID:           card.ID,
+			Status:       card.Status,
+			LocationType: card.LocationType,
+			PlayerID:     card.PlayerID,
+			Hidden:       true,
+		}
 	}
-	defer drawResp.Body.Close()
 
-	body, err = io.ReadAll(drawResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading draw response body: %v", err)
-	}
+	return revealGameCard(card)
+}
 
-	fmt.Printf("Draw cards response: %s\n", string(body))
+// revealGameCard shows the card face regardless of who is looking.
+func revealGameCard(card models.Card) GameCard {
+	gameCard := GameCard{
+		ID:           card.ID,
+		Code:         card.Code,
+		Value:        card.Value,
+		Suit:         card.Suit,
+		Status:       card.Status,
+		LocationType: card.LocationType,
+		PlayerID:     card.PlayerID,
+	}
+	if card.ImageURL != nil {
+		gameCard.ImageURL = *card.ImageURL
+	}
+	return gameCard
+}
 
-	var deck Deck
-	if err := json.Unmarshal(body, &deck); err != nil {
-		return nil, fmt.Errorf("error decoding cards response: %v", err)
+func isHiddenFrom(card models.Card, viewerPlayerID uuid.UUID) bool {
+	// Face-down cards stay hidden even from their owner until they are played.
+	if card.LocationType == "deck" || card.Status == "hidden" {
+		return true
 	}
 
-	if !deck.Success {
-		return nil, fmt.Errorf("card draw unsuccessful")
+	if card.PlayerID == nil || *card.PlayerID == viewerPlayerID {
+		return false
 	}
 
-	if len(deck.Cards) != 52 {
-		return nil, fmt.Errorf("expected 52 cards, got %d", len(deck.Cards))
+	return card.Status == "hand" || card.Status == "hidden"
+}
+
+func getPlayerSummaries(ctx context.Context, db database.Service, gameId string, currentPlayerID uuid.UUID) ([]PlayerSummary, error) {
+	var players []models.Player
+	if err := db.DB().WithContext(ctx).
+		Preload("User").
+		Where("game_id = ?", gameId).
+		Find(&players).Error; err != nil {
+		return nil, err
 	}
 
-	return deck.Cards, nil
-}
+	playerIDs := make([]uuid.UUID, len(players))
+	userIDs := make([]uuid.UUID, len(players))
+	for i, p := range players {
+		playerIDs[i] = p.ID
+		userIDs[i] = p.UserID
+	}
 
-func isSpecialCard(value string) bool {
-	specialValues := map[string]bool{
-		"6":  true,
-		"10": true,
+	privacy, err := service.LoadPrivacySettings(db.DB().WithContext(ctx), userIDs)
+	if err != nil {
+		return nil, err
 	}
-	return specialValues[value]
-}
 
-func getSpecialAction(value string) string {
-	specialActions := map[string]string{
-		"6":  "any",
-		"10": "clear",
-		"":   "none",
+	var counts []struct {
+		PlayerID uuid.UUID
+		Count    int64
 	}
-	action, exists := specialActions[value]
-	if !exists {
-		return "none"
+	if len(playerIDs) > 0 {
+		if err := db.DB().WithContext(ctx).Model(&models.Card{}).
+			Select("player_id, COUNT(*) AS count").
+			Where("player_id IN ?", playerIDs).
+			Group("player_id").
+			Scan(&counts).Error; err != nil {
+			return nil, err
+		}
 	}
-	return action
-}
 
-func (h *CardHandler) getPlayerSummaries(gameId string, currentPlayerID uuid.UUID) ([]PlayerSummary, error) {
-	var players []models.Player
-	if err := h.db.DB().
-		Preload("User").
-		Where("game_id = ?", gameId).
-		Find(&players).Error; err != nil {
-		return nil, err
+	cardCounts := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		cardCounts[count.PlayerID] = count.Count
 	}
 
 	summaries := make([]PlayerSummary, len(players))
 	for i, p := range players {
-		var cardCount int64
-		h.db.DB().Model(&models.Card{}).Where("player_id = ?", p.ID).Count(&cardCount)
-
 		summaries[i] = PlayerSummary{
-			ID:        p.ID,
-			Name:      p.User.Name,
-			Email:     p.User.Email,
-			Avatar:    p.User.Avatar,
-			CardCount: cardCount,
-			IsCurrent: p.ID == currentPlayerID,
-			UserID: 	  p.UserID,
+			ID:          p.ID,
+			Name:        p.User.Name,
+			DisplayName: p.User.DisplayName,
+			Avatar:      p.User.Avatar,
+			Level:       progression.LevelFor(p.User.XP),
+			CardCount:   cardCounts[p.ID],
+			IsCurrent:   p.ID == currentPlayerID,
+			UserID:      p.UserID,
+		}
+		if settings := privacy[p.UserID]; !settings.HideEmail && settings.ProfileVisibility == "public" {
+			summaries[i].Email = p.User.Email
 		}
 	}
 
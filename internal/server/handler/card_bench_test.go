@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// benchCards is eight packs, enough to show where each batch size starts
+// needing more than one statement.
+const benchCards = 416
+
+var benchBatchSizes = []int{26, 52, cardBatchSize, 208, benchCards}
+
+// testDatabase connects to the Postgres database named by TEST_DATABASE_URL
+// and brings its schema up to date. Benchmarks that need one are skipped when
+// it is not set.
+func testDatabase(b *testing.B) database.Service {
+	b.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db := database.New(config.Database{URL: url})
+	if _, err := db.Migrate(context.Background()); err != nil {
+		b.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// scratchCards opens a transaction in which cards is a temporary copy of the
+// real table without its foreign keys, so cards can be written without a
+// game, deck and players behind them. It is dropped when the benchmark ends.
+func scratchCards(b *testing.B, db database.Service) *gorm.DB {
+	b.Helper()
+
+	tx := db.DB().Begin()
+	b.Cleanup(func() { tx.Rollback() })
+
+	if err := tx.Exec("CREATE TEMP TABLE cards (LIKE public.cards INCLUDING DEFAULTS) ON COMMIT DROP").Error; err != nil {
+		b.Fatalf("creating scratch cards table: %v", err)
+	}
+	return tx
+}
+
+func benchDeck() []models.Card {
+	gameID, deckID := uuid.New(), uuid.New()
+	now := time.Now()
+
+	cards := make([]models.Card, benchCards)
+	for i := range cards {
+		cards[i] = models.Card{
+			ID:            uuid.New(),
+			DeckID:        deckID,
+			GameID:        gameID,
+			Code:          fmt.Sprintf("C%d", i),
+			Value:         "7",
+			Suit:          "HEARTS",
+			Status:        "in_deck",
+			LocationType:  "deck",
+			SpecialAction: "none",
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+	return cards
+}
+
+// BenchmarkCardBatches times dealing and then moving benchCards cards at
+// several batch sizes, cardBatchSize among them.
+func BenchmarkCardBatches(b *testing.B) {
+	db := testDatabase(b)
+
+	for _, size := range benchBatchSizes {
+		b.Run(fmt.Sprintf("create/batch=%d", size), func(b *testing.B) {
+			tx := scratchCards(b, db)
+
+			for i := 0; i < b.N; i++ {
+				cards := benchDeck()
+				if err := tx.CreateInBatches(&cards, size).Error; err != nil {
+					b.Fatal(err)
+				}
+
+				b.StopTimer()
+				if err := tx.Exec("DELETE FROM cards").Error; err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+			}
+		})
+
+		b.Run(fmt.Sprintf("update/batch=%d", size), func(b *testing.B) {
+			tx := scratchCards(b, db)
+
+			cards := benchDeck()
+			if err := tx.CreateInBatches(&cards, cardBatchSize).Error; err != nil {
+				b.Fatal(err)
+			}
+
+			playerID := uuid.New()
+			updates := make([]cardUpdate, len(cards))
+			for i, card := range cards {
+				position := i
+				updates[i] = cardUpdate{
+					ID:           card.ID,
+					Status:       "hand",
+					LocationType: "player",
+					PlayerID:     &playerID,
+					PilePosition: &position,
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := updateCardsInBatches(tx, updates, size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/challenges"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ChallengeHandler struct {
+	challenges service.ChallengeService
+	hub        *GameHub
+}
+
+func NewChallengeHandler(challenges service.ChallengeService, hub *GameHub) *ChallengeHandler {
+	return &ChallengeHandler{
+		challenges: challenges,
+		hub:        hub,
+	}
+}
+
+// Index lists today's and this week's challenges with the caller's progress.
+func (h *ChallengeHandler) Index(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	active, err := h.challenges.Active(c.UserContext(), userID, time.Now().UTC())
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching challenges")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": active,
+	})
+}
+
+// Claim grants the XP reward of a completed challenge.
+func (h *ChallengeHandler) Claim(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	challengeID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid challenge ID")
+	}
+
+	progress, event, err := h.challenges.Claim(c.UserContext(), userID, challengeID, time.Now().UTC())
+	switch {
+	case errors.Is(err, service.ErrChallengeNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Challenge not found")
+	case errors.Is(err, service.ErrChallengeIncomplete):
+		return utils.NewError(fiber.StatusConflict, "Challenge is not completed yet").WithCode("CHALLENGE_INCOMPLETE")
+	case errors.Is(err, service.ErrChallengeClaimed):
+		return utils.NewError(fiber.StatusConflict, "Challenge has already been claimed").WithCode("CHALLENGE_CLAIMED")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error claiming challenge")
+	}
+
+	if event.LevelAfter > event.LevelBefore {
+		h.hub.SendToUser(userID.String(), GameMessage{
+			Type: "level_up",
+			Payload: fiber.Map{
+				"user_id":        userID,
+				"level":          event.LevelAfter,
+				"previous_level": event.LevelBefore,
+				"xp_gained":      event.Amount,
+			},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"challenge": progress,
+		"xp_gained": event.Amount,
+		"level":     event.LevelAfter,
+	})
+}
+
+// GenerateChallenges is a scheduler job that hands out the challenges of a
+// new day or week as soon as it starts.
+func (h *ChallengeHandler) GenerateChallenges() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return h.challenges.Generate(ctx, time.Now().UTC())
+}
+
+// trackGameChallenges counts what every human player did in the game from its
+// events and adds it to their challenge progress inside tx. Games only count
+// toward games played and won for players who saw them through.
+func trackGameChallenges(tx *gorm.DB, gameID uuid.UUID, players []models.Player, winners map[uuid.UUID]bool) error {
+	userIDs := make([]uuid.UUID, len(players))
+	for i, player := range players {
+		userIDs[i] = player.UserID
+	}
+
+	var bots []uuid.UUID
+	if err := tx.Model(&models.User{}).Where("id IN ? AND is_bot = ?", userIDs, true).Pluck("id", &bots).Error; err != nil {
+		return err
+	}
+	isBot := make(map[uuid.UUID]bool, len(bots))
+	for _, id := range bots {
+		isBot[id] = true
+	}
+
+	tallies := make(map[uuid.UUID]map[challenges.Metric]int, len(players))
+	userOf := make(map[uuid.UUID]uuid.UUID, len(players))
+	for _, player := range players {
+		if isBot[player.UserID] {
+			continue
+		}
+		tally := make(map[challenges.Metric]int)
+		if player.Status != "forfeited" {
+			tally[challenges.GamesPlayed] = 1
+			if winners[player.ID] {
+				tally[challenges.GamesWon] = 1
+			}
+		}
+		tallies[player.UserID] = tally
+		userOf[player.ID] = player.UserID
+	}
+
+	var events []models.GameEvent
+	if err := tx.Where("game_id = ? AND type IN ? AND player_id IS NOT NULL", gameID, []string{"play", "burn"}).
+		Find(&events).Error; err != nil {
+		return err
+	}
+
+	config, err := gameRules(tx, gameID)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		userID, ok := userOf[*event.PlayerID]
+		if !ok {
+			continue
+		}
+		tally := tallies[userID]
+
+		if event.Type == "burn" {
+			tally[challenges.PilesBurned]++
+			continue
+		}
+
+		var play struct {
+			Values []string `json:"values"`
+		}
+		if err := json.Unmarshal(event.Payload, &play); err != nil {
+			continue
+		}
+		tally[challenges.CardsPlayed] += len(play.Values)
+		for _, value := range play.Values {
+			if config.IsSpecial(value) {
+				tally[challenges.SpecialCardsPlayed]++
+			}
+		}
+	}
+
+	return service.TrackChallenges(tx, tallies, time.Now().UTC())
+}
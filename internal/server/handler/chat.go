@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxChatLength = 500
+
+type MuteInLobbyRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+// errNameNotAllowed rejects a lobby name containing a blocked word.
+func errNameNotAllowed() *utils.APIError {
+	return utils.NewError(fiber.StatusBadRequest, "Lobby name contains a blocked word").WithCode("NAME_NOT_ALLOWED")
+}
+
+// activeMute returns the global mute currently in force for the user, or nil
+// when there is none.
+func activeMute(tx *gorm.DB, userID uuid.UUID) (*models.Mute, error) {
+	var mute models.Mute
+	err := tx.Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now().UTC()).
+		Order("expires_at DESC NULLS FIRST").
+		First(&mute).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// describeMute tells a muted user why and for how long.
+func describeMute(mute *models.Mute) string {
+	if mute.ExpiresAt != nil {
+		return fmt.Sprintf("You are muted until %s: %s", mute.ExpiresAt.Format(time.RFC3339), mute.Reason)
+	}
+	return "You are muted: " + mute.Reason
+}
+
+// sendChat posts a message to the game's chat. Only seated players can chat,
+// and not while muted globally or by the lobby owner. Blocked words are
+// masked before the message is stored and broadcast.
+func (h *GameHandler) sendChat(ctx context.Context, gameID string, session models.Session, payload ChatPayload) error {
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		return rejectMove(CodeInvalidPayload, "Message cannot be empty", nil)
+	}
+	if utf8.RuneCountInString(body) > maxChatLength {
+		return rejectMove(CodeInvalidPayload, fmt.Sprintf("Messages are limited to %d characters", maxChatLength), nil)
+	}
+
+	db := h.db.DB().WithContext(ctx)
+
+	var player models.Player
+	if err := db.Preload("User").Where("game_id = ? AND user_id = ?", gameID, session.UserID).First(&player).Error; err != nil {
+		return rejectMove(CodeNotInGame, "You are not a player in this game", nil)
+	}
+
+	mute, err := activeMute(db, session.UserID)
+	if err != nil {
+		return err
+	}
+	if mute != nil {
+		return rejectMove(CodeMuted, describeMute(mute), nil)
+	}
+
+	var lobbyMutes int64
+	if err := db.Model(&models.LobbyMute{}).
+		Where("lobby_id = ? AND user_id = ?", player.LobbyID, session.UserID).
+		Count(&lobbyMutes).Error; err != nil {
+		return err
+	}
+	if lobbyMutes > 0 {
+		return rejectMove(CodeLobbyMuted, "The lobby owner has muted you", nil)
+	}
+
+	message := models.ChatMessage{
+		ID:        uuid.New(),
+		GameID:    player.GameID,
+		UserID:    session.UserID,
+		Body:      h.filter.Mask(body),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := db.Create(&message).Error; err != nil {
+		return err
+	}
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "chat_message",
+		Payload: fiber.Map{
+			"id":         message.ID,
+			"user_id":    message.UserID,
+			"name":       player.User.Name,
+			"body":       message.Body,
+			"created_at": message.CreatedAt,
+		},
+	})
+	return nil
+}
+
+// MuteInLobby lets the lobby owner silence a player in the lobby's chat.
+func (h *LobbyHandler) MuteInLobby(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid lobby ID")
+	}
+
+	var req MuteInLobbyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	if req.UserID == userID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot mute yourself")
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var lobby models.Lobby
+	if err := db.Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+	if lobby.OwnerID != userID {
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can mute players")
+	}
+
+	var player models.Player
+	if err := db.Where("lobby_id = ? AND user_id = ?", lobbyID, req.UserID).First(&player).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Player not found in lobby")
+	}
+
+	mute := models.LobbyMute{
+		ID:        uuid.New(),
+		LobbyID:   lobbyID,
+		UserID:    req.UserID,
+		MutedBy:   userID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mute).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error muting player")
+	}
+
+	h.broadcastToLobby(lobbyID, GameMessage{
+		Type:    "player_muted",
+		Payload: fiber.Map{"lobby_id": lobbyID, "user_id": req.UserID},
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Player muted",
+	})
+}
+
+// UnmuteInLobby lifts a mute set by the lobby owner.
+func (h *LobbyHandler) UnmuteInLobby(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid lobby ID")
+	}
+
+	targetID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var lobby models.Lobby
+	if err := db.Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+	if lobby.OwnerID != userID {
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can unmute players")
+	}
+
+	result := db.Where("lobby_id = ? AND user_id = ?", lobbyID, targetID).Delete(&models.LobbyMute{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error unmuting player")
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Player is not muted")
+	}
+
+	h.broadcastToLobby(lobbyID, GameMessage{
+		Type:    "player_unmuted",
+		Payload: fiber.Map{"lobby_id": lobbyID, "user_id": targetID},
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Player unmuted",
+	})
+}
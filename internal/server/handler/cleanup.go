@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/service"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const sessionLifetime = 24 * time.Hour
+
+// CleanupHandler holds the periodic housekeeping jobs run by the scheduler.
+type CleanupHandler struct {
+	db      database.Service
+	hub     *GameHub
+	games   *GameHandler
+	lobbies service.LobbyService
+	game    config.Game
+	audit   service.AuditService
+}
+
+func NewCleanupHandler(db database.Service, hub *GameHub, games *GameHandler, lobbies service.LobbyService, game config.Game, audit service.AuditService) *CleanupHandler {
+	return &CleanupHandler{
+		db:      db,
+		hub:     hub,
+		games:   games,
+		lobbies: lobbies,
+		game:    game,
+		audit:   audit,
+	}
+}
+
+func (h *CleanupHandler) ExpireInvitations() error {
+	result := h.db.DB().Model(&models.LobbyInvitation{}).
+		Where("status = ? AND expires_at < ?", "pending", time.Now()).
+		Updates(map[string]interface{}{
+			"status":     "expired",
+			"updated_at": time.Now(),
+		})
+	if result.RowsAffected > 0 {
+		log.Printf("Expired %d lobby invitations", result.RowsAffected)
+	}
+	return result.Error
+}
+
+func (h *CleanupHandler) DeleteStaleSessions() error {
+	cutoff := time.Now().Add(-sessionLifetime).Unix()
+	result := h.db.DB().Where("last_activity < ?", cutoff).Delete(&models.Session{})
+	if result.RowsAffected > 0 {
+		log.Printf("Deleted %d stale sessions", result.RowsAffected)
+	}
+	return result.Error
+}
+
+func (h *CleanupHandler) PurgeNotifications() error {
+	cutoff := time.Now().Add(-h.game.NotificationRetention)
+	result := h.db.DB().Where("created_at < ?", cutoff).Delete(&models.Notification{})
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d old notifications", result.RowsAffected)
+	}
+	return result.Error
+}
+
+func (h *CleanupHandler) PurgeLoginAttempts() error {
+	cutoff := time.Now().UTC().Add(-loginAttemptRetention)
+	result := h.db.DB().Where("created_at < ?", cutoff).Delete(&models.LoginAttempt{})
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d old login attempts", result.RowsAffected)
+	}
+	return result.Error
+}
+
+// idleLobbies selects waiting lobbies nobody has touched since cutoff that
+// still have an unready player and no game under way.
+func idleLobbies(db *gorm.DB, cutoff time.Time) *gorm.DB {
+	return db.Model(&models.Lobby{}).
+		Where("status = ? AND updated_at < ?", "waiting", cutoff).
+		Where("EXISTS (SELECT 1 FROM players WHERE players.lobby_id = lobbies.id AND players.is_ready = ?)", false).
+		Where("NOT EXISTS (SELECT 1 FROM games WHERE games.lobby_id = lobbies.id AND games.status IN ?)", []string{"setup", "in_progress"})
+}
+
+// CloseIdleLobbies removes lobbies left waiting on unready players for longer
+// than the lobby idle timeout, the same way an owner leaving does. Members
+// are notified, and queued users and spectators are told to go elsewhere.
+func (h *CleanupHandler) CloseIdleLobbies() error {
+	cutoff := time.Now().Add(-h.game.LobbyIdleTimeout)
+
+	var lobbyIDs []string
+	if err := idleLobbies(h.db.DB(), cutoff).Pluck("id", &lobbyIDs).Error; err != nil {
+		return err
+	}
+
+	closed := 0
+	for _, lobbyID := range lobbyIDs {
+		var lobby models.Lobby
+		var gameIDs, members, waiting []uuid.UUID
+		if err := h.db.DB().Transaction(func(tx *gorm.DB) error {
+			if err := idleLobbies(tx, cutoff).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ?", lobbyID).
+				First(&lobby).Error; err != nil {
+				return nil
+			}
+
+			if err := tx.Model(&models.Game{}).Where("lobby_id = ?", lobbyID).Pluck("id", &gameIDs).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Player{}).Where("lobby_id = ? AND is_bot = ?", lobbyID, false).Pluck("user_id", &members).Error; err != nil {
+				return err
+			}
+			if err := tx.Raw(`SELECT user_id FROM lobby_queues WHERE lobby_id = ?
+UNION SELECT user_id FROM lobby_spectators WHERE lobby_id = ?`, lobbyID, lobbyID).Scan(&waiting).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbySpectator{}).Error; err != nil {
+				return err
+			}
+			if err := service.DeleteLobby(tx, lobbyID); err != nil {
+				return err
+			}
+
+			// Notifications about the lobby went with it; these are created
+			// after so they survive.
+			for _, userID := range append(members, waiting...) {
+				if err := service.CreateNotification(tx, userID, "lobby_closed", fiber.Map{
+					"lobby_id":   lobby.ID,
+					"lobby_name": lobby.Name,
+					"reason":     "idle",
+					"message":    fmt.Sprintf("%s was closed because it sat idle", lobby.Name),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if lobby.ID == uuid.Nil {
+			continue
+		}
+		closed++
+		h.lobbies.Invalidate()
+
+		closedMessage := GameMessage{
+			Type:    "lobby_closed",
+			Payload: fiber.Map{"lobby_id": lobby.ID, "reason": "idle"},
+		}
+		for _, gameID := range gameIDs {
+			h.games.countdowns.cancel(gameID)
+			h.hub.BroadcastToGame(gameID.String(), closedMessage)
+		}
+		for _, userID := range waiting {
+			h.hub.SendToUser(userID.String(), closedMessage)
+		}
+
+		if err := h.audit.Record(context.Background(), service.AuditEntry{
+			Action:     "lobby_delete",
+			TargetType: "lobby",
+			TargetID:   lobbyID,
+			Details:    map[string]interface{}{"name": lobby.Name, "reason": "idle"},
+		}); err != nil {
+			log.Printf("Error recording lobby_delete audit entry: %v", err)
+		}
+	}
+
+	if closed > 0 {
+		log.Printf("Closed %d idle lobbies", closed)
+	}
+	return nil
+}
+
+// AbandonStaleGames ends games under way that have gone without an event
+// for longer than the abandon timeout, for instance because every player
+// closed the app. They end without a winner, placements or rating changes.
+func (h *CleanupHandler) AbandonStaleGames() error {
+	cutoff := time.Now().Add(-h.game.AbandonAfter)
+
+	stale := func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.Game{}).
+			Where("status IN ?", []string{"setup", "in_progress"}).
+			Where("COALESCE((SELECT MAX(created_at) FROM game_events WHERE game_events.game_id = games.id), games.updated_at) < ?", cutoff)
+	}
+
+	var gameIDs []uuid.UUID
+	if err := stale(h.db.DB()).Pluck("id", &gameIDs).Error; err != nil {
+		return err
+	}
+
+	abandoned := 0
+	for _, gameID := range gameIDs {
+		ended := false
+		if err := h.db.DB().Transaction(func(tx *gorm.DB) error {
+			var game models.Game
+			if err := stale(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", gameID).First(&game).Error; err != nil {
+				return nil
+			}
+
+			if err := tx.Model(&game).Updates(map[string]interface{}{
+				"status":     "completed",
+				"winner":     "none",
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+
+			ended = true
+			return recordGameEvent(tx, gameID, "game_abandoned", nil, fiber.Map{
+				"idle_since": cutoff,
+			})
+		}); err != nil {
+			return err
+		}
+
+		if !ended {
+			continue
+		}
+		abandoned++
+		h.lobbies.Invalidate()
+
+		h.games.timers.cancel(gameID)
+		h.games.countdowns.cancel(gameID)
+		h.hub.BroadcastToGame(gameID.String(), GameMessage{
+			Type:    "game_closed",
+			Payload: fiber.Map{"game_id": gameID, "reason": "abandoned"},
+		})
+	}
+
+	if abandoned > 0 {
+		log.Printf("Abandoned %d inactive games", abandoned)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/rules"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deckDraw is what a draw took from a game's deck.
+type deckDraw struct {
+	Cards []models.Card
+	// Reshuffled counts the burned cards shuffled back into the deck to
+	// cover the draw.
+	Reshuffled int
+	// Exhausted is set when the draw took the last card of the deck.
+	Exhausted bool
+}
+
+// drawFromDeck moves up to count cards from the game's deck into the player's
+// hand inside tx. When the deck cannot cover the draw and the house rules
+// allow it, the burned cards are shuffled back in first. Drawing from an
+// empty deck takes nothing and is not an error.
+func drawFromDeck(tx *gorm.DB, cfg rules.Config, gameID, playerID uuid.UUID, count int) (deckDraw, error) {
+	var result deckDraw
+	if count <= 0 {
+		return result, nil
+	}
+
+	// Locking the deck serialises draws, so two of them cannot take the same
+	// card or lose a decrement.
+	var deck models.Deck
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("game_id = ?", gameID).First(&deck).Error; err != nil {
+		return result, err
+	}
+
+	var available int64
+	if err := tx.Model(&models.Card{}).
+		Where("game_id = ? AND deck_id = ? AND location_type = ? AND player_id IS NULL", gameID, deck.ID, "deck").
+		Count(&available).Error; err != nil {
+		return result, err
+	}
+
+	if available < int64(count) && cfg.ReshuffleBurned {
+		reshuffled, err := reshuffleBurned(tx, gameID)
+		if err != nil {
+			return result, err
+		}
+		result.Reshuffled = int(reshuffled)
+		available += reshuffled
+	}
+
+	if available == 0 {
+		return result, nil
+	}
+
+	if err := tx.Where("game_id = ? AND deck_id = ? AND location_type = ? AND player_id IS NULL", gameID, deck.ID, "deck").
+		Order("random()").Limit(count).Find(&result.Cards).Error; err != nil {
+		return result, err
+	}
+
+	updates := make([]cardUpdate, len(result.Cards))
+	cardIDs := make([]uuid.UUID, len(result.Cards))
+	values := make([]string, len(result.Cards))
+	for i := range result.Cards {
+		result.Cards[i].Status = "hand"
+		result.Cards[i].LocationType = "player"
+		result.Cards[i].PlayerID = &playerID
+		updates[i] = cardUpdate{
+			ID:           result.Cards[i].ID,
+			Status:       "hand",
+			LocationType: "player",
+			PlayerID:     &playerID,
+		}
+		cardIDs[i] = result.Cards[i].ID
+		values[i] = result.Cards[i].Value
+	}
+
+	if err := batchUpdateCards(tx, updates); err != nil {
+		return result, err
+	}
+
+	remaining := int(available) - len(result.Cards)
+	if err := tx.Model(&deck).Updates(map[string]interface{}{
+		"remaining_cards": remaining,
+		"updated_at":      time.Now(),
+	}).Error; err != nil {
+		return result, err
+	}
+
+	if err := recordGameEvent(tx, gameID, "draw", &playerID, fiber.Map{
+		"card_ids": cardIDs,
+		"values":   values,
+	}); err != nil {
+		return result, err
+	}
+
+	if remaining == 0 {
+		result.Exhausted = true
+		if err := recordGameEvent(tx, gameID, "deck_empty", nil, fiber.Map{}); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// reshuffleBurned puts every burned card of the game back into its deck and
+// returns how many there were.
+func reshuffleBurned(tx *gorm.DB, gameID uuid.UUID) (int64, error) {
+	result := tx.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "burned").
+		Updates(map[string]interface{}{
+			"status":        "in_deck",
+			"location_type": "deck",
+			"player_id":     nil,
+			"pile_position": nil,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, recordGameEvent(tx, gameID, "deck_reshuffled", nil, fiber.Map{
+		"card_count": result.RowsAffected,
+	})
+}
+
+// broadcastDraw tells the table about a committed draw: the cards themselves,
+// then whether the deck was topped up with burned cards or has run out.
+func (h *GameHandler) broadcastDraw(gameID string, playerID uuid.UUID, draw deckDraw) {
+	if draw.Reshuffled > 0 {
+		h.hub.BroadcastToGame(gameID, GameMessage{
+			Type: "deck_reshuffled",
+			Payload: fiber.Map{
+				"game_id":    gameID,
+				"card_count": draw.Reshuffled,
+			},
+		})
+	}
+
+	if len(draw.Cards) > 0 {
+		h.broadcastCardsDrawn(gameID, playerID, draw.Cards)
+	}
+
+	if draw.Exhausted {
+		h.hub.BroadcastToGame(gameID, GameMessage{
+			Type: "deck_empty",
+			Payload: fiber.Map{
+				"game_id":   gameID,
+				"player_id": playerID,
+			},
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/settings"
+	"api/internal/server/utils"
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errGameNotInProgress = errors.New("game is not in progress")
+
+func (h *GameHandler) Forfeit(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	player, err := h.forfeit(c.UserContext(), gameID, userID)
+	if errors.Is(err, errNotInLobby) {
+		return utils.NewError(fiber.StatusBadRequest, "You are not a player in this game")
+	} else if errors.Is(err, errGameNotInProgress) {
+		return utils.NewError(fiber.StatusBadRequest, "The game is not in progress")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error forfeiting game")
+	}
+
+	return c.JSON(fiber.Map{
+		"message":   "You forfeited the game",
+		"placement": player.Placement,
+	})
+}
+
+// forfeit takes the user out of the turn rotation with the lowest open
+// placement, deals with their cards per the lobby's rule and ends the game if
+// only one opponent is left.
+func (h *GameHandler) forfeit(ctx context.Context, gameID, userID uuid.UUID) (models.Player, error) {
+	tx := h.db.DB().WithContext(ctx).Begin()
+
+	var game models.Game
+	if err := tx.Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+
+	if game.Status != "in_progress" && game.Status != "setup" {
+		tx.Rollback()
+		return models.Player{}, errGameNotInProgress
+	}
+
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", gameID, userID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return models.Player{}, errNotInLobby
+	}
+
+	if player.Status == "finished" || player.Status == "forfeited" {
+		tx.Rollback()
+		return models.Player{}, errGameNotInProgress
+	}
+
+	var seated, forfeited int64
+	if err := tx.Model(&models.Player{}).Where("game_id = ?", gameID).Count(&seated).Error; err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+	if err := tx.Model(&models.Player{}).
+		Where("game_id = ? AND status = ?", gameID, "forfeited").
+		Count(&forfeited).Error; err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+
+	placement := int(seated - forfeited)
+	if err := tx.Model(&player).Updates(map[string]interface{}{
+		"status":    "forfeited",
+		"placement": placement,
+	}).Error; err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+	player.Status = "forfeited"
+	player.Placement = &placement
+
+	rule := lobbySettings(game.Lobby.GameSettings).ForfeitCards
+	moved, err := releaseForfeitedCards(tx, gameID, player.ID, rule)
+	if err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+
+	if err := recordGameEvent(tx, gameID, "forfeit", &player.ID, fiber.Map{
+		"placement":  placement,
+		"card_rule":  rule,
+		"card_count": moved,
+	}); err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+
+	over, err := completeGameIfOver(tx, gameID)
+	if err != nil {
+		tx.Rollback()
+		return models.Player{}, err
+	}
+
+	if !over && game.CurrentTurnPlayerID == player.ID {
+		if err := h.moveToNextPlayer(tx, gameID); err != nil {
+			tx.Rollback()
+			return models.Player{}, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return models.Player{}, err
+	}
+
+	if game.CurrentTurnPlayerID == player.ID {
+		h.startTurnTimer(gameID)
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "player_forfeited",
+		Payload: fiber.Map{
+			"game_id":    gameID,
+			"player_id":  player.ID,
+			"placement":  placement,
+			"card_rule":  rule,
+			"card_count": moved,
+		},
+	})
+
+	h.announceGameOver(gameID)
+
+	return player, nil
+}
+
+func releaseForfeitedCards(tx *gorm.DB, gameID, playerID uuid.UUID, rule string) (int64, error) {
+	updates := map[string]interface{}{
+		"status":        "burned",
+		"location_type": "burned",
+		"player_id":     nil,
+	}
+	if rule == settings.ForfeitShuffle {
+		updates["status"] = "in_deck"
+		updates["location_type"] = "deck"
+	}
+
+	result := tx.Model(&models.Card{}).Where("game_id = ? AND player_id = ?", gameID, playerID).Updates(updates)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if rule == settings.ForfeitShuffle && result.RowsAffected > 0 {
+		if err := tx.Model(&models.Deck{}).Where("game_id = ?", gameID).
+			Update("remaining_cards", gorm.Expr("remaining_cards + ?", result.RowsAffected)).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (h *GameHandler) handleForfeitMessage(ctx context.Context, gameID string, session models.Session) error {
+	parsedGameID, err := uuid.Parse(gameID)
+	if err != nil {
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	_, err = h.forfeit(ctx, parsedGameID, session.UserID)
+	return protocolErrorFor(err)
+}
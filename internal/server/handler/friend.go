@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type FriendHandler struct {
+	db       database.Service
+	presence service.PresenceService
+}
+
+type FriendRequestRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+type FriendResponse struct {
+	ID           uuid.UUID         `json:"id"`
+	Name         string            `json:"name"`
+	Avatar       *string           `json:"avatar"`
+	Online       bool              `json:"online"`
+	Presence     *service.Presence `json:"presence"`
+	LobbyID      *uuid.UUID        `json:"lobby_id"`
+	FriendshipID uuid.UUID         `json:"friendship_id"`
+	Since        time.Time         `json:"since"`
+}
+
+func NewFriendHandler(db database.Service, presence service.PresenceService) *FriendHandler {
+	return &FriendHandler{
+		db:       db,
+		presence: presence,
+	}
+}
+
+func (h *FriendHandler) Index(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var friendships []models.Friendship
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Requester", selectPublicUser).
+		Preload("Addressee", selectPublicUser).
+		Where("(requester_id = ? OR addressee_id = ?) AND status = ?", userID, userID, "accepted").
+		Order("updated_at DESC").
+		Find(&friendships).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching friends")
+	}
+
+	friendIDs := make([]uuid.UUID, len(friendships))
+	for i, friendship := range friendships {
+		friendIDs[i] = friendship.RequesterID
+		if friendship.RequesterID == userID {
+			friendIDs[i] = friendship.AddresseeID
+		}
+	}
+
+	presences, err := h.presence.Many(c.UserContext(), friendIDs)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching presence")
+	}
+	privacy, err := service.LoadPrivacySettings(h.db.DB().WithContext(c.UserContext()), friendIDs)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching privacy settings")
+	}
+
+	var players []models.Player
+	if len(friendIDs) > 0 {
+		if err := h.db.DB().WithContext(c.UserContext()).Select("user_id, lobby_id").Where("user_id IN ?", friendIDs).Find(&players).Error; err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error fetching friend lobbies")
+		}
+	}
+	lobbies := make(map[uuid.UUID]uuid.UUID, len(players))
+	for _, player := range players {
+		lobbies[player.UserID] = player.LobbyID
+	}
+
+	response := make([]FriendResponse, len(friendships))
+	for i, friendship := range friendships {
+		friend := friendship.Requester
+		if friendship.RequesterID == userID {
+			friend = friendship.Addressee
+		}
+
+		response[i] = FriendResponse{
+			ID:           friend.ID,
+			Name:         friend.Name,
+			Avatar:       friend.Avatar,
+			FriendshipID: friendship.ID,
+			Since:        friendship.UpdatedAt,
+		}
+		// Friends see each other's presence unless the profile is private.
+		if privacy[friend.ID].ProfileVisibility != "private" {
+			presence := presences[friend.ID]
+			response[i].Presence = &presence
+			response[i].Online = isOnline(presence)
+		}
+		if lobbyID, ok := lobbies[friend.ID]; ok {
+			response[i].LobbyID = &lobbyID
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"data": response,
+	})
+}
+
+func (h *FriendHandler) Requests(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var incoming []models.Friendship
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Requester", selectPublicUser).
+		Where("addressee_id = ? AND status = ?", userID, "requested").
+		Order("created_at DESC").
+		Find(&incoming).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching friend requests")
+	}
+
+	var outgoing []models.Friendship
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Addressee", selectPublicUser).
+		Where("requester_id = ? AND status = ?", userID, "requested").
+		Order("created_at DESC").
+		Find(&outgoing).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching friend requests")
+	}
+
+	return c.JSON(fiber.Map{
+		"incoming": incoming,
+		"outgoing": outgoing,
+	})
+}
+
+func (h *FriendHandler) SendRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req FriendRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.UserID == userID {
+		return utils.NewError(fiber.StatusBadRequest, "Cannot send a friend request to yourself")
+	}
+
+	var target models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Select("id, name").Where("id = ? AND is_bot = ?", req.UserID, false).First(&target).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var existing models.Friendship
+	err := tx.Where("(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)",
+		userID, req.UserID, req.UserID, userID).First(&existing).Error
+	if err == nil {
+		if existing.Status == "blocked" {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusForbidden, "You cannot send a friend request to this user")
+		}
+
+		// A request in the other direction is treated as mutual consent.
+		if existing.Status == "requested" && existing.AddresseeID == userID {
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"status":     "accepted",
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+				tx.Rollback()
+				return utils.NewError(fiber.StatusInternalServerError, "Error accepting friend request")
+			}
+
+			if err := service.CreateNotification(tx, req.UserID, "friend_request_accepted", fiber.Map{
+				"friendship_id": existing.ID,
+				"user_id":       userID,
+				"message":       "Your friend request was accepted",
+			}); err != nil {
+				tx.Rollback()
+				return utils.NewError(fiber.StatusInternalServerError, "Failed to create notification")
+			}
+
+			if err := tx.Commit().Error; err != nil {
+				return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+			}
+
+			return c.JSON(fiber.Map{
+				"message":    "Friend request accepted",
+				"friendship": existing,
+			})
+		}
+
+		tx.Rollback()
+		return utils.NewError(fiber.StatusConflict, "Friendship already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking friendship")
+	}
+
+	now := time.Now()
+	friendship := models.Friendship{
+		ID:          uuid.New(),
+		RequesterID: userID,
+		AddresseeID: req.UserID,
+		Status:      "requested",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := tx.Create(&friendship).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error sending friend request")
+	}
+
+	if err := service.CreateNotification(tx, req.UserID, "friend_request", fiber.Map{
+		"friendship_id": friendship.ID,
+		"user_id":       userID,
+		"message":       "You have a new friend request",
+	}); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Failed to create notification")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Friend request sent",
+		"friendship": friendship,
+	})
+}
+
+func (h *FriendHandler) AcceptRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	friendship, err := pendingRequestFor(tx, c.Params("id"), userID)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Friend request not found")
+	}
+
+	if err := tx.Model(&friendship).Updates(map[string]interface{}{
+		"status":     "accepted",
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error accepting friend request")
+	}
+
+	if err := service.CreateNotification(tx, friendship.RequesterID, "friend_request_accepted", fiber.Map{
+		"friendship_id": friendship.ID,
+		"user_id":       userID,
+		"message":       "Your friend request was accepted",
+	}); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Failed to create notification")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Friend request accepted",
+	})
+}
+
+func (h *FriendHandler) DeclineRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	friendship, err := pendingRequestFor(tx, c.Params("id"), userID)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Friend request not found")
+	}
+
+	if err := tx.Delete(&friendship).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error declining friend request")
+	}
+
+	if err := tx.Where("user_id = ? AND type = ? AND data->>'friendship_id' = ?",
+		userID, "friend_request", friendship.ID.String()).
+		Delete(&models.Notification{}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error removing notification")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Friend request declined",
+	})
+}
+
+// Remove ends a friendship or withdraws a pending request in either direction.
+func (h *FriendHandler) Remove(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	friendID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	result := h.db.DB().WithContext(c.UserContext()).
+		Where("((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)) AND status IN ?",
+			userID, friendID, friendID, userID, []string{"requested", "accepted"}).
+		Delete(&models.Friendship{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error removing friend")
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Friendship not found")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Friend removed",
+	})
+}
+
+func pendingRequestFor(tx *gorm.DB, friendshipID string, addresseeID uuid.UUID) (models.Friendship, error) {
+	var friendship models.Friendship
+	err := tx.Where("id = ? AND addressee_id = ? AND status = ?", friendshipID, addresseeID, "requested").
+		First(&friendship).Error
+	return friendship, err
+}
+
+func selectPublicUser(db *gorm.DB) *gorm.DB {
+	return db.Select("id, name, avatar")
+}
+
+// isOnline reports whether the presence counts as online in the older,
+// boolean sense: connected and recently active.
+func isOnline(presence service.Presence) bool {
+	switch presence.Status {
+	case service.PresenceOnline, service.PresenceInLobby, service.PresenceInGame:
+		return true
+	}
+	return false
+}
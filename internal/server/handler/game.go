@@ -1,394 +1,473 @@
 package handler
 
 import (
+	"api/internal/config"
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/game/decks"
+	"api/internal/game/rules"
+	"api/internal/moderation"
+	"api/internal/service"
+	"api/internal/telemetry"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"sync"
-	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GameMessage struct {
+	// ID echoes the client message an ack or error is answering.
+	ID string `json:"id,omitempty"`
+	// Seq is the game's latest event sequence when the message was broadcast.
+	Seq     int64       `json:"seq,omitempty"`
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
 }
 
-type Client struct {
-	UserId string
-	GameId string
-}
-
-type GameHub struct {
-	clients    map[*websocket.Conn]Client
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
-	broadcast  chan GameMessage
-}
-
-func NewGameHub() *GameHub {
-	return &GameHub{
-		clients:    make(map[*websocket.Conn]Client),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
-		broadcast:  make(chan GameMessage),
-	}
-}
-
-func (h *GameHub) Run() {
-	for {
-		select {
-		case conn := <-h.register:
-			h.clients[conn] = Client{}
-
-		case conn := <-h.unregister:
-			if _, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-				conn.Close()
-			}
-
-		case message := <-h.broadcast:
-			messageBytes, err := json.Marshal(message)
-			if err != nil {
-				continue
-			}
-
-			for connection := range h.clients {
-				if err := connection.WriteMessage(websocket.TextMessage, messageBytes); err != nil {
-					h.unregister <- connection
-					connection.WriteMessage(websocket.CloseMessage, []byte{})
-					connection.Close()
-				}
-			}
-		}
-	}
-}
-
 type GameHandler struct {
-	db   database.Service
-	hub  *GameHub
-	once sync.Once
+	db         database.Service
+	hub        *GameHub
+	decks      decks.Provider
+	game       config.Game
+	games      service.GameService
+	lobbies    service.LobbyService
+	users      service.UserService
+	filter     *moderation.Filter
+	timers     *timerSet
+	seatHolds  *timerSet
+	countdowns *timerSet
+
+	rematchVotes *voteSet
+
+	// sockets counts open game connections so shutdown can wait for their
+	// in-flight moves to commit.
+	sockets sync.WaitGroup
 }
 
-func NewGameHandler(db database.Service) *GameHandler {
+func NewGameHandler(db database.Service, hub *GameHub, deckProvider decks.Provider, game config.Game, games service.GameService, lobbies service.LobbyService, users service.UserService, filter *moderation.Filter) *GameHandler {
 	return &GameHandler{
-		db:  db,
-		hub: NewGameHub(),
+		db:         db,
+		hub:        hub,
+		decks:      deckProvider,
+		game:       game,
+		games:      games,
+		lobbies:    lobbies,
+		users:      users,
+		filter:     filter,
+		timers:     newTimerSet(),
+		seatHolds:  newTimerSet(),
+		countdowns: newTimerSet(),
+
+		rematchVotes: newVoteSet(),
 	}
 }
 
 func (h *GameHandler) Game(c *websocket.Conn) {
-	h.once.Do(func() {
-		go h.hub.Run()
-	})
+	gameID := c.Params("gameId")
+
+	client := Client{GameId: gameID}
+	connSession, err := h.users.Session(context.Background(), c.Cookies("session_id"))
+	if err != nil {
+		c.WriteJSON(GameMessage{
+			Type:    "game_error",
+			Payload: fiber.Map{"error": "Invalid Session"},
+		})
+		c.Close()
+		return
+	}
+	client.UserId = connSession.UserID.String()
+
+	role, err := h.games.ConnectionRole(context.Background(), gameID, connSession.UserID)
+	if err != nil {
+		c.WriteJSON(GameMessage{
+			Type:    "game_error",
+			Payload: fiber.Map{"error": "Not allowed to join this game"},
+		})
+		c.Close()
+		return
+	}
+	client.Spectator = role != "player"
+
+	h.sockets.Add(1)
+	defer h.sockets.Done()
+
+	keepAlive(c)
+	limiter := newMessageLimiter()
+	registered := h.hub.register(c, client)
+	if registered == nil {
+		c.WriteJSON(GameMessage{
+			Type:    "game_error",
+			Payload: fiber.Map{"error": "Server is shutting down"},
+		})
+		c.Close()
+		return
+	}
 
-	h.hub.register <- c
+	h.handleConnect(c, gameID, connSession)
 
 	defer func() {
-		h.hub.unregister <- c
+		h.hub.unregister(c)
+		<-registered.done
+		if !client.Spectator && !h.hub.userConnected(gameID, client.UserId) {
+			h.handleDisconnect(gameID, connSession)
+		}
 	}()
 
 	for {
 		_, messageBytes, err := c.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Printf("Closing unresponsive connection for user %s in game %s", client.UserId, gameID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("Error reading message: %v", err)
 			}
 			return
 		}
 
-		var message GameMessage
+		var message ClientMessage
 		if err := json.Unmarshal(messageBytes, &message); err != nil {
-			log.Printf("Error parsing message: %v", err)
+			h.respond(c, message, rejectMove(CodeInvalidPayload, "Malformed message", nil))
 			continue
 		}
 
-		sessionId := c.Cookies("session_id")
-		var session models.Session
-		if err := h.db.DB().Where("id = ?", sessionId).First(&session).Error; err != nil {
-			h.hub.broadcast <- GameMessage{
-				Type: "game_error",
-				Payload: fiber.Map{
-					"error": "Invalid Session",
-				},
+		if ok, retryAfter, abusive := limiter.allow(message.Type); !ok {
+			if abusive {
+				log.Printf("Disconnecting user %s from game %s for flooding messages", client.UserId, gameID)
+				h.hub.SendToConn(c, GameMessage{
+					Type:    "game_error",
+					Payload: fiber.Map{"error": "Disconnected for sending too many messages"},
+				})
+				return
 			}
+
+			h.hub.SendToConn(c, errorMessage(message, &protocolError{
+				Code:    CodeRateLimited,
+				Message: "Too many messages, slow down",
+			}, retryAfter))
+			continue
 		}
 
-		switch message.Type {
-		case "game_action":
-			h.handleGameAction(message)
-		case "lobby_ready":
-			payload, ok := message.Payload.(map[string]interface{})
-			if !ok {
-				log.Printf("Invalid payload format for lobby_ready: %v", message.Payload)
-				break
-			}
+		if client.Spectator {
+			h.respond(c, message, rejectMove(CodeSpectator, "Spectators cannot send game messages", nil))
+			continue
+		}
 
-			lobbyID, ok := payload["lobbyId"].(string)
+		session, err := h.users.Session(context.Background(), c.Cookies("session_id"))
+		if err != nil {
+			h.respond(c, message, rejectMove(CodeInvalidSession, "Invalid Session", nil))
+			continue
+		}
 
-			if !ok || lobbyID == "" {
-				log.Printf("Invalid or missing lobbyId in payload: %v", payload)
-				break
-			}
+		ctx, span := telemetry.Tracer().Start(context.Background(), "ws "+message.Type,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("game.id", gameID),
+				attribute.String("user.id", client.UserId),
+			),
+		)
+		err = h.dispatch(ctx, gameID, session, message)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
 
-			userId := session.UserID
+		h.respond(c, message, err)
+	}
+}
 
-			tx := h.db.DB().Begin()
+// Shutdown tells every connected client the server is going away, closes
+// their sockets and waits for the handlers to finish the moves they were
+// applying, so games are left in a consistent state for the next instance.
+// Pending turn, seat and countdown timers are stopped afterwards.
+func (h *GameHandler) Shutdown(ctx context.Context) error {
+	if err := h.hub.Shutdown(ctx, GameMessage{
+		Type:    "server_shutdown",
+		Payload: fiber.Map{"message": "The server is restarting, reconnect shortly"},
+	}); err != nil {
+		return err
+	}
 
-			var player models.Player
-			if err := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, userId).First(&player).Error; err != nil {
-				tx.Rollback()
-				log.Printf("Player not found in lobby: %v", payload)
-				break
-			}
+	drained := make(chan struct{})
+	go func() {
+		h.sockets.Wait()
+		close(drained)
+	}()
 
-			if player.IsReady {
-				log.Print("Aready ready")
-				h.hub.broadcast <- GameMessage{
-					Type: "lobby_ready",
-					Payload: fiber.Map{
-						"message":  "Already ready",
-						"is_ready": "true",
-					},
-				}
-				break
-			}
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-			if err := tx.Model(&player).Update("is_ready", "true").Error; err != nil {
-				tx.Rollback()
-				log.Print("Error updating player status")
-				break
-			}
+	h.timers.stopAll()
+	h.seatHolds.stopAll()
+	h.countdowns.stopAll()
+	return nil
+}
 
-			if err := tx.Commit().Error; err != nil {
-				tx.Rollback()
-				log.Print("Error committing transaction")
-				break
-			}
+// dispatch decodes a client message's payload and hands it to the matching
+// handler. A nil error means the message was applied. The queries the
+// handlers run are traced under ctx.
+func (h *GameHandler) dispatch(ctx context.Context, gameID string, session models.Session, message ClientMessage) error {
+	switch message.Type {
+	case "lobby_ready", "lobby_unready":
+		var payload LobbyPayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
 
-			h.hub.broadcast <- GameMessage{
-				Type: "lobby_ready",
-				Payload: fiber.Map{
-					"message":  "Succesfully ready up",
-					"is_ready": "true",
-					"player":   player,
-				},
-			}
-		case "play_card":
-			payload, ok := message.Payload.(map[string]interface{})
-			if !ok {
-				log.Printf("Invalid payload format for play_card: %v", message.Payload)
-				break
-			}
+		_, err := h.setReady(ctx, payload.LobbyID.String(), session.UserID, message.Type == "lobby_ready")
+		return protocolErrorFor(err)
 
-			cardID, ok := payload["cardId"].(string)
-			gameID, ok2 := payload["gameId"].(string)
+	case "play_card":
+		var payload PlayCardPayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.playCards(ctx, session, payload)
 
-			if !ok || !ok2  {
-				log.Printf("Missing required fields in payload: %v", payload)
-				break
-			}
+	case "pick_up_pile":
+		var payload GamePayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.pickUpPile(ctx, session, payload)
 
-			tx := h.db.DB().Begin()
+	case "draw_card":
+		var payload DrawCardPayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.drawCard(ctx, gameID, session, payload)
 
-			parsedCardID, err := uuid.Parse(cardID)
-			if err != nil {
-				tx.Rollback()
-				log.Printf("Invalid card ID: %v", err)
-				break
-			}
+	case "swap_cards":
+		var payload SwapCardsPayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.swapCards(ctx, session, payload)
 
-			parsedGameID, err := uuid.Parse(gameID)
-			if err != nil {
-				tx.Rollback()
-				log.Printf("Invalid game ID: %v", err)
-				break
-			}
+	case "finish_swap":
+		var payload GamePayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.finishSwap(ctx, session, payload)
 
-			var card models.Card
-			if err := tx.Where("id = ?", parsedCardID).First(&card).Error; err != nil {
-				tx.Rollback()
-				log.Printf("Card not found: %v", err)
-				break
-			}
+	case "chat_message":
+		var payload ChatPayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.sendChat(ctx, gameID, session, payload)
 
-			updates := map[string]interface{}{
-				"location_type": "play_pile",
-				"player_id":     nil,
-			}
+	case "forfeit":
+		return h.handleForfeitMessage(ctx, gameID, session)
 
-			if err := tx.Model(&card).Updates(updates).Error; err != nil {
-				tx.Rollback()
-				log.Printf("Error updating card location: %v", err)
-				break
-			}
+	case "rematch":
+		return h.handleRematchMessage(ctx, gameID, session)
 
-			if err := h.moveToNextPlayer(tx, parsedGameID); err != nil {
-				tx.Rollback()
-				log.Printf("Error moving to next player: %v", err)
-				break
-			}
+	case "start_game":
+		var payload GamePayload
+		if err := decodePayload(message, &payload); err != nil {
+			return err
+		}
+		return h.startGame(ctx, gameID, session, payload)
+	}
 
-			if err := tx.Commit().Error; err != nil {
-				tx.Rollback()
-				log.Printf("Error committing transaction: %v", err)
-				break
-			}
+	return rejectMove(CodeUnknownType, fmt.Sprintf("Unknown message type: %s", message.Type), nil)
+}
 
-			h.hub.broadcast <- GameMessage{
-				Type: "game_update",
-				Payload: fiber.Map{
-					"card_played": card,
-					"game_id":     parsedGameID.String(),
-				},
-			}
+// respond answers a client message with an ack, or with an error describing
+// why it was rejected. Unexpected errors are logged and reported generically.
+func (h *GameHandler) respond(c *websocket.Conn, message ClientMessage, err error) {
+	if err == nil {
+		h.hub.SendToConn(c, ackMessage(message))
+		return
+	}
 
-		case "draw_card":
-			payload, ok := message.Payload.(map[string]interface{})
-			if !ok {
-				log.Printf("Invalid payload format for draw_card: %v", message.Payload)
-				break
-			}
+	var rejected *protocolError
+	if !errors.As(err, &rejected) {
+		log.Printf("Error handling %s message: %v", message.Type, err)
+		rejected = &protocolError{Code: CodeInternal, Message: "Something went wrong"}
+	}
+	h.hub.SendToConn(c, errorMessage(message, rejected, 0))
+}
 
-			playerID, ok := payload["playerId"].(string)
-			if !ok {
-				log.Printf("Missing playerID in payload: %v", payload)
-				break
-			}
+// drawCard gives the player whose turn it is one card from their own game's
+// deck. playerId in the payload is optional, but when sent it has to be the
+// caller's own seat.
+func (h *GameHandler) drawCard(ctx context.Context, gameID string, session models.Session, payload DrawCardPayload) error {
+	parsedGameID, err := uuid.Parse(gameID)
+	if err != nil {
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
 
-			tx := h.db.DB().Begin()
+	tx := h.db.DB().WithContext(ctx).Begin()
 
-			var card models.Card
-			if err := tx.Where("location_type = ? AND player_id IS NULL", "deck").
-				Order("random()").First(&card).Error; err != nil {
-				tx.Rollback()
-				log.Printf("No cards left in deck: %v", err)
-				break
-			}
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", parsedGameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
 
-			if err := tx.Model(&card).Updates(map[string]interface{}{
-				"status":        "hand",
-				"location_type": "hand",
-				"player_id":     playerID,
-			}).Error; err != nil {
-				tx.Rollback()
-				log.Printf("Error updating drawn card: %v", err)
-				break
-			}
+	if game.Status != "in_progress" {
+		tx.Rollback()
+		return rejectMove(CodeGameNotStarted, "The game is not in progress", nil)
+	}
 
-			if err := tx.Commit().Error; err != nil {
-				tx.Rollback()
-				log.Printf("Error committing transaction: %v", err)
-				break
-			}
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", parsedGameID, session.UserID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You are not a player in this game", nil)
+	}
 
-			h.hub.broadcast <- GameMessage{
-				Type: "game_update",
-				Payload: fiber.Map{
-					"card_drawn": card,
-					"player_id":  playerID,
-				},
-			}
-		case "start_game":
-			payload, ok := message.Payload.(map[string]interface{})
-			if !ok {
-				log.Printf("Invalid payload format for start_game: %v", message.Payload)
-				break
-			}
+	if payload.PlayerID != uuid.Nil && payload.PlayerID != player.ID {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You can only draw for yourself", nil)
+	}
 
-			gameId, ok := payload["gameId"].(string)
-			if !ok || gameId == "" {
-				log.Printf("Invalid or missing gameId in payload: %v", payload)
-				continue
-			}
+	if game.CurrentTurnPlayerID != player.ID {
+		tx.Rollback()
+		return rejectMove(CodeNotYourTurn, "It is not your turn", nil)
+	}
 
-			var game models.Game
-			if err := h.db.DB().Preload("Lobby.Players").
-				Where("id = ?", gameId).
-				First(&game).Error; err != nil {
-				log.Printf("Game not found with ID: %s, error: %v", gameId, err)
-				continue
-			}
+	cfg, err := gameRules(tx, parsedGameID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error loading game rules: %w", err)
+	}
 
-			if game.Status != "waiting" {
-				log.Printf("Game with ID %s is not in waiting status. Current status: %s", gameId, game.Status)
-				continue
-			}
+	// Manual draws only top a hand back up. The rest of the deck is the
+	// refill for everyone else.
+	counts, err := playerCardCounts(tx, player.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error counting player cards: %w", err)
+	}
+	if rules.CardsToDraw(cfg, counts["hand"]) == 0 {
+		tx.Rollback()
+		return rejectMove(CodeHandFull, "Your hand is already full", nil)
+	}
 
-			game.Status = "in_progress"
-			game.UpdatedAt = time.Now()
-			if err := h.db.DB().Save(&game).Error; err != nil {
-				log.Printf("Failed to update game status for ID %s: %v", gameId, err)
-				continue
-			}
+	drawn, err := drawFromDeck(tx, cfg, parsedGameID, player.ID, 1)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error drawing card: %w", err)
+	}
+	if len(drawn.Cards) == 0 {
+		tx.Rollback()
+		return rejectMove(CodeDeckEmpty, "No cards left in the deck", nil)
+	}
 
-			h.hub.broadcast <- GameMessage{
-				Type: "game_started",
-				Payload: fiber.Map{
-					"game_id":  game.ID,
-					"players":  game.Lobby.Players,
-					"redirect": fmt.Sprintf("/games/%s", game.ID),
-				},
-			}
-		default:
-			log.Printf("Unknown message type: %s", message.Type)
-		}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
 	}
+
+	h.broadcastDraw(gameID, player.ID, drawn)
+
+	return nil
 }
 
-func (h *GameHandler) handleGameAction(message GameMessage) {
-	h.hub.broadcast <- GameMessage{
-		Type:    "game_update",
-		Payload: message.Payload,
+// startGame lets the lobby owner skip the ready countdown once every player
+// is ready. Only the game this socket is connected to can be started.
+func (h *GameHandler) startGame(ctx context.Context, gameID string, session models.Session, payload GamePayload) error {
+	if payload.GameID != uuid.Nil && payload.GameID.String() != gameID {
+		return rejectMove(CodeInvalidPayload, "You can only start the game you are connected to", nil)
 	}
-}
 
-func isValidPlay(card, topCard models.Card) bool {
-	if topCard.ID == uuid.Nil {
-		return true
+	var game models.Game
+	if err := h.db.DB().WithContext(ctx).Preload("Lobby").
+		Where("id = ?", gameID).
+		First(&game).Error; err != nil {
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	if game.Status != "waiting" {
+		return rejectMove(CodeGameStarted, "The game has already started", nil)
+	}
+
+	if game.Lobby.OwnerID != session.UserID {
+		return rejectMove(CodeNotLobbyOwner, "Only the lobby owner can start the game", nil)
+	}
+
+	ready, err := h.allPlayersReady(game.ID)
+	if err != nil {
+		return fmt.Errorf("error checking ready state for game %s: %w", game.ID, err)
+	}
+	if !ready {
+		return rejectMove(CodePlayersNotReady, "Every player has to be ready first", nil)
 	}
 
-	if card.Value == "6" || card.Value == "10" {
-		return true
+	h.countdowns.cancel(game.ID)
+
+	if err := h.launchGame(game.ID); err != nil {
+		if errors.Is(err, service.ErrGameStarted) || errors.Is(err, errAlreadyDealt) {
+			return rejectMove(CodeGameStarted, "The game has already started", nil)
+		}
+		return err
 	}
 
-	return card.Value == topCard.Value
+	return nil
 }
 
 func (h *GameHandler) moveToNextPlayer(tx *gorm.DB, gameID uuid.UUID) error {
-    var game models.Game
-    if err := tx.Preload("Lobby").Preload("Lobby.Players").Where("id = ?", gameID).First(&game).Error; err != nil {
-        return err
-    }
+	var game models.Game
+	if err := tx.Where("id = ?", gameID).First(&game).Error; err != nil {
+		return err
+	}
+
+	var players []models.Player
+	if err := tx.Where("game_id = ?", gameID).Order("created_at ASC, id ASC").Find(&players).Error; err != nil {
+		return err
+	}
+
+	if len(players) == 0 {
+		return fmt.Errorf("no players in the game")
+	}
+	players = teamTurnOrder(players)
 
-    if len(game.Lobby.Players) == 0 {
-        return fmt.Errorf("no players in the game lobby")
-    }
+	currentPlayerIndex := -1
+	out := make([]bool, len(players))
+	for i, player := range players {
+		if player.ID == game.CurrentTurnPlayerID {
+			currentPlayerIndex = i
+		}
+		out[i] = player.Status == "forfeited" || player.Status == "finished"
+	}
 
-    currentPlayerIndex := -1
-    for i, player := range game.Lobby.Players {
-        if player.ID == game.CurrentTurnPlayerID {
-            currentPlayerIndex = i
-            break
-        }
-    }
+	if currentPlayerIndex == -1 {
+		return fmt.Errorf("current player not found")
+	}
 
-    if currentPlayerIndex == -1 {
-        return fmt.Errorf("current player not found")
-    }
+	nextPlayerIndex := rules.NextTurn(currentPlayerIndex, out)
+	if nextPlayerIndex == -1 {
+		return fmt.Errorf("no active players left in the game")
+	}
 
-    nextPlayerIndex := (currentPlayerIndex + 1) % len(game.Lobby.Players)
+	previousPlayerID := game.CurrentTurnPlayerID
+	game.CurrentTurnPlayerID = players[nextPlayerIndex].ID
 
-    game.CurrentTurnPlayerID = game.Lobby.Players[nextPlayerIndex].ID
+	log.Printf("Next player index: %d, Player ID: %s", nextPlayerIndex, game.CurrentTurnPlayerID)
 
-    log.Printf("Next player index: %d, Player ID: %s", nextPlayerIndex, game.CurrentTurnPlayerID)
+	if err := tx.Model(&game).Update("current_turn_player_id", game.CurrentTurnPlayerID).Error; err != nil {
+		return err
+	}
 
-    return tx.Save(&game).Error
+	return recordGameEvent(tx, gameID, "turn_change", nil, fiber.Map{
+		"from_player_id": previousPlayerID,
+		"to_player_id":   game.CurrentTurnPlayerID,
+	})
 }
-
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GameEventHandler struct {
+	db    database.Service
+	games service.GameService
+}
+
+func NewGameEventHandler(db database.Service, games service.GameService) *GameEventHandler {
+	return &GameEventHandler{
+		db:    db,
+		games: games,
+	}
+}
+
+// Replay returns a finished game's events in sequence order. Events carry the
+// contents of every card, so they are only served once nothing is hidden any
+// more.
+func (h *GameEventHandler) Replay(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	var game models.Game
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", gameID).First(&game).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Game not found")
+	}
+
+	if game.Status != "completed" {
+		return utils.NewError(fiber.StatusForbidden, "Replay is available once the game has finished")
+	}
+
+	events, nextCursor, err := h.page(c, gameID, "after")
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"game_id":       gameID,
+		"data":          events,
+		"last_sequence": game.EventSequence,
+		"has_more":      len(events) > 0 && events[len(events)-1].Sequence < game.EventSequence,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// Events returns the events recorded after the since sequence, so a client that
+// missed socket messages can catch up on just the gap. Card values the caller
+// is not allowed to see are stripped.
+func (h *GameEventHandler) Events(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	game, err := h.games.Find(c.UserContext(), gameID.String())
+	if err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Game not found")
+	}
+
+	if _, err := h.games.ConnectionRole(c.UserContext(), gameID.String(), userID); err != nil {
+		return utils.NewError(fiber.StatusForbidden, "Not allowed to view this game")
+	}
+
+	viewerPlayerID := uuid.Nil
+	var player models.Player
+	if err := h.db.DB().WithContext(c.UserContext()).Where("game_id = ? AND user_id = ?", gameID, userID).First(&player).Error; err == nil {
+		viewerPlayerID = player.ID
+	}
+
+	events, nextCursor, err := h.page(c, gameID, "since")
+	if err != nil {
+		return err
+	}
+
+	if game.Status != "completed" {
+		for i := range events {
+			events[i] = redactEvent(events[i], viewerPlayerID)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"game_id":       gameID,
+		"data":          events,
+		"last_sequence": game.EventSequence,
+		"has_more":      len(events) > 0 && events[len(events)-1].Sequence < game.EventSequence,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// page reads a page of a game's events, oldest first. A cursor from an earlier
+// page's next_cursor continues by (created_at, id), like the other history
+// endpoints; without one, the sequence in sequenceParam is where the page
+// starts, which is how socket clients catch up on broadcasts they missed.
+func (h *GameEventHandler) page(c *fiber.Ctx, gameID uuid.UUID, sequenceParam string) ([]models.GameEvent, *string, error) {
+	limit := utils.ParseLimit(c.Query("limit"), 500, 1000)
+	query := h.db.DB().WithContext(c.UserContext()).Where("game_id = ?", gameID)
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return nil, nil, utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID)
+	} else {
+		sequence, err := strconv.ParseInt(c.Query(sequenceParam, "0"), 10, 64)
+		if err != nil || sequence < 0 {
+			return nil, nil, utils.NewError(fiber.StatusBadRequest, "Invalid "+sequenceParam+" parameter")
+		}
+		query = query.Where("sequence > ?", sequence)
+	}
+
+	var events []models.GameEvent
+	if err := query.
+		Order("created_at ASC, id ASC").
+		Limit(limit + 1).
+		Find(&events).Error; err != nil {
+		return nil, nil, utils.NewError(fiber.StatusInternalServerError, "Error fetching game events")
+	}
+
+	var nextCursor *string
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+	}
+	return events, nextCursor, nil
+}
+
+// redactEvent hides the value of cards drawn by anyone other than the viewer.
+// Every other event only carries information the whole table can see.
+func redactEvent(event models.GameEvent, viewerPlayerID uuid.UUID) models.GameEvent {
+	if event.Type != "draw" || (event.PlayerID != nil && *event.PlayerID == viewerPlayerID) {
+		return event
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		event.Payload = json.RawMessage(`{}`)
+		return event
+	}
+	delete(payload, "value")
+	delete(payload, "values")
+
+	if data, err := json.Marshal(payload); err == nil {
+		event.Payload = data
+	}
+	return event
+}
+
+// Sequence returns the game's latest event sequence, or 0 if it cannot be
+// read. It is installed as the hub's sequencer.
+func (h *GameEventHandler) Sequence(gameID string) int64 {
+	var game models.Game
+	if err := h.db.DB().Select("event_sequence").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return 0
+	}
+	return game.EventSequence
+}
+
+// recordGameEvent appends an event to the game's log inside tx. The sequence
+// comes from an atomic increment on the game row, which also serialises
+// concurrent writers for the same game.
+func recordGameEvent(tx *gorm.DB, gameID uuid.UUID, eventType string, playerID *uuid.UUID, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var sequence int64
+	if err := tx.Raw(
+		"UPDATE games SET event_sequence = event_sequence + 1 WHERE id = ? RETURNING event_sequence",
+		gameID,
+	).Scan(&sequence).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.GameEvent{
+		ID:        uuid.New(),
+		GameID:    gameID,
+		Sequence:  sequence,
+		Type:      eventType,
+		PlayerID:  playerID,
+		Payload:   data,
+		CreatedAt: time.Now(),
+	}).Error
+}
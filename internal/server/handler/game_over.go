@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/teams"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// markPlayerFinished gives the player the next placement once they have shed
+// every card. It reports whether they finished.
+func markPlayerFinished(tx *gorm.DB, gameID, playerID uuid.UUID) (bool, error) {
+	var held int64
+	if err := tx.Model(&models.Card{}).Where("player_id = ?", playerID).Count(&held).Error; err != nil {
+		return false, err
+	}
+	if held > 0 {
+		return false, nil
+	}
+
+	var finished int64
+	if err := tx.Model(&models.Player{}).
+		Where("game_id = ? AND status = ?", gameID, "finished").
+		Count(&finished).Error; err != nil {
+		return false, err
+	}
+
+	if err := tx.Model(&models.Player{}).Where("id = ?", playerID).Updates(map[string]interface{}{
+		"status":    "finished",
+		"placement": finished + 1,
+	}).Error; err != nil {
+		return false, err
+	}
+
+	return true, recordGameEvent(tx, gameID, "player_finished", &playerID, fiber.Map{
+		"placement": finished + 1,
+	})
+}
+
+// completeGameIfOver ends the game once at most one player is still holding
+// cards. That player is the shithead and takes the last open placement; the
+// result, winner, cumulative scores, ratings, XP and challenge progress are
+// all written inside tx. Team games end as soon as one team has every member
+// finished, the players still holding cards being placed by how few they
+// have left.
+func completeGameIfOver(tx *gorm.DB, gameID uuid.UUID) (bool, error) {
+	var game models.Game
+	if err := tx.Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return false, err
+	}
+
+	var players []models.Player
+	if err := tx.Where("game_id = ?", gameID).Order("created_at ASC, id ASC").Find(&players).Error; err != nil {
+		return false, err
+	}
+
+	var remaining []int
+	taken := make(map[int]bool, len(players))
+	for i, player := range players {
+		if player.Placement != nil {
+			taken[*player.Placement] = true
+		}
+		if player.Status != "finished" && player.Status != "forfeited" {
+			remaining = append(remaining, i)
+		}
+	}
+
+	teamGame := isTeamGame(players)
+	if teamGame {
+		if !teams.IsOver(teamMembers(players)) {
+			return false, nil
+		}
+		if err := orderByCardsHeld(tx, players, remaining); err != nil {
+			return false, err
+		}
+	} else if len(remaining) > 1 {
+		return false, nil
+	}
+
+	for _, i := range remaining {
+		placement := 1
+		for taken[placement] {
+			placement++
+		}
+		taken[placement] = true
+		players[i].Placement = &placement
+
+		if err := tx.Model(&players[i]).Update("placement", placement).Error; err != nil {
+			return false, err
+		}
+	}
+
+	sort.SliceStable(players, func(i, j int) bool {
+		return placementOf(players[i]) < placementOf(players[j])
+	})
+
+	points := lobbySettings(game.Lobby.GameSettings).Points(len(players))
+	placements := make([]uuid.UUID, len(players))
+	awarded := make(map[uuid.UUID]int, len(players))
+	for i, player := range players {
+		placements[i] = player.ID
+		awarded[player.ID] = points[i]
+
+		if err := tx.Model(&player).Update("score", gorm.Expr("score + ?", points[i])).Error; err != nil {
+			return false, err
+		}
+	}
+
+	winner := players[0].Role
+	winners := map[uuid.UUID]bool{players[0].ID: true}
+	var teamRanks map[int]int
+	if teamGame {
+		teamRanks = teams.Rank(teamMembers(players))
+		winners = make(map[uuid.UUID]bool, teams.Size)
+		for _, player := range players {
+			if teamRanks[*player.Team] == 1 {
+				winners[player.ID] = true
+				winner = teams.Name(*player.Team)
+			}
+		}
+	}
+
+	if err := tx.Model(&models.Game{}).Where("id = ?", gameID).Updates(map[string]interface{}{
+		"status":     "completed",
+		"winner":     winner,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return false, err
+	}
+
+	if err := recordMatchResult(tx, gameID, placements, teamRanks); err != nil {
+		return false, err
+	}
+
+	if err := updateRatingsForGame(tx, gameID, placements[len(placements)-1], teamRanks); err != nil {
+		return false, err
+	}
+
+	xp, err := awardGameXP(tx, gameID, players, winners)
+	if err != nil {
+		return false, err
+	}
+
+	if err := trackGameChallenges(tx, gameID, players, winners); err != nil {
+		return false, err
+	}
+
+	event := fiber.Map{
+		"placements": placements,
+		"points":     awarded,
+		"xp":         xp,
+	}
+	if teamGame {
+		event["team_placements"] = teamRanks
+	}
+	return true, recordGameEvent(tx, gameID, "game_over", nil, event)
+}
+
+// orderByCardsHeld sorts the indexes of players still in a team game so the
+// ones holding the fewest cards come first and take the better placements.
+func orderByCardsHeld(tx *gorm.DB, players []models.Player, remaining []int) error {
+	if len(remaining) < 2 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(remaining))
+	for i, index := range remaining {
+		ids[i] = players[index].ID
+	}
+
+	var rows []struct {
+		PlayerID uuid.UUID
+		Count    int
+	}
+	if err := tx.Model(&models.Card{}).
+		Select("player_id, COUNT(*) AS count").
+		Where("player_id IN ?", ids).
+		Group("player_id").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	held := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		held[row.PlayerID] = row.Count
+	}
+
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return held[players[remaining[i]].ID] < held[players[remaining[j]].ID]
+	})
+	return nil
+}
+
+func placementOf(player models.Player) int {
+	if player.Placement == nil {
+		return int(^uint(0) >> 1)
+	}
+	return *player.Placement
+}
+
+// announceGameOver broadcasts the final standings, and any level-ups they
+// brought, if the game has ended.
+func (h *GameHandler) announceGameOver(gameID uuid.UUID) {
+	var game models.Game
+	if err := h.db.DB().Where("id = ?", gameID).First(&game).Error; err != nil || game.Status != "completed" {
+		return
+	}
+
+	h.timers.cancel(gameID)
+	h.lobbies.Invalidate()
+
+	var players []models.Player
+	if err := h.db.DB().Preload("User").Where("game_id = ?", gameID).
+		Order("placement ASC").Find(&players).Error; err != nil {
+		log.Printf("Error loading standings for game %s: %v", gameID, err)
+		return
+	}
+
+	var xpEvents []models.XPEvent
+	if err := h.db.DB().Where("game_id = ?", gameID).Find(&xpEvents).Error; err != nil {
+		log.Printf("Error loading XP for game %s: %v", gameID, err)
+	}
+	xpGained := make(map[uuid.UUID]int, len(xpEvents))
+	for _, event := range xpEvents {
+		xpGained[event.UserID] += event.Amount
+	}
+
+	standings := make([]fiber.Map, len(players))
+	for i, player := range players {
+		standings[i] = fiber.Map{
+			"player_id": player.ID,
+			"user_id":   player.UserID,
+			"name":      player.User.Name,
+			"role":      player.Role,
+			"placement": player.Placement,
+			"status":    player.Status,
+			"score":     player.Score,
+			"xp_gained": xpGained[player.UserID],
+		}
+		if player.Team != nil {
+			standings[i]["team"] = *player.Team
+		}
+	}
+
+	var shitheadPlayerID *uuid.UUID
+	if len(players) > 0 {
+		shitheadPlayerID = &players[len(players)-1].ID
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "game_over",
+		Payload: fiber.Map{
+			"game_id":            gameID,
+			"round_number":       game.RoundNumber,
+			"winner":             game.Winner,
+			"shithead_player_id": shitheadPlayerID,
+			"standings":          standings,
+		},
+	})
+
+	h.announceLevelUps(gameID, xpEvents)
+}
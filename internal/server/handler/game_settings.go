@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/rules"
+	"api/internal/game/settings"
+	"api/internal/server/utils"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// validateGameSettings checks the game_settings sent with a lobby and returns
+// them with every default filled in, which is what gets stored.
+func validateGameSettings(raw json.RawMessage) (json.RawMessage, error) {
+	parsed, err := settings.Parse(raw)
+	if err != nil {
+		return nil, invalidGameSettings(err)
+	}
+
+	normalized, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, utils.NewError(fiber.StatusInternalServerError, "Error encoding game settings")
+	}
+	return normalized, nil
+}
+
+// invalidGameSettings turns settings.Errors into a 422 carrying a message per
+// setting.
+func invalidGameSettings(err error) error {
+	apiErr := utils.NewError(fiber.StatusUnprocessableEntity, "Invalid game settings").WithCode("INVALID_GAME_SETTINGS")
+
+	var invalid settings.Errors
+	if !errors.As(err, &invalid) {
+		return apiErr
+	}
+	details := make(map[string]string, len(invalid))
+	for field, message := range invalid {
+		if field != "game_settings" {
+			field = "game_settings." + field
+		}
+		details[field] = message
+	}
+	return apiErr.WithDetails(details)
+}
+
+// lobbySettings reads a lobby's stored game settings. Lobbies saved before
+// the settings were validated may hold anything; those play by the defaults.
+func lobbySettings(raw json.RawMessage) settings.Settings {
+	parsed, err := settings.Parse(raw)
+	if err != nil {
+		log.Printf("Ignoring invalid game settings: %v", err)
+	}
+	return parsed
+}
+
+// gameSettings loads the house rules of the lobby the game belongs to.
+func gameSettings(tx *gorm.DB, gameID uuid.UUID) (settings.Settings, error) {
+	var lobby struct {
+		GameSettings json.RawMessage
+	}
+	if err := tx.Model(&models.Lobby{}).
+		Select("lobbies.game_settings").
+		Joins("JOIN games ON games.lobby_id = lobbies.id").
+		Where("games.id = ?", gameID).
+		Scan(&lobby).Error; err != nil {
+		return settings.Default(), err
+	}
+	return lobbySettings(lobby.GameSettings), nil
+}
+
+// gameRules is the rules engine configuration a game is played by.
+func gameRules(tx *gorm.DB, gameID uuid.UUID) (rules.Config, error) {
+	house, err := gameSettings(tx, gameID)
+	if err != nil {
+		return rules.Config{}, err
+	}
+	return house.Rules(), nil
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// SeatState is a player as everyone at the table sees them: how many cards
+// they hold in each zone and their face-up cards.
+type SeatState struct {
+	PlayerSummary
+	Status        string     `json:"status"`
+	IsBot         bool       `json:"is_bot"`
+	IsReady       bool       `json:"is_ready"`
+	Placement     *int       `json:"placement"`
+	Team          *int       `json:"team,omitempty"`
+	HandCount     int        `json:"hand_count"`
+	FaceUp        []GameCard `json:"face_up"`
+	FaceDownCount int        `json:"face_down_count"`
+}
+
+// GameSnapshot is the whole table as one viewer is allowed to see it. Only
+// the viewer's own hand is shown; everyone else's is just counted.
+type GameSnapshot struct {
+	GameID              uuid.UUID   `json:"game_id"`
+	Status              string      `json:"status"`
+	RoundNumber         int         `json:"round_number"`
+	CurrentTurnPlayerID *uuid.UUID  `json:"current_turn_player_id"`
+	ViewerPlayerID      *uuid.UUID  `json:"viewer_player_id"`
+	Players             []SeatState `json:"players"`
+	Hand                []GameCard  `json:"hand"`
+	PlayPile            PileState   `json:"play_pile"`
+	BurnedCount         int         `json:"burned_count"`
+	RemainingCards      int64       `json:"remaining_cards"`
+	TurnTimerSeconds    int         `json:"turn_timer_seconds"`
+	// TurnEndsAt and StartsAt are set while the turn timer or the ready
+	// countdown is running.
+	TurnEndsAt *time.Time `json:"turn_ends_at"`
+	StartsAt   *time.Time `json:"starts_at"`
+}
+
+// State returns a read-only snapshot of a game for the caller, redacted the
+// same way the WebSocket feed is. It never changes the game, so it is safe
+// to poll and works before the cards are dealt.
+func (h *GameHandler) State(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if _, err := h.games.ConnectionRole(c.UserContext(), gameID.String(), userID); err != nil {
+		if errors.Is(err, service.ErrGameNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "Game not found")
+		}
+		return utils.NewError(fiber.StatusForbidden, "Not allowed to view this game")
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var game models.Game
+	if err := db.Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Game not found")
+	}
+
+	var players []models.Player
+	if err := db.Where("game_id = ?", gameID).Order("created_at ASC, id ASC").Find(&players).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching players")
+	}
+	players = teamTurnOrder(players)
+
+	snapshot := GameSnapshot{
+		GameID:           game.ID,
+		Status:           game.Status,
+		RoundNumber:      game.RoundNumber,
+		Players:          make([]SeatState, 0, len(players)),
+		Hand:             []GameCard{},
+		TurnTimerSeconds: lobbySettings(game.Lobby.GameSettings).TurnTimer(h.game.TurnTimerSeconds),
+	}
+	if game.CurrentTurnPlayerID != uuid.Nil {
+		snapshot.CurrentTurnPlayerID = &game.CurrentTurnPlayerID
+	}
+
+	viewerPlayerID := uuid.Nil
+	for _, player := range players {
+		if player.UserID == userID {
+			viewerPlayerID = player.ID
+			snapshot.ViewerPlayerID = &player.ID
+			break
+		}
+	}
+
+	summaries, err := getPlayerSummaries(c.UserContext(), h.db, gameID.String(), game.CurrentTurnPlayerID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching players")
+	}
+	summaryByID := make(map[uuid.UUID]PlayerSummary, len(summaries))
+	for _, summary := range summaries {
+		summaryByID[summary.ID] = summary
+	}
+
+	var cards []models.Card
+	if err := db.Where("game_id = ? AND location_type = ?", gameID, "player").
+		Order("updated_at ASC, id ASC").
+		Find(&cards).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching cards")
+	}
+
+	seats := make(map[uuid.UUID]*SeatState, len(players))
+	for _, player := range players {
+		snapshot.Players = append(snapshot.Players, SeatState{
+			PlayerSummary: summaryByID[player.ID],
+			Status:        player.Status,
+			IsBot:         player.IsBot,
+			IsReady:       player.IsReady,
+			Placement:     player.Placement,
+			Team:          player.Team,
+			FaceUp:        []GameCard{},
+		})
+		seats[player.ID] = &snapshot.Players[len(snapshot.Players)-1]
+	}
+
+	for _, card := range cards {
+		if card.PlayerID == nil {
+			continue
+		}
+		seat, ok := seats[*card.PlayerID]
+		if !ok {
+			continue
+		}
+		switch card.Status {
+		case "hand":
+			seat.HandCount++
+			if *card.PlayerID == viewerPlayerID {
+				snapshot.Hand = append(snapshot.Hand, toGameCard(card, viewerPlayerID))
+			}
+		case "faceup":
+			seat.FaceUp = append(seat.FaceUp, toGameCard(card, viewerPlayerID))
+		case "hidden":
+			seat.FaceDownCount++
+		}
+	}
+
+	if snapshot.PlayPile, err = loadPile(db, gameID, "play_pile"); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching play pile")
+	}
+
+	var burned int64
+	if err := db.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "burned").
+		Count(&burned).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error counting burned pile")
+	}
+	snapshot.BurnedCount = int(burned)
+
+	if err := db.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "deck").
+		Count(&snapshot.RemainingCards).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error counting deck")
+	}
+
+	if game.Status == "in_progress" {
+		if at, ok := h.timers.deadline(gameID); ok {
+			snapshot.TurnEndsAt = &at
+		}
+	}
+	if game.Status == "waiting" {
+		if at, ok := h.countdowns.deadline(gameID); ok {
+			snapshot.StartsAt = &at
+		}
+	}
+
+	return c.JSON(snapshot)
+}
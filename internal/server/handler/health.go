@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/service"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthHandler struct {
+	db      database.Service
+	hub     *GameHub
+	lobbies service.LobbyService
+}
+
+func NewHealthHandler(db database.Service, hub *GameHub, lobbies service.LobbyService) *HealthHandler {
+	return &HealthHandler{
+		db:      db,
+		hub:     hub,
+		lobbies: lobbies,
+	}
+}
+
+// Health is the liveness check. It answers 200 as long as the process is
+// serving requests and includes the database and cache stats for monitoring.
+func (h *HealthHandler) Health(c *fiber.Ctx) error {
+	stats := h.db.Health()
+
+	cache := h.lobbies.CacheStats()
+	stats["lobby_cache_hits"] = strconv.FormatUint(cache.Hits, 10)
+	stats["lobby_cache_misses"] = strconv.FormatUint(cache.Misses, 10)
+
+	return c.JSON(stats)
+}
+
+// Ready is the readiness check. It answers 503 unless the database responds,
+// every migration has been applied and the hub is accepting sockets.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	stats := h.db.Health()
+	ready := stats["status"] == "up"
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), time.Second)
+	defer cancel()
+
+	latest := database.LatestMigration()
+	stats["migration_latest"] = strconv.FormatInt(latest, 10)
+	if version, err := h.db.MigrationVersion(ctx); err != nil {
+		ready = false
+		stats["migrations"] = "unknown"
+	} else {
+		stats["migration_version"] = strconv.FormatInt(version, 10)
+		stats["migrations"] = "applied"
+		if version < latest {
+			ready = false
+			stats["migrations"] = "pending"
+		}
+	}
+
+	stats["hub"] = "running"
+	stats["hub_clients"] = strconv.Itoa(h.hub.ClientCount())
+	if !h.hub.Running() {
+		ready = false
+		stats["hub"] = "stopped"
+	}
+
+	if !ready {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(stats)
+	}
+	return c.JSON(stats)
+}
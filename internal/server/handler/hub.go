@@ -0,0 +1,486 @@
+package handler
+
+import (
+	"api/internal/backplane"
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	// sendBufferSize is how many messages a client may fall behind by before
+	// the hub drops it.
+	sendBufferSize = 64
+	// pongWait is how long a connection may stay silent, pongs included,
+	// before it is treated as half-open and closed.
+	pongWait = 60 * time.Second
+	// pingPeriod is kept below pongWait so a healthy client always has time
+	// to answer.
+	pingPeriod = pongWait * 9 / 10
+	writeWait  = 10 * time.Second
+	// backplaneChannel carries every hub broadcast between instances.
+	backplaneChannel = "shithead:hub"
+
+	notificationRoomPrefix = "notifications:"
+)
+
+type Client struct {
+	UserId    string
+	GameId    string
+	Spectator bool
+
+	conn *websocket.Conn
+	send chan []byte
+	// done is closed once the writer goroutine has stopped using conn.
+	done chan struct{}
+}
+
+// enqueue adds payload to the client's outbox without blocking. It reports
+// false when the outbox is full.
+func (c *Client) enqueue(payload []byte) bool {
+	select {
+	case c.send <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+// GameHub tracks every open game socket. Each client has its own buffered
+// outbox drained by a dedicated writer goroutine, so a slow connection never
+// holds up the rest of its room.
+type GameHub struct {
+	mu      sync.RWMutex
+	clients map[*websocket.Conn]*Client
+	rooms   map[string]map[*websocket.Conn]*Client
+
+	// sequencer returns a game's latest event sequence, which is stamped on
+	// everything broadcast to its room.
+	sequencer func(gameID string) int64
+
+	// presence, when set, is told about every socket as it registers.
+	presence func(Client)
+
+	// backplane, when set, carries broadcasts between API instances.
+	backplane  backplane.Backplane
+	instanceID string
+
+	stopped bool
+}
+
+func NewGameHub() *GameHub {
+	return &GameHub{
+		clients:    make(map[*websocket.Conn]*Client),
+		rooms:      make(map[string]map[*websocket.Conn]*Client),
+		instanceID: uuid.NewString(),
+	}
+}
+
+// Running reports whether the hub is still accepting sockets.
+func (h *GameHub) Running() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return !h.stopped
+}
+
+// ClientCount returns how many sockets are connected to this instance.
+func (h *GameHub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients)
+}
+
+// SetSequencer installs the function used to stamp broadcasts with the game's
+// event sequence. It must be called before the hub starts serving sockets.
+func (h *GameHub) SetSequencer(sequencer func(gameID string) int64) {
+	h.sequencer = sequencer
+}
+
+// TrackPresence installs fn to be called with every socket that registers.
+// It must be called before the hub starts serving sockets.
+func (h *GameHub) TrackPresence(fn func(Client)) {
+	h.presence = fn
+}
+
+// Clients returns a snapshot of the sockets connected to this instance.
+func (h *GameHub) Clients() []Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]Client, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, Client{
+			UserId:    client.UserId,
+			GameId:    client.GameId,
+			Spectator: client.Spectator,
+		})
+	}
+	return clients
+}
+
+// stamp sets message's sequence to the game's latest event, so clients know
+// where to resume from if they miss anything.
+func (h *GameHub) stamp(gameID string, message *GameMessage) {
+	if h.sequencer != nil {
+		message.Seq = h.sequencer(gameID)
+	}
+}
+
+// register adds conn to its game's room and starts writing its outbox. The
+// caller must wait on the returned client's done channel before letting go of
+// conn. It returns nil once the hub is shutting down.
+func (h *GameHub) register(conn *websocket.Conn, client Client) *Client {
+	registered := &client
+	registered.conn = conn
+	registered.send = make(chan []byte, sendBufferSize)
+	registered.done = make(chan struct{})
+
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return nil
+	}
+	h.clients[conn] = registered
+	room, ok := h.rooms[client.GameId]
+	if !ok {
+		room = make(map[*websocket.Conn]*Client)
+		h.rooms[client.GameId] = room
+	}
+	room[conn] = registered
+	h.mu.Unlock()
+
+	go h.writePump(registered)
+
+	if h.presence != nil {
+		h.presence(client)
+	}
+
+	return registered
+}
+
+// unregister removes conn from the hub and closes its outbox, which in turn
+// stops its writer and closes the socket. Calling it more than once is fine.
+func (h *GameHub) unregister(conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client, ok := h.clients[conn]; ok {
+		h.removeClient(client)
+	}
+}
+
+// removeClient drops a registered client and closes its outbox, which ends its
+// writer. The caller must hold h.mu for writing.
+func (h *GameHub) removeClient(client *Client) {
+	delete(h.clients, client.conn)
+	h.removeFromRoom(client.GameId, client.conn)
+	close(client.send)
+}
+
+// Shutdown stops accepting sockets, sends notice to every connected client
+// and closes them, waiting until their writers have finished or ctx is done.
+// The backplane is closed last.
+func (h *GameHub) Shutdown(ctx context.Context, notice GameMessage) error {
+	payload, err := json.Marshal(notice)
+	if err != nil {
+		return err
+	}
+
+	// The notice goes out under the lock so that a writer failing at the same
+	// moment cannot close an outbox between the enqueue and the close here.
+	h.mu.Lock()
+	h.stopped = true
+	clients := make([]*Client, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, client)
+		client.enqueue(payload)
+		h.removeClient(client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		select {
+		case <-client.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if h.backplane != nil {
+		return h.backplane.Close()
+	}
+	return nil
+}
+
+// writePump drains the client's outbox and pings it every pingPeriod so the
+// reader notices when the other end has silently gone away.
+func (h *GameHub) writePump(client *Client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+		close(client.done)
+	}()
+
+	for {
+		select {
+		case payload, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := client.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				h.unregister(client.conn)
+				return
+			}
+
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				h.unregister(client.conn)
+				return
+			}
+		}
+	}
+}
+
+// keepAlive arms the read deadline on conn and pushes it back whenever the
+// client answers a ping, so reads fail once the client stops responding.
+func keepAlive(conn *websocket.Conn) {
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+// userConnected reports whether the user still has a socket open in the game.
+func (h *GameHub) userConnected(gameID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, client := range h.rooms[gameID] {
+		if client.UserId == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *GameHub) removeFromRoom(gameID string, conn *websocket.Conn) {
+	room, ok := h.rooms[gameID]
+	if !ok {
+		return
+	}
+
+	delete(room, conn)
+	if len(room) == 0 {
+		delete(h.rooms, gameID)
+	}
+}
+
+// deliver queues on each client the payload pick returns for it, skipping
+// clients it returns nil for. An empty gameID walks every connected client
+// rather than a single room. Clients whose outbox is full are disconnected.
+func (h *GameHub) deliver(gameID string, pick func(*Client) []byte) {
+	h.mu.RLock()
+	clients := h.clients
+	if gameID != "" {
+		clients = h.rooms[gameID]
+	}
+
+	var stalled []*websocket.Conn
+	for conn, client := range clients {
+		payload := pick(client)
+		if payload == nil {
+			continue
+		}
+		if !client.enqueue(payload) {
+			stalled = append(stalled, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range stalled {
+		log.Printf("Dropping websocket client that fell %d messages behind", sendBufferSize)
+		h.unregister(conn)
+	}
+}
+
+func encodeMessage(message GameMessage) []byte {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error encoding %s message: %v", message.Type, err)
+		return nil
+	}
+	return messageBytes
+}
+
+// hubEnvelope is a broadcast in a form that can be relayed to other API
+// instances over the backplane and delivered there as it would be locally.
+type hubEnvelope struct {
+	Origin string `json:"origin"`
+	GameID string `json:"game_id,omitempty"`
+	// UserID addresses every socket of one user instead of a game's room.
+	UserID string `json:"user_id,omitempty"`
+	// ExceptUserID leaves one user's sockets out of a room broadcast.
+	ExceptUserID string          `json:"except_user_id,omitempty"`
+	Message      json.RawMessage `json:"message"`
+	// Public, when set, goes to spectators in place of Message.
+	Public json.RawMessage `json:"public,omitempty"`
+}
+
+// UseBackplane relays this hub's broadcasts to other instances through bp and
+// delivers theirs to the sockets connected here. It must be called before the
+// hub starts serving sockets.
+func (h *GameHub) UseBackplane(bp backplane.Backplane) error {
+	h.backplane = bp
+	return bp.Subscribe(backplaneChannel, func(data []byte) {
+		var envelope hubEnvelope
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			log.Printf("Error decoding backplane message: %v", err)
+			return
+		}
+		if envelope.Origin == h.instanceID {
+			return
+		}
+		h.deliverEnvelope(envelope)
+	})
+}
+
+// publish delivers envelope to the sockets on this instance and relays it to
+// the others.
+func (h *GameHub) publish(envelope hubEnvelope) {
+	h.deliverEnvelope(envelope)
+
+	if h.backplane == nil {
+		return
+	}
+
+	envelope.Origin = h.instanceID
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("Error encoding backplane message: %v", err)
+		return
+	}
+	if err := h.backplane.Publish(backplaneChannel, data); err != nil {
+		log.Printf("Error publishing to backplane: %v", err)
+	}
+}
+
+func (h *GameHub) deliverEnvelope(envelope hubEnvelope) {
+	if envelope.UserID != "" {
+		h.deliver("", func(client *Client) []byte {
+			if client.UserId != envelope.UserID || isNotificationRoom(client.GameId) {
+				return nil
+			}
+			return envelope.Message
+		})
+		return
+	}
+
+	h.deliver(envelope.GameID, func(client *Client) []byte {
+		if envelope.ExceptUserID != "" && client.UserId == envelope.ExceptUserID {
+			return nil
+		}
+		if client.Spectator && envelope.Public != nil {
+			return envelope.Public
+		}
+		return envelope.Message
+	})
+}
+
+func (h *GameHub) BroadcastToGame(gameID string, message GameMessage) {
+	h.stamp(gameID, &message)
+	payload := encodeMessage(message)
+	if payload == nil {
+		return
+	}
+
+	h.publish(hubEnvelope{GameID: gameID, Message: payload})
+}
+
+// BroadcastToGameRedacted sends message to the players in a game and public to
+// its spectators, for events that reveal private card contents.
+func (h *GameHub) BroadcastToGameRedacted(gameID string, message, public GameMessage) {
+	h.stamp(gameID, &message)
+	public.Seq = message.Seq
+	payload, publicPayload := encodeMessage(message), encodeMessage(public)
+	if payload == nil || publicPayload == nil {
+		return
+	}
+
+	h.publish(hubEnvelope{GameID: gameID, Message: payload, Public: publicPayload})
+}
+
+// BroadcastToGameExcept sends message to everyone in a game apart from the
+// given user's connections. Pair it with SendToUser to give that user a
+// private version.
+func (h *GameHub) BroadcastToGameExcept(gameID, userID string, message GameMessage) {
+	h.stamp(gameID, &message)
+	payload := encodeMessage(message)
+	if payload == nil {
+		return
+	}
+
+	h.publish(hubEnvelope{GameID: gameID, ExceptUserID: userID, Message: payload})
+}
+
+// SendToConn answers a single socket. The socket is always held by this
+// instance, so nothing is relayed.
+func (h *GameHub) SendToConn(conn *websocket.Conn, message GameMessage) {
+	payload := encodeMessage(message)
+	if payload == nil {
+		return
+	}
+
+	h.deliver("", func(client *Client) []byte {
+		if client.conn != conn {
+			return nil
+		}
+		return payload
+	})
+}
+
+// notificationRoom is the room holding a user's notification sockets. It is
+// kept apart from game rooms so game traffic never reaches those sockets.
+func notificationRoom(userID string) string {
+	return notificationRoomPrefix + userID
+}
+
+func isNotificationRoom(room string) bool {
+	return strings.HasPrefix(room, notificationRoomPrefix)
+}
+
+// pushNotification sends message to the user's notification sockets on this
+// instance only; every instance hears about new notifications itself.
+func (h *GameHub) pushNotification(userID string, message GameMessage) {
+	payload := encodeMessage(message)
+	if payload == nil {
+		return
+	}
+
+	h.deliver(notificationRoom(userID), func(*Client) []byte {
+		return payload
+	})
+}
+
+// SendToUser sends message to every socket the user has open, across all of
+// their games and every API instance.
+func (h *GameHub) SendToUser(userID string, message GameMessage) {
+	payload := encodeMessage(message)
+	if payload == nil {
+		return
+	}
+
+	h.publish(hubEnvelope{UserID: userID, Message: payload})
+}
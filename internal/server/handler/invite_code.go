@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const defaultInviteCodeLifetime = 24 * time.Hour
+
+type InviteCodeRequest struct {
+	ExpiresInMinutes int `json:"expires_in_minutes" validate:"omitempty,min=1,max=10080"`
+}
+
+func (h *LobbyHandler) RegenerateInviteCode(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req InviteCodeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	var lobby models.Lobby
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if lobby.OwnerID != userID {
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can manage the invite code")
+	}
+
+	lifetime := defaultInviteCodeLifetime
+	if req.ExpiresInMinutes > 0 {
+		lifetime = time.Duration(req.ExpiresInMinutes) * time.Minute
+	}
+
+	code := generateInviteCode()
+	expiresAt := time.Now().Add(lifetime)
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&lobby).Updates(map[string]interface{}{
+		"invite_code":            code,
+		"invite_code_expires_at": expiresAt,
+		"updated_at":             time.Now(),
+	}).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error generating invite code")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"invite_code": code,
+		"expires_at":  expiresAt,
+	})
+}
+
+func (h *LobbyHandler) ExpireInviteCode(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var lobby models.Lobby
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if lobby.OwnerID != userID {
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can manage the invite code")
+	}
+
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&lobby).Updates(map[string]interface{}{
+		"invite_code":            nil,
+		"invite_code_expires_at": nil,
+		"updated_at":             time.Now(),
+	}).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error expiring invite code")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"message": "Invite code expired",
+	})
+}
+
+func (h *LobbyHandler) ShowByCode(c *fiber.Ctx) error {
+	code := strings.ToLower(c.Params("code"))
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	user, err := h.users.Find(c.UserContext(), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	}
+
+	lobby, err := h.lobbies.FindByInviteCode(c.UserContext(), code)
+	if err != nil || !service.ValidInviteCode(&lobby, code) {
+		return utils.NewError(fiber.StatusNotFound, "Invalid invite code")
+	}
+
+	return c.JSON(h.formatLobbyResponse(lobby, user))
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const leaderboardStatsQuery = `
+SELECT p.user_id,
+       COUNT(*) AS games_played,
+       COUNT(*) FILTER (WHERE g.winner = p.role OR g.winner = 'team' || p.team) AS wins,
+       ? AS computed_at
+FROM players p
+JOIN games g ON g.id = p.game_id
+WHERE g.status = 'completed'`
+
+type LeaderboardHandler struct {
+	db database.Service
+
+	// pages holds encoded responses of Index for ttl. A nil store or a
+	// zero ttl serves every request from the snapshot table.
+	pages fiber.Storage
+	ttl   time.Duration
+}
+
+type LeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	Avatar      *string   `json:"avatar,omitempty"`
+	GamesPlayed int       `json:"games_played"`
+	Wins        int       `json:"wins"`
+}
+
+type leaderboardPage struct {
+	Data       []LeaderboardEntry `json:"data"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	ComputedAt *time.Time         `json:"computed_at"`
+}
+
+func NewLeaderboardHandler(db database.Service, pages fiber.Storage, ttl time.Duration) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		db:    db,
+		pages: pages,
+		ttl:   ttl,
+	}
+}
+
+func (h *LeaderboardHandler) Index(c *fiber.Ctx) error {
+	limit := utils.ParseLimit(c.Query("limit"), 25, 100)
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	key := fmt.Sprintf("page:%d:limit:%d", page, limit)
+	if encoded := h.cached(key); encoded != nil {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(encoded)
+	}
+
+	var snapshots []models.LeaderboardSnapshot
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("User").
+		Order("wins DESC, games_played ASC, user_id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&snapshots).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching leaderboard")
+	}
+
+	var computedAt *time.Time
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&models.LeaderboardSnapshot{}).
+		Select("MAX(computed_at)").
+		Scan(&computedAt).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching leaderboard")
+	}
+
+	entries := make([]LeaderboardEntry, len(snapshots))
+	for i, snapshot := range snapshots {
+		entries[i] = LeaderboardEntry{
+			Rank:        offset + i + 1,
+			UserID:      snapshot.UserID,
+			Name:        snapshot.User.Name,
+			Avatar:      snapshot.User.Avatar,
+			GamesPlayed: snapshot.GamesPlayed,
+			Wins:        snapshot.Wins,
+		}
+	}
+
+	response := leaderboardPage{
+		Data:       entries,
+		Page:       page,
+		Limit:      limit,
+		ComputedAt: computedAt,
+	}
+	h.store(key, response)
+
+	return c.JSON(response)
+}
+
+func (h *LeaderboardHandler) cached(key string) []byte {
+	if h.pages == nil || h.ttl <= 0 {
+		return nil
+	}
+	encoded, err := h.pages.Get(key)
+	if err != nil {
+		log.Printf("Error reading leaderboard cache: %v", err)
+		return nil
+	}
+	return encoded
+}
+
+func (h *LeaderboardHandler) store(key string, response leaderboardPage) {
+	if h.pages == nil || h.ttl <= 0 {
+		return
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	if err := h.pages.Set(key, encoded, h.ttl); err != nil {
+		log.Printf("Error caching leaderboard: %v", err)
+	}
+}
+
+// invalidate drops every cached page so the next request reads the snapshot
+// table that was just rebuilt.
+func (h *LeaderboardHandler) invalidate() {
+	if h.pages == nil {
+		return
+	}
+	if err := h.pages.Reset(); err != nil {
+		log.Printf("Error clearing leaderboard cache: %v", err)
+	}
+}
+
+func (h *LeaderboardHandler) RunRefresher(interval time.Duration) {
+	h.refresh()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.refresh()
+	}
+}
+
+func (h *LeaderboardHandler) refresh() {
+	if err := refreshLeaderboard(h.db.DB()); err != nil {
+		log.Printf("Error refreshing leaderboard: %v", err)
+		return
+	}
+	h.invalidate()
+}
+
+func refreshLeaderboard(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM leaderboard_snapshots").Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(
+			"INSERT INTO leaderboard_snapshots (user_id, games_played, wins, computed_at)"+
+				leaderboardStatsQuery+" GROUP BY p.user_id",
+			time.Now(),
+		).Error
+	})
+}
+
+// updateLeaderboardForGame recomputes the snapshot rows of everyone who took
+// part in a game so results show up without waiting for the next refresh.
+func updateLeaderboardForGame(tx *gorm.DB, gameID uuid.UUID) error {
+	return tx.Exec(
+		"INSERT INTO leaderboard_snapshots (user_id, games_played, wins, computed_at)"+
+			leaderboardStatsQuery+
+			" AND p.user_id IN (SELECT user_id FROM players WHERE game_id = ?) GROUP BY p.user_id"+
+			" ON CONFLICT (user_id) DO UPDATE SET games_played = EXCLUDED.games_played,"+
+			" wins = EXCLUDED.wins, computed_at = EXCLUDED.computed_at",
+		time.Now(), gameID,
+	).Error
+}
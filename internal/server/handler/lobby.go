@@ -15,26 +15,61 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
+	"api/internal/config"
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/game/teams"
+	"api/internal/moderation"
+	"api/internal/server/middleware"
+	"api/internal/server/utils"
+	"api/internal/service"
 )
 
 type LobbyHandler struct {
-	db database.Service
+	db      database.Service
+	hub     *GameHub
+	game    config.Game
+	lobbies service.LobbyService
+	users   service.UserService
+	filter  *moderation.Filter
+	audit   service.AuditService
 }
 
 type CreateLobbyRequest struct {
 	Name             string          `json:"name" validate:"required"`
 	Type             string          `json:"type" validate:"required,oneof=public private tournament"`
 	Status           string          `json:"status" validate:"omitempty,oneof=waiting in_progress completed"`
-	MaxPlayers       int             `json:"max_players" validate:"required,min=2,max=4"`
-	GameMode         string          `json:"game_mode" validate:"omitempty,oneof=casual ranked tournament"`
+	MaxPlayers       int             `json:"max_players" validate:"required,min=2,max=8"`
+	GameMode         string          `json:"game_mode" validate:"omitempty,oneof=casual ranked tournament teams"`
 	PrivacyLevel     string          `json:"privacy_level" validate:"omitempty,oneof=open invite_only password_protected"`
 	Password         string          `json:"password" validate:"omitempty,min=6"`
 	SpectatorAllowed bool            `json:"spectator_allowed"`
+	MaxSpectators    *int            `json:"max_spectators" validate:"omitempty,min=1,max=100"`
 	GameSettings     json.RawMessage `json:"game_settings"`
 }
 
+type LobbyListRequest struct {
+	Page             int    `query:"page"`
+	Limit            int    `query:"limit"`
+	Status           string `query:"status" validate:"omitempty,oneof=waiting in_progress completed"`
+	Type             string `query:"type" validate:"omitempty,oneof=public private tournament"`
+	GameMode         string `query:"game_mode" validate:"omitempty,oneof=casual ranked tournament teams"`
+	HasOpenSeats     *bool  `query:"has_open_seats"`
+	SpectatorAllowed *bool  `query:"spectator_allowed"`
+	Sort             string `query:"sort" validate:"omitempty,oneof=created_at current_players"`
+	Order            string `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type UpdateLobbyRequest struct {
+	Name             *string          `json:"name" validate:"omitempty,min=1"`
+	MaxPlayers       *int             `json:"max_players" validate:"omitempty,min=2,max=8"`
+	PrivacyLevel     *string          `json:"privacy_level" validate:"omitempty,oneof=open invite_only password_protected"`
+	Password         *string          `json:"password" validate:"omitempty,min=6"`
+	SpectatorAllowed *bool            `json:"spectator_allowed"`
+	MaxSpectators    *int             `json:"max_spectators" validate:"omitempty,min=1,max=100"`
+	GameSettings     *json.RawMessage `json:"game_settings"`
+}
+
 type JoinLobbyRequest struct {
 	InviteCode string `json:"invite_code,omitempty"`
 	Password   string `json:"password,omitempty"`
@@ -48,127 +83,145 @@ type AcceptInvitationRequest struct {
 	LobbyID uuid.UUID `json:"lobby_id" validate:"required"`
 }
 
-func NewLobbyHandler(db database.Service) *LobbyHandler {
+type DeclineInvitationRequest struct {
+	LobbyID uuid.UUID `json:"lobby_id" validate:"required"`
+}
+
+func NewLobbyHandler(db database.Service, hub *GameHub, game config.Game, lobbies service.LobbyService, users service.UserService, filter *moderation.Filter, audit service.AuditService) *LobbyHandler {
 	return &LobbyHandler{
-		db: db,
+		db:      db,
+		hub:     hub,
+		game:    game,
+		lobbies: lobbies,
+		users:   users,
+		filter:  filter,
+		audit:   audit,
 	}
 }
 
 func generateInviteCode() string {
-	bytes := make([]byte, 2)
+	bytes := make([]byte, 4)
 	rand.Read(bytes)
 	return hex.EncodeToString(bytes)
 }
 
 func (h *LobbyHandler) Index(c *fiber.Ctx) error {
-	sessionID := c.Cookies("session_id")
-	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
-		})
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	currentUser, err := h.users.Find(c.UserContext(), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
 	}
 
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+	var req LobbyListRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid query parameters")
 	}
 
-	var currentUser models.User
-	if err := h.db.DB().First(&currentUser, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	var lobbies []models.Lobby
-	if err := h.db.DB().
-		Preload("Owner").
-		Preload("Players").
-		Preload("LobbyInvitations").
-		Preload("Games").
-		Preload("LobbyQueues").
-		Find(&lobbies).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching lobbies",
-		})
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	lobbies, total, err := h.lobbies.List(c.UserContext(), service.LobbyFilter{
+		Status:           req.Status,
+		Type:             req.Type,
+		GameMode:         req.GameMode,
+		HasOpenSeats:     req.HasOpenSeats,
+		SpectatorAllowed: req.SpectatorAllowed,
+		Sort:             req.Sort,
+		Order:            req.Order,
+		Offset:           (req.Page - 1) * req.Limit,
+		Limit:            req.Limit,
+	})
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching lobbies")
 	}
 
+	// Listings may be served from the cache, which keeps no invite codes, so
+	// owners find theirs on the lobby itself.
 	formattedLobbies := make([]fiber.Map, len(lobbies))
 	for i, lobby := range lobbies {
 		formattedLobbies[i] = h.formatLobbyResponse(lobby, currentUser)
+		delete(formattedLobbies[i], "invite_code")
+		delete(formattedLobbies[i], "invite_code_expires_at")
 	}
 
-	return c.JSON(formattedLobbies)
+	return c.JSON(fiber.Map{
+		"data":  formattedLobbies,
+		"page":  req.Page,
+		"limit": req.Limit,
+		"total": total,
+	})
 }
 
 func (h *LobbyHandler) Store(c *fiber.Ctx) error {
 	var req CreateLobbyRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	sessionID := c.Cookies("session_id")
-	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+	if h.filter.Contains(req.Name) {
+		return errNameNotAllowed()
 	}
 
+	userID := c.Locals("user_id").(uuid.UUID)
+
 	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	if err := h.db.DB().WithContext(c.UserContext()).First(&user, userID).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	}
+
+	if service.RequiresVerifiedEmail(h.game, req.GameMode) && user.EmailVerifiedAt == nil {
+		return utils.NewError(fiber.StatusForbidden, "Verify your email address to play this game mode")
 	}
 
 	// Check existing lobby and player
 	var existingLobby models.Lobby
-	err := h.db.DB().Where("owner_id = ?", user.ID).First(&existingLobby).Error
+	err := h.db.DB().WithContext(c.UserContext()).Where("owner_id = ?", user.ID).First(&existingLobby).Error
 	if err == nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You already have an active lobby",
-		})
+		return utils.NewError(fiber.StatusForbidden, "You already have an active lobby")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error checking user's lobbies",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking user's lobbies")
 	}
 
 	var existingPlayer models.Player
-	err = h.db.DB().Where("user_id = ?", user.ID).First(&existingPlayer).Error
+	err = h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", user.ID).First(&existingPlayer).Error
 	if err == nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You are already in another lobby",
-		})
+		return utils.NewError(fiber.StatusForbidden, "You are already in another lobby")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error checking user's player status",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking user's player status")
+	}
+
+	if err := checkTeamTable(req.GameMode, req.MaxPlayers); err != nil {
+		return err
+	}
+
+	storedSettings, err := validateGameSettings(req.GameSettings)
+	if err != nil {
+		return err
 	}
 
 	var passwordHash *string
 	if req.Password != "" {
 		hashedPass, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error hashing password",
-			})
+			return utils.NewError(fiber.StatusInternalServerError, "Error hashing password")
 		}
 		hashStr := string(hashedPass)
 		passwordHash = &hashStr
 	}
-	tx := h.db.DB().Begin()
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
 
 	lobby := models.Lobby{
 		ID:               uuid.New(),
@@ -181,449 +234,399 @@ func (h *LobbyHandler) Store(c *fiber.Ctx) error {
 		PrivacyLevel:     req.PrivacyLevel,
 		PasswordHash:     passwordHash,
 		SpectatorAllowed: req.SpectatorAllowed,
-		GameSettings:     req.GameSettings,
+		GameSettings:     storedSettings,
 		CurrentPlayers:   1,
 	}
+	if req.MaxSpectators != nil {
+		lobby.MaxSpectators = *req.MaxSpectators
+	}
 
-	if err := tx.Create(&lobby).Error; err != nil {
+	if err := createLobby(tx, &lobby); err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating lobby",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating lobby")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"lobby": lobby,
+	})
+}
+
+// createLobby inserts lobby along with its waiting game and seats the owner
+// in it under a random role.
+func createLobby(tx *gorm.DB, lobby *models.Lobby) error {
+	if lobby.MaxSpectators == 0 {
+		lobby.MaxSpectators = defaultMaxSpectators
+	}
+	if err := tx.Create(lobby).Error; err != nil {
+		return err
 	}
 
-	gameID := uuid.New()
 	game := models.Game{
-		ID:                  gameID,
+		ID:                  uuid.New(),
 		LobbyID:             lobby.ID,
 		Status:              "waiting",
-		OwnerID:             user.ID,
+		OwnerID:             lobby.OwnerID,
 		CurrentTurnPlayerID: uuid.Nil,
 		RoundNumber:         1,
 		Winner:              "none",
 	}
-
 	if err := tx.Create(&game).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating game",
-		})
+		return err
 	}
 
-	max := big.NewInt(4)
-	randomIndex, err := rand.Int(rand.Reader, max)
+	randomIndex, err := rand.Int(rand.Reader, big.NewInt(int64(lobby.MaxPlayers)))
 	if err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error generating random role",
-		})
+		return err
+	}
+
+	team, err := service.AssignTeam(tx, lobby, game.ID)
+	if err != nil {
+		return err
 	}
 
 	player := models.Player{
 		ID:      uuid.New(),
 		LobbyID: lobby.ID,
-		GameID:  gameID,
-		UserID:  user.ID,
+		GameID:  game.ID,
+		UserID:  lobby.OwnerID,
 		Role:    fmt.Sprintf("player%d", randomIndex.Int64()+1),
 		IsReady: false,
 		Score:   0,
+		Team:    team,
 	}
-
 	if err := tx.Create(&player).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error creating player",
-		})
-	}
-
-	if err := tx.Model(&game).Update("current_turn_player_id", player.ID).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating game with player ID",
-		})
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
+		return err
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"lobby": lobby,
-	})
+	return tx.Model(&game).Update("current_turn_player_id", player.ID).Error
 }
 
 func (h *LobbyHandler) Show(c *fiber.Ctx) error {
 	lobbyID := c.Params("id")
 
-	sessionID := c.Cookies("session_id")
-	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
-		})
-	}
-
-	var session models.Session
-
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
-	}
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	user, err := h.users.Find(c.UserContext(), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
 	}
 
-	var lobby models.Lobby
-	if err := h.db.DB().Preload("Owner").Preload("Players.User").Preload("Games").
-		Preload("LobbyInvitations").Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Lobby not found",
-		})
+	lobby, err := h.lobbies.Find(c.UserContext(), lobbyID)
+	if err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
 	}
 
 	response := h.formatLobbyResponse(lobby, user)
 	return c.JSON(response)
 }
 
-func (h *LobbyHandler) JoinLobby(c *fiber.Ctx) error {
-	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
-
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Wrong lobby id",
-		})
-	}
-
-	sessionID := c.Cookies("session_id")
-	if sessionID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Session ID not provided",
-		})
-	}
-
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
-	}
+func (h *LobbyHandler) Update(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	var req UpdateLobbyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	var req JoinLobbyRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	tx := h.db.DB().Begin()
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
 
 	var lobby models.Lobby
-	if err := tx.Preload("Players").Preload("LobbyInvitations").
-		First(&lobby, lobbyID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Lobby not found",
-		})
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
 	}
 
-	if lobby.Status != "waiting" {
+	if lobby.OwnerID != userID {
 		tx.Rollback()
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Lobby not accepting players",
-		})
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can update the lobby")
 	}
 
-	var existingPlayer models.Player
-	if err := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, user.ID).First(&existingPlayer).Error; err == nil {
-		if err := tx.Commit().Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error committing transaction",
-			})
-		}
-		return c.JSON(fiber.Map{
-			"message":  "Successfully joined lobby",
-			"lobby_id": lobby.ID,
-		})
-	}
-
-	var currentUser models.User
-	if err := tx.First(&currentUser, user.ID).Error; err != nil {
+	if lobby.Status != "waiting" {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Lobby settings can only be changed while waiting")
 	}
 
-	var currentPlayer *models.Player
-	for _, player := range lobby.Players {
-		if player.UserID == currentUser.ID {
-			currentPlayer = &player
-			break
+	updates := map[string]interface{}{}
+
+	if req.Name != nil {
+		if h.filter.Contains(*req.Name) {
+			tx.Rollback()
+			return errNameNotAllowed()
 		}
+		updates["name"] = *req.Name
 	}
 
-	if existingPlayer.ID != currentPlayer.ID {
-		switch lobby.PrivacyLevel {
-		case "password_protected":
-			if err := h.handlePasswordProtectedJoin(&lobby, req.Password); err != nil {
+	if req.MaxPlayers != nil {
+		if *req.MaxPlayers < lobby.CurrentPlayers {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusBadRequest, "Max players cannot be lower than the current player count")
+		}
+		if err := checkTeamTable(lobby.GameMode, *req.MaxPlayers); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if lobby.GameMode == teams.Mode {
+			var outside int64
+			if err := tx.Model(&models.Player{}).
+				Where("lobby_id = ? AND team > ?", lobby.ID, teams.Count(*req.MaxPlayers)).
+				Count(&outside).Error; err != nil {
 				tx.Rollback()
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Error committing transaction",
-				})
+				return utils.NewError(fiber.StatusInternalServerError, "Error checking teams")
+			}
+			if outside > 0 {
+				tx.Rollback()
+				return utils.NewError(fiber.StatusBadRequest, "Players are still seated in the teams that would be removed")
 			}
 		}
+		updates["max_players"] = *req.MaxPlayers
 	}
 
-	if lobby.CurrentPlayers >= lobby.MaxPlayers {
-		return h.handleQueueJoin(tx, c, &lobby, user.ID)
+	privacyLevel := lobby.PrivacyLevel
+	if req.PrivacyLevel != nil {
+		switch *req.PrivacyLevel {
+		case "open", "invite_only", "password_protected":
+		default:
+			tx.Rollback()
+			return utils.NewError(fiber.StatusBadRequest, "Invalid privacy level")
+		}
+		privacyLevel = *req.PrivacyLevel
+		updates["privacy_level"] = privacyLevel
 	}
 
-	if err := h.addPlayerToLobby(tx, &lobby, user.ID); err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
+	if req.Password != nil {
+		if len(*req.Password) < 6 {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusBadRequest, "Password must be at least 6 characters")
+		}
+		hashedPass, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error hashing password")
+		}
+		updates["password_hash"] = string(hashedPass)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
+	if privacyLevel == "password_protected" && req.Password == nil && lobby.PasswordHash == nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "A password is required for password protected lobbies")
+	}
+	if privacyLevel != "password_protected" && req.PrivacyLevel != nil {
+		updates["password_hash"] = nil
 	}
 
-	return c.JSON(fiber.Map{
-		"message":  "Successfully joined lobby",
-		"lobby_id": lobby.ID,
-	})
-}
-
-func (h *LobbyHandler) LeaveLobby(c *fiber.Ctx) error {
-	lobbyID := c.Params("lobbyId")
-	userID := c.Locals("user_id").(uuid.UUID)
-
-	tx := h.db.DB().Begin()
+	if req.SpectatorAllowed != nil {
+		updates["spectator_allowed"] = *req.SpectatorAllowed
+	}
 
-	var lobby models.Lobby
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Lobby not found",
-		})
+	if req.MaxSpectators != nil {
+		updates["max_spectators"] = *req.MaxSpectators
 	}
 
-	if lobby.OwnerID == userID {
-		if err := h.deleteLobbyAndRelatedRecords(tx, lobbyID); err != nil {
+	if req.GameSettings != nil {
+		storedSettings, err := validateGameSettings(*req.GameSettings)
+		if err != nil {
 			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error deleting lobby and related records",
-			})
-		}
-
-		if err := tx.Commit().Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error committing transaction",
-			})
+			return err
 		}
-
-		return c.JSON(fiber.Map{
-			"message": "Successfully deleted lobby",
-		})
+		updates["game_settings"] = storedSettings
 	}
 
-	var player models.Player
-	if err := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, userID).First(&player).Error; err != nil {
+	if len(updates) == 0 {
 		tx.Rollback()
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Not in lobby",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Nothing to update")
 	}
+	updates["updated_at"] = time.Now()
 
-	if err := tx.Delete(&player).Error; err != nil {
+	if err := tx.Model(&lobby).Updates(updates).Error; err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error removing player",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating lobby")
 	}
 
-	if err := tx.Model(&lobby).Update("current_players", gorm.Expr("current_players - ?", 1)).Error; err != nil {
+	// Spectators already watching keep their place when the cap is lowered;
+	// the queue just waits longer.
+	if req.SpectatorAllowed != nil && !*req.SpectatorAllowed {
+		if err := service.ClearSpectators(tx, lobby.ID); err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error removing spectators")
+		}
+	} else if _, err := service.PromoteSpectators(tx, lobby.ID); err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating player count",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error promoting queued spectators")
 	}
 
-	if err := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, userID).Delete(&models.LobbyQueue{}).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error removing from queue",
-		})
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
+	h.lobbies.Invalidate()
+
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", lobby.ID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching lobby")
+	}
+
+	settings := fiber.Map{
+		"lobby_id":          lobby.ID,
+		"name":              lobby.Name,
+		"max_players":       lobby.MaxPlayers,
+		"privacy_level":     lobby.PrivacyLevel,
+		"spectator_allowed": lobby.SpectatorAllowed,
+		"max_spectators":    lobby.MaxSpectators,
+		"game_settings":     lobby.GameSettings,
 	}
+	h.broadcastToLobby(lobby.ID, GameMessage{
+		Type:    "lobby_updated",
+		Payload: settings,
+	})
 
 	return c.JSON(fiber.Map{
-		"message": "Successfully left lobby",
+		"message": "Lobby updated",
+		"lobby":   settings,
 	})
 }
 
-func (h *LobbyHandler) deleteLobbyAndRelatedRecords(tx *gorm.DB, lobbyID string) error {
-	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbyInvitation{}).Error; err != nil {
-		return err
-	}
+func (h *LobbyHandler) JoinLobby(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
 
-	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.Player{}).Error; err != nil {
-		return err
+	if err != nil {
+		return utils.NewError(fiber.StatusUnauthorized, "Wrong lobby id")
 	}
 
-	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbyQueue{}).Error; err != nil {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := middleware.CheckBan(c, h.db, userID); err != nil {
 		return err
 	}
 
-	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.Game{}).Error; err != nil {
-		return err
+	var req JoinLobbyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := tx.Where("data->>'lobby_id' = ?", lobbyID).Delete(&models.Notification{}).Error; err != nil {
-		return err
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	if err := tx.Where("id = ?", lobbyID).Delete(&models.Lobby{}).Error; err != nil {
-		return err
+	result, err := h.lobbies.Join(c.UserContext(), lobbyID.String(), userID, req.InviteCode, req.Password)
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	case errors.Is(err, service.ErrLobbyNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	case errors.Is(err, service.ErrLobbyClosed):
+		return utils.NewError(fiber.StatusBadRequest, "Lobby not accepting players")
+	case errors.Is(err, service.ErrEmailUnverified):
+		return utils.NewError(fiber.StatusForbidden, "Verify your email address to play this game mode")
+	case errors.Is(err, service.ErrInviteOnly):
+		return utils.NewError(fiber.StatusForbidden, "This lobby is invite only")
+	case errors.Is(err, service.ErrWrongPassword):
+		return utils.NewError(fiber.StatusUnauthorized, "Invalid password")
+	case errors.Is(err, service.ErrAlreadyQueued):
+		return utils.NewError(fiber.StatusBadRequest, "Already in queue")
+	case errors.Is(err, service.ErrLobbyFull):
+		return utils.NewError(fiber.StatusBadRequest, "Lobby is full")
+	case errors.Is(err, service.ErrGameStarted):
+		return utils.NewError(fiber.StatusConflict, "The game has already started")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error joining lobby")
 	}
 
-	return nil
-}
+	h.lobbies.Invalidate()
 
-func (h *LobbyHandler) InviteUser(c *fiber.Ctx) error {
-	lobbyID := c.Params("lobbyId")
+	if result.Queued {
+		h.broadcastQueueUpdate(result.Lobby.ID)
 
-	sessionID := c.Cookies("session_id")
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":        "Added to queue",
+			"queue_position": result.QueuePosition,
 		})
 	}
 
-	var currentUser models.User
-	if err := h.db.DB().First(&currentUser, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
-	}
+	return c.JSON(fiber.Map{
+		"message":  "Successfully joined lobby",
+		"lobby_id": result.Lobby.ID,
+	})
+}
 
-	var req InviteUserRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
+func (h *LobbyHandler) LeaveLobby(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	if req.InvitedUserID == currentUser.ID {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot invite yourself",
-		})
+	result, err := h.lobbies.Leave(c.UserContext(), lobbyID, userID)
+	switch {
+	case errors.Is(err, service.ErrLobbyNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	case errors.Is(err, service.ErrNotInLobby):
+		return utils.NewError(fiber.StatusBadRequest, "Not in lobby")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error leaving lobby")
 	}
 
-	var lobby models.Lobby
-	if err := h.db.DB().Where("id = ?", lobbyID).Preload("Owner").First(&lobby).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Lobby not found",
-		})
-	}
+	h.lobbies.Invalidate()
 
-	if lobby.OwnerID != currentUser.ID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only the lobby owner can send invitations",
+	if result.Deleted {
+		recordAudit(c, h.audit, service.AuditEntry{
+			Action:     "lobby_delete",
+			TargetType: "lobby",
+			TargetID:   lobbyID,
+			Details:    map[string]interface{}{"name": result.Lobby.Name, "reason": "owner_left"},
 		})
-	}
 
-	if lobby.CurrentPlayers >= lobby.MaxPlayers {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Lobby is full",
+		return c.JSON(fiber.Map{
+			"message": "Successfully deleted lobby",
 		})
 	}
 
-	var existingInvitation models.LobbyInvitation
-	existingErr := h.db.DB().Where("lobby_id = ? AND invited_user_id = ? AND status = ?",
-		lobbyID, req.InvitedUserID, "pending").First(&existingInvitation).Error
-	if existingErr == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "Invitation already exists for this user",
-		})
+	if result.NewOwnerID != uuid.Nil {
+		h.broadcastOwnerChanged(result.Lobby.ID, userID, result.NewOwnerID)
 	}
+	h.broadcastQueueUpdate(result.Lobby.ID)
 
-	now := time.Now().UTC()
-	invitation := models.LobbyInvitation{
-		ID:            uuid.New(),
-		LobbyID:       lobby.ID,
-		InviterID:     currentUser.ID,
-		InvitedUserID: req.InvitedUserID,
-		Status:        "pending",
-		ExpiresAt:     now.Add(30 * time.Minute),
-		CreatedAt:     &now,
-		UpdatedAt:     &now,
-	}
+	return c.JSON(fiber.Map{
+		"message": "Successfully left lobby",
+	})
+}
 
-	tx := h.db.DB().Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+func (h *LobbyHandler) InviteUser(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
 
-	if err := tx.Create(&invitation).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create invitation",
-		})
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req InviteUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	messageType := "lobby_invitation"
-	notification := models.Notification{
-		ID:     uuid.New(),
-		Type:   &messageType,
-		UserID: req.InvitedUserID,
-		Data: json.RawMessage(
-			fmt.Sprintf(
-				`{"lobby_id": "%s", "expires_at": "%s", "lobby_name": "%s", "message": "You have been invited to a lobby"}`,
-				lobby.ID,
-				invitation.ExpiresAt,
-				lobby.Name,
-			),
-		),
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-
-	if err := tx.Create(&notification).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create notification",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to commit transaction",
-		})
+	invitation, err := h.lobbies.Invite(c.UserContext(), lobbyID, userID, req.InvitedUserID)
+	switch {
+	case errors.Is(err, service.ErrSelfInvite):
+		return utils.NewError(fiber.StatusBadRequest, "Cannot invite yourself")
+	case errors.Is(err, service.ErrBlocked):
+		return utils.NewError(fiber.StatusForbidden, "You cannot invite this user")
+	case errors.Is(err, service.ErrLobbyNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	case errors.Is(err, service.ErrNotLobbyOwner):
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can send invitations")
+	case errors.Is(err, service.ErrLobbyFull):
+		return utils.NewError(fiber.StatusBadRequest, "Lobby is full")
+	case errors.Is(err, service.ErrInvitationExists):
+		return utils.NewError(fiber.StatusConflict, "Invitation already exists for this user")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Failed to create invitation")
 	}
 
 	return c.JSON(fiber.Map{
@@ -637,95 +640,36 @@ func (h *LobbyHandler) InviteUser(c *fiber.Ctx) error {
 func (h *LobbyHandler) AcceptInvitation(c *fiber.Ctx) error {
 	var req AcceptInvitationRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	sessionID := c.Cookies("session_id")
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
-	}
-
-	userID := session.UserID
-	tx := h.db.DB().Begin()
-
-	fmt.Printf("Looking for invitation with lobby_id: %s and user_id: %s\n", req.LobbyID, userID)
-
-	var invitation models.LobbyInvitation
-	if err := tx.Debug().Where("lobby_id = ? AND invited_user_id = ?",
-		req.LobbyID, userID).First(&invitation).Error; err != nil {
-		fmt.Printf("Error finding invitation: %v\n", err)
-		tx.Rollback()
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Invalid invitation",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	if invitation.ExpiresAt.Before(time.Now()) {
-		tx.Rollback()
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invitation has expired",
-		})
-	}
-
-	if invitation.Status != "pending" {
-		tx.Rollback()
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invitation has already been processed",
-		})
-	}
-
-	var lobby *models.Lobby
-	if err := tx.First(&lobby, invitation.LobbyID).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Lobby not found",
-		})
-	}
-
-	if lobby.CurrentPlayers >= lobby.MaxPlayers {
-		tx.Rollback()
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Lobby is full",
-		})
-	}
-
-	if err := tx.Model(&invitation).Updates(map[string]interface{}{
-		"status":     "accepted",
-		"updated_at": time.Now(),
-	}).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating invitation",
-		})
-	}
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	if err := h.addPlayerToLobby(tx, lobby, userID); err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error adding user to lobby",
-		})
+	if err := middleware.CheckBan(c, h.db, userID); err != nil {
+		return err
 	}
 
-	if err := tx.Model(&lobby).Updates(map[string]interface{}{
-		"current_players": lobby.CurrentPlayers + 1,
-		"updated_at":      time.Now(),
-	}).Error; err != nil {
-		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating lobby player count",
-		})
+	lobby, err := h.lobbies.AcceptInvitation(c.UserContext(), req.LobbyID.String(), userID)
+	switch {
+	case errors.Is(err, service.ErrInvitationNotFound):
+		return utils.NewError(fiber.StatusNotFound, "No pending invitation to this lobby")
+	case errors.Is(err, service.ErrInvitationExpired):
+		return utils.NewError(fiber.StatusBadRequest, "Invitation has expired")
+	case errors.Is(err, service.ErrLobbyNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	case errors.Is(err, service.ErrLobbyFull):
+		return utils.NewError(fiber.StatusBadRequest, "Lobby is full")
+	case errors.Is(err, service.ErrGameStarted):
+		return utils.NewError(fiber.StatusConflict, "The game has already started")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error adding user to lobby")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
-	}
+	h.lobbies.Invalidate()
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -734,109 +678,56 @@ func (h *LobbyHandler) AcceptInvitation(c *fiber.Ctx) error {
 	})
 }
 
-func (h *LobbyHandler) handlePasswordProtectedJoin(lobby *models.Lobby, password string) error {
-	if password == "" || !checkPasswordHash(password, *lobby.PasswordHash) {
-		return &fiber.Error{
-			Code:    fiber.StatusUnauthorized,
-			Message: "Invalid password",
-		}
-	}
-	return nil
-}
-
-func (h *LobbyHandler) handleQueueJoin(tx *gorm.DB, c *fiber.Ctx, lobby *models.Lobby, userID uuid.UUID) error {
-	var existingQueue models.LobbyQueue
-	if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&existingQueue).Error; err == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Already in queue",
-		})
-	}
-
-	queuePosition := int(1)
-	var lastQueue models.LobbyQueue
-	if err := tx.Where("lobby_id = ?", lobby.ID).Order("queue_position desc").First(&lastQueue).Error; err == nil {
-		queuePosition = *lastQueue.Position + int(1)
-	}
-
-	queue := models.LobbyQueue{
-		LobbyID:   lobby.ID,
-		UserID:    userID,
-		QueueType: "player",
-		Position:  &queuePosition,
-	}
-
-	if err := tx.Create(&queue).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error joining queue",
-		})
+func (h *LobbyHandler) DeclineInvitation(c *fiber.Ctx) error {
+	var req DeclineInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error committing transaction",
-		})
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
-		"message":        "Added to queue",
-		"queue_position": queuePosition,
-	})
-}
+	userID := c.Locals("user_id").(uuid.UUID)
 
-func (h *LobbyHandler) handleExistingPlayer(tx *gorm.DB, c *fiber.Ctx, lobby *models.Lobby, userID uuid.UUID) error {
-	if err := h.addPlayerToLobby(tx, lobby, userID); err != nil {
-		return err
+	err := h.lobbies.DeclineInvitation(c.UserContext(), req.LobbyID.String(), userID)
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	case errors.Is(err, service.ErrInvitationNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Invalid invitation")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error declining invitation")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Successfully joined already existing lobby",
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Invitation declined",
 	})
 }
 
-func (h *LobbyHandler) addPlayerToLobby(tx *gorm.DB, lobby *models.Lobby, userID uuid.UUID) error {
-	var game models.Game
-	err := tx.Where("lobby_id = ? AND status = ?", lobby.ID, "waiting").First(&game).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		game = models.Game{
-			LobbyID:     lobby.ID,
-			RoundNumber: 1,
-			Status:      "waiting",
-			Winner:      "none",
-		}
-		if err := tx.Create(&game).Error; err != nil {
-			return err
-		}
-	} else if err != nil {
-		return err
-	}
-
-	var existingPlayer models.Player
-	if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&existingPlayer).Error; err == nil {
-		return nil
-	}
-
-	playerNumber := lobby.CurrentPlayers
-	player := models.Player{
-		ID:      uuid.New(),
-		LobbyID: lobby.ID,
-		GameID:  game.ID,
-		UserID:  userID,
-		Role:    fmt.Sprintf("player%d", playerNumber),
-		Score:   0,
-	}
+func (h *LobbyHandler) CancelInvitation(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	invitationID := c.Params("invitationId")
 
-	if err := tx.Model(&lobby).Update("current_players", gorm.Expr("current_players + ?", 1)).Error; err != nil {
-		return err
-	}
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	if err := tx.Create(&player).Error; err != nil {
-		return err
+	err := h.lobbies.CancelInvitation(c.UserContext(), lobbyID, invitationID, userID)
+	switch {
+	case errors.Is(err, service.ErrInvitationNotFound):
+		return utils.NewError(fiber.StatusNotFound, "Invitation not found")
+	case errors.Is(err, service.ErrNotLobbyOwner):
+		return utils.NewError(fiber.StatusForbidden, "Only the inviter or lobby owner can cancel this invitation")
+	case errors.Is(err, service.ErrInvitationProcessed):
+		return utils.NewError(fiber.StatusBadRequest, "Invitation has already been processed")
+	case err != nil:
+		return utils.NewError(fiber.StatusInternalServerError, "Error cancelling invitation")
 	}
 
-	if err := tx.Save(lobby).Error; err != nil {
-		return err
-	}
-	return nil
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Invitation cancelled",
+	})
 }
 
 func (h *LobbyHandler) formatLobbyResponse(lobby models.Lobby, currentUser models.User) fiber.Map {
@@ -853,7 +744,7 @@ func (h *LobbyHandler) formatLobbyResponse(lobby models.Lobby, currentUser model
 		}
 	}
 
-	return fiber.Map{
+	response := fiber.Map{
 		"id":   lobby.ID,
 		"name": lobby.Name,
 		"owner": fiber.Map{
@@ -861,7 +752,7 @@ func (h *LobbyHandler) formatLobbyResponse(lobby models.Lobby, currentUser model
 			"name": lobby.Owner.Name,
 		},
 		"max_players":       lobby.MaxPlayers,
-		"current_user":      currentUser,
+		"current_user":      newUserResponse(currentUser),
 		"is_player":         currentPlayer != nil,
 		"player_role":       getPlayerRole(currentPlayer),
 		"current_players":   lobby.CurrentPlayers,
@@ -871,24 +762,30 @@ func (h *LobbyHandler) formatLobbyResponse(lobby models.Lobby, currentUser model
 		"participants":      h.formatParticipants(lobby.Players),
 		"current_game":      h.formatGame(currentGame),
 		"spectator_allowed": lobby.SpectatorAllowed,
+		"spectator_count":   lobby.SpectatorCount,
+		"max_spectators":    lobby.MaxSpectators,
 		"game_settings":     lobby.GameSettings,
 		"queue":             h.formatQueue(lobby.LobbyQueues),
 		"created_at":        lobby.CreatedAt,
 		"updated_at":        lobby.UpdatedAt,
 		"privacy_level":     lobby.PrivacyLevel,
+		"has_password":      lobby.HasPassword,
 	}
+	if lobby.OwnerID == currentUser.ID {
+		response["invite_code"] = lobby.InviteCode
+		response["invite_code_expires_at"] = lobby.InviteExpiresAt
+	}
+
+	return response
 }
 
+// formatParticipants expects the players' users to be preloaded.
 func (h *LobbyHandler) formatParticipants(players []models.Player) []fiber.Map {
 	result := make([]fiber.Map, len(players))
 	for i, player := range players {
-		var user models.User
-		if err := h.db.DB().First(&user, player.UserID).Error; err != nil {
-			continue
-		}
 		result[i] = fiber.Map{
-			"id":       user.ID,
-			"name":     user.Name,
+			"id":       player.UserID,
+			"name":     player.User.Name,
 			"role":     player.Role,
 			"score":    player.Score,
 			"is_ready": player.IsReady,
@@ -920,14 +817,20 @@ func (h *LobbyHandler) formatQueue(queue []models.LobbyQueue) []fiber.Map {
 	return result
 }
 
-func checkPasswordHash(password string, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
 func getPlayerRole(player *models.Player) string {
 	if player == nil {
 		return ""
 	}
 	return player.Role
 }
+
+// broadcastToLobby sends message to everyone connected to the lobby's waiting
+// game, which is where lobby members sit before the game starts.
+func (h *LobbyHandler) broadcastToLobby(lobbyID uuid.UUID, message GameMessage) {
+	var game models.Game
+	if err := h.db.DB().Where("lobby_id = ? AND status = ?", lobbyID, "waiting").First(&game).Error; err != nil {
+		return
+	}
+
+	h.hub.BroadcastToGame(game.ID.String(), message)
+}
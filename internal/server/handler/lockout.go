@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/mail"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// loginAttemptRetention is how long failed logins are kept. It only has to
+// cover the per-IP window.
+const loginAttemptRetention = 24 * time.Hour
+
+// LockoutHandler tracks failed logins, locks accounts that keep failing and
+// lets their owners unlock them again from the link emailed with the lock.
+type LockoutHandler struct {
+	db      database.Service
+	mailer  mail.Mailer
+	appURL  string
+	key     []byte
+	lockout config.Lockout
+}
+
+func NewLockoutHandler(db database.Service, mailer mail.Mailer, appURL, appKey string, lockout config.Lockout) *LockoutHandler {
+	return &LockoutHandler{
+		db:      db,
+		mailer:  mailer,
+		appURL:  strings.TrimRight(appURL, "/"),
+		key:     signingKey(appKey, "account unlock"),
+		lockout: lockout,
+	}
+}
+
+// ipBlocked reports whether ip has failed too many logins recently and, if
+// so, how long until its oldest counted failure ages out.
+func (h *LockoutHandler) ipBlocked(ctx context.Context, ip string) (time.Duration, bool, error) {
+	if h.lockout.MaxAttemptsPerIP <= 0 {
+		return 0, false, nil
+	}
+
+	since := time.Now().UTC().Add(-h.lockout.Duration)
+
+	var failures int64
+	if err := h.db.DB().WithContext(ctx).Model(&models.LoginAttempt{}).
+		Where("ip_address = ? AND created_at > ?", ip, since).
+		Count(&failures).Error; err != nil {
+		return 0, false, err
+	}
+	if failures < int64(h.lockout.MaxAttemptsPerIP) {
+		return 0, false, nil
+	}
+
+	var oldest models.LoginAttempt
+	if err := h.db.DB().WithContext(ctx).
+		Where("ip_address = ? AND created_at > ?", ip, since).
+		Order("created_at ASC").First(&oldest).Error; err != nil {
+		return 0, false, err
+	}
+	return time.Until(oldest.CreatedAt.Add(h.lockout.Duration)), true, nil
+}
+
+// recordFailure logs a failed login. When user is known its failure count
+// goes up, and every MaxAttempts failures lock the account for twice as long
+// as the last lock. It returns when the account is now locked until, if it
+// was locked by this failure.
+func (h *LockoutHandler) recordFailure(ctx context.Context, email, ip string, user *models.User) (*time.Time, error) {
+	var lockedUntil *time.Time
+
+	err := h.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt := models.LoginAttempt{
+			ID:        uuid.New(),
+			Email:     email,
+			IPAddress: ip,
+			CreatedAt: time.Now().UTC(),
+		}
+		if user != nil {
+			attempt.UserID = &user.ID
+		}
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+
+		if user == nil || h.lockout.MaxAttempts <= 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("failed_logins", gorm.Expr("failed_logins + ?", 1)).Error; err != nil {
+			return err
+		}
+
+		var failures int
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Pluck("failed_logins", &failures).Error; err != nil {
+			return err
+		}
+		if failures%h.lockout.MaxAttempts != 0 {
+			return nil
+		}
+
+		until := time.Now().UTC().Add(h.lockDuration(failures / h.lockout.MaxAttempts)).Truncate(time.Second)
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+			Update("locked_until", until).Error; err != nil {
+			return err
+		}
+		lockedUntil = &until
+
+		return service.CreateNotification(tx, user.ID, "account_locked", fiber.Map{
+			"locked_until": until,
+			"ip_address":   ip,
+			"message":      "Your account was locked after repeated failed sign-in attempts",
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedUntil != nil {
+		h.sendUnlockEmail(*user, *lockedUntil)
+	}
+	return lockedUntil, nil
+}
+
+// lockDuration doubles the lock for each run of failures, starting from
+// Duration and capped at MaxDuration.
+func (h *LockoutHandler) lockDuration(lockouts int) time.Duration {
+	duration := h.lockout.Duration
+	for i := 1; i < lockouts && duration < h.lockout.MaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > h.lockout.MaxDuration {
+		return h.lockout.MaxDuration
+	}
+	return duration
+}
+
+// recordSuccess clears the failure count after a successful login.
+func (h *LockoutHandler) recordSuccess(ctx context.Context, user models.User) error {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	return clearLockout(h.db.DB().WithContext(ctx), user.ID)
+}
+
+func clearLockout(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+}
+
+func (h *LockoutHandler) sendUnlockEmail(user models.User, until time.Time) {
+	link := fmt.Sprintf("%s/account/unlock/%s?until=%d&signature=%s",
+		h.appURL, user.ID, until.Unix(), h.sign(user.ID.String(), until.Unix()))
+
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("Hi %s,\n\nWe locked your account until %s after several failed sign-in attempts.\n\nIf that was you, open the link below to unlock it now.\n\n%s\n\nIf it was not you, consider resetting your password.\n",
+			user.Name, until.UTC().Format(time.RFC1123), link),
+	}); err != nil {
+		log.Printf("Error sending account locked email: %v", err)
+	}
+}
+
+// Unlock lifts the lock named in an emailed link. A link only works for the
+// lock it was sent for, and only while that lock lasts.
+func (h *LockoutHandler) Unlock(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	until, err := strconv.ParseInt(c.Query("until"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(c.Query("signature")), []byte(h.sign(id, until))) {
+		return utils.NewError(fiber.StatusForbidden, "Invalid unlock link")
+	}
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", id).First(&user).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if user.LockedUntil == nil || !user.LockedUntil.After(time.Now()) {
+		return c.JSON(fiber.Map{
+			"message": "Account is not locked",
+		})
+	}
+
+	if user.LockedUntil.Unix() != until {
+		return utils.NewError(fiber.StatusForbidden, "Unlock link has expired")
+	}
+
+	if err := clearLockout(h.db.DB().WithContext(c.UserContext()), user.ID); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error unlocking account")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account unlocked",
+	})
+}
+
+func (h *LockoutHandler) sign(id string, until int64) string {
+	mac := hmac.New(sha256.New, h.key)
+	fmt.Fprintf(mac, "unlock|%s|%d", id, until)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryAfter sets the Retry-After header to d, rounded up to whole seconds.
+func retryAfter(c *fiber.Ctx, d time.Duration) {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MatchHandler struct {
+	db database.Service
+}
+
+func NewMatchHandler(db database.Service) *MatchHandler {
+	return &MatchHandler{
+		db: db,
+	}
+}
+
+func preloadParticipants(db *gorm.DB) *gorm.DB {
+	return db.Order("placement ASC")
+}
+
+func (h *MatchHandler) UserMatches(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	limit := utils.ParseLimit(c.Query("limit"), 20, 100)
+	query := h.db.DB().WithContext(c.UserContext()).
+		Where("id IN (?)", h.db.DB().WithContext(c.UserContext()).Model(&models.MatchParticipant{}).
+			Select("match_result_id").
+			Where("user_id = ?", userID))
+
+	if mode := c.Query("game_mode"); mode != "" {
+		query = query.Where("game_mode = ?", mode)
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var results []models.MatchResult
+	if err := query.
+		Preload("Participants", preloadParticipants).
+		Preload("Participants.User", selectPublicUser).
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&results).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching match history")
+	}
+
+	var nextCursor *string
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        results,
+		"next_cursor": nextCursor,
+	})
+}
+
+func (h *MatchHandler) GameResult(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	var result models.MatchResult
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Preload("Participants", preloadParticipants).
+		Preload("Participants.User", selectPublicUser).
+		Where("game_id = ?", gameID).
+		First(&result).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "No result recorded for this game")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching game result")
+	}
+
+	return c.JSON(result)
+}
+
+// recordMatchResult stores the durable result of a finished game inside tx.
+// placements lists player IDs in finishing order, so the last one is the
+// shithead. teamRanks holds each team's placement in team games and is nil
+// otherwise.
+func recordMatchResult(tx *gorm.DB, gameID uuid.UUID, placements []uuid.UUID, teamRanks map[int]int) error {
+	var game models.Game
+	if err := tx.Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return err
+	}
+
+	var players []models.Player
+	if err := tx.Where("id IN ?", placements).Find(&players).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]models.Player, len(players))
+	for _, player := range players {
+		byID[player.ID] = player
+	}
+
+	now := time.Now()
+	result := models.MatchResult{
+		ID:              uuid.New(),
+		GameID:          game.ID,
+		LobbyID:         game.LobbyID,
+		GameMode:        game.Lobby.GameMode,
+		StartedAt:       game.CreatedAt,
+		FinishedAt:      now,
+		DurationSeconds: int(now.Sub(game.CreatedAt).Seconds()),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if len(placements) > 0 {
+		if shithead, ok := byID[placements[len(placements)-1]]; ok {
+			result.ShitheadUserID = &shithead.UserID
+		}
+	}
+
+	for team, rank := range teamRanks {
+		if rank == 1 {
+			result.WinningTeam = &team
+		}
+	}
+
+	if err := tx.Create(&result).Error; err != nil {
+		return err
+	}
+
+	for i, playerID := range placements {
+		player, ok := byID[playerID]
+		if !ok {
+			continue
+		}
+
+		participant := models.MatchParticipant{
+			ID:            uuid.New(),
+			MatchResultID: result.ID,
+			UserID:        player.UserID,
+			PlayerID:      player.ID,
+			Role:          player.Role,
+			Placement:     i + 1,
+			CreatedAt:     now,
+		}
+		if player.Team != nil {
+			if rank, ok := teamRanks[*player.Team]; ok {
+				participant.Team = player.Team
+				participant.TeamPlacement = &rank
+			}
+		}
+
+		if err := tx.Create(&participant).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/middleware"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	matchRatingTolerance    = 100
+	matchToleranceGrowth    = 50
+	matchToleranceGrowthGap = 15 * time.Second
+)
+
+type MatchmakingHandler struct {
+	db      database.Service
+	lobbies service.LobbyService
+	game    config.Game
+}
+
+func NewMatchmakingHandler(db database.Service, lobbies service.LobbyService, game config.Game) *MatchmakingHandler {
+	return &MatchmakingHandler{
+		db:      db,
+		lobbies: lobbies,
+		game:    game,
+	}
+}
+
+func (h *MatchmakingHandler) JoinQueue(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := middleware.CheckBan(c, h.db, userID); err != nil {
+		return err
+	}
+
+	if service.RequiresVerifiedEmail(h.game, "ranked") {
+		var user models.User
+		if err := h.db.DB().WithContext(c.UserContext()).Select("id, email_verified_at").Where("id = ?", userID).First(&user).Error; err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+		}
+		if user.EmailVerifiedAt == nil {
+			return utils.NewError(fiber.StatusForbidden, "Verify your email address to play ranked games")
+		}
+	}
+
+	var existingPlayer models.Player
+	err := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID).First(&existingPlayer).Error
+	if err == nil {
+		return utils.NewError(fiber.StatusForbidden, "You are already in another lobby")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking user's player status")
+	}
+
+	var existingEntry models.MatchmakingEntry
+	if err := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID).First(&existingEntry).Error; err == nil {
+		return utils.NewError(fiber.StatusConflict, "Already in matchmaking queue")
+	}
+
+	entry := models.MatchmakingEntry{
+		ID:     uuid.New(),
+		UserID: userID,
+		Rating: playerRating(h.db.DB().WithContext(c.UserContext()), userID),
+	}
+
+	if err := h.db.DB().WithContext(c.UserContext()).Create(&entry).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error joining matchmaking queue")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Added to matchmaking queue",
+		"rating":  entry.Rating,
+	})
+}
+
+func (h *MatchmakingHandler) LeaveQueue(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID).Delete(&models.MatchmakingEntry{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error leaving matchmaking queue")
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Not in matchmaking queue")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Removed from matchmaking queue",
+	})
+}
+
+func (h *MatchmakingHandler) RunMatcher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := h.matchPlayers(); err != nil {
+			log.Printf("Error matching players: %v", err)
+		}
+	}
+}
+
+// matchPlayers walks the queue in rating order and groups neighbours whose
+// rating spread fits the tolerance, which widens the longer the oldest member
+// of the group has been waiting.
+func (h *MatchmakingHandler) matchPlayers() error {
+	var entries []models.MatchmakingEntry
+	if err := h.db.DB().Order("rating ASC, created_at ASC").Find(&entries).Error; err != nil {
+		return err
+	}
+
+	entries, err := h.dropBannedEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]uuid.UUID, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+
+	blocked, err := blockedPairs(h.db.DB(), userIDs)
+	if err != nil {
+		return err
+	}
+
+	size := h.game.MatchmakingPlayers
+	for i := 0; i+size <= len(entries); {
+		group := entries[i : i+size]
+
+		if groupHasBlock(group, blocked) {
+			i++
+			continue
+		}
+
+		oldest := group[0].CreatedAt
+		for _, entry := range group[1:] {
+			if entry.CreatedAt.Before(oldest) {
+				oldest = entry.CreatedAt
+			}
+		}
+
+		tolerance := matchRatingTolerance + matchToleranceGrowth*int(time.Since(oldest)/matchToleranceGrowthGap)
+		if group[size-1].Rating-group[0].Rating > tolerance {
+			i++
+			continue
+		}
+
+		if err := h.createMatch(group); err != nil {
+			log.Printf("Error creating match: %v", err)
+			i++
+			continue
+		}
+		h.lobbies.Invalidate()
+		i += size
+	}
+
+	return nil
+}
+
+// dropBannedEntries removes queue entries of users banned since they queued,
+// so they are never matched.
+func (h *MatchmakingHandler) dropBannedEntries(entries []models.MatchmakingEntry) ([]models.MatchmakingEntry, error) {
+	if len(entries) == 0 {
+		return entries, nil
+	}
+
+	userIDs := make([]uuid.UUID, len(entries))
+	for i, entry := range entries {
+		userIDs[i] = entry.UserID
+	}
+
+	var banned []uuid.UUID
+	if err := h.db.DB().Model(&models.Ban{}).
+		Where("user_id IN ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userIDs, time.Now().UTC()).
+		Distinct().
+		Pluck("user_id", &banned).Error; err != nil {
+		return nil, err
+	}
+	if len(banned) == 0 {
+		return entries, nil
+	}
+
+	if err := h.db.DB().Where("user_id IN ?", banned).Delete(&models.MatchmakingEntry{}).Error; err != nil {
+		return nil, err
+	}
+
+	isBanned := make(map[uuid.UUID]bool, len(banned))
+	for _, id := range banned {
+		isBanned[id] = true
+	}
+
+	kept := entries[:0]
+	for _, entry := range entries {
+		if !isBanned[entry.UserID] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+func groupHasBlock(group []models.MatchmakingEntry, blocked map[uuid.UUID]map[uuid.UUID]bool) bool {
+	for i, a := range group {
+		for _, b := range group[i+1:] {
+			if blocked[a.UserID][b.UserID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *MatchmakingHandler) createMatch(entries []models.MatchmakingEntry) error {
+	return h.db.DB().Transaction(func(tx *gorm.DB) error {
+		userIDs := make([]uuid.UUID, len(entries))
+		for i, entry := range entries {
+			userIDs[i] = entry.UserID
+		}
+
+		result := tx.Where("user_id IN ?", userIDs).Delete(&models.MatchmakingEntry{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if int(result.RowsAffected) != len(entries) {
+			return fmt.Errorf("matchmaking entries changed while matching")
+		}
+
+		lobby := models.Lobby{
+			ID:               uuid.New(),
+			Name:             fmt.Sprintf("Ranked match %s", time.Now().UTC().Format("2006-01-02 15:04")),
+			Type:             "public",
+			OwnerID:          userIDs[0],
+			Status:           "waiting",
+			MaxPlayers:       len(entries),
+			GameMode:         "ranked",
+			PrivacyLevel:     "invite_only",
+			SpectatorAllowed: true,
+			CurrentPlayers:   len(entries),
+		}
+		if err := tx.Create(&lobby).Error; err != nil {
+			return err
+		}
+
+		game := models.Game{
+			ID:                  uuid.New(),
+			LobbyID:             lobby.ID,
+			Status:              "waiting",
+			OwnerID:             userIDs[0],
+			CurrentTurnPlayerID: uuid.Nil,
+			RoundNumber:         1,
+			Winner:              "none",
+		}
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+
+		var firstPlayerID uuid.UUID
+		for i, userID := range userIDs {
+			player := models.Player{
+				ID:      uuid.New(),
+				LobbyID: lobby.ID,
+				GameID:  game.ID,
+				UserID:  userID,
+				Role:    fmt.Sprintf("player%d", i+1),
+			}
+			if err := tx.Create(&player).Error; err != nil {
+				return err
+			}
+			if i == 0 {
+				firstPlayerID = player.ID
+			}
+		}
+
+		if err := tx.Model(&game).Update("current_turn_player_id", firstPlayerID).Error; err != nil {
+			return err
+		}
+
+		for _, userID := range userIDs {
+			if err := service.CreateNotification(tx, userID, "match_found", fiber.Map{
+				"lobby_id":   lobby.ID,
+				"game_id":    game.ID,
+				"lobby_name": lobby.Name,
+				"message":    "A ranked match has been found",
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/moderation"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const maxDirectMessageLength = 1000
+
+type MessageHandler struct {
+	db     database.Service
+	filter *moderation.Filter
+}
+
+type SendMessageRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+func NewMessageHandler(db database.Service, filter *moderation.Filter) *MessageHandler {
+	return &MessageHandler{
+		db:     db,
+		filter: filter,
+	}
+}
+
+// Send delivers a private message to another user, who gets it live as a
+// direct_message notification. Users who blocked each other cannot message
+// one another, and muted users cannot message anyone. Blocked words are
+// masked before the message is stored.
+func (h *MessageHandler) Send(c *fiber.Ctx) error {
+	senderID := c.Locals("user_id").(uuid.UUID)
+
+	recipientID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if recipientID == senderID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot message yourself")
+	}
+
+	var req SendMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		return utils.NewError(fiber.StatusBadRequest, "Message cannot be empty")
+	}
+	if utf8.RuneCountInString(body) > maxDirectMessageLength {
+		return utils.NewError(fiber.StatusBadRequest, fmt.Sprintf("Messages are limited to %d characters", maxDirectMessageLength))
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var recipient models.User
+	if err := db.Select("id").Where("id = ? AND is_bot = ?", recipientID, false).First(&recipient).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	mute, err := activeMute(db, senderID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking mutes")
+	}
+	if mute != nil {
+		return utils.NewError(fiber.StatusForbidden, describeMute(mute)).WithCode(string(CodeMuted))
+	}
+
+	blocked, err := service.IsBlocked(db, senderID, recipientID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking block list")
+	}
+	if blocked {
+		return utils.NewError(fiber.StatusForbidden, "You cannot message this user")
+	}
+
+	var sender models.User
+	if err := db.Select("id", "name").Where("id = ?", senderID).First(&sender).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	message := models.DirectMessage{
+		ID:          uuid.New(),
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Body:        h.filter.Mask(body),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	tx := db.Begin()
+
+	if err := tx.Create(&message).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error sending message")
+	}
+
+	if err := service.CreateNotification(tx, recipientID, "direct_message", fiber.Map{
+		"message_id":  message.ID,
+		"sender_id":   message.SenderID,
+		"sender_name": sender.Name,
+		"body":        message.Body,
+	}); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error notifying recipient")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(message)
+}
+
+// Conversation lists the messages between the caller and another user,
+// newest first. Pass the returned next_cursor as before to page back.
+func (h *MessageHandler) Conversation(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	otherID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	limit := utils.ParseLimit(c.Query("limit"), 50, 100)
+
+	query := h.db.DB().WithContext(c.UserContext()).
+		Where("((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?))",
+			userID, otherID, otherID, userID)
+
+	if raw := c.Query("before"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var messages []models.DirectMessage
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching messages")
+	}
+
+	var nextCursor *string
+	if len(messages) > limit {
+		messages = messages[:limit]
+		last := messages[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        messages,
+		"next_cursor": nextCursor,
+	})
+}
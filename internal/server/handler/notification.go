@@ -3,6 +3,7 @@ package handler
 import (
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/server/utils"
 	"encoding/json"
 	"time"
 
@@ -11,7 +12,8 @@ import (
 )
 
 type NotificationHandler struct {
-	db database.Service
+	db  database.Service
+	hub *GameHub
 }
 
 type NotificationResponse struct {
@@ -22,37 +24,49 @@ type NotificationResponse struct {
 	CreatedAt time.Time       `json:"created_at"`
 }
 
-func NewNotificationHandler(db database.Service) *NotificationHandler {
+func NewNotificationHandler(db database.Service, hub *GameHub) *NotificationHandler {
 	return &NotificationHandler{
-		db: db,
+		db:  db,
+		hub: hub,
 	}
 }
 
 func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
-	sessionID := c.Cookies("session_id")
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
-	}
+	limit := utils.ParseLimit(c.Query("limit"), 50, 100)
+	query := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID)
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	} else if before := c.Query("before"); before != "" {
+		var anchor models.Notification
+		if err := h.db.DB().WithContext(c.UserContext()).Where("id = ? AND user_id = ?", before, userID).First(&anchor).Error; err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid before parameter")
+		}
+		query = query.Where("(created_at, id) < (?, ?)", anchor.CreatedAt, anchor.ID)
 	}
 
 	var notifications []models.Notification
-	if err := h.db.DB().Where("user_id = ?", user.ID).
-		Order("created_at DESC").
-		Limit(50).
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
 		Find(&notifications).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching notifications",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching notifications")
+	}
+
+	var nextCursor *string
+	var nextBefore *uuid.UUID
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+		last := notifications[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+		nextBefore = &last.ID
 	}
 
 	response := make([]NotificationResponse, len(notifications))
@@ -66,73 +80,95 @@ func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(response)
+	return c.JSON(fiber.Map{
+		"data":        response,
+		"next_cursor": nextCursor,
+		"next_before": nextBefore,
+	})
 }
 
 func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
 	notificationID := c.Params("id")
-	sessionID := c.Cookies("session_id")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result := h.db.DB().WithContext(c.UserContext()).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", time.Now())
 
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error marking notification as read")
 	}
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Notification not found")
 	}
 
-	result := h.db.DB().Model(&models.Notification{}).
-		Where("id = ? AND user_id = ?", notificationID, user.ID).
-		Update("read_at", time.Now())
+	return c.JSON(fiber.Map{
+		"message": "Notification marked as read",
+	})
+}
 
+func (h *NotificationHandler) UnreadCount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var count int64
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error counting notifications")
+	}
+
+	return c.JSON(fiber.Map{
+		"count": count,
+	})
+}
+
+func (h *NotificationHandler) Destroy(c *fiber.Ctx) error {
+	notificationID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid notification ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result := h.db.DB().WithContext(c.UserContext()).Where("id = ? AND user_id = ?", notificationID, userID).Delete(&models.Notification{})
 	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error marking notification as read",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error deleting notification")
 	}
 
 	if result.RowsAffected == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Notification not found",
-		})
+		return utils.NewError(fiber.StatusNotFound, "Notification not found")
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Notification marked as read",
+		"message": "Notification deleted",
 	})
 }
 
-func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
-	sessionID := c.Cookies("session_id")
+// DestroyRead deletes every notification the user has already read.
+func (h *NotificationHandler) DestroyRead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	var session models.Session
-	if err := h.db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid session",
-		})
+	result := h.db.DB().WithContext(c.UserContext()).Where("user_id = ? AND read_at IS NOT NULL", userID).Delete(&models.Notification{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error deleting notifications")
 	}
 
-	var user models.User
-	if err := h.db.DB().First(&user, session.UserID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error fetching user",
-		})
-	}
+	return c.JSON(fiber.Map{
+		"message": "Read notifications deleted",
+		"deleted": result.RowsAffected,
+	})
+}
+
+func (h *NotificationHandler) MarkAllAsRead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
 
-	result := h.db.DB().Model(&models.Notification{}).
-		Where("user_id = ? AND read_at IS NULL", user.ID).
+	result := h.db.DB().WithContext(c.UserContext()).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
 		Update("read_at", time.Now())
 
 	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error marking notifications as read",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error marking notifications as read")
 	}
 
 	return c.JSON(fiber.Map{
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+func notificationCategoryNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, category := range service.NotificationCategories {
+		if !seen[category] {
+			seen[category] = true
+			names = append(names, category)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (h *NotificationHandler) GetPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	preferences, err := h.loadPreferences(userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching notification preferences")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": preferences,
+	})
+}
+
+// UpdatePreferences takes a map of category to enabled flag. Categories left
+// out keep their current setting.
+func (h *NotificationHandler) UpdatePreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req map[string]bool
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	known := make(map[string]bool)
+	for _, category := range notificationCategoryNames() {
+		known[category] = true
+	}
+
+	now := time.Now()
+	preferences := make([]models.NotificationPreference, 0, len(req))
+	for category, enabled := range req {
+		if !known[category] {
+			return utils.NewError(fiber.StatusBadRequest, "Unknown notification category: "+category)
+		}
+		preferences = append(preferences, models.NotificationPreference{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Category:  category,
+			Enabled:   enabled,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+
+	if len(preferences) > 0 {
+		if err := h.db.DB().WithContext(c.UserContext()).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "category"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&preferences).Error; err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error updating notification preferences")
+		}
+	}
+
+	updated, err := h.loadPreferences(userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching notification preferences")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Notification preferences updated",
+		"data":    updated,
+	})
+}
+
+// loadPreferences returns every category with the user's setting, defaulting
+// to enabled.
+func (h *NotificationHandler) loadPreferences(userID uuid.UUID) (map[string]bool, error) {
+	var stored []models.NotificationPreference
+	if err := h.db.DB().Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	preferences := make(map[string]bool)
+	for _, category := range notificationCategoryNames() {
+		preferences[category] = true
+	}
+	for _, preference := range stored {
+		preferences[preference.Category] = preference.Enabled
+	}
+	return preferences, nil
+}
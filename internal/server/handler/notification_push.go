@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/service"
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Stream holds a socket open for the signed-in user and pushes each of their
+// notifications to it as soon as it is created. The upgrade request has been
+// through the auth middleware, which left the user on the locals.
+func (h *NotificationHandler) Stream(c *websocket.Conn) {
+	userID, ok := c.Locals("user_id").(uuid.UUID)
+	if !ok {
+		c.WriteJSON(GameMessage{
+			Type:    "notification_error",
+			Payload: fiber.Map{"error": "Invalid Session"},
+		})
+		c.Close()
+		return
+	}
+
+	keepAlive(c)
+	registered := h.hub.register(c, Client{
+		UserId: userID.String(),
+		GameId: notificationRoom(userID.String()),
+	})
+	if registered == nil {
+		c.Close()
+		return
+	}
+
+	defer func() {
+		h.hub.unregister(c)
+		<-registered.done
+	}()
+
+	var unread int64
+	if err := h.db.DB().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&unread).Error; err == nil {
+		h.hub.SendToConn(c, GameMessage{
+			Type:    "notifications_unread",
+			Payload: fiber.Map{"count": unread},
+		})
+	}
+
+	// Clients have nothing to say on this socket; reading only notices when
+	// it closes.
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Error reading notification socket: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// RunPusher listens for newly committed notifications and pushes them to the
+// recipients' sockets on this instance, reconnecting if the listener drops.
+func (h *NotificationHandler) RunPusher(retry time.Duration) {
+	for {
+		err := h.db.Listen(context.Background(), service.NotificationChannel, h.push)
+		log.Printf("Notification listener stopped: %v", err)
+		time.Sleep(retry)
+	}
+}
+
+func (h *NotificationHandler) push(notificationID string) {
+	var notification models.Notification
+	if err := h.db.DB().Where("id = ?", notificationID).First(&notification).Error; err != nil {
+		log.Printf("Error loading notification %s: %v", notificationID, err)
+		return
+	}
+
+	notificationType := ""
+	if notification.Type != nil {
+		notificationType = *notification.Type
+	}
+
+	h.hub.pushNotification(notification.UserID.String(), GameMessage{
+		Type: "notification",
+		Payload: NotificationResponse{
+			ID:        notification.ID,
+			Type:      notificationType,
+			Data:      notification.Data,
+			Read:      notification.ReadAt,
+			CreatedAt: notification.CreatedAt,
+		},
+	})
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+type TransferOwnershipRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+func (h *LobbyHandler) TransferOwnership(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req TransferOwnershipRequest
+	if err := c.BodyParser(&req); err != nil || req.UserID == uuid.Nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.UserID == userID {
+		return utils.NewError(fiber.StatusBadRequest, "You already own this lobby")
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if lobby.OwnerID != userID {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusForbidden, "Only the lobby owner can transfer ownership")
+	}
+
+	var target models.Player
+	if err := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, req.UserID).First(&target).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "User is not a player in this lobby")
+	}
+
+	if target.IsBot {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Ownership cannot be transferred to a bot")
+	}
+
+	if err := service.TransferLobbyOwnership(tx, &lobby, req.UserID); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error transferring lobby ownership")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	h.broadcastOwnerChanged(lobby.ID, userID, req.UserID)
+
+	return c.JSON(fiber.Map{
+		"message":  "Lobby ownership transferred",
+		"owner_id": req.UserID,
+	})
+}
+
+func (h *LobbyHandler) broadcastOwnerChanged(lobbyID, previousOwnerID, newOwnerID uuid.UUID) {
+	h.broadcastToLobby(lobbyID, GameMessage{
+		Type: "lobby_owner_changed",
+		Payload: fiber.Map{
+			"lobby_id":          lobbyID,
+			"previous_owner_id": previousOwnerID,
+			"owner_id":          newOwnerID,
+		},
+	})
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/mail"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const passwordResetTTL = 60 * time.Minute
+
+type PasswordHandler struct {
+	db       database.Service
+	mailer   mail.Mailer
+	frontend string
+	audit    service.AuditService
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Email                string `json:"email" validate:"required,email"`
+	Token                string `json:"token" validate:"required"`
+	Password             string `json:"password" validate:"required,min=6"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+func NewPasswordHandler(db database.Service, mailer mail.Mailer, frontendURL string, audit service.AuditService) *PasswordHandler {
+	return &PasswordHandler{
+		db:       db,
+		mailer:   mailer,
+		frontend: strings.TrimRight(frontendURL, "/"),
+		audit:    audit,
+	}
+}
+
+// Forgot always answers the same way so it cannot be used to discover which
+// emails have accounts.
+func (h *PasswordHandler) Forgot(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	response := fiber.Map{
+		"message": "If that email is registered, a reset link has been sent",
+	}
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Where("email = ? AND is_bot = ?", req.Email, false).First(&user).Error; err != nil {
+		return c.JSON(response)
+	}
+
+	token := utils.GenerateToken()
+	if token == "" {
+		return utils.NewError(fiber.StatusInternalServerError, "Error generating token")
+	}
+
+	now := time.Now()
+	if err := h.db.DB().WithContext(c.UserContext()).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "created_at"}),
+	}).Create(&models.PasswordResetToken{
+		Email:     user.Email,
+		Token:     utils.HashToken(token),
+		CreatedAt: &now,
+	}).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating reset token")
+	}
+
+	link := fmt.Sprintf("%s/password/reset?token=%s&email=%s",
+		h.frontend, url.QueryEscape(token), url.QueryEscape(user.Email))
+
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Name, int(passwordResetTTL.Minutes()), link),
+	}); err != nil {
+		log.Printf("Error sending password reset email: %v", err)
+	}
+
+	return c.JSON(response)
+}
+
+func (h *PasswordHandler) Reset(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var resetToken models.PasswordResetToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("email = ?", req.Email).First(&resetToken).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Invalid or expired reset token")
+	}
+
+	expired := resetToken.CreatedAt == nil || time.Since(*resetToken.CreatedAt) > passwordResetTTL
+	matches := subtle.ConstantTimeCompare([]byte(resetToken.Token), []byte(utils.HashToken(req.Token))) == 1
+	if expired || !matches {
+		if expired {
+			tx.Delete(&resetToken)
+			tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		return utils.NewError(fiber.StatusBadRequest, "Invalid or expired reset token")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error hashing password")
+	}
+
+	var user models.User
+	if err := tx.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Invalid or expired reset token")
+	}
+
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"password":      string(hashedPassword),
+		"failed_logins": 0,
+		"locked_until":  nil,
+		"updated_at":    time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating password")
+	}
+
+	if err := tx.Delete(&resetToken).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error invalidating reset token")
+	}
+
+	if err := revokeSessions(tx, user); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error signing out other sessions")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		ActorID:    &user.ID,
+		Action:     "password_reset",
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	})
+
+	return c.JSON(fiber.Map{
+		"message": "Password has been reset",
+	})
+}
+
+// revokeSessions signs the user out everywhere, so a reset also locks out
+// whoever may have had the old password.
+func revokeSessions(tx *gorm.DB, user models.User) error {
+	return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cards on a pile are ordered bottom to top by pile_position. Cards laid
+// before positions were tracked have none and sit at the bottom.
+const (
+	pileOrder        = "pile_position ASC NULLS FIRST, updated_at ASC, id ASC"
+	pileOrderFromTop = "pile_position DESC NULLS LAST, updated_at DESC, id DESC"
+)
+
+// PileState is one of a game's face-up piles, bottom card first.
+type PileState struct {
+	Size  int        `json:"size"`
+	Top   *GameCard  `json:"top"`
+	Cards []GameCard `json:"cards"`
+}
+
+// stackOnPile lays cards on top of the game's play pile in the order given.
+func stackOnPile(tx *gorm.DB, gameID uuid.UUID, updates []cardUpdate) error {
+	// Positions follow the highest one in use, so two stacks must not read
+	// it at once. Move handlers already hold this lock, in which case taking
+	// it again is free.
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return err
+	}
+
+	var top int
+	if err := tx.Model(&models.Card{}).
+		Select("COALESCE(MAX(pile_position), 0)").
+		Where("game_id = ?", gameID).
+		Scan(&top).Error; err != nil {
+		return err
+	}
+
+	for i := range updates {
+		position := top + i + 1
+		updates[i].PilePosition = &position
+	}
+	return batchUpdateCards(tx, updates)
+}
+
+// loadPile returns the cards at locationType, either "play_pile" or
+// "burned", bottom card first.
+func loadPile(tx *gorm.DB, gameID uuid.UUID, locationType string) (PileState, error) {
+	var cards []models.Card
+	if err := tx.Where("game_id = ? AND location_type = ?", gameID, locationType).
+		Order(pileOrder).
+		Find(&cards).Error; err != nil {
+		return PileState{}, err
+	}
+
+	pile := PileState{
+		Size:  len(cards),
+		Cards: make([]GameCard, len(cards)),
+	}
+	for i, card := range cards {
+		pile.Cards[i] = toGameCard(card, uuid.Nil)
+	}
+	if len(cards) > 0 {
+		pile.Top = &pile.Cards[len(cards)-1]
+	}
+	return pile, nil
+}
+
+// withPile adds the top card of the play pile and the pile's size to a
+// game_update payload, so clients never have to rebuild the pile from
+// individual moves.
+func (h *GameHandler) withPile(gameID uuid.UUID, payload fiber.Map) fiber.Map {
+	var size int64
+	if err := h.db.DB().Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "play_pile").
+		Count(&size).Error; err != nil {
+		log.Printf("Error counting play pile of game %s: %v", gameID, err)
+		return payload
+	}
+
+	payload["pile_size"] = size
+	payload["pile_top"] = nil
+	if size == 0 {
+		return payload
+	}
+
+	var top models.Card
+	if err := h.db.DB().Where("game_id = ? AND location_type = ?", gameID, "play_pile").
+		Order(pileOrderFromTop).
+		First(&top).Error; err != nil {
+		log.Printf("Error loading top of play pile of game %s: %v", gameID, err)
+		return payload
+	}
+	payload["pile_top"] = toGameCard(top, uuid.Nil)
+	return payload
+}
+
+// Piles returns the play pile and the burned pile of a game, bottom card
+// first, along with how many cards are left to draw. It is open to everyone
+// allowed to watch the game.
+func (h *GameHandler) Piles(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if _, err := h.games.ConnectionRole(c.UserContext(), gameID.String(), userID); err != nil {
+		if errors.Is(err, service.ErrGameNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "Game not found")
+		}
+		return utils.NewError(fiber.StatusForbidden, "Not allowed to view this game")
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	playPile, err := loadPile(db, gameID, "play_pile")
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching play pile")
+	}
+
+	burned, err := loadPile(db, gameID, "burned")
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching burned pile")
+	}
+
+	var remaining int64
+	if err := db.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "deck").
+		Count(&remaining).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error counting deck")
+	}
+
+	return c.JSON(fiber.Map{
+		"game_id":         gameID,
+		"play_pile":       playPile,
+		"burned":          burned,
+		"remaining_cards": remaining,
+	})
+}
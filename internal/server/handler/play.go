@@ -0,0 +1,528 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/rules"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (h *GameHandler) playCards(ctx context.Context, session models.Session, payload PlayCardPayload) error {
+	cardIDs := payload.cards()
+	if len(cardIDs) == 0 {
+		return rejectMove(CodeInvalidPayload, "No cards to play", nil)
+	}
+
+	parsedGameID := payload.GameID
+	gameID := parsedGameID.String()
+
+	tx := h.db.DB().WithContext(ctx).Begin()
+
+	// Locking the game serialises moves, so two plays, or a play and the
+	// turn timer, cannot both pass the turn check below.
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", parsedGameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	if game.Status != "in_progress" {
+		tx.Rollback()
+		return rejectMove(CodeGameNotStarted, "The game is not in progress", cardIDs)
+	}
+
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", parsedGameID, session.UserID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You are not a player in this game", cardIDs)
+	}
+
+	if game.CurrentTurnPlayerID != player.ID {
+		tx.Rollback()
+		return rejectMove(CodeNotYourTurn, "It is not your turn", cardIDs)
+	}
+
+	var cards []models.Card
+	if err := tx.Where("id IN ? AND game_id = ?", cardIDs, parsedGameID).Find(&cards).Error; err != nil || len(cards) != len(cardIDs) {
+		tx.Rollback()
+		return rejectMove(CodeCardNotFound, "Card not found in this game", cardIDs)
+	}
+
+	counts, err := playerCardCounts(tx, player.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error counting player cards: %w", err)
+	}
+	phase := rules.CurrentPhase(counts["hand"], counts["faceup"], counts["hidden"])
+
+	cfg, err := gameRules(tx, parsedGameID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error loading game rules: %w", err)
+	}
+
+	if phase == rules.PhaseFaceDown {
+		return h.blindPlay(tx, cfg, gameID, parsedGameID, player.ID, cards)
+	}
+
+	values := make([]string, len(cards))
+	updates := make([]cardUpdate, len(cards))
+	for i, card := range cards {
+		if card.PlayerID == nil || *card.PlayerID != player.ID {
+			tx.Rollback()
+			return rejectMove(CodeCardNotOwned, "Card does not belong to you", cardIDs)
+		}
+
+		if err := rules.CanPlayFrom(phase, card.Status); err != nil {
+			tx.Rollback()
+			return rejectMove(CodeWrongPhase, err.Error(), cardIDs)
+		}
+
+		values[i] = card.Value
+		updates[i] = cardUpdate{
+			ID:           card.ID,
+			Status:       "played",
+			LocationType: "play_pile",
+		}
+	}
+
+	outcome, drawn, err := h.applyPlay(tx, cfg, parsedGameID, player.ID, values, updates)
+	if err != nil {
+		tx.Rollback()
+		if isRuleError(err) {
+			return rejectMove(invalidPlayCode(err), err.Error(), cardIDs)
+		}
+		return fmt.Errorf("error applying play: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	if !outcome.ExtraTurn {
+		h.startTurnTimer(parsedGameID)
+	}
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "game_update",
+		Payload: h.withPile(parsedGameID, fiber.Map{
+			"cards_played": cards,
+			"game_id":      parsedGameID.String(),
+			"burned":       outcome.Burned,
+			"extra_turn":   outcome.ExtraTurn,
+		}),
+	})
+
+	h.broadcastDraw(gameID, player.ID, drawn)
+
+	h.announceGameOver(parsedGameID)
+
+	return nil
+}
+
+// broadcastCardsDrawn shows the drawing player the cards they picked up and
+// tells everyone else only how many were drawn.
+func (h *GameHandler) broadcastCardsDrawn(gameID string, playerID uuid.UUID, drawn []models.Card) {
+	var player models.Player
+	if err := h.db.DB().Where("id = ?", playerID).First(&player).Error; err != nil {
+		log.Printf("Error loading player %s for drawn cards: %v", playerID, err)
+		return
+	}
+
+	public := make([]GameCard, len(drawn))
+	for i, card := range drawn {
+		public[i] = toGameCard(card, uuid.Nil)
+	}
+
+	h.hub.SendToUser(player.UserID.String(), GameMessage{
+		Type: "cards_drawn",
+		Payload: fiber.Map{
+			"game_id":   gameID,
+			"player_id": playerID,
+			"cards":     drawn,
+		},
+	})
+
+	h.hub.BroadcastToGameExcept(gameID, player.UserID.String(), GameMessage{
+		Type: "cards_drawn",
+		Payload: fiber.Map{
+			"game_id":   gameID,
+			"player_id": playerID,
+			"cards":     public,
+		},
+	})
+}
+
+// blindPlay turns over a single face-down card. A legal card is played as
+// usual; otherwise it is revealed onto the pile and the player picks the whole
+// pile up. Takes ownership of tx.
+func (h *GameHandler) blindPlay(tx *gorm.DB, cfg rules.Config, gameID string, parsedGameID, playerID uuid.UUID, cards []models.Card) error {
+	cardIDs := make([]uuid.UUID, len(cards))
+	for i, card := range cards {
+		cardIDs[i] = card.ID
+	}
+
+	if len(cards) != 1 {
+		tx.Rollback()
+		return rejectMove(CodeBlindPlaySingle, "Face-down cards are played one at a time", cardIDs)
+	}
+
+	card := cards[0]
+	if card.PlayerID == nil || *card.PlayerID != playerID {
+		tx.Rollback()
+		return rejectMove(CodeCardNotOwned, "Card does not belong to you", cardIDs)
+	}
+
+	if card.Status != string(rules.PhaseFaceDown) {
+		tx.Rollback()
+		return rejectMove(CodeWrongPhase, rules.ErrWrongPhase.Error(), cardIDs)
+	}
+
+	pile, err := playPileValues(tx, parsedGameID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error fetching play pile: %w", err)
+	}
+
+	if rules.CanPlay(cfg, pile, []string{card.Value}) == nil {
+		outcome, drawn, err := h.applyPlay(tx, cfg, parsedGameID, playerID, []string{card.Value}, []cardUpdate{{
+			ID:           card.ID,
+			Status:       "played",
+			LocationType: "play_pile",
+		}})
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error applying blind play: %w", err)
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			return fmt.Errorf("error committing transaction: %w", err)
+		}
+
+		if !outcome.ExtraTurn {
+			h.startTurnTimer(parsedGameID)
+		}
+
+		h.hub.BroadcastToGame(gameID, GameMessage{
+			Type: "game_update",
+			Payload: h.withPile(parsedGameID, fiber.Map{
+				"cards_played": cards,
+				"game_id":      parsedGameID.String(),
+				"blind":        true,
+				"burned":       outcome.Burned,
+				"extra_turn":   outcome.ExtraTurn,
+			}),
+		})
+
+		h.broadcastDraw(gameID, playerID, drawn)
+
+		h.announceGameOver(parsedGameID)
+		return nil
+	}
+
+	if err := stackOnPile(tx, parsedGameID, []cardUpdate{{
+		ID:           card.ID,
+		Status:       "played",
+		LocationType: "play_pile",
+	}}); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error revealing face-down card: %w", err)
+	}
+
+	if err := recordGameEvent(tx, parsedGameID, "reveal", &playerID, fiber.Map{
+		"card_id": card.ID,
+		"value":   card.Value,
+	}); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error recording face-down reveal: %w", err)
+	}
+
+	pickedUp, err := h.applyPickUp(tx, parsedGameID, playerID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error picking up pile after blind play: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	h.startTurnTimer(parsedGameID)
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "blind_play_failed",
+		Payload: fiber.Map{
+			"game_id":        parsedGameID.String(),
+			"player_id":      playerID,
+			"revealed_card":  card,
+			"pile_picked_up": true,
+			"card_count":     pickedUp,
+		},
+	})
+
+	return nil
+}
+
+func (h *GameHandler) pickUpPile(ctx context.Context, session models.Session, payload GamePayload) error {
+	parsedGameID := payload.GameID
+	gameID := parsedGameID.String()
+
+	tx := h.db.DB().WithContext(ctx).Begin()
+
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", parsedGameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	if game.Status != "in_progress" {
+		tx.Rollback()
+		return rejectMove(CodeGameNotStarted, "The game is not in progress", nil)
+	}
+
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", parsedGameID, session.UserID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You are not a player in this game", nil)
+	}
+
+	if game.CurrentTurnPlayerID != player.ID {
+		tx.Rollback()
+		return rejectMove(CodeNotYourTurn, "It is not your turn", nil)
+	}
+
+	if err := checkPickUpAllowed(tx, parsedGameID, player.ID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, rules.ErrMustPlay) {
+			return rejectMove(CodeMustPlay, err.Error(), nil)
+		}
+		return fmt.Errorf("error checking pile pick up: %w", err)
+	}
+
+	pickedUp, err := h.applyPickUp(tx, parsedGameID, player.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error picking up pile: %w", err)
+	}
+
+	if pickedUp == 0 {
+		tx.Rollback()
+		return rejectMove(CodePileEmpty, "There is no pile to pick up", nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	h.startTurnTimer(parsedGameID)
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "game_update",
+		Payload: h.withPile(parsedGameID, fiber.Map{
+			"pile_picked_up": true,
+			"player_id":      player.ID,
+			"card_count":     pickedUp,
+			"game_id":        parsedGameID.String(),
+		}),
+	})
+
+	return nil
+}
+
+// applyPlay lays the given cards on the pile inside tx, burning the pile and
+// advancing the turn as the rules dictate. The player's hand is refilled from
+// the deck and any cards drawn are returned alongside the outcome. A player
+// who sheds their last card is placed, which may end the game.
+func (h *GameHandler) applyPlay(tx *gorm.DB, cfg rules.Config, gameID, playerID uuid.UUID, values []string, updates []cardUpdate) (rules.Outcome, deckDraw, error) {
+	pile, err := playPileValues(tx, gameID)
+	if err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+
+	outcome, err := rules.ResolvePlay(cfg, pile, values)
+	if err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+
+	if err := stackOnPile(tx, gameID, updates); err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+
+	cardIDs := make([]uuid.UUID, len(updates))
+	for i, update := range updates {
+		cardIDs[i] = update.ID
+	}
+	if err := recordGameEvent(tx, gameID, "play", &playerID, fiber.Map{
+		"card_ids":   cardIDs,
+		"values":     values,
+		"extra_turn": outcome.ExtraTurn,
+	}); err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+
+	if outcome.Burned {
+		result := tx.Model(&models.Card{}).
+			Where("game_id = ? AND location_type = ?", gameID, "play_pile").
+			Updates(map[string]interface{}{
+				"status":        "burned",
+				"location_type": "burned",
+			})
+		if result.Error != nil {
+			return rules.Outcome{}, deckDraw{}, result.Error
+		}
+
+		if err := recordGameEvent(tx, gameID, "burn", &playerID, fiber.Map{
+			"card_count": result.RowsAffected,
+		}); err != nil {
+			return rules.Outcome{}, deckDraw{}, err
+		}
+	}
+
+	drawn, err := h.refillHand(tx, cfg, gameID, playerID)
+	if err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+
+	finished, err := markPlayerFinished(tx, gameID, playerID)
+	if err != nil {
+		return rules.Outcome{}, deckDraw{}, err
+	}
+	if finished {
+		outcome.ExtraTurn = false
+
+		over, err := completeGameIfOver(tx, gameID)
+		if err != nil {
+			return rules.Outcome{}, deckDraw{}, err
+		}
+		if over {
+			return outcome, drawn, nil
+		}
+	}
+
+	if !outcome.ExtraTurn {
+		if err := h.moveToNextPlayer(tx, gameID); err != nil {
+			return rules.Outcome{}, deckDraw{}, err
+		}
+	}
+
+	return outcome, drawn, nil
+}
+
+// refillHand draws from the deck until the player holds a full hand again or
+// the deck runs out.
+func (h *GameHandler) refillHand(tx *gorm.DB, cfg rules.Config, gameID, playerID uuid.UUID) (deckDraw, error) {
+	counts, err := playerCardCounts(tx, playerID)
+	if err != nil {
+		return deckDraw{}, err
+	}
+
+	return drawFromDeck(tx, cfg, gameID, playerID, rules.CardsToDraw(cfg, counts["hand"]))
+}
+
+func (h *GameHandler) applyPickUp(tx *gorm.DB, gameID, playerID uuid.UUID) (int64, error) {
+	result := tx.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "play_pile").
+		Updates(map[string]interface{}{
+			"status":        "hand",
+			"location_type": "player",
+			"player_id":     playerID,
+			"pile_position": nil,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return 0, nil
+	}
+
+	if err := recordGameEvent(tx, gameID, "pickup", &playerID, fiber.Map{
+		"card_count": result.RowsAffected,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := h.moveToNextPlayer(tx, gameID); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected, nil
+}
+
+// checkPickUpAllowed enforces the lobby's allow_pickup_choice setting: with
+// it turned off the pile can only be taken when the player has nothing to
+// play on it. Picking up an empty pile is left for the caller to reject.
+func checkPickUpAllowed(tx *gorm.DB, gameID, playerID uuid.UUID) error {
+	cfg, err := gameRules(tx, gameID)
+	if err != nil || cfg.AllowPickupChoice {
+		return err
+	}
+
+	pile, err := playPileValues(tx, gameID)
+	if err != nil || len(pile) == 0 {
+		return err
+	}
+
+	counts, err := playerCardCounts(tx, playerID)
+	if err != nil {
+		return err
+	}
+	phase := rules.CurrentPhase(counts["hand"], counts["faceup"], counts["hidden"])
+
+	var values []string
+	if err := tx.Model(&models.Card{}).
+		Where("player_id = ? AND status = ?", playerID, string(phase)).
+		Pluck("value", &values).Error; err != nil {
+		return err
+	}
+
+	return rules.CanPickUp(cfg, phase, pile, values)
+}
+
+func playerCardCounts(tx *gorm.DB, playerID uuid.UUID) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := tx.Model(&models.Card{}).
+		Select("status, COUNT(*) AS count").
+		Where("player_id = ?", playerID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func playPileValues(tx *gorm.DB, gameID uuid.UUID) ([]string, error) {
+	var values []string
+	if err := tx.Model(&models.Card{}).
+		Where("game_id = ? AND location_type = ?", gameID, "play_pile").
+		Order(pileOrder).
+		Pluck("value", &values).Error; err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func isRuleError(err error) bool {
+	return errors.Is(err, rules.ErrTooLow) ||
+		errors.Is(err, rules.ErrMustPlayLower) ||
+		errors.Is(err, rules.ErrMixedValues) ||
+		errors.Is(err, rules.ErrEmptyPlay) ||
+		errors.Is(err, rules.ErrWrongPhase) ||
+		errors.Is(err, rules.ErrInvalidSwap) ||
+		errors.Is(err, rules.ErrMustPlay)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StartPracticeRequest struct {
+	Bots int `json:"bots" validate:"required,min=1,max=7"`
+}
+
+// StartPractice creates a private practice lobby for the current user with
+// the requested number of bots and starts the game straight away. Practice
+// games never touch ratings.
+func (h *GameHandler) StartPractice(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req StartPracticeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	var existingPlayer models.Player
+	err := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID).First(&existingPlayer).Error
+	if err == nil {
+		return utils.NewError(fiber.StatusForbidden, "You are already in another lobby")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking user's player status")
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	lobby := models.Lobby{
+		ID:               uuid.New(),
+		Name:             fmt.Sprintf("Practice %s", time.Now().UTC().Format("2006-01-02 15:04")),
+		Type:             "private",
+		OwnerID:          userID,
+		Status:           "in_progress",
+		MaxPlayers:       req.Bots + 1,
+		GameMode:         "practice",
+		PrivacyLevel:     "invite_only",
+		SpectatorAllowed: false,
+		CurrentPlayers:   1,
+	}
+	if err := tx.Create(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating lobby")
+	}
+
+	game := models.Game{
+		ID:                  uuid.New(),
+		LobbyID:             lobby.ID,
+		Status:              "waiting",
+		OwnerID:             userID,
+		CurrentTurnPlayerID: uuid.Nil,
+		RoundNumber:         1,
+		Winner:              "none",
+	}
+	if err := tx.Create(&game).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating game")
+	}
+
+	player := models.Player{
+		ID:      uuid.New(),
+		LobbyID: lobby.ID,
+		GameID:  game.ID,
+		UserID:  userID,
+		Role:    "player1",
+		IsReady: true,
+	}
+	if err := tx.Create(&player).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating player")
+	}
+
+	for i := 0; i < req.Bots; i++ {
+		if _, err := createBotPlayer(tx, &lobby, game.ID); err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error adding bot")
+		}
+	}
+
+	if err := tx.Model(&game).Update("current_turn_player_id", player.ID).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating game with player ID")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	// There is no one to wait for, so deal and start straight away.
+	if _, err := dealCards(h.db, h.decks, game.ID); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error dealing cards")
+	}
+
+	if err := h.beginSwapPhase(game.ID, player.ID); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error starting game")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"lobby_id":  lobby.ID,
+		"game_id":   game.ID,
+		"player_id": player.ID,
+		"status":    "setup",
+		"redirect":  fmt.Sprintf("/games/%s", game.ID),
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PresenceHandler struct {
+	users    service.UserService
+	presence service.PresenceService
+	hub      *GameHub
+}
+
+func NewPresenceHandler(users service.UserService, presence service.PresenceService, hub *GameHub) *PresenceHandler {
+	return &PresenceHandler{
+		users:    users,
+		presence: presence,
+		hub:      hub,
+	}
+}
+
+// Show returns a user's presence to anyone allowed to see their profile.
+func (h *PresenceHandler) Show(c *fiber.Ctx) error {
+	viewerID := c.Locals("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := h.users.Find(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	if viewerID != userID {
+		visible, err := h.users.CanViewProfile(c.UserContext(), viewerID, user)
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error checking privacy settings")
+		}
+		if !visible {
+			return utils.NewError(fiber.StatusForbidden, "This profile is private").WithCode("PROFILE_PRIVATE")
+		}
+	}
+
+	presence, err := h.presence.Get(c.UserContext(), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching presence")
+	}
+
+	return c.JSON(fiber.Map{
+		"user_id":  userID,
+		"presence": presence,
+	})
+}
+
+// Track is app-wide middleware recording the activity of every request that
+// turned out to be authenticated.
+func (h *PresenceHandler) Track(c *fiber.Ctx) error {
+	err := c.Next()
+	if userID, ok := c.Locals("user_id").(uuid.UUID); ok {
+		h.presence.Touch(userID)
+	}
+	return err
+}
+
+// Report records one open socket. Sockets in a game as a player put their
+// user in that game; any other socket only shows they are connected.
+func (h *PresenceHandler) Report(client Client) {
+	userID, err := uuid.Parse(client.UserId)
+	if err != nil {
+		return
+	}
+
+	if !client.Spectator && !isNotificationRoom(client.GameId) {
+		if gameID, err := uuid.Parse(client.GameId); err == nil {
+			h.presence.Connected(userID, &gameID)
+			return
+		}
+	}
+	h.presence.Connected(userID, nil)
+}
+
+// RunHeartbeat reports every socket open on this instance each interval, which
+// must stay below service.PresenceSocketTTL.
+func (h *PresenceHandler) RunHeartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, client := range h.hub.Clients() {
+			h.Report(client)
+		}
+	}
+}
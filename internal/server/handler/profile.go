@@ -1,174 +1,310 @@
 package handler
 
 import (
-	"api/internal/database"
+	"api/internal/avatar"
 	"api/internal/database/models"
+	"api/internal/game/progression"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"api/internal/storage"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
 type ProfileHandler struct {
-	db database.Service
+	users          service.UserService
+	stats          service.StatsService
+	audit          service.AuditService
+	avatars        storage.Storage
+	maxAvatarBytes int
 }
 
 type UpdateProfileRequest struct {
-	Name  string                `form:"name" validate:"required,max=255"`
-	Email string                `form:"email" validate:"required,email"`
-	Avatar *multipart.FileHeader `form:"avatar"`
+	Name        string                `form:"name" validate:"required,max=255"`
+	Email       string                `form:"email" validate:"required,email"`
+	DisplayName string                `form:"display_name" validate:"omitempty,max=50"`
+	Bio         string                `form:"bio" validate:"omitempty,max=500"`
+	Country     string                `form:"country" validate:"omitempty,min=2,max=2"`
+	Avatar      *multipart.FileHeader `form:"avatar"`
+}
+
+type UpdatePrivacyRequest struct {
+	ProfileVisibility string `json:"profile_visibility" validate:"required,oneof=public friends private"`
+	HideEmail         *bool  `json:"hide_email" validate:"required"`
+}
+
+// PublicProfile is what other players see of a profile.
+type PublicProfile struct {
+	ID             uuid.UUID            `json:"id"`
+	Name           string               `json:"name"`
+	DisplayName    *string              `json:"display_name"`
+	Bio            *string              `json:"bio"`
+	Country        *string              `json:"country"`
+	Email          string               `json:"email,omitempty"`
+	AvatarURL      *string              `json:"avatar_url,omitempty"`
+	AvatarVariants map[string]string    `json:"avatar_variants,omitempty"`
+	Level          int                  `json:"level"`
+	Progress       progression.Progress `json:"progress"`
+	Stats          *service.UserStats   `json:"stats,omitempty"`
+	CreatedAt      *time.Time           `json:"created_at"`
 }
 
 type UpdatePasswordRequest struct {
 	CurrentPassword string `json:"current_password" validate:"required"`
 	NewPassword     string `json:"new_password" validate:"required,min=8"`
-	ConfirmPassword string `json:"new_password_confirmation" validate:"required,min=8"`
+	ConfirmPassword string `json:"new_password_confirmation" validate:"required,eqfield=NewPassword"`
 }
 
-func NewProfileHandler(db database.Service) *ProfileHandler {
+func NewProfileHandler(users service.UserService, stats service.StatsService, audit service.AuditService, avatars storage.Storage, maxAvatarBytes int) *ProfileHandler {
 	return &ProfileHandler{
-		db: db,
+		users:          users,
+		stats:          stats,
+		audit:          audit,
+		avatars:        avatars,
+		maxAvatarBytes: maxAvatarBytes,
 	}
 }
 
+// Show returns the whole profile to its owner and admins. Anyone else gets
+// the public fields, and only if the owner's visibility setting lets them;
+// the email is left out when the owner hides it. Both include game stats.
 func (h *ProfileHandler) Show(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var user models.User
-
-	if err := h.db.DB().First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
+	}
+	h.fillAvatarURL(&user)
+
+	viewerID := c.Locals("user_id").(uuid.UUID)
+	viewer := user
+	if viewerID != user.ID {
+		if viewer, err = h.users.Find(c.UserContext(), viewerID); err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Database error")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
 	}
 
-	return c.JSON(user)
+	if viewerID == user.ID || viewer.IsAdmin {
+		stats, err := h.stats.UserStats(c.UserContext(), user.ID)
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error computing stats")
+		}
+		response := newUserResponse(user)
+		response.Stats = &stats
+		return c.JSON(response)
+	}
+
+	visible, err := h.users.CanViewProfile(c.UserContext(), viewerID, user)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	if !visible {
+		return utils.NewError(fiber.StatusForbidden, "This profile is private").WithCode("PROFILE_PRIVATE")
+	}
+
+	privacy, err := h.users.Privacy(c.UserContext(), user.ID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	profile := PublicProfile{
+		ID:             user.ID,
+		Name:           user.Name,
+		DisplayName:    user.DisplayName,
+		Bio:            user.Bio,
+		Country:        user.Country,
+		AvatarURL:      user.AvatarURL,
+		AvatarVariants: user.AvatarVariants,
+		Level:          progression.LevelFor(user.XP),
+		Progress:       progression.ProgressFor(user.XP),
+		CreatedAt:      user.CreatedAt,
+	}
+	if !privacy.HideEmail {
+		profile.Email = user.Email
+	}
+
+	stats, err := h.stats.UserStats(c.UserContext(), user.ID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error computing stats")
+	}
+	profile.Stats = &stats
+
+	return c.JSON(profile)
+}
+
+func (h *ProfileHandler) ShowPrivacy(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
+	}
+
+	settings, err := h.users.Privacy(c.UserContext(), user.ID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching privacy settings")
+	}
+	return c.JSON(settings)
+}
+
+func (h *ProfileHandler) UpdatePrivacy(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdatePrivacyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	settings := models.PrivacySettings{
+		UserID:            user.ID,
+		ProfileVisibility: req.ProfileVisibility,
+		HideEmail:         *req.HideEmail,
+	}
+	if err := h.users.UpdatePrivacy(c.UserContext(), &settings); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating privacy settings")
+	}
+
+	return c.JSON(settings)
 }
 
 func (h *ProfileHandler) Update(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var user models.User
-	if err := h.db.DB().First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
 	}
 
 	var req UpdateProfileRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	country := strings.ToUpper(req.Country)
+	if country != "" && !isCountryCode(country) {
+		return utils.ValidationFailed(c, map[string]string{"country": "Must be a two letter ISO 3166 country code"})
 	}
 
-	var existingUser models.User
-	result := h.db.DB().Where("email = ? AND id != ?", req.Email, id).First(&existingUser)
-	if result.Error == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Email already in use",
-		})
+	if taken, err := h.users.EmailInUse(c.UserContext(), req.Email, user.ID); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	} else if taken {
+		return utils.NewError(fiber.StatusBadRequest, "Email already in use")
 	}
 
+	var oldAvatar string
 	if file, err := c.FormFile("avatar"); err == nil {
-		ext := strings.ToLower(filepath.Ext(file.Filename))
-		if !isValidImageExt(ext) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid file type. Allowed types: jpeg, png, jpg, gif",
-			})
+		processed, err := h.processAvatar(file)
+		if err != nil {
+			return err
 		}
 
-		filename := fmt.Sprintf("avatars/%s%s", uuid.New().String(), ext)
+		filename := fmt.Sprintf("avatars/%s%s", uuid.New().String(), processed.Original.Ext)
 
-		if err := c.SaveFile(file, fmt.Sprintf("./public/%s", filename)); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error saving file",
-			})
+		if err := h.saveAvatar(c, filename, processed); err != nil {
+			log.Printf("Error storing avatar %s: %v", filename, err)
+			return utils.NewError(fiber.StatusInternalServerError, "Error saving file")
 		}
 
-		if *user.Avatar != "" {
-			if err := os.Remove(fmt.Sprintf("./public/%s", *user.Avatar)); err != nil {
-				fmt.Printf("Error deleting old avatar: %v\n", err)
-			}
+		if user.Avatar != nil {
+			oldAvatar = *user.Avatar
 		}
-
-		*user.Avatar = filename
+		user.Avatar = &filename
 	}
 
 	user.Name = req.Name
 	user.Email = req.Email
+	user.DisplayName = optionalString(req.DisplayName)
+	user.Bio = optionalString(req.Bio)
+	user.Country = optionalString(country)
 
-	if err := h.db.DB().Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating user",
-		})
+	if err := h.users.UpdateProfile(c.UserContext(), &user); err != nil {
+		if errors.Is(err, service.ErrEmailTaken) {
+			return utils.NewError(fiber.StatusBadRequest, "Email already in use")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating user")
 	}
 
+	if oldAvatar != "" {
+		h.deleteAvatar(c, oldAvatar)
+	}
+
+	h.fillAvatarURL(&user)
 	return c.JSON(fiber.Map{
-		"success": true,
+		"success":         true,
+		"avatar_url":      user.AvatarURL,
+		"avatar_variants": user.AvatarVariants,
 	})
 }
 
 func (h *ProfileHandler) UpdatePassword(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var user models.User
-	if err := h.db.DB().First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
 	}
 
 	var req UpdatePasswordRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
 	}
 
-	if req.NewPassword != req.ConfirmPassword {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Passwords do not match",
-		})
+	if err := h.users.ChangePassword(c.UserContext(), user.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrPasswordMismatch) {
+			return utils.NewError(fiber.StatusBadRequest, "Current password is incorrect")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Current password is incorrect",
-		})
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "password_change",
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+	})
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+func (h *ProfileHandler) Destroy(c *fiber.Ctx) error {
+	user, err := h.findUser(c)
+	if err != nil {
+		return err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	deleted, err := h.users.Delete(c.UserContext(), user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error hashing password",
-		})
+		return utils.NewError(fiber.StatusInternalServerError, "Error deleting user")
 	}
 
-	user.Password = string(hashedPassword)
-	if err := h.db.DB().Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error updating password",
-		})
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "profile_delete",
+		TargetType: "user",
+		TargetID:   user.ID.String(),
+		Details:    map[string]interface{}{"email": deleted.Email},
+	})
+
+	if deleted.Avatar != nil && *deleted.Avatar != "" {
+		h.deleteAvatar(c, *deleted.Avatar)
 	}
 
 	return c.JSON(fiber.Map{
@@ -176,43 +312,147 @@ func (h *ProfileHandler) UpdatePassword(c *fiber.Ctx) error {
 	})
 }
 
-func (h *ProfileHandler) Destroy(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var user models.User
-	if err := h.db.DB().First(&user, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "User not found",
-			})
+// findUser loads the user named by the :id route parameter.
+func (h *ProfileHandler) findUser(c *fiber.Ctx) (models.User, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return models.User{}, utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	user, err := h.users.Find(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return user, utils.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return user, utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+	return user, nil
+}
+
+// processAvatar reads and checks an uploaded avatar, mapping rejections to
+// client errors.
+func (h *ProfileHandler) processAvatar(file *multipart.FileHeader) (avatar.Processed, error) {
+	tooLarge := utils.NewError(fiber.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Avatar must be at most %d KB", h.maxAvatarBytes/1024)).WithCode("AVATAR_TOO_LARGE")
+	if file.Size > int64(h.maxAvatarBytes) {
+		return avatar.Processed{}, tooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return avatar.Processed{}, utils.NewError(fiber.StatusBadRequest, "Error reading file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, int64(h.maxAvatarBytes)+1))
+	if err != nil {
+		return avatar.Processed{}, utils.NewError(fiber.StatusBadRequest, "Error reading file")
+	}
+
+	processed, err := avatar.Process(data, h.maxAvatarBytes)
+	switch {
+	case errors.Is(err, avatar.ErrTooLarge):
+		return processed, tooLarge
+	case errors.Is(err, avatar.ErrUnsupportedType):
+		return processed, utils.NewError(fiber.StatusBadRequest, "Invalid file type. Allowed types: jpeg, png, gif").WithCode("AVATAR_INVALID_TYPE")
+	case errors.Is(err, avatar.ErrInvalidImage):
+		return processed, utils.NewError(fiber.StatusBadRequest, "Avatar image could not be read").WithCode("AVATAR_INVALID_IMAGE")
+	case err != nil:
+		return processed, utils.NewError(fiber.StatusInternalServerError, "Error processing avatar")
+	}
+	return processed, nil
+}
+
+// saveAvatar stores the avatar and its variants under key, removing what it
+// already wrote if any of them fails.
+func (h *ProfileHandler) saveAvatar(c *fiber.Ctx, key string, processed avatar.Processed) error {
+	files := map[string]avatar.File{key: processed.Original}
+	for size, variant := range processed.Variants {
+		files[avatar.VariantKey(key, size)] = variant
+	}
+
+	var written []string
+	for fileKey, file := range files {
+		if err := h.avatars.Put(c.UserContext(), fileKey, bytes.NewReader(file.Data), file.ContentType); err != nil {
+			for _, done := range written {
+				if err := h.avatars.Delete(c.UserContext(), done); err != nil {
+					log.Printf("Error cleaning up avatar %s: %v", done, err)
+				}
+			}
+			return err
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Database error",
-		})
+		written = append(written, fileKey)
 	}
+	return nil
+}
 
-	if *user.Avatar != "" {
-		if err := os.Remove(fmt.Sprintf("./public/%s", *user.Avatar)); err != nil {
-			fmt.Printf("Error deleting avatar: %v\n", err)
+// deleteAvatar removes an uploaded avatar and its variants. Avatars taken
+// over from a social login are external URLs and are left alone.
+func (h *ProfileHandler) deleteAvatar(c *fiber.Ctx, key string) {
+	if isExternalURL(key) {
+		return
+	}
+	keys := []string{key}
+	for _, size := range avatar.Sizes {
+		keys = append(keys, avatar.VariantKey(key, size))
+	}
+	for _, k := range keys {
+		if err := h.avatars.Delete(c.UserContext(), k); err != nil {
+			log.Printf("Error deleting avatar %s: %v", k, err)
 		}
 	}
+}
 
-	if err := h.db.DB().Delete(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error deleting user",
-		})
+// fillAvatarURL sets where clients can fetch the user's avatar and its
+// variants from. External avatars have no variants.
+func (h *ProfileHandler) fillAvatarURL(user *models.User) {
+	if user.Avatar == nil || *user.Avatar == "" {
+		return
 	}
+	if isExternalURL(*user.Avatar) {
+		user.AvatarURL = user.Avatar
+		return
+	}
+	url, err := h.avatars.URL(*user.Avatar)
+	if err != nil {
+		log.Printf("Error building avatar URL for %s: %v", *user.Avatar, err)
+		return
+	}
+	user.AvatarURL = &url
+
+	user.AvatarVariants = make(map[string]string, len(avatar.Sizes))
+	for _, size := range avatar.Sizes {
+		variantURL, err := h.avatars.URL(avatar.VariantKey(*user.Avatar, size))
+		if err != nil {
+			log.Printf("Error building avatar URL for %s: %v", *user.Avatar, err)
+			continue
+		}
+		user.AvatarVariants[strconv.Itoa(size)] = variantURL
+	}
+}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-	})
+// optionalString trims value and maps an empty result to nil, clearing the
+// column.
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
 }
 
-func isValidImageExt(ext string) bool {
-	validExts := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".gif":  true,
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
 	}
-	return validExts[ext]
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isExternalURL(value string) bool {
+	return strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://")
 }
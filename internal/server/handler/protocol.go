@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"api/internal/game/rules"
+	"api/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClientMessage is a message read from a game socket. ID is picked by the
+// client and echoed on the ack or error that answers it, so clients can match
+// responses to optimistic updates.
+type ClientMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type LobbyPayload struct {
+	LobbyID uuid.UUID `json:"lobbyId"`
+}
+
+type GamePayload struct {
+	GameID uuid.UUID `json:"gameId"`
+}
+
+type PlayCardPayload struct {
+	GameID  uuid.UUID   `json:"gameId"`
+	CardID  *uuid.UUID  `json:"cardId,omitempty"`
+	CardIDs []uuid.UUID `json:"cardIds,omitempty"`
+}
+
+// cards returns the played card IDs, accepting a single cardId for clients
+// that only ever play one card.
+func (p PlayCardPayload) cards() []uuid.UUID {
+	if len(p.CardIDs) == 0 && p.CardID != nil {
+		return []uuid.UUID{*p.CardID}
+	}
+	return p.CardIDs
+}
+
+type DrawCardPayload struct {
+	PlayerID uuid.UUID `json:"playerId"`
+}
+
+type ChatPayload struct {
+	Body string `json:"body"`
+}
+
+type SwapCardsPayload struct {
+	GameID        uuid.UUID   `json:"gameId"`
+	HandCardIDs   []uuid.UUID `json:"handCardIds"`
+	FaceUpCardIDs []uuid.UUID `json:"faceUpCardIds"`
+}
+
+// AckPayload confirms that a client message was applied.
+type AckPayload struct {
+	Type string `json:"type"`
+}
+
+// ErrorPayload tells a client why its message was rejected.
+type ErrorPayload struct {
+	Type         string      `json:"type,omitempty"`
+	Code         ErrorCode   `json:"code"`
+	Message      string      `json:"message"`
+	CardIDs      []uuid.UUID `json:"card_ids,omitempty"`
+	RetryAfterMs int64       `json:"retry_after_ms,omitempty"`
+}
+
+type ErrorCode string
+
+const (
+	CodeInvalidPayload  ErrorCode = "INVALID_PAYLOAD"
+	CodeUnknownType     ErrorCode = "UNKNOWN_MESSAGE_TYPE"
+	CodeInvalidSession  ErrorCode = "INVALID_SESSION"
+	CodeSpectator       ErrorCode = "SPECTATOR_READ_ONLY"
+	CodeRateLimited     ErrorCode = "RATE_LIMITED"
+	CodeGameNotFound    ErrorCode = "GAME_NOT_FOUND"
+	CodeGameNotStarted  ErrorCode = "GAME_NOT_STARTED"
+	CodeGameStarted     ErrorCode = "GAME_ALREADY_STARTED"
+	CodeGameNotFinished ErrorCode = "GAME_NOT_FINISHED"
+	CodeNotInLobby      ErrorCode = "NOT_IN_LOBBY"
+	CodeNotLobbyOwner   ErrorCode = "NOT_LOBBY_OWNER"
+	CodePlayersNotReady ErrorCode = "PLAYERS_NOT_READY"
+	CodeNotInGame       ErrorCode = "NOT_IN_GAME"
+	CodeNotYourTurn     ErrorCode = "NOT_YOUR_TURN"
+	CodeCardNotFound    ErrorCode = "CARD_NOT_FOUND"
+	CodeCardNotOwned    ErrorCode = "CARD_NOT_OWNED"
+	CodeWrongPhase      ErrorCode = "WRONG_PHASE"
+	CodeBlindPlaySingle ErrorCode = "BLIND_PLAY_SINGLE"
+	CodeCardTooLow      ErrorCode = "CARD_TOO_LOW"
+	CodeMustPlayLower   ErrorCode = "MUST_PLAY_LOWER"
+	CodeMixedValues     ErrorCode = "MIXED_VALUES"
+	CodeInvalidPlay     ErrorCode = "INVALID_PLAY"
+	CodePileEmpty       ErrorCode = "PILE_EMPTY"
+	CodeMustPlay        ErrorCode = "MUST_PLAY"
+	CodeDeckEmpty       ErrorCode = "DECK_EMPTY"
+	CodeHandFull        ErrorCode = "HAND_FULL"
+	CodeNotSwapPhase    ErrorCode = "NOT_SWAP_PHASE"
+	CodeSwapConfirmed   ErrorCode = "SWAP_CONFIRMED"
+	CodeInvalidSwap     ErrorCode = "INVALID_SWAP"
+	CodeMuted           ErrorCode = "MUTED"
+	CodeLobbyMuted      ErrorCode = "LOBBY_MUTED"
+	CodeInternal        ErrorCode = "INTERNAL_ERROR"
+)
+
+// protocolError is returned by message handlers to reject a message. Unlike
+// other errors, its code and message are sent back to the client.
+type protocolError struct {
+	Code    ErrorCode
+	Message string
+	CardIDs []uuid.UUID
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func rejectMove(code ErrorCode, message string, cardIDs []uuid.UUID) error {
+	return &protocolError{Code: code, Message: message, CardIDs: cardIDs}
+}
+
+// decodePayload unmarshals a message payload into dst, rejecting payloads that
+// do not match its shape.
+func decodePayload(message ClientMessage, dst interface{}) error {
+	if len(message.Payload) == 0 {
+		return rejectMove(CodeInvalidPayload, fmt.Sprintf("Missing payload for %s", message.Type), nil)
+	}
+	if err := json.Unmarshal(message.Payload, dst); err != nil {
+		return rejectMove(CodeInvalidPayload, fmt.Sprintf("Invalid payload for %s", message.Type), nil)
+	}
+	return nil
+}
+
+// protocolErrorFor maps the errors returned by shared game and lobby helpers
+// onto protocol errors, leaving anything unrecognised as it is.
+func protocolErrorFor(err error) error {
+	switch {
+	case errors.Is(err, errGameNotFound):
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	case errors.Is(err, errGameNotInProgress):
+		return rejectMove(CodeGameNotStarted, "The game is not in progress", nil)
+	case errors.Is(err, service.ErrGameStarted):
+		return rejectMove(CodeGameStarted, "The game has already started", nil)
+	case errors.Is(err, errGameNotFinished):
+		return rejectMove(CodeGameNotFinished, "The game has not finished", nil)
+	case errors.Is(err, errNotInLobby):
+		return rejectMove(CodeNotInLobby, "You are not in this lobby", nil)
+	}
+	return err
+}
+
+func invalidPlayCode(err error) ErrorCode {
+	switch {
+	case errors.Is(err, rules.ErrTooLow):
+		return CodeCardTooLow
+	case errors.Is(err, rules.ErrMustPlayLower):
+		return CodeMustPlayLower
+	case errors.Is(err, rules.ErrMixedValues):
+		return CodeMixedValues
+	case errors.Is(err, rules.ErrWrongPhase):
+		return CodeWrongPhase
+	case errors.Is(err, rules.ErrInvalidSwap):
+		return CodeInvalidSwap
+	case errors.Is(err, rules.ErrMustPlay):
+		return CodeMustPlay
+	}
+	return CodeInvalidPlay
+}
+
+func ackMessage(message ClientMessage) GameMessage {
+	return GameMessage{
+		ID:      message.ID,
+		Type:    "ack",
+		Payload: AckPayload{Type: message.Type},
+	}
+}
+
+func errorMessage(message ClientMessage, err *protocolError, retryAfter time.Duration) GameMessage {
+	return GameMessage{
+		ID:   message.ID,
+		Type: "error",
+		Payload: ErrorPayload{
+			Type:         message.Type,
+			Code:         err.Code,
+			Message:      err.Message,
+			CardIDs:      err.CardIDs,
+			RetryAfterMs: retryAfter.Milliseconds(),
+		},
+	}
+}
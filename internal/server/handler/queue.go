@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (h *LobbyHandler) LeaveQueue(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Wrong lobby id")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	result := tx.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobbyID, userID, service.QueuePlayer).Delete(&models.LobbyQueue{})
+	if result.Error != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error leaving queue")
+	}
+
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Not in queue")
+	}
+
+	if err := service.ReindexQueue(tx, lobbyID); err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating queue positions")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	h.broadcastQueueUpdate(lobbyID)
+
+	return c.JSON(fiber.Map{
+		"message": "Left queue",
+	})
+}
+
+// QueuePosition reports the caller's place in the lobby's player queue, or in
+// its spectator queue with ?type=spectator.
+func (h *LobbyHandler) QueuePosition(c *fiber.Ctx) error {
+	lobbyID, err := uuid.Parse(c.Params("lobbyId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Wrong lobby id")
+	}
+
+	queueType := c.Query("type", service.QueuePlayer)
+	if queueType != service.QueuePlayer && queueType != service.QueueSpectator {
+		return utils.NewError(fiber.StatusBadRequest, fmt.Sprintf("Queue type must be %s or %s", service.QueuePlayer, service.QueueSpectator))
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var entry models.LobbyQueue
+	if err := h.db.DB().WithContext(c.UserContext()).Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobbyID, userID, queueType).First(&entry).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Not in queue")
+	}
+
+	var length int64
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&models.LobbyQueue{}).Where("lobby_id = ? AND queue_type = ?", lobbyID, queueType).Count(&length).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching queue")
+	}
+
+	return c.JSON(fiber.Map{
+		"lobby_id":     lobbyID,
+		"queue_type":   queueType,
+		"position":     entry.Position,
+		"queue_length": length,
+		"joined_at":    entry.CreatedAt,
+	})
+}
+
+// broadcastQueueUpdate pushes the lobby's current player queue order to
+// everyone on its waiting game's socket, including the queued users
+// themselves.
+func (h *LobbyHandler) broadcastQueueUpdate(lobbyID uuid.UUID) {
+	var entries []models.LobbyQueue
+	if err := h.db.DB().Preload("User").Where("lobby_id = ? AND queue_type = ?", lobbyID, service.QueuePlayer).
+		Order("priority desc, position asc").Find(&entries).Error; err != nil {
+		return
+	}
+
+	queue := make([]fiber.Map, len(entries))
+	for i, entry := range entries {
+		queue[i] = fiber.Map{
+			"user_id":  entry.UserID,
+			"name":     entry.User.Name,
+			"position": entry.Position,
+		}
+	}
+
+	h.broadcastToLobby(lobbyID, GameMessage{
+		Type: "queue_update",
+		Payload: fiber.Map{
+			"lobby_id": lobbyID,
+			"queue":    queue,
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/middleware"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QuickJoinRequest struct {
+	GameMode   string `json:"game_mode" validate:"omitempty,oneof=casual ranked tournament teams"`
+	MaxPlayers int    `json:"max_players" validate:"omitempty,min=2,max=8"`
+}
+
+// quickJoinAttempts bounds how many candidate lobbies QuickJoin tries when
+// others keep taking the last seat first.
+const quickJoinAttempts = 3
+
+// QuickJoin seats the user in the fullest open public lobby matching the
+// optional filters, or creates one for others to join when there is none.
+// Lobbies with a user the caller blocked, or who blocked them, are skipped.
+// Two callers that find nothing at the same moment each get a new lobby.
+func (h *LobbyHandler) QuickJoin(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := middleware.CheckBan(c, h.db, userID); err != nil {
+		return err
+	}
+
+	var req QuickJoinRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+	if req.MaxPlayers != 0 {
+		if err := checkTeamTable(req.GameMode, req.MaxPlayers); err != nil {
+			return err
+		}
+	}
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).First(&user, userID).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	}
+
+	if service.RequiresVerifiedEmail(h.game, req.GameMode) && user.EmailVerifiedAt == nil {
+		return utils.NewError(fiber.StatusForbidden, "Verify your email address to play this game mode")
+	}
+
+	var seated models.Player
+	err := h.db.DB().WithContext(c.UserContext()).Preload("Lobby").Where("user_id = ?", userID).First(&seated).Error
+	if err == nil {
+		if seated.Lobby.Status == "waiting" {
+			return c.JSON(fiber.Map{
+				"message":  "Already in a lobby",
+				"lobby_id": seated.LobbyID,
+				"created":  false,
+			})
+		}
+		return utils.NewError(fiber.StatusForbidden, "You are already in another lobby")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking user's player status")
+	}
+
+	for attempt := 0; attempt < quickJoinAttempts; attempt++ {
+		lobby, err := h.quickJoinOpenLobby(c, user, req)
+		if errors.Is(err, service.ErrLobbyFull) {
+			continue
+		}
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error joining lobby")
+		}
+		if lobby == nil {
+			break
+		}
+
+		h.lobbies.Invalidate()
+		return c.JSON(fiber.Map{
+			"message":  "Successfully joined lobby",
+			"lobby_id": lobby.ID,
+			"created":  false,
+		})
+	}
+
+	lobby := models.Lobby{
+		ID:               uuid.New(),
+		Name:             fmt.Sprintf("%s's game", user.Name),
+		Type:             "public",
+		OwnerID:          user.ID,
+		Status:           "waiting",
+		MaxPlayers:       req.MaxPlayers,
+		GameMode:         req.GameMode,
+		PrivacyLevel:     "open",
+		SpectatorAllowed: true,
+		CurrentPlayers:   1,
+	}
+	if lobby.MaxPlayers == 0 {
+		lobby.MaxPlayers = 4
+	}
+	if lobby.GameMode == "" {
+		lobby.GameMode = "casual"
+	}
+
+	if err := h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		return createLobby(tx, &lobby)
+	}); err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating lobby")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Created a new lobby",
+		"lobby_id": lobby.ID,
+		"created":  true,
+	})
+}
+
+// quickJoinOpenLobby seats the user in the best matching lobby inside one
+// transaction. It returns a nil lobby when none matches, and service.ErrLobbyFull
+// when the chosen lobby filled up or started before the user got in.
+func (h *LobbyHandler) quickJoinOpenLobby(c *fiber.Ctx, user models.User, req QuickJoinRequest) (*models.Lobby, error) {
+	var joined *models.Lobby
+
+	err := h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type = ? AND status = ? AND privacy_level = ? AND current_players < max_players", "public", "waiting", "open").
+			Where(`NOT EXISTS (
+    SELECT 1 FROM players p
+    JOIN friendships f ON f.status = 'blocked'
+        AND ((f.requester_id = p.user_id AND f.addressee_id = ?) OR (f.requester_id = ? AND f.addressee_id = p.user_id))
+    WHERE p.lobby_id = lobbies.id)`, user.ID, user.ID).
+			Where("NOT EXISTS (SELECT 1 FROM games WHERE games.lobby_id = lobbies.id AND games.status IN ?)", []string{"setup", "in_progress"})
+		if req.GameMode != "" {
+			query = query.Where("game_mode = ?", req.GameMode)
+		}
+		if req.MaxPlayers != 0 {
+			query = query.Where("max_players = ?", req.MaxPlayers)
+		}
+		if h.game.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+			query = query.Where("game_mode NOT IN ?", []string{"ranked", "tournament"})
+		}
+
+		var lobby models.Lobby
+		err := query.Order("current_players DESC, created_at ASC").First(&lobby).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := service.AddPlayer(tx, &lobby, user.ID); errors.Is(err, service.ErrGameStarted) {
+			return service.ErrLobbyFull
+		} else if err != nil {
+			return err
+		}
+		joined = &lobby
+		return nil
+	})
+	return joined, err
+}
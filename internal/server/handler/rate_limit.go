@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"math"
+	"time"
+)
+
+// tokenBucket allows bursts of up to capacity events and refills at rate
+// tokens per second.
+type tokenBucket struct {
+	capacity float64
+	rate     float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(capacity, rate float64) *tokenBucket {
+	return &tokenBucket{
+		capacity: capacity,
+		rate:     rate,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// allow takes a token if one is available.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait is how long until the next token is available.
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+type rateLimit struct {
+	burst float64
+	rate  float64
+}
+
+var (
+	// connectionRateLimit caps everything a single socket sends.
+	connectionRateLimit = rateLimit{burst: 20, rate: 10}
+	// messageRateLimits caps individual message types; the ones that write
+	// to the database get the tightest limits.
+	messageRateLimits = map[string]rateLimit{
+		"play_card":    {burst: 5, rate: 2},
+		"pick_up_pile": {burst: 3, rate: 1},
+		"draw_card":    {burst: 3, rate: 1},
+		"swap_cards":   {burst: 5, rate: 2},
+		"chat_message": {burst: 5, rate: 1},
+	}
+	defaultMessageRateLimit = rateLimit{burst: 10, rate: 5}
+	// violationRateLimit is how many rejected messages a socket may rack up
+	// before it is disconnected.
+	violationRateLimit = rateLimit{burst: 20, rate: 1}
+)
+
+// messageLimiter rate limits the messages read from one connection. It is
+// only used from that connection's read loop, so it needs no locking.
+type messageLimiter struct {
+	overall    *tokenBucket
+	perType    map[string]*tokenBucket
+	violations *tokenBucket
+}
+
+func newMessageLimiter() *messageLimiter {
+	return &messageLimiter{
+		overall:    newTokenBucket(connectionRateLimit.burst, connectionRateLimit.rate),
+		perType:    make(map[string]*tokenBucket),
+		violations: newTokenBucket(violationRateLimit.burst, violationRateLimit.rate),
+	}
+}
+
+// allow reports whether a message of the given type may be handled. When it
+// may not, it also returns how long the client should back off and whether
+// the connection has been rejected often enough to be dropped.
+func (l *messageLimiter) allow(messageType string) (ok bool, retryAfter time.Duration, abusive bool) {
+	now := time.Now()
+
+	bucket, exists := l.perType[messageType]
+	if !exists {
+		limit, known := messageRateLimits[messageType]
+		if !known {
+			limit = defaultMessageRateLimit
+		}
+		bucket = newTokenBucket(limit.burst, limit.rate)
+		l.perType[messageType] = bucket
+	}
+
+	if !l.overall.allow(now) {
+		return false, l.overall.wait(), !l.violations.allow(now)
+	}
+	if !bucket.allow(now) {
+		return false, bucket.wait(), !l.violations.allow(now)
+	}
+	return true, 0, false
+}
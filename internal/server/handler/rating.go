@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/game/rating"
+	"api/internal/server/utils"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RatingHandler struct {
+	db database.Service
+}
+
+func NewRatingHandler(db database.Service) *RatingHandler {
+	return &RatingHandler{
+		db: db,
+	}
+}
+
+func (h *RatingHandler) Show(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Select("id").Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	current := models.Rating{
+		UserID: userID,
+		Rating: rating.Default,
+	}
+	if err := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID).First(&current).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching rating")
+	}
+
+	limit := utils.ParseLimit(c.Query("limit"), 20, 100)
+	query := h.db.DB().WithContext(c.UserContext()).Where("user_id = ?", userID)
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var history []models.RatingHistory
+	if err := query.
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&history).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching rating history")
+	}
+
+	var nextCursor *string
+	if len(history) > limit {
+		history = history[:limit]
+		last := history[limit-1]
+		encoded := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &encoded
+	}
+
+	return c.JSON(fiber.Map{
+		"user_id":      userID,
+		"rating":       current.Rating,
+		"games_played": current.GamesPlayed,
+		"history":      history,
+		"next_cursor":  nextCursor,
+	})
+}
+
+func playerRating(tx *gorm.DB, userID uuid.UUID) int {
+	var current models.Rating
+	if err := tx.Where("user_id = ?", userID).First(&current).Error; err != nil {
+		return rating.Default
+	}
+	return current.Rating
+}
+
+// updateRatingsForGame applies the Elo result of a finished ranked or team
+// game inside tx. Every player other than the shithead counts as a winner,
+// except in team games where the winning team plays every other team at the
+// members' average ratings, given by teamRanks. Casual games are left
+// untouched.
+func updateRatingsForGame(tx *gorm.DB, gameID, shitheadPlayerID uuid.UUID, teamRanks map[int]int) error {
+	var game models.Game
+	if err := tx.Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return err
+	}
+
+	if game.Lobby.GameMode != "ranked" && teamRanks == nil {
+		return nil
+	}
+
+	var players []models.Player
+	if err := tx.Where("game_id = ?", gameID).Order("created_at, id").Find(&players).Error; err != nil {
+		return err
+	}
+
+	userIDs := make([]uuid.UUID, len(players))
+	for i, player := range players {
+		userIDs[i] = player.UserID
+	}
+
+	var existing []models.Rating
+	if err := tx.Where("user_id IN ?", userIDs).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	current := make(map[uuid.UUID]models.Rating, len(players))
+	for _, r := range existing {
+		current[r.UserID] = r
+	}
+	for _, userID := range userIDs {
+		if _, ok := current[userID]; !ok {
+			current[userID] = models.Rating{UserID: userID, Rating: rating.Default}
+		}
+	}
+
+	var deltas map[uuid.UUID]int
+	if teamRanks != nil {
+		deltas = teamRatingDeltas(players, current, teamRanks)
+	} else {
+		var shitheadUserID uuid.UUID
+		var winnerIDs []uuid.UUID
+		var winnerRatings []int
+		for _, player := range players {
+			if player.ID == shitheadPlayerID {
+				shitheadUserID = player.UserID
+				continue
+			}
+			winnerIDs = append(winnerIDs, player.UserID)
+			winnerRatings = append(winnerRatings, current[player.UserID].Rating)
+		}
+
+		if shitheadUserID == uuid.Nil {
+			return errors.New("shithead is not a player in this game")
+		}
+
+		gains, loss := rating.Adjust(winnerRatings, current[shitheadUserID].Rating)
+
+		deltas = make(map[uuid.UUID]int, len(players))
+		for i, userID := range winnerIDs {
+			deltas[userID] = gains[i]
+		}
+		deltas[shitheadUserID] = loss
+	}
+
+	now := time.Now()
+	for userID, delta := range deltas {
+		before := current[userID]
+		after := models.Rating{
+			UserID:      userID,
+			Rating:      before.Rating + delta,
+			GamesPlayed: before.GamesPlayed + 1,
+			CreatedAt:   before.CreatedAt,
+			UpdatedAt:   now,
+		}
+		if after.CreatedAt.IsZero() {
+			after.CreatedAt = now
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rating", "games_played", "updated_at"}),
+		}).Create(&after).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.RatingHistory{
+			ID:           uuid.New(),
+			UserID:       userID,
+			GameID:       gameID,
+			RatingBefore: before.Rating,
+			RatingAfter:  after.Rating,
+			Delta:        delta,
+			CreatedAt:    now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// teamRatingDeltas rates the winning team against every other team, each at
+// its members' average rating. Every member takes their team's delta.
+func teamRatingDeltas(players []models.Player, current map[uuid.UUID]models.Rating, teamRanks map[int]int) map[uuid.UUID]int {
+	ratings := make(map[int][]int)
+	for _, player := range players {
+		ratings[*player.Team] = append(ratings[*player.Team], current[player.UserID].Rating)
+	}
+
+	var winner int
+	var losers []int
+	for team, rank := range teamRanks {
+		if rank == 1 {
+			winner = team
+		} else {
+			losers = append(losers, team)
+		}
+	}
+	sort.Ints(losers)
+
+	losing := make([]int, len(losers))
+	for i, team := range losers {
+		losing[i] = rating.Average(ratings[team])
+	}
+	gain, losses := rating.AdjustTeams(rating.Average(ratings[winner]), losing)
+
+	teamDeltas := map[int]int{winner: gain}
+	for i, team := range losers {
+		teamDeltas[team] = losses[i]
+	}
+
+	deltas := make(map[uuid.UUID]int, len(players))
+	for _, player := range players {
+		deltas[player.UserID] = teamDeltas[*player.Team]
+	}
+	return deltas
+}
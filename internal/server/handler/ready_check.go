@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/teams"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// readyCountdown is how long the server waits after the last player readies
+// up before it starts the game, giving anyone a chance to back out.
+const readyCountdown = 5 * time.Second
+
+// minPlayersToStart is the smallest table the ready check will start.
+const minPlayersToStart = 2
+
+var errNotInLobby = errors.New("player not found in lobby")
+
+func (h *GameHandler) Ready(c *fiber.Ctx) error {
+	return h.toggleReady(c, true)
+}
+
+func (h *GameHandler) Unready(c *fiber.Ctx) error {
+	return h.toggleReady(c, false)
+}
+
+func (h *GameHandler) toggleReady(c *fiber.Ctx, ready bool) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	player, err := h.setReady(c.UserContext(), lobbyID, userID, ready)
+	if errors.Is(err, errNotInLobby) {
+		return utils.NewError(fiber.StatusBadRequest, "Not in lobby")
+	} else if errors.Is(err, service.ErrGameStarted) {
+		return utils.NewError(fiber.StatusBadRequest, "Game has already started")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error updating ready status")
+	}
+
+	return c.JSON(fiber.Map{
+		"is_ready": player.IsReady,
+		"player":   player,
+	})
+}
+
+// setReady flips a player's ready flag for a waiting game and tells the rest
+// of the lobby. Readying may start the countdown; unreadying cancels it.
+func (h *GameHandler) setReady(ctx context.Context, lobbyID string, userID uuid.UUID, ready bool) (models.Player, error) {
+	messageType := "lobby_ready"
+	if !ready {
+		messageType = "lobby_unready"
+	}
+
+	var player models.Player
+	if err := h.db.DB().WithContext(ctx).Preload("Game").Where("lobby_id = ? AND user_id = ?", lobbyID, userID).First(&player).Error; err != nil {
+		return player, errNotInLobby
+	}
+	gameID := player.GameID.String()
+
+	if player.Game.Status != "waiting" {
+		return player, service.ErrGameStarted
+	}
+
+	if player.IsReady == ready {
+		message := "Already ready"
+		if !ready {
+			message = "Already not ready"
+		}
+		h.hub.BroadcastToGame(gameID, GameMessage{
+			Type: messageType,
+			Payload: fiber.Map{
+				"message":  message,
+				"is_ready": fmt.Sprint(ready),
+			},
+		})
+		return player, nil
+	}
+
+	if err := h.db.DB().WithContext(ctx).Model(&player).Update("is_ready", ready).Error; err != nil {
+		return player, err
+	}
+	player.IsReady = ready
+
+	h.lobbies.Invalidate()
+
+	message := "Succesfully ready up"
+	if !ready {
+		message = "No longer ready"
+	}
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: messageType,
+		Payload: fiber.Map{
+			"message":  message,
+			"is_ready": fmt.Sprint(ready),
+			"player":   player,
+		},
+	})
+
+	if ready {
+		h.checkReady(player.GameID)
+	} else {
+		h.cancelReadyCountdown(player.GameID)
+	}
+
+	return player, nil
+}
+
+// checkReady starts the countdown once every seated player in a waiting game
+// is ready.
+func (h *GameHandler) checkReady(gameID uuid.UUID) {
+	ready, err := h.allPlayersReady(gameID)
+	if err != nil {
+		log.Printf("Error checking ready state for game %s: %v", gameID, err)
+		return
+	}
+	if !ready {
+		return
+	}
+
+	startsAt := time.Now().Add(readyCountdown)
+	h.countdowns.schedule(gameID, readyCountdown, func() {
+		h.autoStartGame(gameID)
+	})
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "ready_countdown",
+		Payload: fiber.Map{
+			"game_id":   gameID,
+			"seconds":   int(readyCountdown.Seconds()),
+			"starts_at": startsAt,
+		},
+	})
+}
+
+// cancelReadyCountdown stops a pending automatic start, e.g. because a player
+// is no longer ready.
+func (h *GameHandler) cancelReadyCountdown(gameID uuid.UUID) {
+	if !h.countdowns.cancel(gameID) {
+		return
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "ready_countdown_cancelled",
+		Payload: fiber.Map{
+			"game_id": gameID,
+		},
+	})
+}
+
+// allPlayersReady reports whether the waiting game can start: enough players
+// are seated, all of them ready and, in team games, every team full.
+func (h *GameHandler) allPlayersReady(gameID uuid.UUID) (bool, error) {
+	var game models.Game
+	if err := h.db.DB().Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return false, err
+	}
+	if game.Status != "waiting" {
+		return false, nil
+	}
+
+	var seated, notReady int64
+	if err := h.db.DB().Model(&models.Player{}).Where("game_id = ?", gameID).Count(&seated).Error; err != nil {
+		return false, err
+	}
+	if err := h.db.DB().Model(&models.Player{}).
+		Where("game_id = ? AND is_ready = ?", gameID, false).
+		Count(&notReady).Error; err != nil {
+		return false, err
+	}
+
+	if seated < minPlayersToStart || notReady > 0 {
+		return false, nil
+	}
+
+	if game.Lobby.GameMode == teams.Mode {
+		var members []int
+		if err := h.db.DB().Model(&models.Player{}).
+			Where("game_id = ?", gameID).
+			Pluck("COALESCE(team, 0)", &members).Error; err != nil {
+			return false, err
+		}
+		return teams.Balanced(members, teams.Count(game.Lobby.MaxPlayers)), nil
+	}
+
+	return true, nil
+}
+
+// autoStartGame runs when the ready countdown ends. It re-checks that
+// everyone is still ready, deals, hands the first turn out and opens the swap
+// phase.
+func (h *GameHandler) autoStartGame(gameID uuid.UUID) {
+	h.countdowns.cancel(gameID)
+
+	ready, err := h.allPlayersReady(gameID)
+	if err != nil {
+		log.Printf("Error checking ready state for game %s: %v", gameID, err)
+		return
+	}
+	if !ready {
+		h.hub.BroadcastToGame(gameID.String(), GameMessage{
+			Type: "ready_countdown_cancelled",
+			Payload: fiber.Map{
+				"game_id": gameID,
+			},
+		})
+		return
+	}
+
+	if err := h.launchGame(gameID); err != nil {
+		log.Printf("Error starting game %s: %v", gameID, err)
+	}
+}
+
+// launchGame deals, hands the first turn out, opens the swap phase and tells
+// the table the game has started.
+func (h *GameHandler) launchGame(gameID uuid.UUID) error {
+	if _, err := dealCards(h.db, h.decks, gameID); err != nil {
+		return fmt.Errorf("error dealing cards: %w", err)
+	}
+
+	var game models.Game
+	if err := h.db.DB().Preload("Lobby.Players").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return fmt.Errorf("error loading game: %w", err)
+	}
+
+	firstPlayerID := game.CurrentTurnPlayerID
+	var current models.Player
+	if err := h.db.DB().Where("id = ? AND game_id = ?", firstPlayerID, gameID).First(&current).Error; err != nil {
+		var first models.Player
+		if err := h.db.DB().Where("game_id = ?", gameID).Order("role asc").First(&first).Error; err != nil {
+			return fmt.Errorf("error choosing first player: %w", err)
+		}
+		firstPlayerID = first.ID
+	}
+
+	if err := h.beginSwapPhase(gameID, firstPlayerID); err != nil {
+		return err
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "game_started",
+		Payload: fiber.Map{
+			"game_id":                gameID,
+			"status":                 "setup",
+			"players":                game.Lobby.Players,
+			"current_turn_player_id": firstPlayerID,
+			"redirect":               fmt.Sprintf("/games/%s", gameID),
+		},
+	})
+	return nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"context"
+	"log"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// handleConnect sends the connecting user a full redacted snapshot of the game
+// and, if they are a player coming back from a drop, gives them their seat back
+// even if a bot has taken it over in the meantime.
+func (h *GameHandler) handleConnect(conn *websocket.Conn, gameID string, session models.Session) {
+	gameUUID, err := uuid.Parse(gameID)
+	if err != nil {
+		return
+	}
+
+	viewerID := uuid.Nil
+	var player models.Player
+	isPlayer := h.db.DB().Where("game_id = ? AND user_id = ?", gameUUID, session.UserID).First(&player).Error == nil
+	if isPlayer {
+		viewerID = player.ID
+	}
+
+	snapshot, err := loadGameSnapshot(context.Background(), h.db, gameUUID, viewerID)
+	if err == nil {
+		message := GameMessage{
+			Type:    "game_state_sync",
+			Payload: snapshot,
+		}
+		// The sequence tells the client where to resume event sync from.
+		h.hub.stamp(gameID, &message)
+		h.hub.SendToConn(conn, message)
+	}
+
+	if !isPlayer {
+		return
+	}
+
+	h.seatHolds.cancel(player.ID)
+
+	if player.Status != "disconnected" && !player.IsBot {
+		return
+	}
+
+	if err := h.db.DB().Model(&player).Updates(map[string]interface{}{
+		"status": "active",
+		"is_bot": false,
+	}).Error; err != nil {
+		log.Printf("Error marking player %s as reconnected: %v", player.ID, err)
+		return
+	}
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "player_reconnected",
+		Payload: fiber.Map{
+			"game_id":   gameID,
+			"player_id": player.ID,
+		},
+	})
+}
+
+func (h *GameHandler) handleDisconnect(gameID string, session models.Session) {
+	gameUUID, err := uuid.Parse(gameID)
+	if err != nil {
+		return
+	}
+
+	var game models.Game
+	if err := h.db.DB().Where("id = ?", gameUUID).First(&game).Error; err != nil || game.Status != "in_progress" {
+		return
+	}
+
+	var player models.Player
+	if err := h.db.DB().Where("game_id = ? AND user_id = ?", gameUUID, session.UserID).First(&player).Error; err != nil {
+		return
+	}
+
+	if player.Status != "active" {
+		return
+	}
+
+	if err := h.db.DB().Model(&player).Update("status", "disconnected").Error; err != nil {
+		log.Printf("Error marking player %s as disconnected: %v", player.ID, err)
+		return
+	}
+
+	grace := h.game.ReconnectGrace
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "player_disconnected",
+		Payload: fiber.Map{
+			"game_id":       gameID,
+			"player_id":     player.ID,
+			"grace_seconds": int(grace.Seconds()),
+		},
+	})
+
+	playerID := player.ID
+	h.seatHolds.schedule(playerID, grace, func() {
+		h.replaceWithBot(gameUUID, playerID)
+	})
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errGameNotFinished = errors.New("game has not finished")
+
+// voteSet tracks rematch votes per finished game. Votes only matter until the
+// rematch starts, so they are kept in memory.
+type voteSet struct {
+	mu    sync.Mutex
+	votes map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func newVoteSet() *voteSet {
+	return &voteSet{
+		votes: make(map[uuid.UUID]map[uuid.UUID]bool),
+	}
+}
+
+func (v *voteSet) add(key, voter uuid.UUID) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.votes[key]; !ok {
+		v.votes[key] = make(map[uuid.UUID]bool)
+	}
+	v.votes[key][voter] = true
+	return len(v.votes[key])
+}
+
+func (v *voteSet) clear(key uuid.UUID) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.votes, key)
+}
+
+func (h *GameHandler) Rematch(c *fiber.Ctx) error {
+	gameID, err := uuid.Parse(c.Params("gameId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid game ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	newGameID, err := h.voteRematch(c.UserContext(), gameID, userID)
+	if errors.Is(err, errNotInLobby) {
+		return utils.NewError(fiber.StatusBadRequest, "You are not a player in this game")
+	} else if errors.Is(err, errGameNotFinished) {
+		return utils.NewError(fiber.StatusBadRequest, "A rematch can only be started once the game is over")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error starting rematch")
+	}
+
+	if newGameID == uuid.Nil {
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Rematch vote recorded",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Rematch started",
+		"game_id":  newGameID,
+		"redirect": fmt.Sprintf("/games/%s", newGameID),
+	})
+}
+
+func (h *GameHandler) handleRematchMessage(ctx context.Context, gameID string, session models.Session) error {
+	parsedGameID, err := uuid.Parse(gameID)
+	if err != nil {
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	_, err = h.voteRematch(ctx, parsedGameID, session.UserID)
+	return protocolErrorFor(err)
+}
+
+// voteRematch counts the user's vote for a rematch of a finished game. The
+// lobby owner's vote starts it straight away; otherwise a majority of the
+// human players is needed. It returns the new game's ID once started.
+func (h *GameHandler) voteRematch(ctx context.Context, gameID, userID uuid.UUID) (uuid.UUID, error) {
+	var game models.Game
+	if err := h.db.DB().WithContext(ctx).Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	if game.Status != "completed" {
+		return uuid.Nil, errGameNotFinished
+	}
+
+	var player models.Player
+	if err := h.db.DB().WithContext(ctx).Where("game_id = ? AND user_id = ? AND is_bot = ?", gameID, userID, false).First(&player).Error; err != nil {
+		return uuid.Nil, errNotInLobby
+	}
+
+	var humans int64
+	if err := h.db.DB().WithContext(ctx).Model(&models.Player{}).
+		Where("game_id = ? AND is_bot = ?", gameID, false).
+		Count(&humans).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	votes := h.rematchVotes.add(gameID, userID)
+	needed := int(humans)/2 + 1
+
+	if game.Lobby.OwnerID != userID && votes < needed {
+		h.hub.BroadcastToGame(gameID.String(), GameMessage{
+			Type: "rematch_vote",
+			Payload: fiber.Map{
+				"game_id":   gameID,
+				"player_id": player.ID,
+				"votes":     votes,
+				"needed":    needed,
+			},
+		})
+		return uuid.Nil, nil
+	}
+
+	h.rematchVotes.clear(gameID)
+	return h.startRematch(game)
+}
+
+// startRematch opens a fresh game in the same lobby, moves the seated players
+// over with their per-round state reset, deals and opens the swap phase.
+// Scores carry over so they add up across the lobby's rounds.
+func (h *GameHandler) startRematch(previous models.Game) (uuid.UUID, error) {
+	game := models.Game{
+		ID:          uuid.New(),
+		LobbyID:     previous.LobbyID,
+		OwnerID:     previous.Lobby.OwnerID,
+		Status:      "waiting",
+		RoundNumber: previous.RoundNumber + 1,
+		Winner:      "none",
+	}
+
+	var first models.Player
+	if err := h.db.DB().Transaction(func(tx *gorm.DB) error {
+		var current models.Game
+		if err := tx.Where("id = ?", previous.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if current.Status != "completed" {
+			return errGameNotFinished
+		}
+
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+
+		// Cards left over from the last round must not count towards the new
+		// one, since players keep their IDs across rounds.
+		if err := tx.Model(&models.Card{}).Where("game_id = ?", previous.ID).
+			Update("player_id", nil).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Player{}).Where("game_id = ?", previous.ID).Updates(map[string]interface{}{
+			"game_id":        game.ID,
+			"is_ready":       gorm.Expr("is_bot"),
+			"status":         "active",
+			"placement":      nil,
+			"swap_confirmed": false,
+			"updated_at":     time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("game_id = ?", game.ID).Order("created_at ASC, id ASC").First(&first).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Lobby{}).Where("id = ?", previous.LobbyID).Update("updated_at", time.Now()).Error
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	h.lobbies.Invalidate()
+
+	if _, err := dealCards(h.db, h.decks, game.ID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := h.beginSwapPhase(game.ID, first.ID); err != nil {
+		return uuid.Nil, err
+	}
+
+	var players []models.Player
+	if err := h.db.DB().Where("game_id = ?", game.ID).Find(&players).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	h.hub.BroadcastToGame(previous.ID.String(), GameMessage{
+		Type: "rematch_started",
+		Payload: fiber.Map{
+			"previous_game_id": previous.ID,
+			"game_id":          game.ID,
+			"round_number":     game.RoundNumber,
+			"status":           "setup",
+			"players":          players,
+			"redirect":         fmt.Sprintf("/games/%s", game.ID),
+		},
+	})
+
+	return game.ID, nil
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errReportClosed = errors.New("report already closed")
+
+type ReportHandler struct {
+	db database.Service
+}
+
+type CreateReportRequest struct {
+	UserID      uuid.UUID  `json:"user_id" validate:"required"`
+	Category    string     `json:"category" validate:"required,oneof=cheating abuse afk"`
+	GameID      *uuid.UUID `json:"game_id"`
+	MessageID   *uuid.UUID `json:"message_id"`
+	Description string     `json:"description" validate:"max=1000"`
+}
+
+// ResolveReportRequest closes a report. Action "none" dismisses it; "mute"
+// and "ban" last until ExpiresAt, or for good when it is omitted.
+type ResolveReportRequest struct {
+	Action    string     `json:"action" validate:"required,oneof=none warn mute ban"`
+	Note      string     `json:"note" validate:"max=1000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func NewReportHandler(db database.Service) *ReportHandler {
+	return &ReportHandler{
+		db: db,
+	}
+}
+
+// Store files a report against another player. A reported chat message must
+// be theirs and ties the report to its game. When it names a game, the
+// reported user must have played in it, and a reporter can only have one
+// open report per player and game.
+func (h *ReportHandler) Store(c *fiber.Ctx) error {
+	reporterID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.UserID == reporterID {
+		return utils.NewError(fiber.StatusBadRequest, "You cannot report yourself")
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var reported models.User
+	if err := db.Select("id").Where("id = ? AND is_bot = ?", req.UserID, false).First(&reported).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	if req.MessageID != nil {
+		var message models.ChatMessage
+		if err := db.Where("id = ? AND user_id = ?", *req.MessageID, req.UserID).First(&message).Error; err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "The reported user did not send that message")
+		}
+		if req.GameID == nil {
+			req.GameID = &message.GameID
+		} else if *req.GameID != message.GameID {
+			return utils.NewError(fiber.StatusBadRequest, "That message was not sent in that game")
+		}
+	}
+
+	if req.GameID != nil {
+		var player models.Player
+		if err := db.Where("game_id = ? AND user_id = ?", *req.GameID, req.UserID).First(&player).Error; err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "The reported user did not play in that game")
+		}
+	}
+
+	duplicate := db.Model(&models.Report{}).
+		Where("reporter_id = ? AND reported_user_id = ? AND status = ?", reporterID, req.UserID, "open")
+	if req.GameID != nil {
+		duplicate = duplicate.Where("game_id = ?", *req.GameID)
+	} else {
+		duplicate = duplicate.Where("game_id IS NULL")
+	}
+	var open int64
+	if err := duplicate.Count(&open).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking existing reports")
+	}
+	if open > 0 {
+		return utils.NewError(fiber.StatusConflict, "You have already reported this player")
+	}
+
+	now := time.Now().UTC()
+	report := models.Report{
+		ID:             uuid.New(),
+		ReporterID:     reporterID,
+		ReportedUserID: req.UserID,
+		Category:       req.Category,
+		GameID:         req.GameID,
+		MessageID:      req.MessageID,
+		Description:    req.Description,
+		Status:         "open",
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := db.Create(&report).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating report")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Report submitted",
+		"id":      report.ID,
+	})
+}
+
+// Reports lists reports for triage, oldest first so the longest waiting are
+// handled first. It defaults to open reports.
+func (h *AdminHandler) Reports(c *fiber.Ctx) error {
+	limit := utils.ParseLimit(c.Query("limit"), 50, 100)
+
+	query := h.db.DB().WithContext(c.UserContext()).
+		Where("status = ?", c.Query("status", "open")).
+		Order("created_at ASC, id ASC").
+		Limit(limit + 1)
+
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+		}
+		query = query.Where("reported_user_id = ?", userID)
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := utils.DecodeCursor(raw)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid cursor")
+		}
+		query = query.Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	var reports []models.Report
+	if err := query.Find(&reports).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching reports")
+	}
+
+	var nextCursor *string
+	if len(reports) > limit {
+		reports = reports[:limit]
+		last := reports[len(reports)-1]
+		cursor := utils.EncodeCursor(last.CreatedAt, last.ID)
+		nextCursor = &cursor
+	}
+
+	return c.JSON(fiber.Map{
+		"data":        reports,
+		"next_cursor": nextCursor,
+	})
+}
+
+// Report shows a single report along with the full, unredacted event log of
+// the game it refers to and how many other reports the player has had.
+func (h *AdminHandler) Report(c *fiber.Ctx) error {
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid report ID")
+	}
+
+	db := h.db.DB().WithContext(c.UserContext())
+
+	var report models.Report
+	if err := db.Where("id = ?", reportID).First(&report).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Report not found")
+	}
+
+	events := []models.GameEvent{}
+	if report.GameID != nil {
+		if err := db.Where("game_id = ?", *report.GameID).Order("sequence ASC").Find(&events).Error; err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error fetching game events")
+		}
+	}
+
+	var priorReports int64
+	if err := db.Model(&models.Report{}).
+		Where("reported_user_id = ? AND id <> ?", report.ReportedUserID, report.ID).
+		Count(&priorReports).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error counting reports")
+	}
+
+	return c.JSON(fiber.Map{
+		"report":        report,
+		"game_events":   events,
+		"other_reports": priorReports,
+	})
+}
+
+// ResolveReport closes a report and applies the chosen action to the reported
+// player: a warning notification, a chat mute or a ban.
+func (h *AdminHandler) ResolveReport(c *fiber.Ctx) error {
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid report ID")
+	}
+
+	var req ResolveReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return utils.NewError(fiber.StatusBadRequest, "Expiry must be in the future")
+	}
+
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	var report models.Report
+	var ban *models.Ban
+	err = h.db.DB().WithContext(c.UserContext()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", reportID).First(&report).Error; err != nil {
+			return err
+		}
+		if report.Status != "open" {
+			return errReportClosed
+		}
+
+		reason := reportReason(report, req.Note)
+		switch req.Action {
+		case "warn":
+			if err := service.CreateNotification(tx, report.ReportedUserID, "moderation_warning", fiber.Map{
+				"category": report.Category,
+				"game_id":  report.GameID,
+				"message":  reason,
+			}); err != nil {
+				return err
+			}
+		case "mute":
+			if _, err := issueMute(tx, report.ReportedUserID, adminID, reason, req.ExpiresAt); err != nil {
+				return err
+			}
+		case "ban":
+			issued, err := issueBan(tx, report.ReportedUserID, adminID, reason, req.ExpiresAt)
+			if err != nil {
+				return err
+			}
+			ban = &issued
+		}
+
+		status := "resolved"
+		if req.Action == "none" {
+			status = "dismissed"
+		}
+
+		now := time.Now().UTC()
+		updates := map[string]interface{}{
+			"status":      status,
+			"action":      req.Action,
+			"resolved_by": adminID,
+			"resolved_at": now,
+			"updated_at":  now,
+		}
+		if req.Note != "" {
+			updates["resolution_note"] = req.Note
+		}
+		return tx.Model(&report).Updates(updates).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "Report not found")
+	} else if errors.Is(err, errReportClosed) {
+		return utils.NewError(fiber.StatusConflict, "Report has already been resolved")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error resolving report")
+	}
+
+	if ban != nil {
+		h.lobbies.Invalidate()
+		h.announceBan(*ban)
+	}
+
+	recordAudit(c, h.audit, service.AuditEntry{
+		Action:     "admin_report_resolve",
+		TargetType: "report",
+		TargetID:   report.ID.String(),
+		Details:    map[string]interface{}{"action": req.Action, "reported_user_id": report.ReportedUserID},
+	})
+	return c.JSON(report)
+}
+
+// reportReason is the reason recorded on the warning, mute or ban a report
+// leads to: the admin's note, or the report category when there is none.
+func reportReason(report models.Report, note string) string {
+	if note != "" {
+		return note
+	}
+	return "Reported for " + report.Category
+}
+
+// issueMute records a global chat mute for the user.
+func issueMute(tx *gorm.DB, userID, adminID uuid.UUID, reason string, expiresAt *time.Time) (models.Mute, error) {
+	now := time.Now().UTC()
+	if expiresAt != nil {
+		utc := expiresAt.UTC()
+		expiresAt = &utc
+	}
+
+	mute := models.Mute{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		IssuedBy:  &adminID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return mute, tx.Create(&mute).Error
+}
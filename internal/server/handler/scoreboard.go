@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Scoreboard lists the lobby's players by their cumulative score across every
+// round played in it.
+func (h *LobbyHandler) Scoreboard(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+
+	var lobby models.Lobby
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	var players []models.Player
+	if err := h.db.DB().WithContext(c.UserContext()).Preload("User").Where("lobby_id = ?", lobby.ID).
+		Order("score DESC, created_at ASC").Find(&players).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching scoreboard")
+	}
+
+	var roundsPlayed int64
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&models.MatchResult{}).Where("lobby_id = ?", lobby.ID).
+		Count(&roundsPlayed).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching scoreboard")
+	}
+
+	var currentRound int
+	if err := h.db.DB().WithContext(c.UserContext()).Model(&models.Game{}).Where("lobby_id = ?", lobby.ID).
+		Select("COALESCE(MAX(round_number), 0)").Scan(&currentRound).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching scoreboard")
+	}
+
+	scores := make([]fiber.Map, len(players))
+	for i, player := range players {
+		scores[i] = fiber.Map{
+			"rank":      i + 1,
+			"player_id": player.ID,
+			"user_id":   player.UserID,
+			"name":      player.User.Name,
+			"is_bot":    player.IsBot,
+			"score":     player.Score,
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"lobby_id":      lobby.ID,
+		"round_number":  currentRound,
+		"rounds_played": roundsPlayed,
+		"scores":        scores,
+	})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"api/internal/server/utils"
+	"api/internal/service"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SeasonHandler struct {
+	seasons service.SeasonService
+}
+
+type SeasonLeaderboardRequest struct {
+	Mode   string `query:"mode" validate:"omitempty,oneof=casual ranked tournament teams"`
+	Season string `query:"season"`
+}
+
+func NewSeasonHandler(seasons service.SeasonService) *SeasonHandler {
+	return &SeasonHandler{
+		seasons: seasons,
+	}
+}
+
+func (h *SeasonHandler) Index(c *fiber.Ctx) error {
+	seasons, err := h.seasons.List(c.UserContext())
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching seasons")
+	}
+
+	return c.JSON(fiber.Map{
+		"data": seasons,
+	})
+}
+
+// Leaderboard ranks players in one game mode of a season, the running one
+// unless ?season names a number or ID.
+func (h *SeasonHandler) Leaderboard(c *fiber.Ctx) error {
+	var req SeasonLeaderboardRequest
+	if err := c.QueryParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid query parameters")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+	if req.Mode == "" {
+		req.Mode = "ranked"
+	}
+
+	limit := utils.ParseLimit(c.Query("limit"), 25, 100)
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	season, err := h.seasons.Find(c.UserContext(), req.Season)
+	if errors.Is(err, service.ErrSeasonNotFound) {
+		return utils.NewError(fiber.StatusNotFound, "Season not found")
+	} else if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching season")
+	}
+
+	standings, total, err := h.seasons.Standings(c.UserContext(), season, req.Mode, offset, limit)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching leaderboard")
+	}
+
+	return c.JSON(fiber.Map{
+		"season": season,
+		"mode":   req.Mode,
+		"data":   standings,
+		"page":   page,
+		"limit":  limit,
+		"total":  total,
+	})
+}
+
+// RolloverSeason is a scheduler job that closes the season once it has ended
+// and opens the next.
+func (h *SeasonHandler) RolloverSeason() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return h.seasons.Rollover(ctx, time.Now().UTC())
+}
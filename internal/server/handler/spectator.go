@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultMaxSpectators is how many spectators a lobby admits unless its owner
+// sets another cap.
+const defaultMaxSpectators = 20
+
+// Spectate registers the caller as a spectator of the lobby's game. Once the
+// lobby's spectator cap is reached they are put in its spectator queue
+// instead and admitted as spectators leave.
+func (h *LobbyHandler) Spectate(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if !lobby.SpectatorAllowed {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusForbidden, "Spectators are not allowed in this lobby")
+	}
+
+	var player models.Player
+	if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&player).Error; err == nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "Players cannot spectate their own lobby")
+	}
+
+	var game models.Game
+	if err := tx.Where("lobby_id = ?", lobby.ID).Order("created_at DESC").First(&game).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "No game to spectate")
+	}
+
+	var spectator models.LobbySpectator
+	err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&spectator).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if lobby.SpectatorCount >= lobby.MaxSpectators {
+			return h.handleSpectatorQueueJoin(tx, c, &lobby, userID)
+		}
+
+		if err := service.AddSpectator(tx, &lobby, userID); err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error joining as spectator")
+		}
+	} else if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking spectator status")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"message":  "Now spectating",
+		"lobby_id": lobby.ID,
+		"game_id":  game.ID,
+	})
+}
+
+// handleSpectatorQueueJoin puts the caller in the lobby's spectator queue,
+// or reports where they already stand in it, and ends tx.
+func (h *LobbyHandler) handleSpectatorQueueJoin(tx *gorm.DB, c *fiber.Ctx, lobby *models.Lobby, userID uuid.UUID) error {
+	var entry models.LobbyQueue
+	err := tx.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobby.ID, userID, service.QueueSpectator).First(&entry).Error
+	if err == nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message":        "Already in the spectator queue",
+			"queue_position": entry.Position,
+		})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking spectator queue")
+	}
+
+	position, err := service.Enqueue(tx, lobby.ID, userID, service.QueueSpectator)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error joining spectator queue")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message":        "The lobby has no room for more spectators, added to the spectator queue",
+		"queue_position": position,
+	})
+}
+
+// StopSpectating removes the caller from the lobby's spectators, letting the
+// next user in the spectator queue in, or takes them out of the spectator
+// queue if they were still waiting there.
+func (h *LobbyHandler) StopSpectating(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	released, err := service.ReleaseSpectatorSlot(tx, lobby.ID, userID)
+	if err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error leaving as spectator")
+	}
+
+	message := "Stopped spectating"
+	if !released {
+		result := tx.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobby.ID, userID, service.QueueSpectator).Delete(&models.LobbyQueue{})
+		if result.Error != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error leaving spectator queue")
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusBadRequest, "Not spectating this lobby")
+		}
+		if err := service.ReindexQueue(tx, lobby.ID); err != nil {
+			tx.Rollback()
+			return utils.NewError(fiber.StatusInternalServerError, "Error updating queue positions")
+		}
+		message = "Left spectator queue"
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"api/internal/server/utils"
+	"api/internal/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type StatsHandler struct {
+	users service.UserService
+	stats service.StatsService
+}
+
+func NewStatsHandler(users service.UserService, stats service.StatsService) *StatsHandler {
+	return &StatsHandler{
+		users: users,
+		stats: stats,
+	}
+}
+
+func (h *StatsHandler) Show(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if _, err := h.users.Find(c.UserContext(), userID); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return utils.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return utils.NewError(fiber.StatusInternalServerError, "Database error")
+	}
+
+	stats, err := h.stats.UserStats(c.UserContext(), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error computing stats")
+	}
+
+	return c.JSON(stats)
+}
+
+// Versus returns the head-to-head record of two players, seen from the first.
+func (h *StatsHandler) Versus(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	otherID, err := uuid.Parse(c.Params("otherId"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid user ID")
+	}
+	if userID == otherID {
+		return utils.NewError(fiber.StatusBadRequest, "Cannot compare a user with themselves")
+	}
+
+	for _, id := range []uuid.UUID{userID, otherID} {
+		if _, err := h.users.Find(c.UserContext(), id); err != nil {
+			if errors.Is(err, service.ErrUserNotFound) {
+				return utils.NewError(fiber.StatusNotFound, "User not found")
+			}
+			return utils.NewError(fiber.StatusInternalServerError, "Database error")
+		}
+	}
+
+	versus, err := h.stats.Versus(c.UserContext(), userID, otherID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error computing head-to-head stats")
+	}
+
+	return c.JSON(versus)
+}
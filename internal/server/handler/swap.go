@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/rules"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// beginSwapPhase moves a freshly dealt game into setup, where players may
+// trade hand cards for face-up cards before the first turn. Bots keep the
+// cards they were dealt, so they are confirmed straight away.
+func (h *GameHandler) beginSwapPhase(gameID, firstPlayerID uuid.UUID) error {
+	if err := h.db.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Player{}).Where("game_id = ?", gameID).
+			Update("swap_confirmed", gorm.Expr("is_bot")).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Game{}).Where("id = ?", gameID).Updates(map[string]interface{}{
+			"status":                 "setup",
+			"current_turn_player_id": firstPlayerID,
+			"updated_at":             time.Now(),
+		}).Error
+	}); err != nil {
+		return err
+	}
+
+	h.lobbies.Invalidate()
+	return nil
+}
+
+func (h *GameHandler) swapCards(ctx context.Context, session models.Session, payload SwapCardsPayload) error {
+	parsedGameID := payload.GameID
+	gameID := parsedGameID.String()
+
+	handIDs, faceUpIDs := payload.HandCardIDs, payload.FaceUpCardIDs
+	cardIDs := append(append([]uuid.UUID{}, handIDs...), faceUpIDs...)
+
+	tx := h.db.DB().WithContext(ctx).Begin()
+
+	var game models.Game
+	if err := tx.Where("id = ?", parsedGameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	if game.Status != "setup" {
+		tx.Rollback()
+		return rejectMove(CodeNotSwapPhase, "Cards can only be swapped before the first turn", cardIDs)
+	}
+
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", parsedGameID, session.UserID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You are not a player in this game", cardIDs)
+	}
+
+	if player.SwapConfirmed {
+		tx.Rollback()
+		return rejectMove(CodeSwapConfirmed, "You have already finished swapping", cardIDs)
+	}
+
+	counts, err := playerCardCounts(tx, player.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error counting player cards: %w", err)
+	}
+
+	if err := rules.ValidateSwap(counts["hand"], counts["faceup"], len(handIDs), len(faceUpIDs)); err != nil {
+		tx.Rollback()
+		return rejectMove(CodeInvalidSwap, err.Error(), cardIDs)
+	}
+
+	var cards []models.Card
+	if err := tx.Where("id IN ? AND game_id = ?", cardIDs, parsedGameID).Find(&cards).Error; err != nil || len(cards) != len(cardIDs) {
+		tx.Rollback()
+		return rejectMove(CodeCardNotFound, "Card not found in this game", cardIDs)
+	}
+
+	fromHand := make(map[uuid.UUID]bool, len(handIDs))
+	for _, id := range handIDs {
+		fromHand[id] = true
+	}
+
+	updates := make([]cardUpdate, len(cards))
+	var faceUp []models.Card
+	for i, card := range cards {
+		if card.PlayerID == nil || *card.PlayerID != player.ID {
+			tx.Rollback()
+			return rejectMove(CodeCardNotOwned, "Card does not belong to you", cardIDs)
+		}
+
+		expected, moveTo := "faceup", "hand"
+		if fromHand[card.ID] {
+			expected, moveTo = "hand", "faceup"
+		}
+		if card.Status != expected {
+			tx.Rollback()
+			return rejectMove(CodeInvalidSwap, rules.ErrInvalidSwap.Error(), cardIDs)
+		}
+
+		updates[i] = cardUpdate{
+			ID:           card.ID,
+			Status:       moveTo,
+			LocationType: card.LocationType,
+			PlayerID:     card.PlayerID,
+		}
+		if moveTo == "faceup" {
+			card.Status = moveTo
+			faceUp = append(faceUp, card)
+		}
+	}
+
+	if err := batchUpdateCards(tx, updates); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error swapping cards: %w", err)
+	}
+
+	if err := recordGameEvent(tx, parsedGameID, "swap", &player.ID, fiber.Map{
+		"hand_card_ids":    handIDs,
+		"face_up_card_ids": faceUpIDs,
+	}); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error recording swap: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	// Face-up cards are public, so everyone sees what moved onto the table.
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "cards_swapped",
+		Payload: fiber.Map{
+			"game_id":       parsedGameID.String(),
+			"player_id":     player.ID,
+			"face_up_cards": faceUp,
+		},
+	})
+
+	return nil
+}
+
+func (h *GameHandler) finishSwap(ctx context.Context, session models.Session, payload GamePayload) error {
+	parsedGameID := payload.GameID
+	gameID := parsedGameID.String()
+
+	tx := h.db.DB().WithContext(ctx).Begin()
+
+	var game models.Game
+	if err := tx.Where("id = ?", parsedGameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeGameNotFound, "Game not found", nil)
+	}
+
+	if game.Status != "setup" {
+		tx.Rollback()
+		return rejectMove(CodeNotSwapPhase, "The swap phase is over", nil)
+	}
+
+	var player models.Player
+	if err := tx.Where("game_id = ? AND user_id = ?", parsedGameID, session.UserID).First(&player).Error; err != nil {
+		tx.Rollback()
+		return rejectMove(CodeNotInGame, "You are not a player in this game", nil)
+	}
+
+	if err := tx.Model(&player).Update("swap_confirmed", true).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error confirming swap: %w", err)
+	}
+
+	var pending int64
+	if err := tx.Model(&models.Player{}).
+		Where("game_id = ? AND swap_confirmed = ?", parsedGameID, false).
+		Count(&pending).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error counting unconfirmed players: %w", err)
+	}
+
+	if pending == 0 {
+		if err := tx.Model(&game).Updates(map[string]interface{}{
+			"status":     "in_progress",
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error starting game %s: %w", parsedGameID, err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "swap_finished",
+		Payload: fiber.Map{
+			"game_id":   parsedGameID.String(),
+			"player_id": player.ID,
+			"pending":   pending,
+		},
+	})
+
+	if pending > 0 {
+		return nil
+	}
+
+	h.lobbies.Invalidate()
+	h.startTurnTimer(parsedGameID)
+
+	h.hub.BroadcastToGame(gameID, GameMessage{
+		Type: "game_in_progress",
+		Payload: fiber.Map{
+			"game_id":                parsedGameID.String(),
+			"current_turn_player_id": game.CurrentTurnPlayerID,
+		},
+	})
+
+	return nil
+}
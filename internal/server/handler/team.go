@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/teams"
+	"api/internal/server/utils"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+type ChooseTeamRequest struct {
+	Team int `json:"team" validate:"required,min=1"`
+}
+
+// ChooseTeam moves the caller to another team with a free seat while their
+// team game is still waiting to start.
+func (h *LobbyHandler) ChooseTeam(c *fiber.Ctx) error {
+	lobbyID := c.Params("lobbyId")
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req ChooseTeamRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	tx := h.db.DB().WithContext(c.UserContext()).Begin()
+
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusNotFound, "Lobby not found")
+	}
+
+	if lobby.GameMode != teams.Mode {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "This lobby is not playing in teams")
+	}
+
+	if req.Team > teams.Count(lobby.MaxPlayers) {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "No such team in this lobby")
+	}
+
+	var player models.Player
+	if err := tx.Joins("JOIN games ON games.id = players.game_id").
+		Where("players.lobby_id = ? AND players.user_id = ? AND games.status = ?", lobby.ID, userID, "waiting").
+		First(&player).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusBadRequest, "You are not waiting in this lobby")
+	}
+
+	if player.Team != nil && *player.Team == req.Team {
+		tx.Rollback()
+		return c.JSON(fiber.Map{"team": req.Team, "player": player})
+	}
+
+	var members int64
+	if err := tx.Model(&models.Player{}).
+		Where("game_id = ? AND team = ?", player.GameID, req.Team).
+		Count(&members).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking team")
+	}
+	if members >= teams.Size {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusConflict, "That team is full").WithCode("TEAM_FULL")
+	}
+
+	if err := tx.Model(&player).Updates(map[string]interface{}{
+		"team":       req.Team,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.NewError(fiber.StatusInternalServerError, "Error changing team")
+	}
+	player.Team = &req.Team
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error committing transaction")
+	}
+
+	h.lobbies.Invalidate()
+
+	h.broadcastToLobby(lobby.ID, GameMessage{
+		Type: "lobby_team_changed",
+		Payload: fiber.Map{
+			"lobby_id":  lobby.ID,
+			"player_id": player.ID,
+			"user_id":   userID,
+			"team":      req.Team,
+		},
+	})
+
+	return c.JSON(fiber.Map{
+		"team":   req.Team,
+		"player": player,
+	})
+}
+
+// teamMembers describes a team game's players for the teams package, in the
+// same order as players.
+func teamMembers(players []models.Player) []teams.Member {
+	members := make([]teams.Member, len(players))
+	for i, player := range players {
+		members[i] = teams.Member{
+			Finished: player.Status == "finished",
+			Out:      player.Status == "finished" || player.Status == "forfeited",
+		}
+		if player.Team != nil {
+			members[i].Team = *player.Team
+		}
+		if player.Placement != nil {
+			members[i].Placement = *player.Placement
+		}
+	}
+	return members
+}
+
+// isTeamGame reports whether the players are seated in teams.
+func isTeamGame(players []models.Player) bool {
+	for _, player := range players {
+		if player.Team == nil {
+			return false
+		}
+	}
+	return len(players) > 0
+}
+
+// teamTurnOrder reorders players so that turns alternate between teams.
+// Outside team games the seating order is kept.
+func teamTurnOrder(players []models.Player) []models.Player {
+	if !isTeamGame(players) {
+		return players
+	}
+
+	seats := make([]int, len(players))
+	for i, player := range players {
+		seats[i] = *player.Team
+	}
+
+	ordered := make([]models.Player, len(players))
+	for i, seat := range teams.TurnOrder(seats) {
+		ordered[i] = players[seat]
+	}
+	return ordered
+}
+
+// checkTeamTable rejects team lobbies whose seats cannot be split into full
+// teams.
+func checkTeamTable(gameMode string, maxPlayers int) error {
+	if gameMode != teams.Mode || teams.ValidTable(maxPlayers) {
+		return nil
+	}
+	return utils.NewError(fiber.StatusBadRequest,
+		fmt.Sprintf("Team games need an even number of players, at least %d", teams.MinPlayers))
+}
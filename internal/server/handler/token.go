@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/middleware"
+	"api/internal/server/utils"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TokenHandler struct {
+	db database.Service
+}
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name" validate:"required,max=255"`
+	Abilities     []string `json:"abilities" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+// TokenResponse is a personal access token without its secret value.
+type TokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Abilities  []string   `json:"abilities"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+func NewTokenHandler(db database.Service) *TokenHandler {
+	return &TokenHandler{
+		db: db,
+	}
+}
+
+func newTokenResponse(token models.PersonalAccessToken) TokenResponse {
+	return TokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Abilities:  middleware.ParseAbilities(token.Abilities),
+		LastUsedAt: token.LastUsedAt,
+		ExpiresAt:  token.ExpiresAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func (h *TokenHandler) Index(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var tokens []models.PersonalAccessToken
+	if err := h.db.DB().WithContext(c.UserContext()).
+		Where("tokenable_type = ? AND tokenable_id = ?", "User", userID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching tokens")
+	}
+
+	data := make([]TokenResponse, len(tokens))
+	for i, token := range tokens {
+		data[i] = newTokenResponse(token)
+	}
+
+	return c.JSON(fiber.Map{
+		"data": data,
+	})
+}
+
+// Store issues a scoped token. Only its hash is stored, so the plain token
+// is returned here and never again.
+func (h *TokenHandler) Store(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	for _, ability := range req.Abilities {
+		if !middleware.IsValidAbility(ability) {
+			return utils.NewError(fiber.StatusBadRequest, "Unknown ability: "+ability).
+				WithDetails(fiber.Map{"abilities": middleware.Abilities})
+		}
+		if !middleware.CanGrant(c, ability) {
+			return utils.NewError(fiber.StatusForbidden, "Token cannot grant the "+ability+" ability it does not hold")
+		}
+	}
+
+	value := utils.GenerateToken()
+	if value == "" {
+		return utils.NewError(fiber.StatusInternalServerError, "Error generating token")
+	}
+
+	abilities, err := json.Marshal(req.Abilities)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error encoding abilities")
+	}
+	encoded := string(abilities)
+
+	now := time.Now()
+	token := models.PersonalAccessToken{
+		ID:            uuid.New(),
+		TokenableType: "User",
+		TokenableID:   userID,
+		Name:          req.Name,
+		Token:         utils.HashToken(value),
+		Abilities:     &encoded,
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := h.db.DB().WithContext(c.UserContext()).Create(&token).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error creating token")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":  newTokenResponse(token),
+		"token": value,
+	})
+}
+
+func (h *TokenHandler) Destroy(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	tokenID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.NewError(fiber.StatusBadRequest, "Invalid token ID")
+	}
+
+	result := h.db.DB().WithContext(c.UserContext()).
+		Where("id = ? AND tokenable_type = ? AND tokenable_id = ?", tokenID, "User", userID).
+		Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error revoking token")
+	}
+
+	if result.RowsAffected == 0 {
+		return utils.NewError(fiber.StatusNotFound, "Token not found")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Token revoked",
+	})
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/rules"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+type timerSet struct {
+	mu        sync.Mutex
+	timers    map[uuid.UUID]*time.Timer
+	deadlines map[uuid.UUID]time.Time
+}
+
+func newTimerSet() *timerSet {
+	return &timerSet{
+		timers:    make(map[uuid.UUID]*time.Timer),
+		deadlines: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (t *timerSet) schedule(key uuid.UUID, d time.Duration, fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if timer, ok := t.timers[key]; ok {
+		timer.Stop()
+	}
+	t.timers[key] = time.AfterFunc(d, fn)
+	t.deadlines[key] = time.Now().Add(d)
+}
+
+// deadline returns when the timer for key fires, if it has yet to.
+func (t *timerSet) deadline(key uuid.UUID) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.deadlines[key]
+	if !ok || !at.After(time.Now()) {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// cancel stops the timer for key and reports whether one was pending.
+func (t *timerSet) cancel(key uuid.UUID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timer, ok := t.timers[key]
+	if !ok {
+		return false
+	}
+	delete(t.timers, key)
+	delete(t.deadlines, key)
+	return timer.Stop()
+}
+
+// stopAll cancels every pending timer.
+func (t *timerSet) stopAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, timer := range t.timers {
+		timer.Stop()
+		delete(t.timers, key)
+		delete(t.deadlines, key)
+	}
+}
+
+// startTurnTimer arms the timer for whoever currently holds the turn. Bots
+// are given a short thinking delay instead; for humans it is a no-op unless
+// the lobby or the server default sets a turn timer.
+func (h *GameHandler) startTurnTimer(gameID uuid.UUID) {
+	var game models.Game
+	if err := h.db.DB().Preload("Lobby").Where("id = ?", gameID).First(&game).Error; err != nil {
+		log.Printf("Error loading game %s for turn timer: %v", gameID, err)
+		return
+	}
+
+	if game.Status != "in_progress" {
+		h.timers.cancel(gameID)
+		return
+	}
+
+	playerID := game.CurrentTurnPlayerID
+
+	var player models.Player
+	if err := h.db.DB().Where("id = ?", playerID).First(&player).Error; err == nil && player.IsBot {
+		h.timers.schedule(gameID, botThinkingDelay, func() {
+			h.playBotTurn(gameID, playerID)
+		})
+		return
+	}
+
+	seconds := lobbySettings(game.Lobby.GameSettings).TurnTimer(h.game.TurnTimerSeconds)
+	if seconds <= 0 {
+		h.timers.cancel(gameID)
+		return
+	}
+
+	h.timers.schedule(gameID, time.Duration(seconds)*time.Second, func() {
+		h.handleTurnTimeout(gameID, playerID)
+	})
+}
+
+func (h *GameHandler) handleTurnTimeout(gameID, playerID uuid.UUID) {
+	action, played, ok := h.autoPlayTurn(gameID, playerID)
+	if !ok {
+		return
+	}
+
+	h.hub.BroadcastToGame(gameID.String(), GameMessage{
+		Type: "turn_timeout",
+		Payload: fiber.Map{
+			"game_id":      gameID.String(),
+			"player_id":    playerID,
+			"action":       action,
+			"cards_played": played,
+		},
+	})
+
+	h.announceGameOver(gameID)
+}
+
+// autoPlayTurn takes the player's turn for them with what the player could see:
+// the lowest legal hand or face-up card is played, saving special cards for
+// when nothing else goes, and on face-down cards one is turned over at random.
+// Otherwise the pile is picked up. It reports whether a move was made.
+func (h *GameHandler) autoPlayTurn(gameID, playerID uuid.UUID) (string, []models.Card, bool) {
+	tx := h.db.DB().Begin()
+
+	// The same lock a player's move takes, so a move racing the timer is
+	// either applied first and ends this turn, or waits for it.
+	var game models.Game
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", gameID).First(&game).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error loading game %s for auto-play: %v", gameID, err)
+		return "", nil, false
+	}
+
+	if game.Status != "in_progress" || game.CurrentTurnPlayerID != playerID {
+		tx.Rollback()
+		return "", nil, false
+	}
+
+	var cards []models.Card
+	if err := tx.Where("player_id = ?", playerID).Find(&cards).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Error loading cards for player %s: %v", playerID, err)
+		return "", nil, false
+	}
+
+	counts := make(map[string]int)
+	for _, card := range cards {
+		counts[card.Status]++
+	}
+	phase := rules.CurrentPhase(counts["hand"], counts["faceup"], counts["hidden"])
+
+	pile, err := playPileValues(tx, gameID)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error fetching play pile: %v", err)
+		return "", nil, false
+	}
+
+	cfg, err := gameRules(tx, gameID)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error loading rules for game %s: %v", gameID, err)
+		return "", nil, false
+	}
+
+	card, chosen := chooseAutoPlay(cfg, phase, pile, cards)
+
+	action := "pick_up"
+	var played []models.Card
+	var drawn deckDraw
+	if chosen && rules.CanPlay(cfg, pile, []string{card.Value}) == nil {
+		if _, drawn, err = h.applyPlay(tx, cfg, gameID, playerID, []string{card.Value}, []cardUpdate{{
+			ID:           card.ID,
+			Status:       "played",
+			LocationType: "play_pile",
+		}}); err != nil {
+			tx.Rollback()
+			log.Printf("Error auto-playing card: %v", err)
+			return "", nil, false
+		}
+		action = "play"
+		played = append(played, card)
+	} else {
+		if chosen {
+			if err := stackOnPile(tx, gameID, []cardUpdate{{
+				ID:           card.ID,
+				Status:       "played",
+				LocationType: "play_pile",
+			}}); err != nil {
+				tx.Rollback()
+				log.Printf("Error revealing face-down card on auto-play: %v", err)
+				return "", nil, false
+			}
+
+			if err := recordGameEvent(tx, gameID, "reveal", &playerID, fiber.Map{
+				"card_id": card.ID,
+				"value":   card.Value,
+			}); err != nil {
+				tx.Rollback()
+				log.Printf("Error recording face-down reveal: %v", err)
+				return "", nil, false
+			}
+		}
+
+		picked, err := h.applyPickUp(tx, gameID, playerID)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error forcing pile pickup on auto-play: %v", err)
+			return "", nil, false
+		}
+
+		if picked == 0 {
+			if err := h.moveToNextPlayer(tx, gameID); err != nil {
+				tx.Rollback()
+				log.Printf("Error moving to next player on auto-play: %v", err)
+				return "", nil, false
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("Error committing auto-play: %v", err)
+		return "", nil, false
+	}
+
+	h.startTurnTimer(gameID)
+
+	h.broadcastDraw(gameID.String(), playerID, drawn)
+
+	return action, played, true
+}
+
+// chooseAutoPlay picks the card autoPlayTurn tries. Face-down values are not
+// looked at, so a random one is turned over as a player would have to. From
+// hand or face-up cards it takes the lowest plain card the pile accepts, then
+// the lowest special one, and reports false when none can be played.
+func chooseAutoPlay(cfg rules.Config, phase rules.Phase, pile []string, cards []models.Card) (models.Card, bool) {
+	var options []models.Card
+	for _, card := range cards {
+		if rules.CanPlayFrom(phase, card.Status) != nil {
+			continue
+		}
+		if phase == rules.PhaseFaceDown || rules.CanPlay(cfg, pile, []string{card.Value}) == nil {
+			options = append(options, card)
+		}
+	}
+	if len(options) == 0 {
+		return models.Card{}, false
+	}
+
+	if phase == rules.PhaseFaceDown {
+		return options[rand.IntN(len(options))], true
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		iSpecial, jSpecial := cfg.IsSpecial(options[i].Value), cfg.IsSpecial(options[j].Value)
+		if iSpecial != jSpecial {
+			return jSpecial
+		}
+		return rules.Rank(options[i].Value) < rules.Rank(options[j].Value)
+	})
+	return options[0], true
+}
@@ -3,43 +3,159 @@ package handler
 import (
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/game/progression"
+	"api/internal/server/utils"
+	"api/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	db database.Service
+	db       database.Service
+	users    service.UserService
+	presence service.PresenceService
 }
 
 type SearchUsersRequest struct {
-	Query string `query:"q" validate:"required,min=2"`
+	Query string `query:"q" validate:"required,min=2,max=100"`
+	// LobbyID leaves out users already in or invited to the lobby, for
+	// picking whom to invite.
+	LobbyID string `query:"lobby_id"`
 }
 
-func NewUserHandler(db database.Service) *UserHandler {
+// UserResponse is a user's own account, as returned to them and to admins.
+type UserResponse struct {
+	ID              uuid.UUID            `json:"id"`
+	Name            string               `json:"name"`
+	DisplayName     *string              `json:"display_name"`
+	Bio             *string              `json:"bio"`
+	Country         *string              `json:"country"`
+	Email           string               `json:"email"`
+	EmailVerifiedAt *time.Time           `json:"email_verified_at"`
+	Avatar          *string              `json:"avatar"`
+	AvatarURL       *string              `json:"avatar_url,omitempty"`
+	AvatarVariants  map[string]string    `json:"avatar_variants,omitempty"`
+	IsBot           bool                 `json:"is_bot"`
+	IsAdmin         bool                 `json:"is_admin"`
+	Level           int                  `json:"level"`
+	Progress        progression.Progress `json:"progress"`
+	Stats           *service.UserStats   `json:"stats,omitempty"`
+	CreatedAt       *time.Time           `json:"created_at"`
+	UpdatedAt       *time.Time           `json:"updated_at"`
+}
+
+// PublicUser is what anyone may see of another user, e.g. in search results.
+type PublicUser struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName *string   `json:"display_name"`
+	Avatar      *string   `json:"avatar"`
+	Level       int       `json:"level"`
+	// Presence is only filled in where the user's profile is public.
+	Presence *service.Presence `json:"presence,omitempty"`
+}
+
+func newUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		DisplayName:     user.DisplayName,
+		Bio:             user.Bio,
+		Country:         user.Country,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Avatar:          user.Avatar,
+		AvatarURL:       user.AvatarURL,
+		AvatarVariants:  user.AvatarVariants,
+		IsBot:           user.IsBot,
+		IsAdmin:         user.IsAdmin,
+		Level:           progression.LevelFor(user.XP),
+		Progress:        progression.ProgressFor(user.XP),
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+func newPublicUser(user models.User) PublicUser {
+	return PublicUser{
+		ID:          user.ID,
+		Name:        user.Name,
+		DisplayName: user.DisplayName,
+		Avatar:      user.Avatar,
+		Level:       progression.LevelFor(user.XP),
+	}
+}
+
+func NewUserHandler(db database.Service, users service.UserService, presence service.PresenceService) *UserHandler {
 	return &UserHandler{
-		db: db,
+		db:       db,
+		users:    users,
+		presence: presence,
 	}
 }
 
 func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
 	var req SearchUsersRequest
 	if err := c.QueryParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid query parameters",
-		})
+		return utils.NewError(fiber.StatusBadRequest, "Invalid query parameters")
 	}
 
-	var users []models.User
-	query := h.db.DB().
-		Where("name LIKE ? OR email LIKE ?", "%"+req.Query+"%", "%"+req.Query+"%").
-		Select("id, name, email, avatar").
-		Limit(10)
+	if errs := utils.Validate(req); errs != nil {
+		return utils.ValidationFailed(c, errs)
+	}
+
+	search := service.UserSearch{
+		Query:    req.Query,
+		ViewerID: c.Locals("user_id").(uuid.UUID),
+		Limit:    utils.ParseLimit(c.Query("limit"), 10, 50),
+	}
+	if req.LobbyID != "" {
+		lobbyID, err := uuid.Parse(req.LobbyID)
+		if err != nil {
+			return utils.NewError(fiber.StatusBadRequest, "Invalid lobby ID")
+		}
+		search.LobbyID = &lobbyID
+	}
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	search.Offset = (page - 1) * search.Limit
+
+	users, total, err := h.users.Search(c.UserContext(), search)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error searching users")
+	}
+
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	privacy, err := service.LoadPrivacySettings(h.db.DB().WithContext(c.UserContext()), ids)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching privacy settings")
+	}
+	presences, err := h.presence.Many(c.UserContext(), ids)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching presence")
+	}
 
-	if err := query.Find(&users).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error searching users",
-		})
+	results := make([]PublicUser, len(users))
+	for i, user := range users {
+		results[i] = newPublicUser(user)
+		if privacy[user.ID].ProfileVisibility == "public" {
+			presence := presences[user.ID]
+			results[i].Presence = &presence
+		}
 	}
 
-	return c.JSON(users)
+	return c.JSON(fiber.Map{
+		"data":  results,
+		"page":  page,
+		"limit": search.Limit,
+		"total": total,
+	})
 }
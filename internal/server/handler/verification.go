@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/mail"
+	"api/internal/server/utils"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const emailVerificationTTL = 60 * time.Minute
+
+type VerificationHandler struct {
+	db     database.Service
+	mailer mail.Mailer
+	appURL string
+	key    []byte
+}
+
+// NewVerificationHandler signs links with appKey. Without one a random key is
+// used, so links stop working after a restart.
+func NewVerificationHandler(db database.Service, mailer mail.Mailer, appURL, appKey string) *VerificationHandler {
+	return &VerificationHandler{
+		db:     db,
+		mailer: mailer,
+		appURL: strings.TrimRight(appURL, "/"),
+		key:    signingKey(appKey, "email verification"),
+	}
+}
+
+// signingKey returns appKey for signing emailed links, or a random key when
+// APP_KEY is not set.
+func signingKey(appKey, purpose string) []byte {
+	key := []byte(appKey)
+	if len(key) == 0 {
+		log.Printf("APP_KEY is not set, %s links will not survive a restart", purpose)
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Printf("Error generating %s key: %v", purpose, err)
+		}
+	}
+	return key
+}
+
+func (h *VerificationHandler) SendNotification(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return c.JSON(fiber.Map{
+			"message": "Email already verified",
+		})
+	}
+
+	expires := time.Now().Add(emailVerificationTTL).Unix()
+	hash := emailHash(user.Email)
+	link := fmt.Sprintf("%s/email/verify/%s/%s?expires=%d&signature=%s",
+		h.appURL, user.ID, hash, expires, h.sign(user.ID.String(), hash, expires))
+
+	if err := h.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below. It expires in %d minutes.\n\n%s\n",
+			user.Name, int(emailVerificationTTL.Minutes()), link),
+	}); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+		return utils.NewError(fiber.StatusInternalServerError, "Error sending verification email")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification link sent",
+	})
+}
+
+func (h *VerificationHandler) Verify(c *fiber.Ctx) error {
+	id := c.Params("id")
+	hash := c.Params("hash")
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return utils.NewError(fiber.StatusForbidden, "Verification link has expired")
+	}
+
+	if !hmac.Equal([]byte(c.Query("signature")), []byte(h.sign(id, hash, expires))) {
+		return utils.NewError(fiber.StatusForbidden, "Invalid verification link")
+	}
+
+	var user models.User
+	if err := h.db.DB().WithContext(c.UserContext()).Where("id = ?", id).First(&user).Error; err != nil {
+		return utils.NewError(fiber.StatusNotFound, "User not found")
+	}
+
+	// The hash pins the link to the address it was sent to, so changing email
+	// invalidates any outstanding links.
+	if !hmac.Equal([]byte(hash), []byte(emailHash(user.Email))) {
+		return utils.NewError(fiber.StatusForbidden, "Invalid verification link")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := h.db.DB().WithContext(c.UserContext()).Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error verifying email")
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Email verified",
+	})
+}
+
+func (h *VerificationHandler) sign(id, hash string, expires int64) string {
+	mac := hmac.New(sha256.New, h.key)
+	fmt.Fprintf(mac, "%s|%s|%d", id, hash, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func emailHash(email string) string {
+	sum := sha1.Sum([]byte(strings.ToLower(email)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/progression"
+	"api/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// xpAward is the XP one player earned from a game, as recorded on the
+// game_over event.
+type xpAward struct {
+	Amount       int                       `json:"amount"`
+	Achievements []progression.Achievement `json:"achievements,omitempty"`
+}
+
+// awardGameXP grants XP inside tx to every player who saw the game through,
+// placements being ordered best first. Members of a winning team other than
+// its best placed player get the win bonus on top. Bots and players who
+// forfeited earn nothing.
+func awardGameXP(tx *gorm.DB, gameID uuid.UUID, players []models.Player, winners map[uuid.UUID]bool) (map[uuid.UUID]xpAward, error) {
+	userIDs := make([]uuid.UUID, len(players))
+	for i, player := range players {
+		userIDs[i] = player.UserID
+	}
+
+	var bots []uuid.UUID
+	if err := tx.Model(&models.User{}).Where("id IN ? AND is_bot = ?", userIDs, true).Pluck("id", &bots).Error; err != nil {
+		return nil, err
+	}
+	isBot := make(map[uuid.UUID]bool, len(bots))
+	for _, id := range bots {
+		isBot[id] = true
+	}
+
+	var counts []struct {
+		PlayerID uuid.UUID
+		Type     string
+		Count    int
+	}
+	if err := tx.Model(&models.GameEvent{}).
+		Select("player_id, type, COUNT(*) AS count").
+		Where("game_id = ? AND type IN ? AND player_id IS NOT NULL", gameID, []string{"pickup", "burn"}).
+		Group("player_id, type").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	feats := make(map[uuid.UUID]progression.Feats, len(players))
+	for _, count := range counts {
+		f := feats[count.PlayerID]
+		switch count.Type {
+		case "pickup":
+			f.Pickups = count.Count
+		case "burn":
+			f.Burns = count.Count
+		}
+		feats[count.PlayerID] = f
+	}
+
+	awards := make(map[uuid.UUID]xpAward, len(players))
+	for i, player := range players {
+		if player.Status == "forfeited" || isBot[player.UserID] {
+			continue
+		}
+
+		placement := i + 1
+		award := xpAward{
+			Amount:       progression.GameXP(placement, len(players)),
+			Achievements: progression.Achievements(placement, feats[player.ID]),
+		}
+		for _, achievement := range award.Achievements {
+			award.Amount += achievement.XP
+		}
+		if placement != 1 && winners[player.ID] {
+			award.Amount += progression.WinBonusXP
+		}
+
+		if _, err := service.GrantXP(tx, player.UserID, award.Amount, "game", &gameID); err != nil {
+			return nil, err
+		}
+		awards[player.ID] = award
+	}
+	return awards, nil
+}
+
+// announceLevelUps tells the game about every player who reached a new level
+// from the XP the game gave them.
+func (h *GameHandler) announceLevelUps(gameID uuid.UUID, events []models.XPEvent) {
+	for _, event := range events {
+		if event.LevelAfter <= event.LevelBefore {
+			continue
+		}
+		h.hub.BroadcastToGame(gameID.String(), GameMessage{
+			Type: "level_up",
+			Payload: fiber.Map{
+				"game_id":        gameID,
+				"user_id":        event.UserID,
+				"level":          event.LevelAfter,
+				"previous_level": event.LevelBefore,
+				"xp_gained":      event.Amount,
+			},
+		})
+	}
+}
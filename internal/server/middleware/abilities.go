@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"api/internal/server/utils"
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Abilities lists every scope a personal access token can be granted.
+var Abilities = []string{
+	"*",
+	"lobby:read",
+	"lobby:write",
+	"game:play",
+	"profile:read",
+	"profile:write",
+	"friends:read",
+	"friends:write",
+	"tokens:manage",
+	"admin",
+}
+
+// adminAbility opens the admin API. Unlike every other ability it is not
+// covered by "*", so only tokens issued with it by name reach admin routes.
+const adminAbility = "admin"
+
+func IsValidAbility(ability string) bool {
+	for _, known := range Abilities {
+		if ability == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAbilities decodes a token's stored abilities. Tokens issued before
+// scopes existed have none stored and keep full access outside the admin API.
+func ParseAbilities(raw *string) []string {
+	if raw == nil || *raw == "" {
+		return []string{"*"}
+	}
+
+	var abilities []string
+	if err := json.Unmarshal([]byte(*raw), &abilities); err != nil {
+		return nil
+	}
+	return abilities
+}
+
+func hasAbility(granted []string, ability string) bool {
+	resource, _, _ := strings.Cut(ability, ":")
+	for _, candidate := range granted {
+		if candidate == ability || candidate == resource+":*" {
+			return true
+		}
+		if candidate == "*" && ability != adminAbility {
+			return true
+		}
+	}
+	return false
+}
+
+// CanGrant reports whether the request may issue a token with the ability.
+// A token can only hand on abilities it holds itself, so a narrow token
+// cannot mint a broader one. Cookie sessions may grant any ability.
+func CanGrant(c *fiber.Ctx, ability string) bool {
+	granted, ok := c.Locals("token_abilities").([]string)
+	return !ok || hasAbility(granted, ability)
+}
+
+// RequireAbility rejects token-authenticated requests whose token lacks the
+// ability. Cookie sessions are first-party and always pass.
+func RequireAbility(ability string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted, ok := c.Locals("token_abilities").([]string)
+		if !ok || hasAbility(granted, ability) {
+			return c.Next()
+		}
+
+		return utils.NewError(fiber.StatusForbidden, "Token is missing the "+ability+" ability")
+	}
+}
+
+// RequireAbilityByMethod applies read to safe methods and write to the rest.
+func RequireAbilityByMethod(read, write string) fiber.Handler {
+	readCheck := RequireAbility(read)
+	writeCheck := RequireAbility(write)
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return readCheck(c)
+		default:
+			return writeCheck(c)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import "testing"
+
+func TestHasAbility(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		ability string
+		want    bool
+	}{
+		{name: "exact match", granted: []string{"lobby:read"}, ability: "lobby:read", want: true},
+		{name: "other ability", granted: []string{"lobby:read"}, ability: "lobby:write", want: false},
+		{name: "resource wildcard", granted: []string{"lobby:*"}, ability: "lobby:write", want: true},
+		{name: "resource wildcard elsewhere", granted: []string{"lobby:*"}, ability: "game:play", want: false},
+		{name: "wildcard", granted: []string{"*"}, ability: "tokens:manage", want: true},
+		{name: "wildcard does not open admin", granted: []string{"*"}, ability: "admin", want: false},
+		{name: "admin by name", granted: []string{"admin"}, ability: "admin", want: true},
+		{name: "nothing granted", granted: nil, ability: "profile:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasAbility(tt.granted, tt.ability); got != tt.want {
+				t.Errorf("hasAbility(%v, %q) = %v, want %v", tt.granted, tt.ability, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"api/internal/database"
 	"api/internal/database/models"
+	"api/internal/server/utils"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,23 +14,31 @@ func AuthMiddleware(db database.Service) fiber.Handler {
     return func(c *fiber.Ctx) error {
         sessionID := c.Cookies("session_id")
         if sessionID == "" {
-            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-                "error": "Session ID is missing",
-            })
+            if token, ok := bearerToken(c); ok {
+                return authenticateToken(c, db, token)
+            }
+
+            return utils.NewError(fiber.StatusUnauthorized, "Session ID is missing")
         }
 
         var session models.Session
-        if err := db.DB().Where("id = ?", sessionID).First(&session).Error; err != nil {
-            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-                "error": "Invalid session",
-            })
+        if err := db.DB().WithContext(c.UserContext()).Where("id = ?", sessionID).First(&session).Error; err != nil {
+            return utils.NewError(fiber.StatusUnauthorized, "Invalid session")
         }
 
         currentTime := int(time.Now().Unix())
         if session.LastActivity + (24 * 3600) < currentTime {
-            return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-                "error": "Session expired",
-            })
+            return utils.NewError(fiber.StatusUnauthorized, "Session expired")
+        }
+
+        // Keep last_activity fresh so it doubles as a presence signal, without
+        // writing on every single request.
+        if session.LastActivity + 60 < currentTime {
+            db.DB().WithContext(c.UserContext()).Model(&session).Update("last_activity", currentTime)
+        }
+
+        if err := CheckBan(c, db, session.UserID); err != nil {
+            return err
         }
 
         c.Locals("user_id", session.UserID)
@@ -37,3 +47,33 @@ func AuthMiddleware(db database.Service) fiber.Handler {
     }
 }
 
+func bearerToken(c *fiber.Ctx) (string, bool) {
+    header := c.Get(fiber.HeaderAuthorization)
+    token, ok := strings.CutPrefix(header, "Bearer ")
+    return token, ok && token != ""
+}
+
+func authenticateToken(c *fiber.Ctx, db database.Service, value string) error {
+    var token models.PersonalAccessToken
+    if err := db.DB().WithContext(c.UserContext()).Where("token = ? AND tokenable_type = ?", utils.HashToken(value), "User").First(&token).Error; err != nil {
+        return utils.NewError(fiber.StatusUnauthorized, "Invalid token")
+    }
+
+    now := time.Now()
+    if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+        return utils.NewError(fiber.StatusUnauthorized, "Token expired")
+    }
+
+    if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > time.Minute {
+        db.DB().WithContext(c.UserContext()).Model(&token).Update("last_used_at", now)
+    }
+
+    if err := CheckBan(c, db, token.TokenableID); err != nil {
+        return err
+    }
+
+    c.Locals("user_id", token.TokenableID)
+    c.Locals("token_id", token.ID)
+    c.Locals("token_abilities", ParseAbilities(token.Abilities))
+    return c.Next()
+}
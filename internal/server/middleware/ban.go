@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ActiveBan returns the ban currently in force for the user, or nil when
+// there is none. A permanent ban wins over a temporary one, otherwise the
+// one that runs longest.
+func ActiveBan(db *gorm.DB, userID uuid.UUID) (*models.Ban, error) {
+	var ban models.Ban
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now().UTC()).
+		Order("expires_at DESC NULLS FIRST").
+		First(&ban).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+// BannedError is the 403 sent to a banned user. The BANNED code tells it
+// apart from an ordinary permission failure and the details carry what a
+// client needs to explain the ban.
+func BannedError(ban *models.Ban) *utils.APIError {
+	return utils.NewError(fiber.StatusForbidden, "Your account is banned").
+		WithCode("BANNED").
+		WithDetails(fiber.Map{
+			"reason":     ban.Reason,
+			"expires_at": ban.ExpiresAt,
+			"permanent":  ban.ExpiresAt == nil,
+		})
+}
+
+// CheckBan fails with BannedError when the user has a ban in force.
+func CheckBan(c *fiber.Ctx, db database.Service, userID uuid.UUID) error {
+	ban, err := ActiveBan(db.DB().WithContext(c.UserContext()), userID)
+	if err != nil {
+		return utils.NewError(fiber.StatusInternalServerError, "Error checking account status")
+	}
+	if ban != nil {
+		return BannedError(ban)
+	}
+	return nil
+}
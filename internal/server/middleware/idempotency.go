@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"api/internal/server/utils"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+type storedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Idempotency replays the stored response when a client retries a request
+// with the same Idempotency-Key header, instead of running it again. Keys are
+// scoped to the user and the request path and kept for ttl. Only successful
+// responses are stored, so a request that failed can be retried for real.
+// Requests without the header pass straight through. It must run after
+// AuthMiddleware.
+func Idempotency(storage fiber.Storage, ttl time.Duration) fiber.Handler {
+	locks := newKeyedMutex()
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotencyKeyHeader)
+		if key == "" || ttl <= 0 {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return utils.NewError(fiber.StatusBadRequest, "Idempotency-Key is too long")
+		}
+
+		userID, _ := c.Locals("user_id").(uuid.UUID)
+		scoped := userID.String() + ":" + c.Method() + ":" + c.Path() + ":" + key
+
+		// Concurrent retries wait for the first attempt and then replay it.
+		unlock := locks.lock(scoped)
+		defer unlock()
+
+		if encoded, err := storage.Get(scoped); err != nil {
+			log.Printf("Error reading idempotency store: %v", err)
+		} else if encoded != nil {
+			var stored storedResponse
+			if err := json.Unmarshal(encoded, &stored); err == nil {
+				c.Set(fiber.HeaderContentType, stored.ContentType)
+				c.Set(idempotencyReplayedHeader, "true")
+				return c.Status(stored.Status).Send(stored.Body)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status < 200 || status >= 300 {
+			return nil
+		}
+
+		encoded, err := json.Marshal(storedResponse{
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        c.Response().Body(),
+		})
+		if err == nil {
+			err = storage.Set(scoped, encoded, ttl)
+		}
+		if err != nil {
+			log.Printf("Error storing idempotent response: %v", err)
+		}
+		return nil
+	}
+}
+
+// keyedMutex serialises work per key, dropping each lock once nobody holds
+// or waits on it.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{
+		locks: make(map[string]*keyedLock),
+	}
+}
+
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.waiters++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		k.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
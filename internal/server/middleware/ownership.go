@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/server/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequireSelfOrAdmin lets a request through only when the user ID in the
+// route parameter param is the authenticated user's own, or the
+// authenticated user is an admin. It must run after AuthMiddleware, on the
+// route itself so the parameter is resolved.
+func RequireSelfOrAdmin(db database.Service, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uuid.UUID)
+		if !ok {
+			return utils.NewError(fiber.StatusUnauthorized, "Not authenticated")
+		}
+
+		if target, err := uuid.Parse(c.Params(param)); err == nil && target == userID {
+			return c.Next()
+		}
+
+		admin, err := isAdmin(c, db, userID)
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+		}
+		if !admin {
+			return utils.NewError(fiber.StatusForbidden, "You can only manage your own profile")
+		}
+
+		return c.Next()
+	}
+}
+
+// RequireAdmin lets a request through only when the authenticated user is an
+// admin. It must run after AuthMiddleware.
+func RequireAdmin(db database.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uuid.UUID)
+		if !ok {
+			return utils.NewError(fiber.StatusUnauthorized, "Not authenticated")
+		}
+
+		admin, err := isAdmin(c, db, userID)
+		if err != nil {
+			return utils.NewError(fiber.StatusInternalServerError, "Error fetching user")
+		}
+		if !admin {
+			return utils.NewError(fiber.StatusForbidden, "Admin access required")
+		}
+
+		return c.Next()
+	}
+}
+
+func isAdmin(c *fiber.Ctx, db database.Service, userID uuid.UUID) (bool, error) {
+	var user models.User
+	if err := db.DB().WithContext(c.UserContext()).Select("is_admin").Where("id = ?", userID).First(&user).Error; err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
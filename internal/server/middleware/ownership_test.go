@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"api/internal/server/utils"
+)
+
+// mockDatabase is a database.Service backed by sqlmock, for middleware that
+// only needs DB().
+type mockDatabase struct {
+	db *gorm.DB
+}
+
+func (m mockDatabase) Health() map[string]string { return nil }
+func (m mockDatabase) Close() error              { return nil }
+func (m mockDatabase) DB() *gorm.DB              { return m.db }
+func (m mockDatabase) Listen(context.Context, string, func(string)) error {
+	return nil
+}
+func (m mockDatabase) MigrationVersion(context.Context) (int64, error) { return 0, nil }
+func (m mockDatabase) Migrate(context.Context) ([]int64, error)        { return nil, nil }
+
+func newMockDatabase(t *testing.T) (mockDatabase, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("opening sqlmock: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening gorm: %v", err)
+	}
+	return mockDatabase{db: db}, mock
+}
+
+// ownProfileApp mounts RequireSelfOrAdmin on /users/:id the way the profile
+// routes do, with the caller authenticated as userID.
+func ownProfileApp(db mockDatabase, userID uuid.UUID) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: utils.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	app.Put("/users/:id", RequireSelfOrAdmin(db, "id"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+func expectAdminLookup(mock sqlmock.Sqlmock, userID uuid.UUID, admin bool) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "is_admin" FROM "users" WHERE id = $1`)).
+		WithArgs(userID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"is_admin"}).AddRow(admin))
+}
+
+func TestRequireSelfOrAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		self   bool
+		admin  bool
+		status int
+	}{
+		{name: "own profile", self: true, status: fiber.StatusNoContent},
+		{name: "another user's profile", status: fiber.StatusForbidden},
+		{name: "admin on another user's profile", admin: true, status: fiber.StatusNoContent},
+		{name: "admin on own profile", self: true, admin: true, status: fiber.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDatabase(t)
+
+			userID := uuid.New()
+			target := uuid.New()
+			if tt.self {
+				target = userID
+			} else {
+				// Only requests for someone else's profile look the caller up.
+				expectAdminLookup(mock, userID, tt.admin)
+			}
+
+			resp, err := ownProfileApp(db, userID).Test(httptest.NewRequest("PUT", "/users/"+target.String(), nil))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
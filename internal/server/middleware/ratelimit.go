@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"api/internal/server/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/google/uuid"
+)
+
+// RateLimit allows max requests per window from each client and answers the
+// rest with 429 and a Retry-After header. Authenticated requests are counted
+// per user, anything else per IP. name keeps the counters of separate limits
+// apart when they share storage; a nil storage keeps them in memory. A max of
+// zero turns the limit off.
+func RateLimit(name string, max int, window time.Duration, storage fiber.Storage) fiber.Handler {
+	if max <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		Storage:    storage,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userID, ok := c.Locals("user_id").(uuid.UUID); ok {
+				return name + ":user:" + userID.String()
+			}
+			return name + ":ip:" + c.IP()
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return utils.NewError(fiber.StatusTooManyRequests, "Too many requests, please try again later")
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"api/internal/config"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/helmet"
+)
+
+// SecurityHeaders sets the helmet headers on every API response.
+func SecurityHeaders(cfg config.Security) fiber.Handler {
+	return helmet.New(helmet.Config{
+		XFrameOptions: cfg.FrameOptions,
+		HSTSMaxAge:    cfg.HSTSMaxAge,
+		// The API only serves JSON, so nothing it returns should load
+		// anything.
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	})
+}
+
+// StaticHeaders is a fiber.Static ModifyResponse hook. It replaces the API
+// policy on uploaded files, which the frontend embeds from its own origin,
+// with one that only allows the image itself and sandboxes anything else.
+func StaticHeaders(cfg config.Security) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentSecurityPolicy, cfg.StaticCSP)
+		c.Set("Cross-Origin-Resource-Policy", "cross-origin")
+		staticETag(c)
+		return nil
+	}
+}
+
+// Compression compresses response bodies at the configured level. WebSocket
+// upgrades are left alone.
+func Compression(cfg config.Security) fiber.Handler {
+	level := compress.LevelBestSpeed
+	switch cfg.Compression {
+	case "off":
+		level = compress.LevelDisabled
+	case "best":
+		level = compress.LevelBestCompression
+	}
+
+	return compress.New(compress.Config{
+		Level: level,
+		Next: func(c *fiber.Ctx) bool {
+			return websocket.IsWebSocketUpgrade(c)
+		},
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// staticETag tags a served file with a weak ETag derived from its size and
+// modification time, and turns the response into a 304 when the client
+// already holds that version. fasthttp only handles If-Modified-Since, which
+// some CDNs and browsers do not send.
+func staticETag(c *fiber.Ctx) {
+	if c.Response().StatusCode() != fiber.StatusOK {
+		return
+	}
+
+	lastModified := c.GetRespHeader(fiber.HeaderLastModified)
+	if lastModified == "" {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(c.GetRespHeader(fiber.HeaderContentLength) + "|" + lastModified))
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+	c.Set(fiber.HeaderETag, etag)
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		c.Response().ResetBody()
+		c.Response().Header.Del(fiber.HeaderContentLength)
+		c.Status(fiber.StatusNotModified)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using weak
+// comparison as RFC 9110 requires for GET.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,11 @@
 package server
 
 import (
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -9,48 +14,246 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/google/uuid"
 
+	"api/internal/backplane"
+	"api/internal/cache"
+	"api/internal/firebase"
+	"api/internal/game/decks"
+	"api/internal/mail"
+	"api/internal/moderation"
+	"api/internal/redis"
 	"api/internal/server/handler"
 	"api/internal/server/middleware"
+	"api/internal/service"
+	"api/internal/storage"
 )
 
 func (s *FiberServer) RegisterFiberRoutes() {
 	s.App.Use(cors.New(cors.Config{
-		// AllowOrigins:     "https://www.troika.id.lv, http://192.168.8.108:3000",
-		AllowOrigins:     "https://www.troika.id.lv, http://10.13.59.2:3000",
+		AllowOrigins:     strings.Join(s.config.CORS.AllowOrigins, ", "),
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Accept,Authorization,Content-Type",
+		AllowHeaders:     "Accept,Authorization,Content-Type,Idempotency-Key",
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 	s.App.Use(logger.New())
 	s.App.Use(recover.New())
 	s.App.Use(requestid.New())
+	s.App.Use(middleware.Tracing())
+	s.App.Use(middleware.SecurityHeaders(s.config.Security))
+	s.App.Use(middleware.Compression(s.config.Security))
+	s.App.Use(middleware.RequestContext(s.config.Database.RequestTimeout))
 	s.store.RegisterType(uuid.New())
 
-	authHandler := handler.NewAuthHandler(s.db, s.store)
-	lobbyHandler := handler.NewLobbyHandler(s.db)
-	profileHandler := handler.NewProfileHandler(s.db)
-	userHandler := handler.NewUserHandler(s.db)
-	notificationHandler := handler.NewNotificationHandler(s.db)
-	gameHandler := handler.NewGameHandler(s.db)
-	cardHandler := handler.NewCardHandler(s.db)
+	deckProvider := decks.NewProvider(s.config.Deck.Provider, s.config.Deck.CardImageBaseURL)
+	mailer := mail.NewMailer(s.config.Mail.Driver, mail.SMTPConfig{
+		Host:     s.config.Mail.SMTPHost,
+		Port:     s.config.Mail.SMTPPort,
+		Username: s.config.Mail.SMTPUsername,
+		Password: s.config.Mail.SMTPPassword,
+		From:     s.config.Mail.From,
+	})
+
+	gameHub := handler.NewGameHub()
+	hubBackplane, err := backplane.New(s.config.Redis.Backplane, s.config.Redis.URL)
+	if err != nil {
+		log.Fatalf("Error configuring hub backplane: %v", err)
+	}
+	if hubBackplane != nil {
+		if err := gameHub.UseBackplane(hubBackplane); err != nil {
+			log.Fatalf("Error subscribing to hub backplane: %v", err)
+		}
+	}
+
+	wordFilter := moderation.NewFilter(s.config.Chat.BlockedWords)
+
+	avatarStorage, err := storage.New(storage.Config{
+		Driver:    s.config.Storage.Driver,
+		PublicURL: s.config.Storage.PublicURL,
+		LocalRoot: s.config.Storage.LocalRoot,
+		S3: storage.S3Config{
+			Endpoint:  s.config.Storage.S3Endpoint,
+			Region:    s.config.Storage.S3Region,
+			Bucket:    s.config.Storage.S3Bucket,
+			AccessKey: s.config.Storage.S3AccessKey,
+			SecretKey: s.config.Storage.S3SecretKey,
+			PathStyle: s.config.Storage.S3PathStyle,
+			URLExpiry: s.config.Storage.S3URLExpiry,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Error configuring avatar storage: %v", err)
+	}
+
+	userService := service.NewUserService(s.db)
+	lobbyService := service.NewLobbyService(s.db, s.config.Game)
+	if s.config.LobbyCache.TTL > 0 {
+		var listings service.ListingStore = cache.NewMemory()
+		if s.config.LobbyCache.Store == "redis" {
+			client, err := redis.NewClient(s.config.Redis.URL)
+			if err != nil {
+				log.Fatalf("Error connecting to lobby cache: %v", err)
+			}
+			listings = redis.NewStorage(client, "shithead:lobbies:")
+		}
+		lobbyService = service.NewCachedLobbyService(lobbyService, listings, s.config.LobbyCache.TTL)
+	}
+	gameService := service.NewGameService(s.db)
+	auditService := service.NewAuditService(s.db)
+	statsService := service.NewStatsService(s.db)
+	seasonService := service.NewSeasonService(s.db, s.config.Season.Length, s.config.Season.RatingDecayPercent)
+	challengeService := service.NewChallengeService(s.db)
+
+	var presenceStore service.PresenceStore = cache.NewMemory()
+	if s.config.Presence.Store == "redis" {
+		client, err := redis.NewClient(s.config.Redis.URL)
+		if err != nil {
+			log.Fatalf("Error connecting to presence store: %v", err)
+		}
+		presenceStore = redis.NewStorage(client, "shithead:presence:")
+	}
+	presenceService := service.NewPresenceService(s.db, presenceStore, s.config.Presence.AwayAfter, s.config.Presence.OfflineAfter)
+
+	lockoutHandler := handler.NewLockoutHandler(s.db, mailer, s.config.AppURL, s.config.AppKey, s.config.Lockout)
+	authHandler := handler.NewAuthHandler(s.db, s.store, userService, lockoutHandler, auditService, firebase.NewVerifier(s.config.Firebase.ProjectID))
+	passwordHandler := handler.NewPasswordHandler(s.db, mailer, s.config.FrontendURL, auditService)
+	verificationHandler := handler.NewVerificationHandler(s.db, mailer, s.config.AppURL, s.config.AppKey)
+	lobbyHandler := handler.NewLobbyHandler(s.db, gameHub, s.config.Game, lobbyService, userService, wordFilter, auditService)
+	profileHandler := handler.NewProfileHandler(userService, statsService, auditService, avatarStorage, s.config.Storage.AvatarMaxBytes)
+	userHandler := handler.NewUserHandler(s.db, userService, presenceService)
+	notificationHandler := handler.NewNotificationHandler(s.db, gameHub)
+	gameHandler := handler.NewGameHandler(s.db, gameHub, deckProvider, s.config.Game, gameService, lobbyService, userService, wordFilter)
+	s.games = gameHandler
+	cardHandler := handler.NewCardHandler(s.db, deckProvider)
+	var leaderboardPages fiber.Storage = cache.NewMemory()
+	if s.config.LeaderboardCache.Store == "redis" {
+		client, err := redis.NewClient(s.config.Redis.URL)
+		if err != nil {
+			log.Fatalf("Error connecting to leaderboard cache: %v", err)
+		}
+		leaderboardPages = redis.NewStorage(client, "shithead:leaderboard:")
+	}
+	leaderboardHandler := handler.NewLeaderboardHandler(s.db, leaderboardPages, s.config.LeaderboardCache.TTL)
+	seasonHandler := handler.NewSeasonHandler(seasonService)
+	challengeHandler := handler.NewChallengeHandler(challengeService, gameHub)
+	presenceHandler := handler.NewPresenceHandler(userService, presenceService, gameHub)
+	gameHub.TrackPresence(presenceHandler.Report)
+	matchmakingHandler := handler.NewMatchmakingHandler(s.db, lobbyService, s.config.Game)
+	ratingHandler := handler.NewRatingHandler(s.db)
+	matchHandler := handler.NewMatchHandler(s.db)
+	statsHandler := handler.NewStatsHandler(userService, statsService)
+	gameEventHandler := handler.NewGameEventHandler(s.db, gameService)
+	gameHub.SetSequencer(gameEventHandler.Sequence)
+	friendHandler := handler.NewFriendHandler(s.db, presenceService)
+	tokenHandler := handler.NewTokenHandler(s.db)
+	cleanupHandler := handler.NewCleanupHandler(s.db, gameHub, gameHandler, lobbyService, s.config.Game, auditService)
+	healthHandler := handler.NewHealthHandler(s.db, gameHub, lobbyService)
+	reportHandler := handler.NewReportHandler(s.db)
+	messageHandler := handler.NewMessageHandler(s.db, wordFilter)
+	adminHandler := handler.NewAdminHandler(s.db, gameHub, lobbyService, gameHandler, auditService)
+
+	go leaderboardHandler.RunRefresher(5 * time.Minute)
+	go matchmakingHandler.RunMatcher(5 * time.Second)
+	go presenceHandler.RunHeartbeat(service.PresenceSocketTTL / 3)
+	go notificationHandler.RunPusher(5 * time.Second)
+
+	s.scheduler.Every(time.Minute, "expire-invitations", cleanupHandler.ExpireInvitations)
+	s.scheduler.Every(15*time.Minute, "delete-stale-sessions", cleanupHandler.DeleteStaleSessions)
+	s.scheduler.Every(time.Hour, "purge-notifications", cleanupHandler.PurgeNotifications)
+	s.scheduler.Every(time.Hour, "purge-login-attempts", cleanupHandler.PurgeLoginAttempts)
+	s.scheduler.Every(5*time.Minute, "close-idle-lobbies", cleanupHandler.CloseIdleLobbies)
+	s.scheduler.Every(time.Minute, "abandon-stale-games", cleanupHandler.AbandonStaleGames)
+	s.scheduler.Every(10*time.Minute, "rollover-season", seasonHandler.RolloverSeason)
+	s.scheduler.Every(5*time.Minute, "generate-challenges", challengeHandler.GenerateChallenges)
+	s.scheduler.Start()
+
+	var limits fiber.Storage
+	if s.config.RateLimit.Store == "redis" {
+		client, err := redis.NewClient(s.config.Redis.URL)
+		if err != nil {
+			log.Fatalf("Error connecting to rate limit store: %v", err)
+		}
+		limits = redis.NewStorage(client, "shithead:ratelimit:")
+	}
+	var replays fiber.Storage = cache.NewMemory()
+	if s.config.Idempotency.Store == "redis" {
+		client, err := redis.NewClient(s.config.Redis.URL)
+		if err != nil {
+			log.Fatalf("Error connecting to idempotency store: %v", err)
+		}
+		replays = redis.NewStorage(client, "shithead:idempotency:")
+	}
+	idempotent := middleware.Idempotency(replays, s.config.Idempotency.TTL)
+
+	authLimit := middleware.RateLimit("auth", s.config.RateLimit.AuthMax, s.config.RateLimit.AuthWindow, limits)
+	lobbyLimit := middleware.RateLimit("lobby", s.config.RateLimit.WriteMax, s.config.RateLimit.WriteWindow, limits)
+	inviteLimit := middleware.RateLimit("invite", s.config.RateLimit.WriteMax, s.config.RateLimit.WriteWindow, limits)
+	reportLimit := middleware.RateLimit("report", s.config.RateLimit.WriteMax, s.config.RateLimit.WriteWindow, limits)
+	messageLimit := middleware.RateLimit("message", s.config.RateLimit.WriteMax, s.config.RateLimit.WriteWindow, limits)
 
-	s.App.Post("/register", authHandler.Register)
-	s.App.Post("/login", authHandler.Login)
+	staticFiles := fiber.Static{
+		ByteRange:      true,
+		MaxAge:         int(s.config.Static.MaxAge.Seconds()),
+		ModifyResponse: middleware.StaticHeaders(s.config.Security),
+	}
+	if s.config.Storage.Driver == "local" {
+		s.App.Static("/avatars", filepath.Join(s.config.Storage.LocalRoot, "avatars"), staticFiles)
+	}
+	s.App.Static("/static/cards", filepath.Join(s.config.Static.Root, "cards"), staticFiles)
+
+	s.App.Use(presenceHandler.Track)
+
+	s.App.Get("/health", healthHandler.Health)
+	s.App.Get("/ready", healthHandler.Ready)
+
+	s.App.Post("/register", authLimit, authHandler.Register)
+	s.App.Post("/login", authLimit, authHandler.Login)
 	s.App.Post("/logout", middleware.AuthMiddleware(s.db), authHandler.Logout)
 	s.App.Get("/user", middleware.AuthMiddleware(s.db), authHandler.GetCurrentUser)
-	s.App.Post("/firebase", authHandler.FirebaseLogin)
+	s.App.Post("/firebase", authLimit, authHandler.FirebaseLogin)
+	s.App.Post("/password/forgot", authLimit, passwordHandler.Forgot)
+	s.App.Post("/password/reset", authLimit, passwordHandler.Reset)
+	s.App.Post("/email/verification-notification", middleware.AuthMiddleware(s.db), verificationHandler.SendNotification)
+	s.App.Get("/email/verify/:id/:hash", verificationHandler.Verify)
+	s.App.Get("/account/unlock/:id", authLimit, lockoutHandler.Unlock)
 
-	lobbies := s.App.Group("/lobbies", middleware.AuthMiddleware(s.db))
+	lobbies := s.App.Group("/lobbies", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("lobby:read", "lobby:write"))
 	lobbies.Get("/", lobbyHandler.Index)
-	lobbies.Post("/", lobbyHandler.Store)
+	lobbies.Post("/", lobbyLimit, idempotent, lobbyHandler.Store)
+	lobbies.Post("/practice", lobbyLimit, gameHandler.StartPractice)
+	lobbies.Post("/quick-join", lobbyLimit, idempotent, lobbyHandler.QuickJoin)
+	lobbies.Get("/by-code/:code", lobbyHandler.ShowByCode)
 	lobbies.Get("/:id/show", lobbyHandler.Show)
-	lobbies.Post("/:lobbyId/join", lobbyHandler.JoinLobby)
+	lobbies.Patch("/:lobbyId", lobbyHandler.Update)
+	lobbies.Post("/:lobbyId/join", idempotent, lobbyHandler.JoinLobby)
 	lobbies.Post("/:lobbyId/leave", lobbyHandler.LeaveLobby)
-	lobbies.Post("/:lobbyId/invite", lobbyHandler.InviteUser)
-	lobbies.Post("/invitation/accept", lobbyHandler.AcceptInvitation)
+	lobbies.Post("/:lobbyId/ready", gameHandler.Ready)
+	lobbies.Post("/:lobbyId/unready", gameHandler.Unready)
+	lobbies.Post("/:lobbyId/transfer", lobbyHandler.TransferOwnership)
+	lobbies.Post("/:lobbyId/team", lobbyHandler.ChooseTeam)
+	lobbies.Get("/:lobbyId/scoreboard", lobbyHandler.Scoreboard)
+	lobbies.Get("/:lobbyId/queue/me", lobbyHandler.QueuePosition)
+	lobbies.Delete("/:lobbyId/queue", lobbyHandler.LeaveQueue)
+	lobbies.Post("/:lobbyId/invite", inviteLimit, idempotent, lobbyHandler.InviteUser)
+	lobbies.Post("/:lobbyId/invite-code", lobbyHandler.RegenerateInviteCode)
+	lobbies.Delete("/:lobbyId/invite-code", lobbyHandler.ExpireInviteCode)
+	lobbies.Post("/:lobbyId/bots", lobbyHandler.AddBot)
+	lobbies.Post("/:lobbyId/spectate", lobbyHandler.Spectate)
+	lobbies.Delete("/:lobbyId/spectate", lobbyHandler.StopSpectating)
+	lobbies.Post("/:lobbyId/mutes", lobbyHandler.MuteInLobby)
+	lobbies.Delete("/:lobbyId/mutes/:userId", lobbyHandler.UnmuteInLobby)
+	lobbies.Post("/invitation/accept", idempotent, lobbyHandler.AcceptInvitation)
+	lobbies.Post("/invitation/decline", lobbyHandler.DeclineInvitation)
+	lobbies.Delete("/:lobbyId/invitations/:invitationId", lobbyHandler.CancelInvitation)
 
-	games := s.App.Group("/games", middleware.AuthMiddleware(s.db))
+	s.App.Get("/games/:gameId/result", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), matchHandler.GameResult)
+	s.App.Get("/games/:gameId/events", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameEventHandler.Events)
+	s.App.Get("/games/:gameId/replay", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameEventHandler.Replay)
+	s.App.Get("/games/:gameId/piles", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameHandler.Piles)
+	s.App.Get("/games/:gameId/state", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameHandler.State)
+	s.App.Post("/games/:gameId/forfeit", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameHandler.Forfeit)
+	s.App.Post("/games/:gameId/rematch", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"), gameHandler.Rematch)
+
+	games := s.App.Group("/games", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"))
 	games.Use("/:gameId", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
 			c.Locals("allowed", true)
@@ -68,18 +271,92 @@ func (s *FiberServer) RegisterFiberRoutes() {
 		gameHandler.Game(c)
 	}))
 
-	cards := s.App.Group("/cards", middleware.AuthMiddleware(s.db))
+	cards := s.App.Group("/cards", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"))
 	cards.Get("/:gameId/get", cardHandler.GetGameCards)
 
-	profiles := s.App.Group("/profile", middleware.AuthMiddleware(s.db))
+	profiles := s.App.Group("/profile", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("profile:read", "profile:write"))
+	ownProfile := middleware.RequireSelfOrAdmin(s.db, "id")
 	profiles.Get("/:id/show", profileHandler.Show)
-	profiles.Put("/:id/update", profileHandler.Update)
-	profiles.Put("/:id/password", profileHandler.UpdatePassword)
-	profiles.Delete("/:id/delete", profileHandler.Destroy)
+	profiles.Put("/:id/update", ownProfile, profileHandler.Update)
+	profiles.Get("/:id/privacy", ownProfile, profileHandler.ShowPrivacy)
+	profiles.Put("/:id/privacy", ownProfile, profileHandler.UpdatePrivacy)
+	profiles.Put("/:id/password", ownProfile, profileHandler.UpdatePassword)
+	profiles.Delete("/:id/delete", ownProfile, profileHandler.Destroy)
+
+	s.App.Get("/users/search", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), userHandler.SearchUsers)
+	s.App.Get("/users/blocked", middleware.AuthMiddleware(s.db), middleware.RequireAbility("friends:read"), userHandler.Blocked)
+	s.App.Post("/users/:id/block", middleware.AuthMiddleware(s.db), middleware.RequireAbility("friends:write"), userHandler.Block)
+	s.App.Delete("/users/:id/block", middleware.AuthMiddleware(s.db), middleware.RequireAbility("friends:write"), userHandler.Unblock)
+	s.App.Get("/users/:id/rating", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), ratingHandler.Show)
+	s.App.Get("/users/:id/matches", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), matchHandler.UserMatches)
+	s.App.Get("/users/:id/stats", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), statsHandler.Show)
+	s.App.Get("/users/:id/versus/:otherId", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), statsHandler.Versus)
+	s.App.Get("/users/:id/presence", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), presenceHandler.Show)
+
+	friends := s.App.Group("/friends", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("friends:read", "friends:write"))
+	friends.Get("/", friendHandler.Index)
+	friends.Get("/requests", friendHandler.Requests)
+	friends.Post("/requests", friendHandler.SendRequest)
+	friends.Post("/requests/:id/accept", friendHandler.AcceptRequest)
+	friends.Post("/requests/:id/decline", friendHandler.DeclineRequest)
+	friends.Delete("/:userId", friendHandler.Remove)
+
+	messages := s.App.Group("/messages", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("friends:read", "friends:write"))
+	messages.Get("/:userId", messageHandler.Conversation)
+	messages.Post("/:userId", messageLimit, messageHandler.Send)
+
+	s.App.Get("/leaderboard", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), leaderboardHandler.Index)
+	s.App.Get("/leaderboards", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), seasonHandler.Leaderboard)
+	s.App.Get("/seasons", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), seasonHandler.Index)
+
+	challenges := s.App.Group("/challenges", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("profile:read", "profile:write"))
+	challenges.Get("/", challengeHandler.Index)
+	challenges.Post("/:id/claim", challengeHandler.Claim)
 
-	s.App.Get("/users/search", userHandler.SearchUsers)
+	matchmaking := s.App.Group("/matchmaking", middleware.AuthMiddleware(s.db), middleware.RequireAbility("game:play"))
+	matchmaking.Post("/queue", matchmakingHandler.JoinQueue)
+	matchmaking.Delete("/queue", matchmakingHandler.LeaveQueue)
+
+	tokens := s.App.Group("/tokens", middleware.AuthMiddleware(s.db), middleware.RequireAbility("tokens:manage"))
+	tokens.Get("/", tokenHandler.Index)
+	tokens.Post("/", tokenHandler.Store)
+	tokens.Delete("/:id", tokenHandler.Destroy)
+
+	s.App.Post("/reports", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:write"), reportLimit, reportHandler.Store)
+
+	admin := s.App.Group("/admin", middleware.AuthMiddleware(s.db), middleware.RequireAbility("admin"), middleware.RequireAdmin(s.db))
+	admin.Get("/lobbies", adminHandler.Lobbies)
+	admin.Post("/lobbies/:lobbyId/close", adminHandler.CloseLobby)
+	admin.Delete("/lobbies/:lobbyId/name", adminHandler.ClearLobbyName)
+	admin.Get("/games", adminHandler.Games)
+	admin.Post("/games/:gameId/close", adminHandler.CloseGame)
+	admin.Get("/games/:gameId/state", adminHandler.GameState)
+	admin.Get("/users", adminHandler.SearchUsers)
+	admin.Get("/users/:id/bans", adminHandler.Bans)
+	admin.Post("/users/:id/ban", adminHandler.BanUser)
+	admin.Delete("/users/:id/ban", adminHandler.UnbanUser)
+	admin.Post("/users/:id/mute", adminHandler.MuteUser)
+	admin.Delete("/users/:id/mute", adminHandler.UnmuteUser)
+	admin.Get("/audit-logs", adminHandler.AuditLogs)
+	admin.Get("/reports", adminHandler.Reports)
+	admin.Get("/reports/:id", adminHandler.Report)
+	admin.Post("/reports/:id/resolve", adminHandler.ResolveReport)
+
+	s.App.Use("/ws/notifications", middleware.AuthMiddleware(s.db), middleware.RequireAbility("profile:read"), func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return fiber.ErrUpgradeRequired
+	})
+	s.App.Get("/ws/notifications", websocket.New(notificationHandler.Stream))
 
-	s.App.Get("/notifications", notificationHandler.GetNotifications)
-	s.App.Put("/notifications/:id/read", notificationHandler.MarkAsRead)
-	s.App.Put("/notifications/read-all", notificationHandler.MarkAllAsRead)
+	notifications := s.App.Group("/notifications", middleware.AuthMiddleware(s.db), middleware.RequireAbilityByMethod("profile:read", "profile:write"))
+	notifications.Get("/", notificationHandler.GetNotifications)
+	notifications.Put("/:id/read", notificationHandler.MarkAsRead)
+	notifications.Get("/unread-count", notificationHandler.UnreadCount)
+	notifications.Get("/preferences", notificationHandler.GetPreferences)
+	notifications.Put("/preferences", notificationHandler.UpdatePreferences)
+	notifications.Put("/read-all", notificationHandler.MarkAllAsRead)
+	notifications.Delete("/read", notificationHandler.DestroyRead)
+	notifications.Delete("/:id", notificationHandler.Destroy)
 }
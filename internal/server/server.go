@@ -1,42 +1,107 @@
 package server
 
 import (
+	"context"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/session"
 
+	"api/internal/config"
 	"api/internal/database"
+	"api/internal/redis"
+	"api/internal/scheduler"
+	"api/internal/server/handler"
+	"api/internal/server/utils"
+	"api/internal/telemetry"
 )
 
 type FiberServer struct {
 	*fiber.App
 
+	config *config.Config
+
 	db database.Service
 
 	store *session.Store
+
+	scheduler *scheduler.Scheduler
+
+	games *handler.GameHandler
+
+	shutdownTracing func(context.Context) error
 }
 
-func New() *FiberServer {
-	store := session.New(session.Config{
+func New(cfg *config.Config) *FiberServer {
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Telemetry)
+	if err != nil {
+		log.Fatalf("Error setting up tracing: %v", err)
+	}
+
+	storeConfig := session.Config{
 		KeyLookup:      "cookie:session_id",
 		Expiration:     24 * time.Hour,
-		CookieSecure:   true,
+		CookieSecure:   cfg.Session.CookieSecure,
 		CookiePath:     "/",
-		CookieSameSite: "Lax",
-		CookieHTTPOnly: true,
-	})
+		CookieSameSite: cfg.Session.CookieSameSite,
+		CookieHTTPOnly: cfg.Session.CookieHTTPOnly,
+	}
+
+	if cfg.Session.Store == "redis" {
+		client, err := redis.NewClient(cfg.Redis.URL)
+		if err != nil {
+			log.Fatalf("Error connecting to session store: %v", err)
+		}
+		storeConfig.Storage = redis.NewStorage(client, "shithead:session:")
+	}
+
+	store := session.New(storeConfig)
 
 	server := &FiberServer{
 		App: fiber.New(fiber.Config{
 			ServerHeader: "api",
 			AppName:      "api",
+			ErrorHandler: utils.ErrorHandler,
 		}),
 
-		db: database.New(),
+		config: cfg,
+
+		db: database.New(cfg.Database),
 
 		store: store,
+
+		scheduler: scheduler.New(),
+
+		shutdownTracing: shutdownTracing,
 	}
 
 	return server
 }
+
+func (s *FiberServer) StopScheduler() {
+	s.scheduler.Stop()
+}
+
+// Drain closes every WebSocket after warning its client and waits for
+// in-flight game moves to finish.
+func (s *FiberServer) Drain(ctx context.Context) error {
+	if s.games == nil {
+		return nil
+	}
+	return s.games.Shutdown(ctx)
+}
+
+// Migrate applies any pending database migrations.
+func (s *FiberServer) Migrate(ctx context.Context) ([]int64, error) {
+	return s.db.Migrate(ctx)
+}
+
+// ShutdownTracing flushes the spans that have not been exported yet.
+func (s *FiberServer) ShutdownTracing(ctx context.Context) error {
+	return s.shutdownTracing(ctx)
+}
+
+func (s *FiberServer) CloseDatabase() error {
+	return s.db.Close()
+}
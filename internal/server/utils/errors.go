@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIError is returned by handlers to fail a request. ErrorHandler renders it
+// as {code, message, details, request_id}.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// NewError builds an APIError whose code follows from the HTTP status.
+func NewError(status int, message string) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    codeForStatus(status),
+		Message: message,
+	}
+}
+
+// WithCode replaces the status-derived code with a more specific one.
+func (e *APIError) WithCode(code string) *APIError {
+	e.Code = code
+	return e
+}
+
+// WithDetails attaches extra machine-readable context, such as per-field
+// validation messages.
+func (e *APIError) WithDetails(details interface{}) *APIError {
+	e.Details = details
+	return e
+}
+
+var statusCodes = map[int]string{
+	fiber.StatusBadRequest:          "BAD_REQUEST",
+	fiber.StatusUnauthorized:        "UNAUTHORIZED",
+	fiber.StatusForbidden:           "FORBIDDEN",
+	fiber.StatusNotFound:            "NOT_FOUND",
+	fiber.StatusConflict:            "CONFLICT",
+	fiber.StatusGone:                "GONE",
+	fiber.StatusUnprocessableEntity: "VALIDATION_FAILED",
+	fiber.StatusLocked:              "LOCKED",
+	fiber.StatusTooManyRequests:     "RATE_LIMITED",
+	fiber.StatusInternalServerError: "INTERNAL_ERROR",
+	fiber.StatusServiceUnavailable:  "SERVICE_UNAVAILABLE",
+}
+
+func codeForStatus(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+}
+
+// ErrorHandler is the app-wide fiber error handler. APIErrors and fiber.Errors
+// keep their status and message; anything else is logged and reported as a
+// generic internal error.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var apiErr *APIError
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &fiberErr):
+		apiErr = NewError(fiberErr.Code, fiberErr.Message)
+	default:
+		log.Printf("Unhandled error on %s %s: %v", c.Method(), c.Path(), err)
+		apiErr = NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	return c.Status(apiErr.Status).JSON(fiber.Map{
+		"code":       apiErr.Code,
+		"message":    apiErr.Message,
+		"details":    apiErr.Details,
+		"request_id": c.Locals("requestid"),
+	})
+}
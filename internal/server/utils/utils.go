@@ -2,7 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 )
 
 func GenerateToken() string {
@@ -13,3 +15,9 @@ func GenerateToken() string {
 	}
 	return base64.StdEncoding.EncodeToString(bytes)
 }
+
+// HashToken returns the hex SHA-256 of a token so only digests are stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// Report fields under the name clients send them by.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, key := range []string{"json", "query", "form"} {
+			if name := strings.Split(field.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return v
+}
+
+// Validate checks v, a struct or pointer to one, against its `validate` tags
+// and returns a message per failing field keyed by the field's json, query or
+// form name, with nested fields joined by dots. A nil map means v is valid.
+func Validate(v interface{}) map[string]string {
+	err := validate.Struct(v)
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil
+	}
+
+	errs := make(map[string]string, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		// The namespace starts with the struct's own type name.
+		_, name, _ := strings.Cut(fieldErr.Namespace(), ".")
+		errs[name] = validationMessage(fieldErr)
+	}
+	return errs
+}
+
+// ValidationFailed fails the request with 422, carrying the per-field
+// messages from Validate as the error details.
+func ValidationFailed(c *fiber.Ctx, errs map[string]string) error {
+	return NewError(fiber.StatusUnprocessableEntity, "Validation failed").WithDetails(errs)
+}
+
+func validationMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "This field is required"
+	case "min":
+		return fmt.Sprintf("Must be at least %s%s", err.Param(), unit(err.Kind()))
+	case "max":
+		return fmt.Sprintf("Must be at most %s%s", err.Param(), unit(err.Kind()))
+	case "email":
+		return "Must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("Must be one of: %s", strings.Join(strings.Fields(err.Param()), ", "))
+	case "eqfield":
+		return "Does not match"
+	}
+	return fmt.Sprintf("Failed the %s rule", err.Tag())
+}
+
+// unit names what min and max count for a kind of value: characters of a
+// string, items of a collection, or nothing for numbers.
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
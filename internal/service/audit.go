@@ -0,0 +1,104 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry describes a sensitive action for the audit trail.
+type AuditEntry struct {
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	IPAddress  string
+	Details    map[string]interface{}
+}
+
+// AuditFilter narrows the audit trail. Zero values leave a filter off.
+type AuditFilter struct {
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	// BeforeAt and BeforeID name the last entry of the previous page, to
+	// continue a listing after it.
+	BeforeAt *time.Time
+	BeforeID uuid.UUID
+	Limit    int
+}
+
+type AuditService interface {
+	// Record appends an entry to the audit trail.
+	Record(ctx context.Context, entry AuditEntry) error
+	// List returns entries matching filter, newest first.
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error)
+}
+
+type auditService struct {
+	db database.Service
+}
+
+func NewAuditService(db database.Service) AuditService {
+	return &auditService{
+		db: db,
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, entry AuditEntry) error {
+	var details json.RawMessage
+	if len(entry.Details) > 0 {
+		encoded, err := json.Marshal(entry.Details)
+		if err != nil {
+			return err
+		}
+		details = encoded
+	}
+
+	return s.db.DB().WithContext(ctx).Create(&models.AuditLog{
+		ID:         uuid.New(),
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IPAddress:  entry.IPAddress,
+		Details:    details,
+		CreatedAt:  time.Now().UTC(),
+	}).Error
+}
+
+func (s *auditService) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error) {
+	query := s.db.DB().WithContext(ctx).Model(&models.AuditLog{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	if filter.BeforeAt != nil {
+		query = query.Where("(created_at, id) < (?, ?)", *filter.BeforeAt, filter.BeforeID)
+	}
+
+	var entries []models.AuditLog
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/game/challenges"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrChallengeNotFound   = errors.New("challenge not found")
+	ErrChallengeIncomplete = errors.New("challenge not completed")
+	ErrChallengeClaimed    = errors.New("challenge already claimed")
+)
+
+// ChallengeProgress is a challenge along with one player's progress on it.
+type ChallengeProgress struct {
+	models.Challenge
+	Progress    int        `json:"progress"`
+	Completed   bool       `json:"completed"`
+	Claimed     bool       `json:"claimed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ClaimedAt   *time.Time `json:"claimed_at"`
+}
+
+type ChallengeService interface {
+	// Generate makes sure the daily and weekly challenges running at now
+	// exist. It is safe to call repeatedly and from several instances.
+	Generate(ctx context.Context, now time.Time) error
+	// Active lists the challenges running at now with the user's progress,
+	// daily ones first.
+	Active(ctx context.Context, userID uuid.UUID, now time.Time) ([]ChallengeProgress, error)
+	// Claim grants the XP reward of a completed challenge. Each challenge
+	// can be claimed once, also after it has ended.
+	Claim(ctx context.Context, userID, challengeID uuid.UUID, now time.Time) (ChallengeProgress, models.XPEvent, error)
+}
+
+type challengeService struct {
+	db database.Service
+}
+
+func NewChallengeService(db database.Service) ChallengeService {
+	return &challengeService{
+		db: db,
+	}
+}
+
+func (s *challengeService) Generate(ctx context.Context, now time.Time) error {
+	db := s.db.DB().WithContext(ctx)
+	for _, period := range []challenges.Period{challenges.Daily, challenges.Weekly} {
+		start, end := challenges.Bounds(period, now)
+
+		var existing int64
+		if err := db.Model(&models.Challenge{}).
+			Where("period = ? AND starts_at = ?", period, start).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			continue
+		}
+
+		picked := challenges.Pick(period, start)
+		rows := make([]models.Challenge, len(picked))
+		for i, template := range picked {
+			rows[i] = models.Challenge{
+				ID:          uuid.New(),
+				Period:      string(period),
+				Metric:      string(template.Metric),
+				Description: template.Description(),
+				Target:      template.Target,
+				XPReward:    template.XP,
+				StartsAt:    start,
+				EndsAt:      end,
+				CreatedAt:   now,
+			}
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *challengeService) Active(ctx context.Context, userID uuid.UUID, now time.Time) ([]ChallengeProgress, error) {
+	if err := s.Generate(ctx, now); err != nil {
+		return nil, err
+	}
+
+	db := s.db.DB().WithContext(ctx)
+
+	var active []models.Challenge
+	if err := db.Where("starts_at <= ? AND ends_at > ?", now, now).
+		Order("CASE period WHEN 'daily' THEN 0 ELSE 1 END, xp_reward ASC, id ASC").
+		Find(&active).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(active))
+	for i, challenge := range active {
+		ids[i] = challenge.ID
+	}
+
+	var rows []models.UserChallenge
+	if len(ids) > 0 {
+		if err := db.Where("user_id = ? AND challenge_id IN ?", userID, ids).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+	byChallenge := make(map[uuid.UUID]models.UserChallenge, len(rows))
+	for _, row := range rows {
+		byChallenge[row.ChallengeID] = row
+	}
+
+	result := make([]ChallengeProgress, len(active))
+	for i, challenge := range active {
+		result[i] = challengeProgress(challenge, byChallenge[challenge.ID])
+	}
+	return result, nil
+}
+
+func (s *challengeService) Claim(ctx context.Context, userID, challengeID uuid.UUID, now time.Time) (ChallengeProgress, models.XPEvent, error) {
+	var progress ChallengeProgress
+	var event models.XPEvent
+
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var challenge models.Challenge
+		if err := tx.Where("id = ?", challengeID).First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChallengeNotFound
+			}
+			return err
+		}
+
+		var row models.UserChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("challenge_id = ? AND user_id = ?", challengeID, userID).
+			First(&row).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChallengeIncomplete
+			}
+			return err
+		}
+		if row.ClaimedAt != nil {
+			return ErrChallengeClaimed
+		}
+		if row.CompletedAt == nil {
+			return ErrChallengeIncomplete
+		}
+
+		row.ClaimedAt = &now
+		row.UpdatedAt = now
+		if err := tx.Model(&row).Updates(map[string]interface{}{
+			"claimed_at": now,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		event, err = GrantXP(tx, userID, challenge.XPReward, "challenge", nil)
+		if err != nil {
+			return err
+		}
+
+		progress = challengeProgress(challenge, row)
+		return nil
+	})
+	return progress, event, err
+}
+
+func challengeProgress(challenge models.Challenge, row models.UserChallenge) ChallengeProgress {
+	return ChallengeProgress{
+		Challenge:   challenge,
+		Progress:    min(row.Progress, challenge.Target),
+		Completed:   row.CompletedAt != nil,
+		Claimed:     row.ClaimedAt != nil,
+		CompletedAt: row.CompletedAt,
+		ClaimedAt:   row.ClaimedAt,
+	}
+}
+
+// TrackChallenges adds what each user did to their progress on the
+// challenges running at at, inside tx. A challenge completes the moment its
+// progress reaches the target.
+func TrackChallenges(tx *gorm.DB, tallies map[uuid.UUID]map[challenges.Metric]int, at time.Time) error {
+	if len(tallies) == 0 {
+		return nil
+	}
+
+	var active []models.Challenge
+	if err := tx.Where("starts_at <= ? AND ends_at > ?", at, at).Find(&active).Error; err != nil {
+		return err
+	}
+
+	for userID, tally := range tallies {
+		for _, challenge := range active {
+			amount := tally[challenges.Metric(challenge.Metric)]
+			if amount <= 0 {
+				continue
+			}
+
+			var completedAt *time.Time
+			if amount >= challenge.Target {
+				completedAt = &at
+			}
+
+			if err := tx.Exec(`
+INSERT INTO user_challenges (id, challenge_id, user_id, progress, completed_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (challenge_id, user_id) DO UPDATE SET
+    progress = user_challenges.progress + EXCLUDED.progress,
+    completed_at = COALESCE(user_challenges.completed_at,
+        CASE WHEN user_challenges.progress + EXCLUDED.progress >= ? THEN EXCLUDED.updated_at END),
+    updated_at = EXCLUDED.updated_at`,
+				uuid.New(), challenge.ID, userID, amount, completedAt, at, at, challenge.Target,
+			).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
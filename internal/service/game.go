@@ -0,0 +1,73 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type GameService interface {
+	Find(ctx context.Context, id string) (models.Game, error)
+	// ConnectionRole decides how a user may watch a game: "player" for
+	// anyone seated, and when the lobby allows spectators, "queued" for its
+	// waiting list and "spectator" for registered spectators. Anyone else,
+	// including users still waiting for room to spectate, gets ErrNotAllowed.
+	ConnectionRole(ctx context.Context, gameID string, userID uuid.UUID) (string, error)
+}
+
+type gameService struct {
+	db database.Service
+}
+
+func NewGameService(db database.Service) GameService {
+	return &gameService{
+		db: db,
+	}
+}
+
+func (s *gameService) Find(ctx context.Context, id string) (models.Game, error) {
+	var game models.Game
+	if err := s.db.DB().WithContext(ctx).Preload("Lobby").Where("id = ?", id).First(&game).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return game, ErrGameNotFound
+		}
+		return game, err
+	}
+	return game, nil
+}
+
+func (s *gameService) ConnectionRole(ctx context.Context, gameID string, userID uuid.UUID) (string, error) {
+	game, err := s.Find(ctx, gameID)
+	if err != nil {
+		return "", err
+	}
+
+	db := s.db.DB().WithContext(ctx)
+
+	var player models.Player
+	if err := db.Where("game_id = ? AND user_id = ?", game.ID, userID).First(&player).Error; err == nil {
+		return "player", nil
+	}
+
+	// Everyone but the players only watches, so the waiting list is held to
+	// the lobby's spectator setting too.
+	if !game.Lobby.SpectatorAllowed {
+		return "", ErrNotAllowed
+	}
+
+	var queued models.LobbyQueue
+	if err := db.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", game.LobbyID, userID, "player").First(&queued).Error; err == nil {
+		return "queued", nil
+	}
+
+	var spectator models.LobbySpectator
+	if err := db.Where("lobby_id = ? AND user_id = ?", game.LobbyID, userID).First(&spectator).Error; err != nil {
+		return "", ErrNotAllowed
+	}
+
+	return "spectator", nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LobbyFilter narrows and orders the public lobby listing. Zero values leave
+// a filter off.
+type LobbyFilter struct {
+	Status           string
+	Type             string
+	GameMode         string
+	HasOpenSeats     *bool
+	SpectatorAllowed *bool
+	// Sort is "created_at" or "current_players"; Order is "asc" or "desc".
+	Sort   string
+	Order  string
+	Offset int
+	Limit  int
+}
+
+type LobbyService interface {
+	// List returns one page of lobbies matching filter and the total number
+	// of matches. Practice lobbies are never listed.
+	List(ctx context.Context, filter LobbyFilter) ([]models.Lobby, int64, error)
+	// Find loads a lobby with its owner, players, games and invitations.
+	Find(ctx context.Context, id string) (models.Lobby, error)
+	// FindByInviteCode loads the lobby currently using code. Whether the code
+	// is still valid is left to the caller.
+	FindByInviteCode(ctx context.Context, code string) (models.Lobby, error)
+	// Invalidate drops any cached listings after a lobby changes.
+	Invalidate()
+	// CacheStats reports how often listings were served from cache.
+	CacheStats() CacheStats
+
+	// Join seats the user in a waiting lobby, or puts them in its queue
+	// when every seat is taken. Private lobbies need a valid invite code or
+	// the lobby password. Joining a lobby the user already sits in succeeds
+	// without changing anything.
+	Join(ctx context.Context, lobbyID string, userID uuid.UUID, inviteCode, password string) (JoinResult, error)
+	// Leave gives up the user's seat and hands it to the next user in the
+	// queue. An owner leaving passes the lobby to the longest-seated human
+	// player, or deletes it when there is none.
+	Leave(ctx context.Context, lobbyID string, userID uuid.UUID) (LeaveResult, error)
+	// Invite lets the lobby owner invite another user, who is notified.
+	Invite(ctx context.Context, lobbyID string, inviterID, invitedID uuid.UUID) (models.LobbyInvitation, error)
+	// AcceptInvitation seats the invited user and returns the lobby.
+	AcceptInvitation(ctx context.Context, lobbyID string, userID uuid.UUID) (models.Lobby, error)
+	// DeclineInvitation turns a pending invitation down and tells the
+	// inviter.
+	DeclineInvitation(ctx context.Context, lobbyID string, userID uuid.UUID) error
+	// CancelInvitation withdraws a pending invitation. Only the inviter or
+	// the lobby owner may.
+	CancelInvitation(ctx context.Context, lobbyID, invitationID string, userID uuid.UUID) error
+}
+
+type lobbyService struct {
+	db   database.Service
+	game config.Game
+}
+
+func NewLobbyService(db database.Service, game config.Game) LobbyService {
+	return &lobbyService{
+		db:   db,
+		game: game,
+	}
+}
+
+func (s *lobbyService) List(ctx context.Context, filter LobbyFilter) ([]models.Lobby, int64, error) {
+	query := s.db.DB().WithContext(ctx).Model(&models.Lobby{}).Where("game_mode <> ?", "practice")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.GameMode != "" {
+		query = query.Where("game_mode = ?", filter.GameMode)
+	}
+	if filter.HasOpenSeats != nil {
+		if *filter.HasOpenSeats {
+			query = query.Where("current_players < max_players")
+		} else {
+			query = query.Where("current_players >= max_players")
+		}
+	}
+	if filter.SpectatorAllowed != nil {
+		query = query.Where("spectator_allowed = ?", *filter.SpectatorAllowed)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("counting lobbies: %w", err)
+	}
+
+	sortColumn := "created_at"
+	if filter.Sort == "current_players" {
+		sortColumn = "current_players"
+	}
+	sortOrder := "DESC"
+	if filter.Order == "asc" {
+		sortOrder = "ASC"
+	}
+
+	var lobbies []models.Lobby
+	if err := query.
+		Preload("Owner").
+		Preload("Players.User").
+		Preload("Games").
+		Preload("LobbyQueues.User").
+		Order(fmt.Sprintf("%s %s, id ASC", sortColumn, sortOrder)).
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&lobbies).Error; err != nil {
+		return nil, 0, fmt.Errorf("fetching lobbies: %w", err)
+	}
+
+	return lobbies, total, nil
+}
+
+func (s *lobbyService) Find(ctx context.Context, id string) (models.Lobby, error) {
+	var lobby models.Lobby
+	err := s.db.DB().WithContext(ctx).
+		Preload("Owner").Preload("Players.User").Preload("Games").Preload("LobbyInvitations").
+		Where("id = ?", id).First(&lobby).Error
+	return lobby, lobbyError(err)
+}
+
+func (s *lobbyService) FindByInviteCode(ctx context.Context, code string) (models.Lobby, error) {
+	var lobby models.Lobby
+	err := s.db.DB().WithContext(ctx).
+		Preload("Owner").Preload("Players.User").Preload("Games").
+		Where("invite_code = ?", code).First(&lobby).Error
+	return lobby, lobbyError(err)
+}
+
+func (s *lobbyService) Invalidate() {}
+
+func (s *lobbyService) CacheStats() CacheStats {
+	return CacheStats{}
+}
+
+func lobbyError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrLobbyNotFound
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/database/models"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	benchLobbies         = 100
+	benchPlayersPerLobby = 4
+	queryCounterName     = "bench:count_queries"
+)
+
+var (
+	queryCount        atomic.Int64
+	registerCountOnce sync.Once
+)
+
+// countQueries makes every statement run through db bump queryCount.
+func countQueries(b *testing.B, db *gorm.DB) {
+	b.Helper()
+
+	var err error
+	registerCountOnce.Do(func() {
+		count := func(*gorm.DB) { queryCount.Add(1) }
+		callbacks := db.Callback()
+		if err = callbacks.Query().After("gorm:query").Register(queryCounterName, count); err != nil {
+			return
+		}
+		err = callbacks.Row().After("gorm:row").Register(queryCounterName, count)
+	})
+	if err != nil {
+		b.Fatalf("registering query counter: %v", err)
+	}
+}
+
+// seedListing fills the database with benchLobbies waiting lobbies of
+// benchPlayersPerLobby players each.
+func seedListing(b *testing.B, db *gorm.DB) {
+	b.Helper()
+
+	users := seedUsers(b, db, benchLobbies*benchPlayersPerLobby)
+	for i := 0; i < benchLobbies; i++ {
+		seats := users[i*benchPlayersPerLobby : (i+1)*benchPlayersPerLobby]
+		lobby := seedLobby(b, db, seats[0], benchPlayersPerLobby)
+
+		var game models.Game
+		if err := db.Where("lobby_id = ?", lobby.ID).First(&game).Error; err != nil {
+			b.Fatalf("loading game: %v", err)
+		}
+
+		players := make([]models.Player, 0, len(seats)-1)
+		for seat, user := range seats[1:] {
+			players = append(players, models.Player{
+				ID:      uuid.New(),
+				LobbyID: lobby.ID,
+				GameID:  game.ID,
+				UserID:  user.ID,
+				Role:    fmt.Sprintf("player%d", seat+2),
+			})
+		}
+		if err := db.Create(&players).Error; err != nil {
+			b.Fatalf("seating players: %v", err)
+		}
+		if err := db.Model(&lobby).Update("current_players", benchPlayersPerLobby).Error; err != nil {
+			b.Fatalf("updating lobby: %v", err)
+		}
+	}
+}
+
+// BenchmarkLobbyListing lists 100 lobbies of 4 players and reads every
+// participant's name, as the lobby index does. Users come preloaded with the
+// players, so the query count does not grow with the number of players.
+func BenchmarkLobbyListing(b *testing.B) {
+	db := testDatabase(b)
+	seedListing(b, db.DB())
+	countQueries(b, db.DB())
+
+	lobbies := NewLobbyService(db, config.Game{})
+	filter := LobbyFilter{Status: "waiting", Limit: benchLobbies}
+
+	b.ResetTimer()
+	queryCount.Store(0)
+	for i := 0; i < b.N; i++ {
+		listed, _, err := lobbies.List(context.Background(), filter)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, lobby := range listed {
+			for _, player := range lobby.Players {
+				_ = player.User.Name
+			}
+		}
+	}
+	b.ReportMetric(float64(queryCount.Load())/float64(b.N), "queries/op")
+}
+
+// BenchmarkLobbyListingPerPlayerLookup is the listing as it was before users
+// were preloaded: one extra query per participant to fetch their name. It is
+// kept to compare against BenchmarkLobbyListing.
+func BenchmarkLobbyListingPerPlayerLookup(b *testing.B) {
+	db := testDatabase(b)
+	seedListing(b, db.DB())
+	countQueries(b, db.DB())
+
+	ctx := context.Background()
+
+	b.ResetTimer()
+	queryCount.Store(0)
+	for i := 0; i < b.N; i++ {
+		var listed []models.Lobby
+		if err := db.DB().WithContext(ctx).
+			Where("game_mode <> ? AND status = ?", "practice", "waiting").
+			Preload("Owner").
+			Preload("Players").
+			Preload("Games").
+			Preload("LobbyQueues.User").
+			Order("created_at DESC, id ASC").
+			Limit(benchLobbies).
+			Find(&listed).Error; err != nil {
+			b.Fatal(err)
+		}
+		for _, lobby := range listed {
+			for _, player := range lobby.Players {
+				var user models.User
+				if err := db.DB().WithContext(ctx).First(&user, player.UserID).Error; err != nil {
+					b.Fatal(err)
+				}
+				_ = user.Name
+			}
+		}
+	}
+	b.ReportMetric(float64(queryCount.Load())/float64(b.N), "queries/op")
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ListingStore holds encoded pages of the public lobby listing. Any
+// fiber.Storage, such as redis.Storage, satisfies it.
+type ListingStore interface {
+	Get(key string) ([]byte, error)
+	Set(key string, val []byte, exp time.Duration) error
+	Reset() error
+}
+
+// CacheStats counts how listing lookups were answered.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// listingPage is a cached page of the listing. It is stored as the lobbies'
+// own JSON, which leaves out password hashes and invite codes.
+type listingPage struct {
+	Lobbies []models.Lobby `json:"lobbies"`
+	Total   int64          `json:"total"`
+}
+
+// cachedLobbyService serves List from a short-lived cache, collapsing
+// concurrent misses for the same page into one query. Everything else passes
+// straight through.
+type cachedLobbyService struct {
+	LobbyService
+
+	store ListingStore
+	ttl   time.Duration
+	group singleflight.Group
+
+	// generation moves on every invalidation, so a query that started before
+	// a change does not write its stale result back.
+	generation atomic.Uint64
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+}
+
+// NewCachedLobbyService caches next's listings in store for ttl.
+func NewCachedLobbyService(next LobbyService, store ListingStore, ttl time.Duration) LobbyService {
+	return &cachedLobbyService{
+		LobbyService: next,
+		store:        store,
+		ttl:          ttl,
+	}
+}
+
+func (s *cachedLobbyService) List(ctx context.Context, filter LobbyFilter) ([]models.Lobby, int64, error) {
+	key := listingKey(filter)
+
+	if page, ok := s.cached(key); ok {
+		s.hits.Add(1)
+		return page.Lobbies, page.Total, nil
+	}
+	s.misses.Add(1)
+
+	result, err, _ := s.group.Do(key, func() (interface{}, error) {
+		generation := s.generation.Load()
+
+		lobbies, total, err := s.LobbyService.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		page := listingPage{Lobbies: lobbies, Total: total}
+
+		if generation == s.generation.Load() {
+			if encoded, err := json.Marshal(page); err == nil {
+				if err := s.store.Set(key, encoded, s.ttl); err != nil {
+					log.Printf("Error caching lobby listing: %v", err)
+				}
+			}
+		}
+		return page, nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page := result.(listingPage)
+	return page.Lobbies, page.Total, nil
+}
+
+func (s *cachedLobbyService) cached(key string) (listingPage, bool) {
+	var page listingPage
+
+	encoded, err := s.store.Get(key)
+	if err != nil {
+		log.Printf("Error reading lobby listing cache: %v", err)
+		return page, false
+	}
+	if encoded == nil || json.Unmarshal(encoded, &page) != nil {
+		return page, false
+	}
+	return page, true
+}
+
+func (s *cachedLobbyService) Invalidate() {
+	s.generation.Add(1)
+	if err := s.store.Reset(); err != nil {
+		log.Printf("Error clearing lobby listing cache: %v", err)
+	}
+}
+
+func (s *cachedLobbyService) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+	}
+}
+
+func listingKey(filter LobbyFilter) string {
+	flag := func(value *bool) string {
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(*value)
+	}
+
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s|%d|%d",
+		filter.Status, filter.Type, filter.GameMode,
+		flag(filter.HasOpenSeats), flag(filter.SpectatorAllowed),
+		filter.Sort, filter.Order, filter.Offset, filter.Limit)
+}
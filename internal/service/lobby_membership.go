@@ -0,0 +1,424 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/database/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invitationLifetime is how long a lobby invitation can be accepted.
+const invitationLifetime = 30 * time.Minute
+
+// DeleteLobby removes the lobby along with its invitations, players, queue,
+// games and the notifications that point at it.
+func DeleteLobby(tx *gorm.DB, lobbyID string) error {
+	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbyInvitation{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.Player{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbyQueue{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.Game{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("data->>'lobby_id' = ?", lobbyID).Delete(&models.Notification{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("id = ?", lobbyID).Delete(&models.Lobby{}).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TransferLobbyOwnership hands the lobby and its games to newOwnerID and lets
+// them know. The caller is responsible for checking they are a seated player.
+func TransferLobbyOwnership(tx *gorm.DB, lobby *models.Lobby, newOwnerID uuid.UUID) error {
+	if err := tx.Model(lobby).Updates(map[string]interface{}{
+		"owner_id":   newOwnerID,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Game{}).Where("lobby_id = ?", lobby.ID).
+		Update("owner_id", newOwnerID).Error; err != nil {
+		return err
+	}
+
+	return CreateNotification(tx, newOwnerID, "lobby_ownership_transferred", map[string]interface{}{
+		"lobby_id":   lobby.ID,
+		"lobby_name": lobby.Name,
+		"message":    "You are now the owner of this lobby",
+	})
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func IsBlocked(db *gorm.DB, a, b uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Friendship{}).
+		Where("((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)) AND status = ?",
+			a, b, b, a, "blocked").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ValidInviteCode reports whether code matches the lobby's current, unexpired
+// invite code. A valid code lets its holder skip the lobby's privacy checks.
+func ValidInviteCode(lobby *models.Lobby, code string) bool {
+	if code == "" || lobby.InviteCode == nil {
+		return false
+	}
+	if lobby.InviteExpiresAt != nil && lobby.InviteExpiresAt.Before(time.Now()) {
+		return false
+	}
+	return strings.EqualFold(*lobby.InviteCode, code)
+}
+
+// RequiresVerifiedEmail reports whether a game mode is limited to verified
+// accounts. The restriction is opt-in via REQUIRE_VERIFIED_EMAIL_FOR_RANKED.
+func RequiresVerifiedEmail(game config.Game, gameMode string) bool {
+	if !game.RequireVerifiedEmail {
+		return false
+	}
+	return gameMode == "ranked" || gameMode == "tournament"
+}
+
+// JoinResult tells the caller whether Join seated the user or, with the
+// lobby full, put them in its queue.
+type JoinResult struct {
+	Lobby         models.Lobby
+	Queued        bool
+	QueuePosition int
+}
+
+// LeaveResult describes what leaving did to the lobby: Deleted when the owner
+// was the last person in it, otherwise NewOwnerID when ownership passed on.
+type LeaveResult struct {
+	Lobby      models.Lobby
+	Deleted    bool
+	NewOwnerID uuid.UUID
+}
+
+func (s *lobbyService) Join(ctx context.Context, lobbyID string, userID uuid.UUID, inviteCode, password string) (JoinResult, error) {
+	var result JoinResult
+
+	var user models.User
+	if err := s.db.DB().WithContext(ctx).Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return result, ErrUserNotFound
+		}
+		return result, err
+	}
+
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lobby := &result.Lobby
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(lobby).Error; err != nil {
+			return lobbyError(err)
+		}
+
+		if lobby.Status != "waiting" {
+			return ErrLobbyClosed
+		}
+
+		if RequiresVerifiedEmail(s.game, lobby.GameMode) && user.EmailVerifiedAt == nil {
+			return ErrEmailUnverified
+		}
+
+		var existing models.Player
+		if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&existing).Error; err == nil {
+			return nil
+		}
+
+		if !ValidInviteCode(lobby, inviteCode) {
+			switch lobby.PrivacyLevel {
+			case "invite_only":
+				return ErrInviteOnly
+			case "password_protected":
+				if password == "" || lobby.PasswordHash == nil ||
+					bcrypt.CompareHashAndPassword([]byte(*lobby.PasswordHash), []byte(password)) != nil {
+					return ErrWrongPassword
+				}
+			}
+		}
+
+		if lobby.CurrentPlayers < lobby.MaxPlayers {
+			return AddPlayer(tx, lobby, userID)
+		}
+
+		var queued models.LobbyQueue
+		if err := tx.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobby.ID, userID, QueuePlayer).First(&queued).Error; err == nil {
+			return ErrAlreadyQueued
+		}
+
+		position, err := Enqueue(tx, lobby.ID, userID, QueuePlayer)
+		if err != nil {
+			return err
+		}
+		result.Queued = true
+		result.QueuePosition = position
+		return nil
+	})
+	return result, err
+}
+
+func (s *lobbyService) Leave(ctx context.Context, lobbyID string, userID uuid.UUID) (LeaveResult, error) {
+	var result LeaveResult
+
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lobby := &result.Lobby
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(lobby).Error; err != nil {
+			return lobbyError(err)
+		}
+
+		if lobby.OwnerID == userID {
+			var successor models.Player
+			err := tx.Where("lobby_id = ? AND user_id <> ? AND is_bot = ?", lobby.ID, userID, false).
+				Order("created_at asc, id asc").First(&successor).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result.Deleted = true
+				return DeleteLobby(tx, lobby.ID.String())
+			} else if err != nil {
+				return fmt.Errorf("finding new lobby owner: %w", err)
+			}
+
+			if err := TransferLobbyOwnership(tx, lobby, successor.UserID); err != nil {
+				return fmt.Errorf("transferring lobby ownership: %w", err)
+			}
+			result.NewOwnerID = successor.UserID
+		}
+
+		var player models.Player
+		if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&player).Error; err != nil {
+			return ErrNotInLobby
+		}
+
+		if err := tx.Delete(&player).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(lobby).Update("current_players", gorm.Expr("current_players - ?", 1)).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).Delete(&models.LobbyQueue{}).Error; err != nil {
+			return err
+		}
+
+		_, err := PromoteFromQueue(tx, lobby.ID)
+		return err
+	})
+	return result, err
+}
+
+func (s *lobbyService) Invite(ctx context.Context, lobbyID string, inviterID, invitedID uuid.UUID) (models.LobbyInvitation, error) {
+	var invitation models.LobbyInvitation
+
+	if invitedID == inviterID {
+		return invitation, ErrSelfInvite
+	}
+
+	db := s.db.DB().WithContext(ctx)
+
+	blocked, err := IsBlocked(db, inviterID, invitedID)
+	if err != nil {
+		return invitation, err
+	}
+	if blocked {
+		return invitation, ErrBlocked
+	}
+
+	var lobby models.Lobby
+	if err := db.Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return invitation, lobbyError(err)
+	}
+
+	if lobby.OwnerID != inviterID {
+		return invitation, ErrNotLobbyOwner
+	}
+
+	if lobby.CurrentPlayers >= lobby.MaxPlayers {
+		return invitation, ErrLobbyFull
+	}
+
+	now := time.Now().UTC()
+	invitation = models.LobbyInvitation{
+		ID:            uuid.New(),
+		LobbyID:       lobby.ID,
+		InviterID:     inviterID,
+		InvitedUserID: invitedID,
+		Status:        "pending",
+		ExpiresAt:     now.Add(invitationLifetime),
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.Model(&models.LobbyInvitation{}).
+			Where("lobby_id = ? AND invited_user_id = ? AND status = ?", lobby.ID, invitedID, "pending").
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrInvitationExists
+		}
+
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&invitation)
+		if created.Error != nil {
+			return created.Error
+		}
+		if created.RowsAffected == 0 {
+			return ErrInvitationExists
+		}
+
+		return CreateNotification(tx, invitedID, "lobby_invitation", map[string]interface{}{
+			"lobby_id":      lobby.ID,
+			"invitation_id": invitation.ID,
+			"expires_at":    invitation.ExpiresAt,
+			"lobby_name":    lobby.Name,
+			"message":       "You have been invited to a lobby",
+		})
+	})
+	return invitation, err
+}
+
+func (s *lobbyService) AcceptInvitation(ctx context.Context, lobbyID string, userID uuid.UUID) (models.Lobby, error) {
+	var lobby models.Lobby
+
+	err := s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Only a pending invitation can be accepted. The row stays locked so
+		// the owner cannot revoke it while the seat is being taken.
+		var invitation models.LobbyInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("lobby_id = ? AND invited_user_id = ? AND status = ?", lobbyID, userID, "pending").
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+
+		if invitation.ExpiresAt.Before(time.Now()) {
+			return ErrInvitationExpired
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", invitation.LobbyID).First(&lobby).Error; err != nil {
+			return lobbyError(err)
+		}
+
+		if lobby.CurrentPlayers >= lobby.MaxPlayers {
+			return ErrLobbyFull
+		}
+
+		if err := tx.Model(&invitation).Updates(map[string]interface{}{
+			"status":     "accepted",
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		return AddPlayer(tx, &lobby, userID)
+	})
+	return lobby, err
+}
+
+func (s *lobbyService) DeclineInvitation(ctx context.Context, lobbyID string, userID uuid.UUID) error {
+	db := s.db.DB().WithContext(ctx)
+
+	var user models.User
+	if err := db.Select("id", "name").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var invitation models.LobbyInvitation
+		if err := tx.Preload("Lobby").Where("lobby_id = ? AND invited_user_id = ? AND status = ?",
+			lobbyID, userID, "pending").First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+
+		if err := closeInvitation(tx, &invitation, "declined"); err != nil {
+			return err
+		}
+
+		return notifyInvitation(tx, invitation.InviterID, "lobby_invitation_declined", invitation,
+			fmt.Sprintf("%s declined your invitation", user.Name))
+	})
+}
+
+func (s *lobbyService) CancelInvitation(ctx context.Context, lobbyID, invitationID string, userID uuid.UUID) error {
+	return s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invitation models.LobbyInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lobby").
+			Where("id = ? AND lobby_id = ?", invitationID, lobbyID).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvitationNotFound
+			}
+			return err
+		}
+
+		if invitation.InviterID != userID && invitation.Lobby.OwnerID != userID {
+			return ErrNotLobbyOwner
+		}
+
+		if invitation.Status != "pending" {
+			return ErrInvitationProcessed
+		}
+
+		if err := closeInvitation(tx, &invitation, "cancelled"); err != nil {
+			return err
+		}
+
+		return notifyInvitation(tx, invitation.InvitedUserID, "lobby_invitation_cancelled", invitation,
+			"Your invitation to the lobby was cancelled")
+	})
+}
+
+// closeInvitation settles a pending invitation and withdraws the invitation
+// notification the invited user got for it.
+func closeInvitation(tx *gorm.DB, invitation *models.LobbyInvitation, status string) error {
+	if err := tx.Model(invitation).Updates(map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("user_id = ? AND type = ? AND data->>'lobby_id' = ?",
+		invitation.InvitedUserID, "lobby_invitation", invitation.LobbyID.String()).
+		Delete(&models.Notification{}).Error
+}
+
+func notifyInvitation(tx *gorm.DB, userID uuid.UUID, messageType string, invitation models.LobbyInvitation, message string) error {
+	return CreateNotification(tx, userID, messageType, map[string]interface{}{
+		"lobby_id":      invitation.LobbyID,
+		"lobby_name":    invitation.Lobby.Name,
+		"invitation_id": invitation.ID,
+		"message":       message,
+	})
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"api/internal/config"
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// testDatabase connects to the Postgres database named by TEST_DATABASE_URL
+// and brings its schema up to date. Tests that need one are skipped when it
+// is not set.
+func testDatabase(t testing.TB) database.Service {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db := database.New(config.Database{
+		URL:          url,
+		MaxOpenConns: 32,
+		MaxIdleConns: 32,
+	})
+	if _, err := db.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// seedUsers creates n users and removes them, and everything hanging off
+// them, when the test ends.
+func seedUsers(t testing.TB, db *gorm.DB, n int) []models.User {
+	t.Helper()
+
+	users := make([]models.User, n)
+	for i := range users {
+		id := uuid.New()
+		users[i] = models.User{
+			ID:       id,
+			Name:     fmt.Sprintf("player-%d", i),
+			Email:    id.String() + "@example.test",
+			Password: "not-a-real-hash",
+		}
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("creating users: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ids := make([]uuid.UUID, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		var lobbyIDs []uuid.UUID
+		db.Model(&models.Lobby{}).Where("owner_id IN ?", ids).Pluck("id", &lobbyIDs)
+		for _, lobbyID := range lobbyIDs {
+			DeleteLobby(db, lobbyID.String())
+		}
+		db.Where("user_id IN ?", ids).Delete(&models.Notification{})
+		db.Where("id IN ?", ids).Delete(&models.User{})
+	})
+	return users
+}
+
+// seedLobby opens a waiting lobby for owner, seated in its game, the way
+// creating a lobby through the API does.
+func seedLobby(t testing.TB, db *gorm.DB, owner models.User, maxPlayers int) models.Lobby {
+	t.Helper()
+
+	now := time.Now()
+	lobby := models.Lobby{
+		ID:             uuid.New(),
+		Name:           "capacity",
+		OwnerID:        owner.ID,
+		Type:           "public",
+		Status:         "waiting",
+		MaxPlayers:     maxPlayers,
+		CurrentPlayers: 1,
+		PrivacyLevel:   "open",
+		MaxSpectators:  20,
+		GameMode:       "casual",
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	game := models.Game{
+		ID:          uuid.New(),
+		LobbyID:     lobby.ID,
+		OwnerID:     owner.ID,
+		Status:      "waiting",
+		RoundNumber: 1,
+		Winner:      "none",
+	}
+	player := models.Player{
+		ID:      uuid.New(),
+		LobbyID: lobby.ID,
+		GameID:  game.ID,
+		UserID:  owner.ID,
+		Role:    "player1",
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&lobby).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+		return tx.Create(&player).Error
+	})
+	if err != nil {
+		t.Fatalf("creating lobby: %v", err)
+	}
+	return lobby
+}
+
+func TestJoinNeverExceedsCapacity(t *testing.T) {
+	db := testDatabase(t)
+	gormDB := db.DB()
+
+	const maxPlayers, joiners = 4, 16
+
+	users := seedUsers(t, gormDB, joiners+1)
+	lobby := seedLobby(t, gormDB, users[0], maxPlayers)
+	lobbies := NewLobbyService(db, config.Game{})
+
+	results := make([]JoinResult, joiners)
+	errs := make([]error, joiners)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, user := range users[1:] {
+		wg.Add(1)
+		go func(i int, userID uuid.UUID) {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = lobbies.Join(context.Background(), lobby.ID.String(), userID, "", "")
+		}(i, user.ID)
+	}
+	close(start)
+	wg.Wait()
+
+	var seated, queued int
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("join %d: %v", i, err)
+		}
+		if results[i].Queued {
+			queued++
+		} else {
+			seated++
+		}
+	}
+
+	if want := maxPlayers - 1; seated != want {
+		t.Errorf("seated %d joiners, want %d", seated, want)
+	}
+	if want := joiners - (maxPlayers - 1); queued != want {
+		t.Errorf("queued %d joiners, want %d", queued, want)
+	}
+
+	var stored models.Lobby
+	if err := gormDB.Where("id = ?", lobby.ID).First(&stored).Error; err != nil {
+		t.Fatalf("reloading lobby: %v", err)
+	}
+	if stored.CurrentPlayers != maxPlayers {
+		t.Errorf("current_players = %d, want %d", stored.CurrentPlayers, maxPlayers)
+	}
+
+	var players int64
+	if err := gormDB.Model(&models.Player{}).Where("lobby_id = ?", lobby.ID).Count(&players).Error; err != nil {
+		t.Fatalf("counting players: %v", err)
+	}
+	if players != maxPlayers {
+		t.Errorf("lobby has %d players, want %d", players, maxPlayers)
+	}
+}
+
+func TestClaimSeatGuardsCapacity(t *testing.T) {
+	db := testDatabase(t)
+	gormDB := db.DB()
+
+	const maxPlayers, claims = 3, 12
+
+	users := seedUsers(t, gormDB, 1)
+	lobby := seedLobby(t, gormDB, users[0], maxPlayers)
+
+	errs := make([]error, claims)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < claims; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = gormDB.Transaction(func(tx *gorm.DB) error {
+				seat := lobby
+				return ClaimSeat(tx, &seat)
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var claimed int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			claimed++
+		case errors.Is(err, ErrLobbyFull):
+		default:
+			t.Fatalf("claim %d: %v", i, err)
+		}
+	}
+
+	if want := maxPlayers - 1; claimed != want {
+		t.Errorf("%d claims succeeded, want %d", claimed, want)
+	}
+
+	var stored models.Lobby
+	if err := gormDB.Where("id = ?", lobby.ID).First(&stored).Error; err != nil {
+		t.Fatalf("reloading lobby: %v", err)
+	}
+	if stored.CurrentPlayers != maxPlayers {
+		t.Errorf("current_players = %d, want %d", stored.CurrentPlayers, maxPlayers)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationChannel is the Postgres NOTIFY channel new notification IDs are
+// published on.
+const NotificationChannel = "notifications"
+
+// NotificationCategories groups notification types into the categories users
+// can opt out of. Types missing from the map cannot be turned off.
+var NotificationCategories = map[string]string{
+	"lobby_invitation":            "invites",
+	"lobby_invitation_declined":   "invites",
+	"lobby_invitation_cancelled":  "invites",
+	"friend_request":              "friend_requests",
+	"friend_request_accepted":     "friend_requests",
+	"direct_message":              "direct_messages",
+	"turn_reminder":               "turn_reminders",
+	"lobby_queue_promoted":        "lobby_updates",
+	"spectator_queue_promoted":    "lobby_updates",
+	"lobby_ownership_transferred": "lobby_updates",
+	"lobby_closed":                "lobby_updates",
+	"match_found":                 "matchmaking",
+	"announcement":                "marketing",
+}
+
+// CreateNotification stores a notification for the user, unless they turned
+// its category off, and announces it on NotificationChannel.
+func CreateNotification(tx *gorm.DB, userID uuid.UUID, messageType string, data map[string]interface{}) error {
+	enabled, err := notificationEnabled(tx, userID, messageType)
+	if err != nil || !enabled {
+		return err
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	notification := models.Notification{
+		ID:        uuid.New(),
+		Type:      &messageType,
+		UserID:    userID,
+		Data:      encoded,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := tx.Create(&notification).Error; err != nil {
+		return err
+	}
+
+	// NOTIFY is only delivered once tx commits, so nothing is pushed for a
+	// notification that gets rolled back.
+	return tx.Exec("SELECT pg_notify(?, ?)", NotificationChannel, notification.ID.String()).Error
+}
+
+// notificationEnabled reports whether the user still wants notifications of
+// the given type. Categories without a stored preference are on.
+func notificationEnabled(tx *gorm.DB, userID uuid.UUID, messageType string) (bool, error) {
+	category, ok := NotificationCategories[messageType]
+	if !ok {
+		return true, nil
+	}
+
+	var preference models.NotificationPreference
+	err := tx.Where("user_id = ? AND category = ?", userID, category).First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return preference.Enabled, nil
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"api/internal/database"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PresenceOnline  = "online"
+	PresenceInLobby = "in_lobby"
+	PresenceInGame  = "in_game"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// PresenceSocketTTL is how long a reported socket keeps counting as open.
+// Connected sockets must be reported again well within it.
+const PresenceSocketTTL = 45 * time.Second
+
+const (
+	// presenceSeenTTL is how long a user's last activity is remembered for
+	// last_seen_at.
+	presenceSeenTTL = 30 * 24 * time.Hour
+	// presenceTouchEvery throttles how often HTTP activity is written to the
+	// store for the same user.
+	presenceTouchEvery = 30 * time.Second
+)
+
+// Presence is what a user is doing right now.
+type Presence struct {
+	Status     string     `json:"status"`
+	LobbyID    *uuid.UUID `json:"lobby_id,omitempty"`
+	GameID     *uuid.UUID `json:"game_id,omitempty"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// PresenceStore holds presence signals with an expiry. Any fiber.Storage,
+// such as redis.Storage, satisfies it.
+type PresenceStore interface {
+	Get(key string) ([]byte, error)
+	Set(key string, val []byte, exp time.Duration) error
+}
+
+type PresenceService interface {
+	// Touch records that the user just made an authenticated request.
+	Touch(userID uuid.UUID)
+	// Connected records that the user has a socket open, playing in gameID
+	// when set. It has to be repeated within PresenceSocketTTL for as long
+	// as the socket stays open.
+	Connected(userID uuid.UUID, gameID *uuid.UUID)
+	// Get resolves one user's presence. A user playing with a game socket
+	// open is in_game; an active user seated in a waiting lobby is
+	// in_lobby; otherwise recent HTTP activity makes them online, and an
+	// idle socket or older activity away.
+	Get(ctx context.Context, userID uuid.UUID) (Presence, error)
+	Many(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]Presence, error)
+}
+
+type presenceService struct {
+	db           database.Service
+	store        PresenceStore
+	awayAfter    time.Duration
+	offlineAfter time.Duration
+
+	mu      sync.Mutex
+	touched map[uuid.UUID]time.Time
+}
+
+func NewPresenceService(db database.Service, store PresenceStore, awayAfter, offlineAfter time.Duration) PresenceService {
+	return &presenceService{
+		db:           db,
+		store:        store,
+		awayAfter:    awayAfter,
+		offlineAfter: offlineAfter,
+		touched:      make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (s *presenceService) Touch(userID uuid.UUID) {
+	now := time.Now()
+
+	s.mu.Lock()
+	if last, ok := s.touched[userID]; ok && now.Sub(last) < presenceTouchEvery {
+		s.mu.Unlock()
+		return
+	}
+	if len(s.touched) > 100000 {
+		s.touched = make(map[uuid.UUID]time.Time)
+	}
+	s.touched[userID] = now
+	s.mu.Unlock()
+
+	_ = s.store.Set(presenceKey("seen", userID), []byte(strconv.FormatInt(now.Unix(), 10)), presenceSeenTTL)
+}
+
+func (s *presenceService) Connected(userID uuid.UUID, gameID *uuid.UUID) {
+	_ = s.store.Set(presenceKey("socket", userID), []byte("1"), PresenceSocketTTL)
+	if gameID != nil {
+		_ = s.store.Set(presenceKey("game", userID), []byte(gameID.String()), PresenceSocketTTL)
+	}
+}
+
+func (s *presenceService) Get(ctx context.Context, userID uuid.UUID) (Presence, error) {
+	presences, err := s.Many(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return Presence{}, err
+	}
+	return presences[userID], nil
+}
+
+func (s *presenceService) Many(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]Presence, error) {
+	now := time.Now()
+	presences := make(map[uuid.UUID]Presence, len(userIDs))
+
+	var active []uuid.UUID
+	for _, userID := range userIDs {
+		presence := Presence{Status: PresenceOffline}
+
+		var seen *time.Time
+		if raw, err := s.store.Get(presenceKey("seen", userID)); err != nil {
+			return nil, err
+		} else if unix, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			at := time.Unix(unix, 0).UTC()
+			seen = &at
+			presence.LastSeenAt = seen
+		}
+
+		socket, err := s.store.Get(presenceKey("socket", userID))
+		if err != nil {
+			return nil, err
+		}
+		recent := seen != nil && now.Sub(*seen) < s.awayAfter
+
+		switch {
+		case len(socket) > 0 || recent:
+			game, err := s.store.Get(presenceKey("game", userID))
+			if err != nil {
+				return nil, err
+			}
+			if gameID, err := uuid.ParseBytes(game); err == nil {
+				presence.Status = PresenceInGame
+				presence.GameID = &gameID
+			} else if recent {
+				presence.Status = PresenceOnline
+				active = append(active, userID)
+			} else {
+				presence.Status = PresenceAway
+				active = append(active, userID)
+			}
+		case seen != nil && now.Sub(*seen) < s.offlineAfter:
+			presence.Status = PresenceAway
+		}
+
+		presences[userID] = presence
+	}
+
+	if len(active) == 0 {
+		return presences, nil
+	}
+
+	var seats []struct {
+		UserID  uuid.UUID
+		LobbyID uuid.UUID
+	}
+	if err := s.db.DB().WithContext(ctx).Table("players").
+		Select("players.user_id, players.lobby_id").
+		Joins("JOIN lobbies ON lobbies.id = players.lobby_id").
+		Where("players.user_id IN ? AND lobbies.status = ?", active, "waiting").
+		Scan(&seats).Error; err != nil {
+		return nil, err
+	}
+	for _, seat := range seats {
+		presence := presences[seat.UserID]
+		presence.Status = PresenceInLobby
+		presence.LobbyID = &seat.LobbyID
+		presences[seat.UserID] = presence
+	}
+	return presences, nil
+}
+
+func presenceKey(signal string, userID uuid.UUID) string {
+	return signal + ":" + userID.String()
+}
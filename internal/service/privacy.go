@@ -0,0 +1,88 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *userService) Privacy(ctx context.Context, userID uuid.UUID) (models.PrivacySettings, error) {
+	settings, err := LoadPrivacySettings(s.db.DB().WithContext(ctx), []uuid.UUID{userID})
+	if err != nil {
+		return models.PrivacySettings{}, err
+	}
+	return settings[userID], nil
+}
+
+func (s *userService) UpdatePrivacy(ctx context.Context, settings *models.PrivacySettings) error {
+	now := time.Now().UTC()
+	settings.CreatedAt = now
+	settings.UpdatedAt = now
+	return s.db.DB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"profile_visibility", "hide_email", "updated_at"}),
+	}).Create(settings).Error
+}
+
+func (s *userService) CanViewProfile(ctx context.Context, viewerID uuid.UUID, target models.User) (bool, error) {
+	if viewerID == target.ID {
+		return true, nil
+	}
+
+	db := s.db.DB().WithContext(ctx)
+
+	var relations []models.Friendship
+	if err := db.Select("status").
+		Where("(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)",
+			viewerID, target.ID, target.ID, viewerID).
+		Find(&relations).Error; err != nil {
+		return false, err
+	}
+	friends := false
+	for _, relation := range relations {
+		switch relation.Status {
+		case "blocked":
+			return false, nil
+		case "accepted":
+			friends = true
+		}
+	}
+
+	settings, err := s.Privacy(ctx, target.ID)
+	if err != nil {
+		return false, err
+	}
+	switch settings.ProfileVisibility {
+	case "public":
+		return true, nil
+	case "friends":
+		return friends, nil
+	default:
+		return false, nil
+	}
+}
+
+// LoadPrivacySettings returns the privacy settings of each of the given users,
+// falling back to the defaults for users who never changed them.
+func LoadPrivacySettings(tx *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]models.PrivacySettings, error) {
+	settings := make(map[uuid.UUID]models.PrivacySettings, len(userIDs))
+	for _, id := range userIDs {
+		settings[id] = models.DefaultPrivacySettings(id)
+	}
+	if len(userIDs) == 0 {
+		return settings, nil
+	}
+
+	var stored []models.PrivacySettings
+	if err := tx.Where("user_id IN ?", userIDs).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	for _, setting := range stored {
+		settings[setting.UserID] = setting
+	}
+	return settings, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Queue types of a lobby's queue: users waiting for a seat and users waiting
+// for room to spectate.
+const (
+	QueuePlayer    = "player"
+	QueueSpectator = "spectator"
+)
+
+// PromoteFromQueue fills an open seat in a waiting lobby with the first user
+// in its queue. It returns the promoted user's ID, or uuid.Nil when there was
+// no seat, nobody waiting or the cards have already been dealt.
+func PromoteFromQueue(tx *gorm.DB, lobbyID uuid.UUID) (uuid.UUID, error) {
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	if lobby.Status != "waiting" || lobby.CurrentPlayers >= lobby.MaxPlayers {
+		return uuid.Nil, nil
+	}
+
+	var entry models.LobbyQueue
+	err := tx.Where("lobby_id = ? AND queue_type = ?", lobbyID, QueuePlayer).Order("priority desc, position asc").First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil
+	} else if err != nil {
+		return uuid.Nil, err
+	}
+
+	game, err := LockOpenGame(tx, lobbyID)
+	if errors.Is(err, ErrGameStarted) {
+		return uuid.Nil, nil
+	} else if err != nil {
+		return uuid.Nil, err
+	}
+
+	var seated int64
+	if err := tx.Model(&models.Player{}).Where("lobby_id = ?", lobbyID).Count(&seated).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	team, err := AssignTeam(tx, &lobby, game.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	player := models.Player{
+		ID:      uuid.New(),
+		LobbyID: lobbyID,
+		GameID:  game.ID,
+		UserID:  entry.UserID,
+		Team:    team,
+		Role:    fmt.Sprintf("player%d", seated+1),
+	}
+	if err := tx.Create(&player).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := ClaimSeat(tx, &lobby); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := StopWatching(tx, lobbyID, entry.UserID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Delete(&entry).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := ReindexQueue(tx, lobbyID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := CreateNotification(tx, entry.UserID, "lobby_queue_promoted", map[string]interface{}{
+		"lobby_id":   lobby.ID,
+		"lobby_name": lobby.Name,
+		"message":    "A seat opened up and you have joined the lobby",
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	return entry.UserID, nil
+}
+
+// Enqueue puts the user at the back of one of the lobby's queues and returns
+// their position in it.
+func Enqueue(tx *gorm.DB, lobbyID, userID uuid.UUID, queueType string) (int, error) {
+	position := 1
+	var last models.LobbyQueue
+	if err := tx.Where("lobby_id = ? AND queue_type = ?", lobbyID, queueType).Order("position desc").First(&last).Error; err == nil && last.Position != nil {
+		position = *last.Position + 1
+	}
+
+	entry := models.LobbyQueue{
+		ID:        uuid.New(),
+		LobbyID:   lobbyID,
+		UserID:    userID,
+		QueueType: queueType,
+		Position:  &position,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return 0, err
+	}
+	return position, nil
+}
+
+// ReindexQueue renumbers each of a lobby's queues from 1 so positions stay
+// contiguous after someone leaves one.
+func ReindexQueue(tx *gorm.DB, lobbyID uuid.UUID) error {
+	var entries []models.LobbyQueue
+	if err := tx.Where("lobby_id = ?", lobbyID).Order("queue_type, priority desc, position asc").Find(&entries).Error; err != nil {
+		return err
+	}
+
+	positions := make(map[string]int)
+	for _, entry := range entries {
+		positions[entry.QueueType]++
+		position := positions[entry.QueueType]
+		if entry.Position != nil && *entry.Position == position {
+			continue
+		}
+		if err := tx.Model(&entry).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"api/internal/game/rating"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSeasonNotFound = errors.New("season not found")
+
+// Standing is one row of a season leaderboard. Rating is only set for ranked.
+type Standing struct {
+	Rank        int       `json:"rank"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	Avatar      *string   `json:"avatar,omitempty"`
+	Rating      *int      `json:"rating,omitempty"`
+	GamesPlayed int       `json:"games_played"`
+	Wins        int       `json:"wins"`
+}
+
+type SeasonService interface {
+	// Current returns the running season, starting the first one when there
+	// has never been any.
+	Current(ctx context.Context) (models.Season, error)
+	// Find resolves "current", a season number or a season ID.
+	Find(ctx context.Context, ref string) (models.Season, error)
+	List(ctx context.Context) ([]models.Season, error)
+	// Standings returns a page of the season's leaderboard for a game mode
+	// along with the number of ranked users. Ranked orders by rating, every
+	// other mode by wins. Running seasons are computed from match results;
+	// archived ones read the standings stored at rollover.
+	Standings(ctx context.Context, season models.Season, mode string, offset, limit int) ([]Standing, int64, error)
+	// Rollover ends the running season once its end has passed: it archives
+	// the standings of every mode played, decays ratings toward the default
+	// and starts the next season.
+	Rollover(ctx context.Context, now time.Time) error
+}
+
+type seasonService struct {
+	db     database.Service
+	length time.Duration
+	// keep is the share of a rating's distance from the default that
+	// survives a rollover: 1 keeps ratings as they are, 0 resets them.
+	keep float64
+}
+
+// NewSeasonService runs seasons of the given length. decayPercent is how
+// much of each rating's distance from the default is removed at rollover,
+// 100 being a full reset.
+func NewSeasonService(db database.Service, length time.Duration, decayPercent int) SeasonService {
+	return &seasonService{
+		db:     db,
+		length: length,
+		keep:   float64(100-decayPercent) / 100,
+	}
+}
+
+func (s *seasonService) Current(ctx context.Context) (models.Season, error) {
+	season, err := s.active(s.db.DB().WithContext(ctx))
+	if !errors.Is(err, ErrSeasonNotFound) {
+		return season, err
+	}
+
+	if err := s.Rollover(ctx, time.Now().UTC()); err != nil {
+		return season, err
+	}
+	return s.active(s.db.DB().WithContext(ctx))
+}
+
+func (s *seasonService) Find(ctx context.Context, ref string) (models.Season, error) {
+	if ref == "" || ref == "current" {
+		return s.Current(ctx)
+	}
+
+	var season models.Season
+	query := s.db.DB().WithContext(ctx)
+	if number, err := strconv.Atoi(ref); err == nil {
+		query = query.Where("number = ?", number)
+	} else if id, err := uuid.Parse(ref); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		return season, ErrSeasonNotFound
+	}
+
+	if err := query.First(&season).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return season, ErrSeasonNotFound
+		}
+		return season, err
+	}
+	return season, nil
+}
+
+func (s *seasonService) List(ctx context.Context) ([]models.Season, error) {
+	var seasons []models.Season
+	err := s.db.DB().WithContext(ctx).Order("number DESC").Find(&seasons).Error
+	return seasons, err
+}
+
+func (s *seasonService) Standings(ctx context.Context, season models.Season, mode string, offset, limit int) ([]Standing, int64, error) {
+	db := s.db.DB().WithContext(ctx)
+	if season.Status == "archived" {
+		return archivedStandings(db, season, mode, offset, limit)
+	}
+
+	query, args := liveStandingsQuery(season, mode)
+
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) FROM ("+query+") standings", args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var standings []Standing
+	if err := db.Raw(query+" LIMIT ? OFFSET ?", append(args, limit, offset)...).Scan(&standings).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range standings {
+		standings[i].Rank = offset + i + 1
+	}
+	return standings, total, nil
+}
+
+func (s *seasonService) Rollover(ctx context.Context, now time.Time) error {
+	return s.db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var season models.Season
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status = ?", "active").First(&season).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var last models.Season
+			if err := tx.Order("number DESC").Limit(1).Find(&last).Error; err != nil {
+				return err
+			}
+			start := now
+			if last.ID != uuid.Nil {
+				start = last.EndsAt
+			}
+			return s.start(tx, last.Number+1, start, now)
+		} else if err != nil {
+			return err
+		}
+
+		if now.Before(season.EndsAt) {
+			return nil
+		}
+
+		if err := s.archive(tx, season, now); err != nil {
+			return fmt.Errorf("archiving season %d: %w", season.Number, err)
+		}
+
+		if s.keep < 1 {
+			if err := tx.Exec("UPDATE ratings SET rating = ? + ROUND((rating - ?) * ?), updated_at = ?",
+				rating.Default, rating.Default, s.keep, now).Error; err != nil {
+				return fmt.Errorf("decaying ratings: %w", err)
+			}
+		}
+
+		return s.start(tx, season.Number+1, season.EndsAt, now)
+	})
+}
+
+// start opens season number at start. A season that would already be over,
+// because rollover did not run for a while, is stretched to end one full
+// length from now.
+func (s *seasonService) start(tx *gorm.DB, number int, start, now time.Time) error {
+	end := start.Add(s.length)
+	if !end.After(now) {
+		end = now.Add(s.length)
+	}
+
+	season := models.Season{
+		ID:        uuid.New(),
+		Number:    number,
+		Name:      fmt.Sprintf("Season %d", number),
+		Status:    "active",
+		StartsAt:  start,
+		EndsAt:    end,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return tx.Create(&season).Error
+}
+
+// archive stores the final standings of every mode played in the season and
+// marks it archived.
+func (s *seasonService) archive(tx *gorm.DB, season models.Season, now time.Time) error {
+	var modes []string
+	if err := tx.Model(&models.MatchResult{}).
+		Where("finished_at >= ? AND finished_at < ?", season.StartsAt, season.EndsAt).
+		Distinct().
+		Pluck("game_mode", &modes).Error; err != nil {
+		return err
+	}
+
+	for _, mode := range modes {
+		query, args := liveStandingsQuery(season, mode)
+
+		var standings []Standing
+		if err := tx.Raw(query, args...).Scan(&standings).Error; err != nil {
+			return err
+		}
+
+		rows := make([]models.SeasonStanding, len(standings))
+		for i, standing := range standings {
+			rows[i] = models.SeasonStanding{
+				ID:          uuid.New(),
+				SeasonID:    season.ID,
+				GameMode:    mode,
+				UserID:      standing.UserID,
+				Rank:        i + 1,
+				Rating:      standing.Rating,
+				GamesPlayed: standing.GamesPlayed,
+				Wins:        standing.Wins,
+				CreatedAt:   now,
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Model(&season).Updates(map[string]interface{}{
+		"status":      "archived",
+		"archived_at": now,
+		"updated_at":  now,
+	}).Error
+}
+
+func (s *seasonService) active(db *gorm.DB) (models.Season, error) {
+	var season models.Season
+	if err := db.Where("status = ?", "active").First(&season).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return season, ErrSeasonNotFound
+		}
+		return season, err
+	}
+	return season, nil
+}
+
+// liveStandingsQuery ranks the non-bot users who finished a game of the mode
+// during the season.
+func liveStandingsQuery(season models.Season, mode string) (string, []interface{}) {
+	args := []interface{}{mode, season.StartsAt, season.EndsAt}
+	const from = `
+FROM match_participants mp
+JOIN match_results mr ON mr.id = mp.match_result_id
+JOIN users u ON u.id = mp.user_id
+`
+	const where = `WHERE mr.game_mode = ? AND mr.finished_at >= ? AND mr.finished_at < ? AND u.is_bot = false`
+
+	if mode == "ranked" {
+		return `SELECT mp.user_id, u.name, u.avatar, COALESCE(r.rating, ` + strconv.Itoa(rating.Default) + `) AS rating,
+       COUNT(*) AS games_played, COUNT(*) FILTER (WHERE COALESCE(mp.team_placement, mp.placement) = 1) AS wins` + from +
+			`LEFT JOIN ratings r ON r.user_id = mp.user_id
+` + where + `
+GROUP BY mp.user_id, u.name, u.avatar, r.rating
+ORDER BY rating DESC, wins DESC, mp.user_id ASC`, args
+	}
+
+	return `SELECT mp.user_id, u.name, u.avatar,
+       COUNT(*) AS games_played, COUNT(*) FILTER (WHERE COALESCE(mp.team_placement, mp.placement) = 1) AS wins` + from + where + `
+GROUP BY mp.user_id, u.name, u.avatar
+ORDER BY wins DESC, games_played ASC, mp.user_id ASC`, args
+}
+
+func archivedStandings(db *gorm.DB, season models.Season, mode string, offset, limit int) ([]Standing, int64, error) {
+	query := db.Model(&models.SeasonStanding{}).Where("season_id = ? AND game_mode = ?", season.ID, mode)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []models.SeasonStanding
+	if err := query.
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Select("id, name, avatar") }).
+		Order("rank ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	standings := make([]Standing, len(rows))
+	for i, row := range rows {
+		standings[i] = Standing{
+			Rank:        row.Rank,
+			UserID:      row.UserID,
+			Name:        row.User.Name,
+			Avatar:      row.User.Avatar,
+			Rating:      row.Rating,
+			GamesPlayed: row.GamesPlayed,
+			Wins:        row.Wins,
+		}
+	}
+	return standings, total, nil
+}
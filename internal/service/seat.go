@@ -0,0 +1,144 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/teams"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddPlayer seats the user in the lobby's waiting game, creating the game
+// if there is none yet. Seating a user who already has a seat does nothing.
+func AddPlayer(tx *gorm.DB, lobby *models.Lobby, userID uuid.UUID) error {
+	var existingPlayer models.Player
+	if err := tx.Where("lobby_id = ? AND user_id = ?", lobby.ID, userID).First(&existingPlayer).Error; err == nil {
+		return nil
+	}
+
+	game, err := LockOpenGame(tx, lobby.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		game = models.Game{
+			LobbyID:     lobby.ID,
+			RoundNumber: 1,
+			Status:      "waiting",
+			Winner:      "none",
+		}
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	team, err := AssignTeam(tx, lobby, game.ID)
+	if err != nil {
+		return err
+	}
+
+	playerNumber := lobby.CurrentPlayers
+	player := models.Player{
+		ID:      uuid.New(),
+		LobbyID: lobby.ID,
+		GameID:  game.ID,
+		UserID:  userID,
+		Role:    fmt.Sprintf("player%d", playerNumber),
+		Score:   0,
+		Team:    team,
+	}
+
+	// A concurrent join may have seated the user since the check above; the
+	// unique index turns that into a no-op rather than a second seat.
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&player)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := ClaimSeat(tx, lobby); err != nil {
+		return err
+	}
+
+	return StopWatching(tx, lobby.ID, userID)
+}
+
+// LockOpenGame locks the lobby's waiting game for a new seat. Seats are only
+// handed out until the cards are dealt, so it fails with ErrGameStarted once
+// any game in the lobby has been dealt, and with gorm.ErrRecordNotFound when
+// there is no waiting game.
+func LockOpenGame(tx *gorm.DB, lobbyID uuid.UUID) (models.Game, error) {
+	var game models.Game
+
+	var underway int64
+	if err := tx.Model(&models.Game{}).
+		Where("lobby_id = ? AND status IN ?", lobbyID, []string{"setup", "in_progress"}).
+		Count(&underway).Error; err != nil {
+		return game, err
+	}
+	if underway > 0 {
+		return game, ErrGameStarted
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lobby_id = ? AND status = ?", lobbyID, "waiting").
+		First(&game).Error; err != nil {
+		return game, err
+	}
+
+	var dealt int64
+	if err := tx.Model(&models.Deck{}).Where("game_id = ?", game.ID).Count(&dealt).Error; err != nil {
+		return game, err
+	}
+	if dealt > 0 {
+		return game, ErrGameStarted
+	}
+	return game, nil
+}
+
+// ClaimSeat takes one of the lobby's seats, failing with ErrLobbyFull once it
+// is at capacity. The capacity check and the increment are a single guarded
+// UPDATE, so simultaneous joins cannot push a lobby past MaxPlayers.
+func ClaimSeat(tx *gorm.DB, lobby *models.Lobby) error {
+	result := tx.Model(&models.Lobby{}).
+		Where("id = ? AND current_players < max_players", lobby.ID).
+		Updates(map[string]interface{}{
+			"current_players": gorm.Expr("current_players + ?", 1),
+			"updated_at":      time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLobbyFull
+	}
+
+	lobby.CurrentPlayers++
+	return nil
+}
+
+// AssignTeam picks the team a player joining the lobby's game sits in: the
+// emptiest one. It returns nil outside team games.
+func AssignTeam(tx *gorm.DB, lobby *models.Lobby, gameID uuid.UUID) (*int, error) {
+	if lobby.GameMode != teams.Mode {
+		return nil, nil
+	}
+
+	var members []int
+	if err := tx.Model(&models.Player{}).
+		Where("game_id = ? AND team IS NOT NULL", gameID).
+		Pluck("team", &members).Error; err != nil {
+		return nil, err
+	}
+
+	team := teams.Assign(members, teams.Count(lobby.MaxPlayers))
+	if team == 0 {
+		return nil, nil
+	}
+	return &team, nil
+}
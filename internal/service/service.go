@@ -0,0 +1,33 @@
+// Package service holds the business rules behind the HTTP and WebSocket
+// handlers. Services take plain values and return models or sentinel errors,
+// so they can be exercised without Fiber; handlers only map requests and
+// errors to responses.
+package service
+
+import "errors"
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrEmailTaken       = errors.New("email already in use")
+	ErrPasswordMismatch = errors.New("current password is incorrect")
+	ErrLobbyNotFound    = errors.New("lobby not found")
+	ErrGameNotFound     = errors.New("game not found")
+	ErrNotAllowed       = errors.New("not allowed to join this game")
+
+	ErrLobbyFull           = errors.New("lobby is full")
+	ErrLobbyClosed         = errors.New("lobby not accepting players")
+	ErrGameStarted         = errors.New("game has already started")
+	ErrNotInLobby          = errors.New("not in lobby")
+	ErrNotLobbyOwner       = errors.New("not the lobby owner")
+	ErrAlreadyQueued       = errors.New("already in queue")
+	ErrInviteOnly          = errors.New("lobby is invite only")
+	ErrWrongPassword       = errors.New("invalid lobby password")
+	ErrEmailUnverified     = errors.New("email address not verified")
+	ErrBlocked             = errors.New("users have blocked one another")
+	ErrSelfInvite          = errors.New("cannot invite yourself")
+	ErrInvitationExists    = errors.New("invitation already exists")
+	ErrInvitationNotFound  = errors.New("invitation not found")
+	ErrInvitationExpired   = errors.New("invitation has expired")
+	ErrInvitationProcessed = errors.New("invitation has already been processed")
+)
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddSpectator registers the user as one of the lobby's spectators.
+func AddSpectator(tx *gorm.DB, lobby *models.Lobby, userID uuid.UUID) error {
+	now := time.Now()
+	spectator := models.LobbySpectator{
+		ID:        uuid.New(),
+		LobbyID:   lobby.ID,
+		UserID:    userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := tx.Create(&spectator).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(lobby).Update("spectator_count", gorm.Expr("spectator_count + ?", 1)).Error; err != nil {
+		return err
+	}
+	lobby.SpectatorCount++
+	return nil
+}
+
+// ReleaseSpectatorSlot drops the user from the lobby's spectators and hands
+// the freed slot to the spectator queue. It reports whether the user was
+// spectating.
+func ReleaseSpectatorSlot(tx *gorm.DB, lobbyID, userID uuid.UUID) (bool, error) {
+	result := tx.Where("lobby_id = ? AND user_id = ?", lobbyID, userID).Delete(&models.LobbySpectator{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	if err := tx.Model(&models.Lobby{}).Where("id = ?", lobbyID).
+		Update("spectator_count", gorm.Expr("GREATEST(spectator_count - ?, 0)", 1)).Error; err != nil {
+		return true, err
+	}
+
+	_, err := PromoteSpectators(tx, lobbyID)
+	return true, err
+}
+
+// StopWatching takes a user who has just been seated out of the lobby's
+// spectators and its spectator queue.
+func StopWatching(tx *gorm.DB, lobbyID, userID uuid.UUID) error {
+	if _, err := ReleaseSpectatorSlot(tx, lobbyID, userID); err != nil {
+		return err
+	}
+
+	result := tx.Where("lobby_id = ? AND user_id = ? AND queue_type = ?", lobbyID, userID, QueueSpectator).Delete(&models.LobbyQueue{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return ReindexQueue(tx, lobbyID)
+}
+
+// PromoteSpectators admits users from the lobby's spectator queue, first in
+// line first, until its spectator cap is reached. It returns the admitted
+// users.
+func PromoteSpectators(tx *gorm.DB, lobbyID uuid.UUID) ([]uuid.UUID, error) {
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lobbyID).First(&lobby).Error; err != nil {
+		return nil, err
+	}
+
+	if !lobby.SpectatorAllowed || lobby.SpectatorCount >= lobby.MaxSpectators {
+		return nil, nil
+	}
+
+	var entries []models.LobbyQueue
+	if err := tx.Where("lobby_id = ? AND queue_type = ?", lobbyID, QueueSpectator).
+		Order("priority desc, position asc").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	var promoted []uuid.UUID
+	for _, entry := range entries {
+		if lobby.SpectatorCount >= lobby.MaxSpectators {
+			break
+		}
+
+		if err := tx.Delete(&entry).Error; err != nil {
+			return nil, err
+		}
+
+		// Users who took a seat while they waited no longer need to watch.
+		var seated int64
+		if err := tx.Model(&models.Player{}).Where("lobby_id = ? AND user_id = ?", lobbyID, entry.UserID).Count(&seated).Error; err != nil {
+			return nil, err
+		}
+		if seated > 0 {
+			continue
+		}
+
+		if err := AddSpectator(tx, &lobby, entry.UserID); err != nil {
+			return nil, err
+		}
+
+		if err := CreateNotification(tx, entry.UserID, "spectator_queue_promoted", map[string]interface{}{
+			"lobby_id":   lobby.ID,
+			"lobby_name": lobby.Name,
+			"message":    "Room opened up and you are now spectating the lobby",
+		}); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, entry.UserID)
+	}
+
+	return promoted, ReindexQueue(tx, lobbyID)
+}
+
+// ClearSpectators removes every spectator of the lobby and empties its
+// spectator queue, for when the lobby stops allowing spectators.
+func ClearSpectators(tx *gorm.DB, lobbyID uuid.UUID) error {
+	if err := tx.Where("lobby_id = ?", lobbyID).Delete(&models.LobbySpectator{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("lobby_id = ? AND queue_type = ?", lobbyID, QueueSpectator).Delete(&models.LobbyQueue{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Lobby{}).Where("id = ?", lobbyID).Update("spectator_count", 0).Error
+}
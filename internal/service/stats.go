@@ -0,0 +1,212 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserStats summarises a user's finished games. A win is finishing first, or
+// in team games being on the team that did.
+type UserStats struct {
+	GamesPlayed   int     `json:"games_played"`
+	Wins          int     `json:"wins"`
+	ShitheadCount int     `json:"shithead_count"`
+	WinRate       float64 `json:"win_rate"`
+	// CurrentStreak counts the wins in a row up to the latest game, and
+	// BestStreak the longest such run ever.
+	CurrentStreak      int                  `json:"current_streak"`
+	BestStreak         int                  `json:"best_streak"`
+	AverageGameSeconds int                  `json:"average_game_seconds"`
+	Modes              map[string]ModeStats `json:"modes"`
+	// Partners breaks team games down by teammate, most games together
+	// first.
+	Partners []PartnerStats `json:"partners"`
+}
+
+type ModeStats struct {
+	GamesPlayed int     `json:"games_played"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"win_rate"`
+}
+
+type PartnerStats struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	GamesPlayed int       `json:"games_played"`
+	Wins        int       `json:"wins"`
+	WinRate     float64   `json:"win_rate"`
+}
+
+// Versus compares two users over the games they both finished. A user wins
+// a game against the other by finishing ahead of them.
+type Versus struct {
+	UserID                uuid.UUID    `json:"user_id"`
+	OtherUserID           uuid.UUID    `json:"other_user_id"`
+	GamesPlayed           int          `json:"games_played"`
+	Wins                  int          `json:"wins"`
+	OtherWins             int          `json:"other_wins"`
+	AveragePlacement      float64      `json:"average_placement"`
+	OtherAveragePlacement float64      `json:"other_average_placement"`
+	RecentGames           []VersusGame `json:"recent_games"`
+}
+
+type VersusGame struct {
+	GameID         uuid.UUID `json:"game_id"`
+	GameMode       string    `json:"game_mode"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Placement      int       `json:"placement"`
+	OtherPlacement int       `json:"other_placement"`
+	WinnerID       uuid.UUID `json:"winner_id"`
+}
+
+// VersusRecentGames is how many of the latest shared games Versus lists.
+const VersusRecentGames = 10
+
+type StatsService interface {
+	// UserStats aggregates the match results the user took part in. Users
+	// without any finished game get zeroed stats.
+	UserStats(ctx context.Context, userID uuid.UUID) (UserStats, error)
+	// Versus aggregates the match results both users took part in, seen
+	// from userID.
+	Versus(ctx context.Context, userID, otherID uuid.UUID) (Versus, error)
+}
+
+type statsService struct {
+	db database.Service
+}
+
+func NewStatsService(db database.Service) StatsService {
+	return &statsService{
+		db: db,
+	}
+}
+
+func (s *statsService) UserStats(ctx context.Context, userID uuid.UUID) (UserStats, error) {
+	var rows []struct {
+		Placement       int
+		TeamPlacement   *int
+		GameMode        string
+		DurationSeconds int
+		ShitheadUserID  *uuid.UUID
+		FinishedAt      time.Time
+	}
+	if err := s.db.DB().WithContext(ctx).Model(&models.MatchParticipant{}).
+		Select("match_participants.placement, match_participants.team_placement, match_results.game_mode, match_results.duration_seconds, match_results.shithead_user_id, match_results.finished_at").
+		Joins("JOIN match_results ON match_results.id = match_participants.match_result_id").
+		Where("match_participants.user_id = ?", userID).
+		Order("match_results.finished_at ASC, match_results.id ASC").
+		Scan(&rows).Error; err != nil {
+		return UserStats{}, err
+	}
+
+	stats := UserStats{Modes: make(map[string]ModeStats)}
+	totalSeconds := 0
+	for _, row := range rows {
+		won := row.Placement == 1
+		if row.TeamPlacement != nil {
+			won = *row.TeamPlacement == 1
+		}
+
+		stats.GamesPlayed++
+		totalSeconds += row.DurationSeconds
+		if row.ShitheadUserID != nil && *row.ShitheadUserID == userID {
+			stats.ShitheadCount++
+		}
+
+		mode := stats.Modes[row.GameMode]
+		mode.GamesPlayed++
+		if won {
+			stats.Wins++
+			mode.Wins++
+			stats.CurrentStreak++
+			stats.BestStreak = max(stats.BestStreak, stats.CurrentStreak)
+		} else {
+			stats.CurrentStreak = 0
+		}
+		stats.Modes[row.GameMode] = mode
+	}
+
+	if stats.GamesPlayed > 0 {
+		stats.WinRate = winRate(stats.Wins, stats.GamesPlayed)
+		stats.AverageGameSeconds = totalSeconds / stats.GamesPlayed
+	}
+	for name, mode := range stats.Modes {
+		mode.WinRate = winRate(mode.Wins, mode.GamesPlayed)
+		stats.Modes[name] = mode
+	}
+
+	stats.Partners = []PartnerStats{}
+	if err := s.db.DB().WithContext(ctx).Raw(`
+SELECT b.user_id, u.name, COUNT(*) AS games_played, COUNT(*) FILTER (WHERE a.team_placement = 1) AS wins
+FROM match_participants a
+JOIN match_participants b ON b.match_result_id = a.match_result_id AND b.team = a.team AND b.user_id <> a.user_id
+JOIN users u ON u.id = b.user_id
+WHERE a.user_id = ? AND a.team IS NOT NULL
+GROUP BY b.user_id, u.name
+ORDER BY games_played DESC, wins DESC, b.user_id ASC`, userID).Scan(&stats.Partners).Error; err != nil {
+		return UserStats{}, err
+	}
+	for i, partner := range stats.Partners {
+		stats.Partners[i].WinRate = winRate(partner.Wins, partner.GamesPlayed)
+	}
+	return stats, nil
+}
+
+func (s *statsService) Versus(ctx context.Context, userID, otherID uuid.UUID) (Versus, error) {
+	var rows []VersusGame
+	if err := s.db.DB().WithContext(ctx).Raw(`
+SELECT mr.game_id, mr.game_mode, mr.finished_at, a.placement, b.placement AS other_placement
+FROM match_participants a
+JOIN match_participants b ON b.match_result_id = a.match_result_id
+JOIN match_results mr ON mr.id = a.match_result_id
+WHERE a.user_id = ? AND b.user_id = ?
+ORDER BY mr.finished_at DESC, mr.id DESC`, userID, otherID).Scan(&rows).Error; err != nil {
+		return Versus{}, err
+	}
+
+	versus := Versus{
+		UserID:      userID,
+		OtherUserID: otherID,
+		GamesPlayed: len(rows),
+		RecentGames: make([]VersusGame, 0, min(len(rows), VersusRecentGames)),
+	}
+
+	placements, otherPlacements := 0, 0
+	for i, row := range rows {
+		placements += row.Placement
+		otherPlacements += row.OtherPlacement
+
+		if row.Placement < row.OtherPlacement {
+			versus.Wins++
+			row.WinnerID = userID
+		} else {
+			versus.OtherWins++
+			row.WinnerID = otherID
+		}
+
+		if i < VersusRecentGames {
+			versus.RecentGames = append(versus.RecentGames, row)
+		}
+	}
+
+	if versus.GamesPlayed > 0 {
+		versus.AveragePlacement = averagePlacement(placements, versus.GamesPlayed)
+		versus.OtherAveragePlacement = averagePlacement(otherPlacements, versus.GamesPlayed)
+	}
+	return versus, nil
+}
+
+// averagePlacement is rounded to two decimal places.
+func averagePlacement(total, games int) float64 {
+	return math.Round(float64(total)/float64(games)*100) / 100
+}
+
+// winRate is the share of games won, rounded to four decimal places.
+func winRate(wins, games int) float64 {
+	return math.Round(float64(wins)/float64(games)*10000) / 10000
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"api/internal/database"
+	"api/internal/database/models"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UserService interface {
+	Find(ctx context.Context, id uuid.UUID) (models.User, error)
+	// Session looks up a cookie session by its ID.
+	Session(ctx context.Context, sessionID string) (models.Session, error)
+	// Search returns a page of the users matching search, best match first,
+	// along with the total number of matches.
+	Search(ctx context.Context, search UserSearch) ([]models.User, int64, error)
+	// UpdateProfile saves the user's name, email, avatar and profile
+	// details, failing with ErrEmailTaken if another account already uses
+	// the email.
+	UpdateProfile(ctx context.Context, user *models.User) error
+	EmailInUse(ctx context.Context, email string, exceptID uuid.UUID) (bool, error)
+	ChangePassword(ctx context.Context, id uuid.UUID, current, replacement string) error
+	// Delete removes the user and returns the deleted record so the caller
+	// can clean up files it references.
+	Delete(ctx context.Context, id uuid.UUID) (models.User, error)
+
+	// Privacy returns the user's privacy settings, or the defaults when they
+	// never changed them.
+	Privacy(ctx context.Context, userID uuid.UUID) (models.PrivacySettings, error)
+	UpdatePrivacy(ctx context.Context, settings *models.PrivacySettings) error
+	// CanViewProfile reports whether the viewer may see the target's
+	// profile under the target's visibility setting. Users who blocked one
+	// another never can.
+	CanViewProfile(ctx context.Context, viewerID uuid.UUID, target models.User) (bool, error)
+}
+
+type userService struct {
+	db database.Service
+}
+
+func NewUserService(db database.Service) UserService {
+	return &userService{
+		db: db,
+	}
+}
+
+func (s *userService) Find(ctx context.Context, id uuid.UUID) (models.User, error) {
+	var user models.User
+	if err := s.db.DB().WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, ErrUserNotFound
+		}
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *userService) Session(ctx context.Context, sessionID string) (models.Session, error) {
+	var session models.Session
+	if sessionID == "" {
+		return session, ErrSessionNotFound
+	}
+	if err := s.db.DB().WithContext(ctx).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, ErrSessionNotFound
+		}
+		return session, err
+	}
+	return session, nil
+}
+
+// UserSearch describes a user search. Query matches names and display names
+// case-insensitively, and email addresses only in full. The viewer and users
+// blocked on either side are never returned; with LobbyID set, neither are
+// users already seated in or invited to that lobby.
+type UserSearch struct {
+	Query    string
+	ViewerID uuid.UUID
+	LobbyID  *uuid.UUID
+	Offset   int
+	Limit    int
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (s *userService) Search(ctx context.Context, search UserSearch) ([]models.User, int64, error) {
+	escaped := likeEscaper.Replace(search.Query)
+	contains := "%" + escaped + "%"
+	prefix := escaped + "%"
+
+	query := s.db.DB().WithContext(ctx).Model(&models.User{}).
+		Where("(name ILIKE ? OR display_name ILIKE ? OR LOWER(email) = LOWER(?))", contains, contains, search.Query).
+		Where("id <> ?", search.ViewerID).
+		Where("id NOT IN (SELECT addressee_id FROM friendships WHERE requester_id = ? AND status = ?)", search.ViewerID, "blocked").
+		Where("id NOT IN (SELECT requester_id FROM friendships WHERE addressee_id = ? AND status = ?)", search.ViewerID, "blocked")
+	if search.LobbyID != nil {
+		query = query.
+			Where("id NOT IN (SELECT user_id FROM players WHERE lobby_id = ?)", *search.LobbyID).
+			Where("id NOT IN (SELECT invited_user_id FROM lobby_invitations WHERE lobby_id = ? AND status = ?)", *search.LobbyID, "pending")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Exact matches rank first, then names starting with the query, then
+	// the rest; shorter names win ties since more of them matched.
+	var users []models.User
+	err := query.
+		Select("id, name, display_name, avatar, xp").
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL: `CASE
+    WHEN LOWER(name) = LOWER(?) OR LOWER(display_name) = LOWER(?) OR LOWER(email) = LOWER(?) THEN 0
+    WHEN name ILIKE ? OR display_name ILIKE ? THEN 1
+    ELSE 2
+END, LENGTH(name), name, id`,
+			Vars:               []interface{}{search.Query, search.Query, search.Query, prefix, prefix},
+			WithoutParentheses: true,
+		}}).
+		Offset(search.Offset).
+		Limit(search.Limit).
+		Find(&users).Error
+	return users, total, err
+}
+
+func (s *userService) EmailInUse(ctx context.Context, email string, exceptID uuid.UUID) (bool, error) {
+	var count int64
+	err := s.db.DB().WithContext(ctx).Model(&models.User{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (s *userService) UpdateProfile(ctx context.Context, user *models.User) error {
+	taken, err := s.EmailInUse(ctx, user.Email, user.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	return s.db.DB().WithContext(ctx).Model(user).Updates(map[string]interface{}{
+		"name":         user.Name,
+		"email":        user.Email,
+		"avatar":       user.Avatar,
+		"display_name": user.DisplayName,
+		"bio":          user.Bio,
+		"country":      user.Country,
+	}).Error
+}
+
+func (s *userService) ChangePassword(ctx context.Context, id uuid.UUID, current, replacement string) error {
+	user, err := s.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)); err != nil {
+		return ErrPasswordMismatch
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(replacement), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.DB().WithContext(ctx).Model(&user).Update("password", string(hashed)).Error
+}
+
+func (s *userService) Delete(ctx context.Context, id uuid.UUID) (models.User, error) {
+	user, err := s.Find(ctx, id)
+	if err != nil {
+		return user, err
+	}
+	return user, s.db.DB().WithContext(ctx).Delete(&user).Error
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"api/internal/database/models"
+	"api/internal/game/progression"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GrantXP adds amount XP to the user inside tx and records it in the XP
+// ledger with the levels before and after, so callers can announce level-ups
+// once tx commits. gameID is set for XP earned in a game.
+func GrantXP(tx *gorm.DB, userID uuid.UUID, amount int, source string, gameID *uuid.UUID) (models.XPEvent, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id, xp").
+		Where("id = ?", userID).
+		First(&user).Error; err != nil {
+		return models.XPEvent{}, err
+	}
+
+	event := models.XPEvent{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Amount:      amount,
+		Source:      source,
+		GameID:      gameID,
+		LevelBefore: progression.LevelFor(user.XP),
+		LevelAfter:  progression.LevelFor(user.XP + amount),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("xp", user.XP+amount).Error; err != nil {
+		return event, err
+	}
+	return event, tx.Create(&event).Error
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	s3Service        = "s3"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3RequestTimeout = 30 * time.Second
+	// s3MaxURLExpiry is the longest validity S3 accepts for a signed URL.
+	s3MaxURLExpiry = 7 * 24 * time.Hour
+)
+
+// S3 keeps files in a bucket of any S3-compatible service, signing requests
+// with the AWS SDK's Signature Version 4 signer.
+type S3 struct {
+	cfg         S3Config
+	endpoint    *url.URL
+	publicURL   string
+	client      *http.Client
+	signer      *v4.Signer
+	credentials aws.Credentials
+}
+
+func NewS3(cfg S3Config, publicURL string) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 storage needs a bucket, access key and secret key")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.URLExpiry <= 0 || cfg.URLExpiry > s3MaxURLExpiry {
+		cfg.URLExpiry = time.Hour
+	}
+
+	return &S3{
+		cfg:       cfg,
+		endpoint:  endpoint,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		client:    &http.Client{Timeout: s3RequestTimeout},
+		// S3 signs the path as sent rather than escaping it a second time.
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+		credentials: aws.Credentials{
+			AccessKeyID:     cfg.AccessKey,
+			SecretAccessKey: cfg.SecretKey,
+		},
+	}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(payload))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	sum := sha256.Sum256(payload)
+	return s.do(req, hex.EncodeToString(sum[:]))
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	// S3 answers 204 whether or not the object existed.
+	return s.do(req, emptyPayloadHash)
+}
+
+func (s *S3) URL(key string) (string, error) {
+	if s.publicURL != "" {
+		return s.publicURL + "/" + key, nil
+	}
+	return s.presign(key, time.Now().UTC())
+}
+
+// do signs and sends req, turning any non-2xx answer into an error.
+func (s *S3) do(req *http.Request, payloadHash string) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(req.Context(), s.credentials, req, payloadHash, s3Service, s.cfg.Region, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// presign builds a GET URL for key that is valid for the configured expiry
+// without any credentials.
+func (s *S3) presign(key string, now time.Time) (string, error) {
+	target := s.objectURL(key)
+	target.RawQuery = "X-Amz-Expires=" + strconv.Itoa(int(s.cfg.URLExpiry.Seconds()))
+
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	signed, _, err := s.signer.PresignHTTP(context.Background(), s.credentials, req, s3UnsignedBody, s3Service, s.cfg.Region, now)
+	return signed, err
+}
+
+// objectURL addresses key in the bucket, path style or virtual-hosted.
+func (s *S3) objectURL(key string) *url.URL {
+	target := *s.endpoint
+	path := "/" + key
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	} else {
+		target.Host = s.cfg.Bucket + "." + target.Host
+	}
+	basePath := strings.TrimRight(target.Path, "/")
+	target.Path = basePath + path
+	target.RawPath = escapePath(basePath) + escapePath(path)
+	return &target
+}
+
+// emptyPayloadHash is the SHA-256 of an empty body.
+var emptyPayloadHash = func() string {
+	sum := sha256.Sum256(nil)
+	return hex.EncodeToString(sum[:])
+}()
+
+// escapePath percent-encodes everything in path but slashes and unreserved
+// characters, which is the form S3 signs object keys in.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+// Package storage keeps user uploads, such as avatars, on local disk or in an
+// S3-compatible bucket so every API instance sees the same files.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Storage saves and serves files by key, a slash-separated path such as
+// "avatars/<id>.png".
+type Storage interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Delete removes the file. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can fetch the file: a public URL, or a
+	// signed one that expires when the bucket is private.
+	URL(key string) (string, error)
+}
+
+type Config struct {
+	// Driver is "local" or "s3".
+	Driver string
+	// PublicURL is the base URL files are served from, such as a CDN. The
+	// local driver requires it; with S3 an empty PublicURL makes URL sign
+	// each link instead.
+	PublicURL string
+
+	// LocalRoot is the directory the local driver writes into.
+	LocalRoot string
+
+	S3 S3Config
+}
+
+type S3Config struct {
+	// Endpoint is the service's base URL, e.g.
+	// https://s3.eu-central-1.amazonaws.com or http://minio:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle puts the bucket in the path rather than the host name, as
+	// MinIO and most self-hosted services expect.
+	PathStyle bool
+	// URLExpiry is how long signed URLs stay valid.
+	URLExpiry time.Duration
+}
+
+// New returns the storage selected by cfg.Driver.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocal(cfg.LocalRoot, cfg.PublicURL), nil
+	case "s3":
+		return NewS3(cfg.S3, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}